@@ -61,7 +61,7 @@ func main() {
 	flag.Parse()
 	ctx := context.Background()
 
-	env, err := integration.NewEnv()
+	env, err := integration.NewEnv(integration.SQLBackend)
 	if err != nil {
 		glog.Fatalf("Could not create Env: %v", err)
 	}
@@ -0,0 +1,119 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// keytransparency-archive exports the mutations of revisions older than
+// --max-age into segment files under --archive-dir, and deletes them from the
+// database. Servers started with the same --archive-dir keep serving the
+// archived revisions.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/google/keytransparency/core/archive"
+	"github.com/google/keytransparency/impl/sql/domain"
+	"github.com/google/keytransparency/impl/sql/engine"
+	"github.com/google/keytransparency/impl/sql/mutationstorage"
+
+	"github.com/golang/glog"
+	"github.com/google/trillian"
+	"google.golang.org/grpc"
+
+	domaindef "github.com/google/keytransparency/core/domain"
+	tclient "github.com/google/trillian/client"
+)
+
+var (
+	serverDBPath     = flag.String("db", "db", "Database connection string")
+	mapURL           = flag.String("map-url", "", "URL of Trillian Map Server")
+	domainID         = flag.String("domain", "", "Domain to archive. Defaults to all domains")
+	archiveDir       = flag.String("archive-dir", "", "Directory to write segment files to")
+	maxAge           = flag.Duration("max-age", 30*24*time.Hour, "Archive revisions older than this")
+	segmentRevisions = flag.Int64("segment-revisions", 1000, "Maximum number of revisions per segment file")
+)
+
+func openDB() *sql.DB {
+	db, err := sql.Open(engine.DriverName, *serverDBPath)
+	if err != nil {
+		glog.Exitf("sql.Open(): %v", err)
+	}
+	if err := db.Ping(); err != nil {
+		glog.Exitf("db.Ping(): %v", err)
+	}
+	return db
+}
+
+func main() {
+	flag.Parse()
+	ctx := context.Background()
+	if *archiveDir == "" {
+		glog.Exitf("Please specify an archive directory")
+	}
+
+	mconn, err := grpc.Dial(*mapURL, grpc.WithInsecure())
+	if err != nil {
+		glog.Exitf("grpc.Dial(%v): %v", *mapURL, err)
+	}
+	defer mconn.Close()
+	tmap := trillian.NewTrillianMapClient(mconn)
+	mapAdmin := trillian.NewTrillianAdminClient(mconn)
+
+	sqldb := openDB()
+	defer sqldb.Close()
+	mutations, err := mutationstorage.New(sqldb)
+	if err != nil {
+		glog.Exitf("Failed to create mutations object: %v", err)
+	}
+	domainStorage, err := domain.NewStorage(sqldb)
+	if err != nil {
+		glog.Exitf("Failed to create domain storage object: %v", err)
+	}
+
+	var domains []*domaindef.Domain
+	if *domainID != "" {
+		d, err := domainStorage.Read(ctx, *domainID, true)
+		if err != nil {
+			glog.Exitf("Read(%v): %v", *domainID, err)
+		}
+		domains = append(domains, d)
+	} else if domains, err = domainStorage.List(ctx, true); err != nil {
+		glog.Exitf("List(): %v", err)
+	}
+
+	a := archive.New(tmap, mutations, mutations, archive.NewDir(*archiveDir), *segmentRevisions)
+	before := time.Now().Add(-*maxAge)
+	for _, d := range domains {
+		mapTree, err := mapAdmin.GetTree(ctx, &trillian.GetTreeRequest{TreeId: d.MapID})
+		if err != nil {
+			glog.Exitf("GetTree(%v): %v", d.MapID, err)
+		}
+		mapVerifier, err := tclient.NewMapVerifierFromTree(mapTree)
+		if err != nil {
+			glog.Exitf("NewMapVerifierFromTree(): %v", err)
+		}
+		last, err := a.Archive(ctx, d, mapVerifier, before)
+		if err != nil {
+			glog.Exitf("Archive(%v): %v", d.DomainID, err)
+		}
+		if last < 0 {
+			fmt.Printf("Domain %v: no revisions older than %v\n", d.DomainID, before)
+			continue
+		}
+		fmt.Printf("Domain %v: revisions 0 to %v are archived\n", d.DomainID, last)
+	}
+}
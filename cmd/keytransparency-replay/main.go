@@ -0,0 +1,113 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// keytransparency-replay recomputes the map roots of a domain from the
+// mutations in the database and reports the first revision whose root differs
+// from the map root stored by the Trillian map server.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+
+	"github.com/google/keytransparency/core/archive"
+	"github.com/google/keytransparency/core/mutator"
+	"github.com/google/keytransparency/core/mutator/entry"
+	"github.com/google/keytransparency/core/replay"
+	"github.com/google/keytransparency/impl/sql/domain"
+	"github.com/google/keytransparency/impl/sql/engine"
+	"github.com/google/keytransparency/impl/sql/mutationstorage"
+
+	"github.com/golang/glog"
+	"github.com/google/trillian"
+	"google.golang.org/grpc"
+
+	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
+	tclient "github.com/google/trillian/client"
+)
+
+var (
+	serverDBPath = flag.String("db", "db", "Database connection string")
+	mapURL       = flag.String("map-url", "", "URL of Trillian Map Server")
+	domainID     = flag.String("domain", "", "Domain to replay")
+	archiveDir   = flag.String("archive-dir", "", "Directory of the segment files written by keytransparency-archive, if any")
+)
+
+func openDB() *sql.DB {
+	db, err := sql.Open(engine.DriverName, *serverDBPath)
+	if err != nil {
+		glog.Exitf("sql.Open(): %v", err)
+	}
+	if err := db.Ping(); err != nil {
+		glog.Exitf("db.Ping(): %v", err)
+	}
+	return db
+}
+
+func main() {
+	flag.Parse()
+	ctx := context.Background()
+	if *domainID == "" {
+		glog.Exitf("Please specify a domain")
+	}
+
+	mconn, err := grpc.Dial(*mapURL, grpc.WithInsecure())
+	if err != nil {
+		glog.Exitf("grpc.Dial(%v): %v", *mapURL, err)
+	}
+	defer mconn.Close()
+	tmap := trillian.NewTrillianMapClient(mconn)
+	mapAdmin := trillian.NewTrillianAdminClient(mconn)
+
+	sqldb := openDB()
+	defer sqldb.Close()
+	mutations, err := mutationstorage.New(sqldb)
+	if err != nil {
+		glog.Exitf("Failed to create mutations object: %v", err)
+	}
+	domainStorage, err := domain.NewStorage(sqldb)
+	if err != nil {
+		glog.Exitf("Failed to create domain storage object: %v", err)
+	}
+
+	d, err := domainStorage.Read(ctx, *domainID, true)
+	if err != nil {
+		glog.Exitf("Read(%v): %v", *domainID, err)
+	}
+	mapTree, err := mapAdmin.GetTree(ctx, &trillian.GetTreeRequest{TreeId: d.MapID})
+	if err != nil {
+		glog.Exitf("GetTree(%v): %v", d.MapID, err)
+	}
+	mapVerifier, err := tclient.NewMapVerifierFromTree(mapTree)
+	if err != nil {
+		glog.Exitf("NewMapVerifierFromTree(): %v", err)
+	}
+
+	mutators := mutator.Registry{pb.SequencingConfig_ENTRY: entry.New()}
+	var mutationStore mutator.MutationStorage = mutations
+	if *archiveDir != "" {
+		mutationStore = archive.NewStorage(mutations, archive.NewDir(*archiveDir))
+	}
+	r := replay.New(tmap, mutationStore, mutators)
+	latest, err := r.Replay(ctx, d, mapVerifier)
+	if derr, ok := err.(*replay.DivergenceError); ok {
+		glog.Exitf("Domain %v diverges at revision %v: recomputed root %x, stored root %x",
+			d.DomainID, derr.Revision, derr.Got, derr.Want)
+	} else if err != nil {
+		glog.Exitf("Replay(%v): %v", d.DomainID, err)
+	}
+	fmt.Printf("Domain %v: revisions 0 to %v match the stored map roots\n", d.DomainID, latest)
+}
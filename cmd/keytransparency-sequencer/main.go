@@ -18,13 +18,18 @@ import (
 	"context"
 	"database/sql"
 	"flag"
+	"fmt"
+	"os"
 	"time"
 
+	"github.com/google/keytransparency/cmd/serverutil"
 	"github.com/google/keytransparency/core/adminserver"
 	"github.com/google/keytransparency/core/mutator"
 	"github.com/google/keytransparency/core/mutator/entry"
 	"github.com/google/keytransparency/core/sequencer"
+	"github.com/google/keytransparency/core/sequencer/alert"
 	"github.com/google/keytransparency/impl/sql/domain"
+	"github.com/google/keytransparency/impl/sql/election"
 	"github.com/google/keytransparency/impl/sql/engine"
 	"github.com/google/keytransparency/impl/sql/mutationstorage"
 
@@ -35,6 +40,8 @@ import (
 
 	"github.com/google/trillian/crypto/keys/der"
 	"github.com/google/trillian/crypto/keyspb"
+
+	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
 )
 
 var (
@@ -44,6 +51,16 @@ var (
 	mapURL  = flag.String("map-url", "", "URL of Trillian Map Server")
 	logURL  = flag.String("log-url", "", "URL of Trillian Log Server for Signed Map Heads")
 	refresh = flag.Duration("domain-refresh", 5*time.Second, "Time to detect new domain")
+
+	// Leader election between sequencer replicas.
+	electionID  = flag.String("election-id", "", "Unique identifier of this replica in master elections. Defaults to hostname and pid")
+	masterLease = flag.Duration("master-lease", 30*time.Second, "How long mastership of a domain lasts without renewal. Must be longer than domain-refresh")
+
+	// Emergency alerts.
+	alertWebhook = flag.String("alert-webhook", "", "URL to POST emergency alerts to, as JSON")
+	alertExec    = flag.String("alert-exec", "", "Command to run for each emergency alert, with the alert as JSON on stdin")
+	alertFile    = flag.String("alert-file", "", "File to append emergency alerts to, one JSON object per line")
+	alertTimeout = flag.Duration("alert-timeout", 10*time.Second, "Timeout for delivering an alert to each of alert-webhook, alert-exec and alert-file")
 )
 
 func openDB() *sql.DB {
@@ -57,9 +74,37 @@ func openDB() *sql.DB {
 	return db
 }
 
+// alertSink returns the sink for the alert flags that are set, or nil if none
+// are set.
+func alertSink() alert.Sink {
+	var sinks []alert.Sink
+	if *alertWebhook != "" {
+		sinks = append(sinks, alert.WithTimeout(alert.NewWebhook(*alertWebhook, *alertTimeout), *alertTimeout))
+	}
+	if *alertExec != "" {
+		sinks = append(sinks, alert.WithTimeout(alert.NewExec(*alertExec), *alertTimeout))
+	}
+	if *alertFile != "" {
+		sinks = append(sinks, alert.WithTimeout(alert.NewFile(*alertFile), *alertTimeout))
+	}
+	if len(sinks) == 0 {
+		return nil
+	}
+	return alert.Multi(sinks...)
+}
+
 func main() {
 	flag.Parse()
 
+	if flag.Arg(0) == "migrate" {
+		sqldb := openDB()
+		defer sqldb.Close()
+		if err := serverutil.Migrate(context.Background(), sqldb, flag.Args()[1:], os.Stdout); err != nil {
+			glog.Exitf("migrate: %v", err)
+		}
+		return
+	}
+
 	// Connect to trillian log and map backends.
 	mconn, err := grpc.Dial(*mapURL, grpc.WithInsecure())
 	if err != nil {
@@ -87,13 +132,29 @@ func main() {
 		glog.Exitf("Failed to create domain storage object: %v", err)
 	}
 	queue := mutator.MutationQueue(mutations)
+	if *masterLease <= *refresh {
+		glog.Exitf("master-lease (%v) must be longer than domain-refresh (%v)", *masterLease, *refresh)
+	}
+	id := *electionID
+	if id == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			glog.Exitf("os.Hostname(): %v", err)
+		}
+		id = fmt.Sprintf("%s.%d", hostname, os.Getpid())
+	}
+	elections, err := election.NewFactory(sqldb, id, *masterLease)
+	if err != nil {
+		glog.Exitf("Failed to create election factory: %v", err)
+	}
 
 	// Create servers
-	signer := sequencer.New(tlog, logAdmin, tmap, mapAdmin, entry.New(), domainStorage, mutations, queue)
+	mutators := mutator.Registry{pb.SequencingConfig_ENTRY: entry.New()}
+	signer := sequencer.New(tlog, logAdmin, tmap, mapAdmin, mutators, domainStorage, mutations, queue, elections, alertSink())
 	keygen := func(ctx context.Context, spec *keyspb.Specification) (proto.Message, error) {
 		return der.NewProtoFromSpec(spec)
 	}
-	adminServer := adminserver.New(tlog, tmap, logAdmin, mapAdmin, domainStorage, keygen)
+	adminServer := adminserver.New(tlog, tmap, logAdmin, mapAdmin, domainStorage, mutations, keygen)
 	glog.Infof("Signer starting")
 
 	// Run servers
@@ -104,6 +165,7 @@ func main() {
 	if err := signer.ListenForNewDomains(cctx, *refresh); err != nil {
 		glog.Errorf("StartSequencingAll(): %v", err)
 	}
+	signer.Close()
 	httpServer.Shutdown(cctx)
 	glog.Errorf("Signer exiting")
 }
@@ -15,12 +15,15 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"flag"
 	"log"
 	"net/http"
+	"os"
 
 	"github.com/google/keytransparency/cmd/serverutil"
+	"github.com/google/keytransparency/core/archive"
 	"github.com/google/keytransparency/core/keyserver"
 	"github.com/google/keytransparency/core/mutator"
 	"github.com/google/keytransparency/core/mutator/entry"
@@ -54,6 +57,11 @@ var (
 
 	mapURL = flag.String("map-url", "", "URL of Trillian Map Server")
 	logURL = flag.String("log-url", "", "URL of Trillian Log Server for Signed Map Heads")
+
+	archiveDir = flag.String("archive-dir", "", "Directory of the segment files written by keytransparency-archive, if any")
+
+	maxQueueDepth     = flag.Int64("max-queue-depth", 0, "Refuse updates while a domain has this many mutations waiting to be sequenced. 0 is unlimited")
+	maxPendingPerUser = flag.Int("max-pending-per-user", 0, "Refuse updates from users with this many mutations waiting to be sequenced. 0 is unlimited")
 )
 
 func openDB() *sql.DB {
@@ -70,6 +78,15 @@ func openDB() *sql.DB {
 func main() {
 	flag.Parse()
 
+	if flag.Arg(0) == "migrate" {
+		sqldb := openDB()
+		defer sqldb.Close()
+		if err := serverutil.Migrate(context.Background(), sqldb, flag.Args()[1:], os.Stdout); err != nil {
+			glog.Exitf("migrate: %v", err)
+		}
+		return
+	}
+
 	// Open Resources.
 	sqldb := openDB()
 	defer sqldb.Close()
@@ -122,8 +139,19 @@ func main() {
 
 	// Create gRPC server.
 	queue := mutator.MutationQueue(mutations)
+	mutators := mutator.Registry{pb.SequencingConfig_ENTRY: entry.New()}
+	var mutationStore mutator.MutationStorage = mutations
+	if *archiveDir != "" {
+		// Serve archived revisions from their segments.
+		mutationStore = archive.NewStorage(mutations, archive.NewDir(*archiveDir))
+	}
 	ksvr := keyserver.New(tlog, tmap, logAdmin, mapAdmin,
-		entry.New(), domains, queue, mutations)
+		mutators, domains, queue, mutationStore)
+	ksvr.SetLimits(keyserver.Limits{
+		MaxQueueDepth:     *maxQueueDepth,
+		MaxPendingPerUser: *maxPendingPerUser,
+	})
+	ksvr.SetAuthorizer(authz.AuthorizeUpdate)
 	grpcServer := grpc.NewServer(
 		grpc.Creds(creds),
 		grpc.StreamInterceptor(grpc_middleware.ChainStreamServer(
@@ -139,6 +167,10 @@ func main() {
 					AuthnFunc: authFunc,
 					AuthzFunc: authz.Authorize,
 				},
+				"/google.keytransparency.v1.KeyTransparency/BatchUpdateEntries": {
+					// The key server authorizes each update.
+					AuthnFunc: authFunc,
+				},
 			}),
 		)),
 	)
@@ -0,0 +1,85 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serverutil
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/google/keytransparency/impl/sql/domain"
+	"github.com/google/keytransparency/impl/sql/election"
+	"github.com/google/keytransparency/impl/sql/keysets"
+	"github.com/google/keytransparency/impl/sql/migrate"
+	"github.com/google/keytransparency/impl/sql/mutationstorage"
+)
+
+// Schemas are the versioned schemas of the SQL tables used by the servers.
+var Schemas = []migrate.Schema{
+	domain.Schema,
+	keysets.Schema,
+	mutationstorage.Schema,
+	election.Schema,
+}
+
+// Migrate runs the migrate subcommand with args, and prints the resulting
+// schema version of every component to w. Without flags, it upgrades all
+// tables to their latest version. -component and -version upgrade or downgrade
+// the tables of one component, and -status only prints the versions.
+func Migrate(ctx context.Context, db *sql.DB, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.SetOutput(w)
+	status := fs.Bool("status", false, "Print the schema versions without migrating")
+	component := fs.String("component", "", "Component whose tables to migrate. Defaults to all")
+	version := fs.Int("version", -1, "Version to migrate the tables of component to. Defaults to the latest")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *version >= 0 && *component == "" {
+		return fmt.Errorf("migrate: -version requires -component")
+	}
+
+	found := false
+	for _, s := range Schemas {
+		if *component != "" && s.Component != *component {
+			continue
+		}
+		found = true
+		if *status {
+			continue
+		}
+		target := *version
+		if target < 0 {
+			target = s.Latest()
+		}
+		if err := s.MigrateTo(ctx, db, target); err != nil {
+			return err
+		}
+	}
+	if !found {
+		return fmt.Errorf("migrate: unknown component %q", *component)
+	}
+
+	for _, s := range Schemas {
+		v, err := migrate.Version(ctx, db, s.Component)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%-10s version %d of %d\n", s.Component, v, s.Latest())
+	}
+	return nil
+}
@@ -18,6 +18,7 @@ package adminserver
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/golang/glog"
@@ -29,6 +30,7 @@ import (
 
 	"github.com/google/keytransparency/core/crypto/vrf/p256"
 	"github.com/google/keytransparency/core/domain"
+	"github.com/google/keytransparency/core/mutator"
 	"github.com/google/trillian/client"
 	"github.com/google/trillian/crypto/keys"
 	"github.com/google/trillian/crypto/keys/der"
@@ -76,14 +78,22 @@ var (
 	}
 )
 
+const (
+	// defaultPageSize and maxPageSize bound the number of rejected mutations
+	// returned by ListRejectedMutations.
+	defaultPageSize = int32(64)
+	maxPageSize     = int32(1024)
+)
+
 // Server implements pb.KeyTransparencyAdminServer
 type Server struct {
-	tlog     tpb.TrillianLogClient
-	tmap     tpb.TrillianMapClient
-	logAdmin tpb.TrillianAdminClient
-	mapAdmin tpb.TrillianAdminClient
-	domains  domain.Storage
-	keygen   keys.ProtoGenerator
+	tlog      tpb.TrillianLogClient
+	tmap      tpb.TrillianMapClient
+	logAdmin  tpb.TrillianAdminClient
+	mapAdmin  tpb.TrillianAdminClient
+	domains   domain.Storage
+	mutations mutator.MutationStorage
+	keygen    keys.ProtoGenerator
 }
 
 // New returns a KeyTransparencyAdmin implementation.
@@ -92,15 +102,17 @@ func New(
 	tmap tpb.TrillianMapClient,
 	logAdmin, mapAdmin tpb.TrillianAdminClient,
 	domains domain.Storage,
+	mutations mutator.MutationStorage,
 	keygen keys.ProtoGenerator,
 ) *Server {
 	return &Server{
-		tlog:     tlog,
-		tmap:     tmap,
-		logAdmin: logAdmin,
-		mapAdmin: mapAdmin,
-		domains:  domains,
-		keygen:   keygen,
+		tlog:      tlog,
+		tmap:      tmap,
+		logAdmin:  logAdmin,
+		mapAdmin:  mapAdmin,
+		domains:   domains,
+		mutations: mutations,
+		keygen:    keygen,
 	}
 }
 
@@ -144,9 +156,28 @@ func (s *Server) fetchDomain(ctx context.Context, d *domain.Domain) (*pb.Domain,
 		MinInterval: ptypes.DurationProto(d.MinInterval),
 		MaxInterval: ptypes.DurationProto(d.MaxInterval),
 		Deleted:     d.Deleted,
+		ReceiptKey:  d.ReceiptKey,
+		Sequencing:  d.Sequencing,
 	}, nil
 }
 
+// validateSequencing verifies that the sequencing config c can be honored.
+// A nil config selects the defaults.
+func validateSequencing(c *pb.SequencingConfig) error {
+	if c.GetMaxBatchSize() < 0 {
+		return fmt.Errorf("max_batch_size %v must not be negative", c.GetMaxBatchSize())
+	}
+	if _, ok := pb.SequencingConfig_Mutator_name[int32(c.GetMutator())]; !ok {
+		return fmt.Errorf("unknown mutator %v", c.GetMutator())
+	}
+	for _, appID := range c.GetAllowedAppIds() {
+		if appID == "" {
+			return fmt.Errorf("allowed_app_ids must not contain an empty app_id")
+		}
+	}
+	return nil
+}
+
 // GetDomain retrieves the domain info for a given domain.
 func (s *Server) GetDomain(ctx context.Context, in *pb.GetDomainRequest) (*pb.Domain, error) {
 	domain, err := s.domains.Read(ctx, in.GetDomainId(), in.GetShowDeleted())
@@ -196,6 +227,9 @@ func (s *Server) CreateDomain(ctx context.Context, in *pb.CreateDomainRequest) (
 		// Domain already exists.
 		return nil, status.Errorf(codes.AlreadyExists, "Domain %v already exists or is soft deleted.", in.GetDomainId())
 	}
+	if err := validateSequencing(in.GetSequencing()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid sequencing config: %v", err)
+	}
 
 	// Generate VRF key.
 	wrapped, err := privKeyOrGen(ctx, in.GetVrfPrivateKey(), s.keygen)
@@ -211,6 +245,20 @@ func (s *Server) CreateDomain(ctx context.Context, in *pb.CreateDomainRequest) (
 		return nil, err
 	}
 
+	// Generate mutation receipt key.
+	receiptPriv, err := privKeyOrGen(ctx, in.GetReceiptPrivateKey(), s.keygen)
+	if err != nil {
+		return nil, fmt.Errorf("adminserver: keygen(): %v", err)
+	}
+	receiptSigner, err := keys.NewSigner(ctx, receiptPriv)
+	if err != nil {
+		return nil, fmt.Errorf("adminserver: NewSigner(): %v", err)
+	}
+	receiptPublicPB, err := der.ToPublicProto(receiptSigner.Public())
+	if err != nil {
+		return nil, err
+	}
+
 	// Create Trillian keys.
 	logTreeArgs := treeConfig(logArgs, in.GetLogPrivateKey(), in.GetDomainId())
 	logTree, err := client.CreateAndInitTree(ctx, logTreeArgs, s.logAdmin, s.tmap, s.tlog)
@@ -250,8 +298,11 @@ func (s *Server) CreateDomain(ctx context.Context, in *pb.CreateDomainRequest) (
 		LogID:       logTree.TreeId,
 		VRF:         vrfPublicPB,
 		VRFPriv:     wrapped,
+		ReceiptKey:  receiptPublicPB,
+		ReceiptPriv: receiptPriv,
 		MinInterval: minInterval,
 		MaxInterval: maxInterval,
+		Sequencing:  in.GetSequencing(),
 	}); err != nil {
 		return nil, fmt.Errorf("adminserver: domains.Write(): %v", err)
 	}
@@ -262,6 +313,8 @@ func (s *Server) CreateDomain(ctx context.Context, in *pb.CreateDomainRequest) (
 		Vrf:         vrfPublicPB,
 		MinInterval: in.MinInterval,
 		MaxInterval: in.MaxInterval,
+		ReceiptKey:  receiptPublicPB,
+		Sequencing:  in.GetSequencing(),
 	}
 	glog.Infof("Created domain: %v", d)
 	return d, nil
@@ -331,3 +384,44 @@ func (s *Server) UndeleteDomain(ctx context.Context, in *pb.UndeleteDomainReques
 	}
 	return &google_protobuf.Empty{}, nil
 }
+
+// ListRejectedMutations returns the mutations that were rejected while
+// creating an epoch.
+func (s *Server) ListRejectedMutations(ctx context.Context, in *pb.ListRejectedMutationsRequest) (*pb.ListRejectedMutationsResponse, error) {
+	if in.GetDomainId() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Please specify a domain_id")
+	}
+	if in.GetEpoch() < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid epoch %v", in.GetEpoch())
+	}
+	pageSize := in.GetPageSize()
+	switch {
+	case pageSize < 0:
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid page size %v", pageSize)
+	case pageSize == 0:
+		pageSize = defaultPageSize
+	case pageSize > maxPageSize:
+		pageSize = maxPageSize
+	}
+	start := int64(0)
+	if token := in.GetPageToken(); token != "" {
+		var err error
+		if start, err = strconv.ParseInt(token, 10, 64); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "%v is not a valid sequence number", token)
+		}
+	}
+
+	rejected, err := s.mutations.ReadRejectedPage(ctx, in.GetDomainId(), in.GetEpoch(), start, pageSize)
+	if err != nil {
+		glog.Errorf("ReadRejectedPage(%v, %v): %v", in.GetDomainId(), in.GetEpoch(), err)
+		return nil, status.Errorf(codes.Internal, "Cannot read rejected mutations")
+	}
+	nextPageToken := ""
+	if len(rejected) == int(pageSize) {
+		nextPageToken = fmt.Sprintf("%d", rejected[len(rejected)-1].GetSequence()+1)
+	}
+	return &pb.ListRejectedMutationsResponse{
+		Mutations:     rejected,
+		NextPageToken: nextPageToken,
+	}, nil
+}
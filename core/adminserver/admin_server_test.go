@@ -87,10 +87,11 @@ func (e *miniEnv) Close() {
 
 func TestCreateDomain(t *testing.T) {
 	for _, tc := range []struct {
-		desc     string
-		domainID string
-		wantCode codes.Code
-		expect   func(*miniEnv)
+		desc       string
+		domainID   string
+		sequencing *pb.SequencingConfig
+		wantCode   codes.Code
+		expect     func(*miniEnv)
 	}{
 		{
 			desc:     "Already Exists",
@@ -98,6 +99,27 @@ func TestCreateDomain(t *testing.T) {
 			wantCode: codes.AlreadyExists,
 			expect:   func(e *miniEnv) {},
 		},
+		{
+			desc:       "Negative batch size",
+			domainID:   "negativebatch",
+			sequencing: &pb.SequencingConfig{MaxBatchSize: -1},
+			wantCode:   codes.InvalidArgument,
+			expect:     func(e *miniEnv) {},
+		},
+		{
+			desc:       "Unknown mutator",
+			domainID:   "unknownmutator",
+			sequencing: &pb.SequencingConfig{Mutator: 100},
+			wantCode:   codes.InvalidArgument,
+			expect:     func(e *miniEnv) {},
+		},
+		{
+			desc:       "Empty app id",
+			domainID:   "emptyappid",
+			sequencing: &pb.SequencingConfig{AllowedAppIds: []string{"app", ""}},
+			wantCode:   codes.InvalidArgument,
+			expect:     func(e *miniEnv) {},
+		},
 		{
 			desc:     "Create map fails",
 			domainID: "mapinitfails",
@@ -145,6 +167,7 @@ func TestCreateDomain(t *testing.T) {
 				DomainId:    tc.domainID,
 				MinInterval: ptypes.DurationProto(60 * time.Hour),
 				MaxInterval: ptypes.DurationProto(60 * time.Hour),
+				Sequencing:  tc.sequencing,
 			}); status.Code(err) != tc.wantCode {
 				t.Errorf("CreateDomain(): %v, want %v", err, tc.wantCode)
 			}
@@ -171,22 +194,30 @@ func TestCreateRead(t *testing.T) {
 		t.Fatalf("Failed to create trillian log server: %v", err)
 	}
 
-	svr := New(logEnv.Log, mapEnv.Map, logEnv.Admin, mapEnv.Admin, storage, vrfKeyGen)
+	svr := New(logEnv.Log, mapEnv.Map, logEnv.Admin, mapEnv.Admin, storage, fake.NewMutationStorage(), vrfKeyGen)
 
 	for _, tc := range []struct {
 		domainID                 string
 		minInterval, maxInterval time.Duration
+		sequencing               *pb.SequencingConfig
 	}{
 		{
 			domainID:    "testdomain",
 			minInterval: 1 * time.Second,
 			maxInterval: 5 * time.Second,
 		},
+		{
+			domainID:    "configureddomain",
+			minInterval: 1 * time.Second,
+			maxInterval: 5 * time.Second,
+			sequencing:  &pb.SequencingConfig{MaxBatchSize: 10, AllowedAppIds: []string{"app"}},
+		},
 	} {
 		_, err := svr.CreateDomain(ctx, &pb.CreateDomainRequest{
 			DomainId:    tc.domainID,
 			MinInterval: ptypes.DurationProto(tc.minInterval),
 			MaxInterval: ptypes.DurationProto(tc.maxInterval),
+			Sequencing:  tc.sequencing,
 		})
 		if err != nil {
 			t.Fatalf("CreateDomain(): %v", err)
@@ -201,5 +232,61 @@ func TestCreateRead(t *testing.T) {
 		if got, want := domain.Map.TreeType, tpb.TreeType_MAP; got != want {
 			t.Errorf("Map.TreeType: %v, want %v", got, want)
 		}
+		if domain.ReceiptKey == nil {
+			t.Errorf("ReceiptKey: nil, want a receipt key")
+		}
+		if got, want := domain.Sequencing, tc.sequencing; !proto.Equal(got, want) {
+			t.Errorf("Sequencing: %v, want %v", got, want)
+		}
+	}
+}
+
+func TestListRejectedMutations(t *testing.T) {
+	ctx := context.Background()
+	mutations := fake.NewMutationStorage()
+	rejected := []*pb.RejectedMutation{
+		{Index: []byte("a"), Epoch: 2, Sequence: 0, Reason: "mutation replay"},
+		{Index: []byte("b"), Epoch: 2, Sequence: 2, Reason: "mutation: unauthorized"},
+		{Index: []byte("c"), Epoch: 2, Sequence: 5, Reason: "mutation: invalid signature"},
+	}
+	if err := mutations.WriteRejected(ctx, "domain", 2, rejected); err != nil {
+		t.Fatalf("WriteRejected(): %v", err)
+	}
+	srv := &Server{mutations: mutations}
+
+	for _, tc := range []struct {
+		desc      string
+		in        *pb.ListRejectedMutationsRequest
+		wantCode  codes.Code
+		want      []*pb.RejectedMutation
+		wantToken string
+	}{
+		{desc: "no domain", in: &pb.ListRejectedMutationsRequest{Epoch: 2}, wantCode: codes.InvalidArgument},
+		{desc: "bad token", in: &pb.ListRejectedMutationsRequest{DomainId: "domain", Epoch: 2, PageToken: "x"}, wantCode: codes.InvalidArgument},
+		{desc: "all", in: &pb.ListRejectedMutationsRequest{DomainId: "domain", Epoch: 2}, want: rejected},
+		{desc: "first page", in: &pb.ListRejectedMutationsRequest{DomainId: "domain", Epoch: 2, PageSize: 2}, want: rejected[:2], wantToken: "3"},
+		{desc: "second page", in: &pb.ListRejectedMutationsRequest{DomainId: "domain", Epoch: 2, PageSize: 2, PageToken: "3"}, want: rejected[2:]},
+		{desc: "no rejections", in: &pb.ListRejectedMutationsRequest{DomainId: "domain", Epoch: 3}, want: []*pb.RejectedMutation{}},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			resp, err := srv.ListRejectedMutations(ctx, tc.in)
+			if status.Code(err) != tc.wantCode {
+				t.Fatalf("ListRejectedMutations(): %v, want %v", err, tc.wantCode)
+			}
+			if err != nil {
+				return
+			}
+			if got, want := len(resp.GetMutations()), len(tc.want); got != want {
+				t.Fatalf("ListRejectedMutations(): %v mutations, want %v", got, want)
+			}
+			for i, m := range resp.GetMutations() {
+				if !proto.Equal(m, tc.want[i]) {
+					t.Errorf("Mutations[%d]: %v, want %v", i, m, tc.want[i])
+				}
+			}
+			if got, want := resp.GetNextPageToken(), tc.wantToken; got != want {
+				t.Errorf("NextPageToken: %q, want %q", got, want)
+			}
+		})
 	}
 }
@@ -37,6 +37,28 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
+// Mutator selects the function used to apply mutations to map leaves.
+type SequencingConfig_Mutator int32
+
+const (
+	// ENTRY applies signed Entry mutations.
+	SequencingConfig_ENTRY SequencingConfig_Mutator = 0
+)
+
+var SequencingConfig_Mutator_name = map[int32]string{
+	0: "ENTRY",
+}
+var SequencingConfig_Mutator_value = map[string]int32{
+	"ENTRY": 0,
+}
+
+func (x SequencingConfig_Mutator) String() string {
+	return proto.EnumName(SequencingConfig_Mutator_name, int32(x))
+}
+func (SequencingConfig_Mutator) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_admin_7e64f73bca4e160a, []int{1, 0}
+}
+
 // Domain contains information on a single domain
 type Domain struct {
 	// DomainId can be any URL safe string.
@@ -53,17 +75,21 @@ type Domain struct {
 	MaxInterval *duration.Duration `protobuf:"bytes,6,opt,name=max_interval,json=maxInterval" json:"max_interval,omitempty"`
 	// Deleted indicates whether the domain has been marked as deleted.
 	// By its presence in a response, this domain has not been garbage collected.
-	Deleted              bool     `protobuf:"varint,7,opt,name=deleted" json:"deleted,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Deleted bool `protobuf:"varint,7,opt,name=deleted" json:"deleted,omitempty"`
+	// receipt_key contains the public key used to sign mutation receipts.
+	ReceiptKey *keyspb.PublicKey `protobuf:"bytes,8,opt,name=receipt_key,json=receiptKey" json:"receipt_key,omitempty"`
+	// sequencing contains the policies used to accept and sequence mutations.
+	Sequencing           *SequencingConfig `protobuf:"bytes,9,opt,name=sequencing" json:"sequencing,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
 }
 
 func (m *Domain) Reset()         { *m = Domain{} }
 func (m *Domain) String() string { return proto.CompactTextString(m) }
 func (*Domain) ProtoMessage()    {}
 func (*Domain) Descriptor() ([]byte, []int) {
-	return fileDescriptor_admin_c3b05a971594dbf6, []int{0}
+	return fileDescriptor_admin_7e64f73bca4e160a, []int{0}
 }
 func (m *Domain) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Domain.Unmarshal(m, b)
@@ -132,6 +158,81 @@ func (m *Domain) GetDeleted() bool {
 	return false
 }
 
+func (m *Domain) GetReceiptKey() *keyspb.PublicKey {
+	if m != nil {
+		return m.ReceiptKey
+	}
+	return nil
+}
+
+func (m *Domain) GetSequencing() *SequencingConfig {
+	if m != nil {
+		return m.Sequencing
+	}
+	return nil
+}
+
+// SequencingConfig contains the per-domain policies of the key server and
+// the sequencer.
+type SequencingConfig struct {
+	// max_batch_size limits the number of mutations sequenced per epoch.
+	// Zero selects the sequencer's default.
+	MaxBatchSize int32 `protobuf:"varint,1,opt,name=max_batch_size,json=maxBatchSize" json:"max_batch_size,omitempty"`
+	// mutator is the mutation function of the domain.
+	Mutator SequencingConfig_Mutator `protobuf:"varint,2,opt,name=mutator,enum=google.keytransparency.v1.SequencingConfig_Mutator" json:"mutator,omitempty"`
+	// allowed_app_ids lists the app_ids that users may update.
+	// All app_ids are allowed if it is empty.
+	AllowedAppIds        []string `protobuf:"bytes,3,rep,name=allowed_app_ids,json=allowedAppIds" json:"allowed_app_ids,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SequencingConfig) Reset()         { *m = SequencingConfig{} }
+func (m *SequencingConfig) String() string { return proto.CompactTextString(m) }
+func (*SequencingConfig) ProtoMessage()    {}
+func (*SequencingConfig) Descriptor() ([]byte, []int) {
+	return fileDescriptor_admin_7e64f73bca4e160a, []int{1}
+}
+func (m *SequencingConfig) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SequencingConfig.Unmarshal(m, b)
+}
+func (m *SequencingConfig) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SequencingConfig.Marshal(b, m, deterministic)
+}
+func (dst *SequencingConfig) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SequencingConfig.Merge(dst, src)
+}
+func (m *SequencingConfig) XXX_Size() int {
+	return xxx_messageInfo_SequencingConfig.Size(m)
+}
+func (m *SequencingConfig) XXX_DiscardUnknown() {
+	xxx_messageInfo_SequencingConfig.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SequencingConfig proto.InternalMessageInfo
+
+func (m *SequencingConfig) GetMaxBatchSize() int32 {
+	if m != nil {
+		return m.MaxBatchSize
+	}
+	return 0
+}
+
+func (m *SequencingConfig) GetMutator() SequencingConfig_Mutator {
+	if m != nil {
+		return m.Mutator
+	}
+	return SequencingConfig_ENTRY
+}
+
+func (m *SequencingConfig) GetAllowedAppIds() []string {
+	if m != nil {
+		return m.AllowedAppIds
+	}
+	return nil
+}
+
 // ListDomains request.
 // No pagination options are provided.
 type ListDomainsRequest struct {
@@ -147,7 +248,7 @@ func (m *ListDomainsRequest) Reset()         { *m = ListDomainsRequest{} }
 func (m *ListDomainsRequest) String() string { return proto.CompactTextString(m) }
 func (*ListDomainsRequest) ProtoMessage()    {}
 func (*ListDomainsRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_admin_c3b05a971594dbf6, []int{1}
+	return fileDescriptor_admin_7e64f73bca4e160a, []int{2}
 }
 func (m *ListDomainsRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ListDomainsRequest.Unmarshal(m, b)
@@ -186,7 +287,7 @@ func (m *ListDomainsResponse) Reset()         { *m = ListDomainsResponse{} }
 func (m *ListDomainsResponse) String() string { return proto.CompactTextString(m) }
 func (*ListDomainsResponse) ProtoMessage()    {}
 func (*ListDomainsResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_admin_c3b05a971594dbf6, []int{2}
+	return fileDescriptor_admin_7e64f73bca4e160a, []int{3}
 }
 func (m *ListDomainsResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ListDomainsResponse.Unmarshal(m, b)
@@ -228,7 +329,7 @@ func (m *GetDomainRequest) Reset()         { *m = GetDomainRequest{} }
 func (m *GetDomainRequest) String() string { return proto.CompactTextString(m) }
 func (*GetDomainRequest) ProtoMessage()    {}
 func (*GetDomainRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_admin_c3b05a971594dbf6, []int{3}
+	return fileDescriptor_admin_7e64f73bca4e160a, []int{4}
 }
 func (m *GetDomainRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GetDomainRequest.Unmarshal(m, b)
@@ -268,19 +369,23 @@ type CreateDomainRequest struct {
 	MinInterval *duration.Duration `protobuf:"bytes,2,opt,name=min_interval,json=minInterval" json:"min_interval,omitempty"`
 	MaxInterval *duration.Duration `protobuf:"bytes,3,opt,name=max_interval,json=maxInterval" json:"max_interval,omitempty"`
 	// The private_key fields allows callers to set the private key.
-	VrfPrivateKey        *any.Any `protobuf:"bytes,4,opt,name=vrf_private_key,json=vrfPrivateKey" json:"vrf_private_key,omitempty"`
-	LogPrivateKey        *any.Any `protobuf:"bytes,5,opt,name=log_private_key,json=logPrivateKey" json:"log_private_key,omitempty"`
-	MapPrivateKey        *any.Any `protobuf:"bytes,6,opt,name=map_private_key,json=mapPrivateKey" json:"map_private_key,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	VrfPrivateKey     *any.Any `protobuf:"bytes,4,opt,name=vrf_private_key,json=vrfPrivateKey" json:"vrf_private_key,omitempty"`
+	LogPrivateKey     *any.Any `protobuf:"bytes,5,opt,name=log_private_key,json=logPrivateKey" json:"log_private_key,omitempty"`
+	MapPrivateKey     *any.Any `protobuf:"bytes,6,opt,name=map_private_key,json=mapPrivateKey" json:"map_private_key,omitempty"`
+	ReceiptPrivateKey *any.Any `protobuf:"bytes,7,opt,name=receipt_private_key,json=receiptPrivateKey" json:"receipt_private_key,omitempty"`
+	// sequencing contains the policies of the new domain. They cannot be changed
+	// once the domain is created.
+	Sequencing           *SequencingConfig `protobuf:"bytes,8,opt,name=sequencing" json:"sequencing,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
 }
 
 func (m *CreateDomainRequest) Reset()         { *m = CreateDomainRequest{} }
 func (m *CreateDomainRequest) String() string { return proto.CompactTextString(m) }
 func (*CreateDomainRequest) ProtoMessage()    {}
 func (*CreateDomainRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_admin_c3b05a971594dbf6, []int{4}
+	return fileDescriptor_admin_7e64f73bca4e160a, []int{5}
 }
 func (m *CreateDomainRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_CreateDomainRequest.Unmarshal(m, b)
@@ -342,6 +447,20 @@ func (m *CreateDomainRequest) GetMapPrivateKey() *any.Any {
 	return nil
 }
 
+func (m *CreateDomainRequest) GetReceiptPrivateKey() *any.Any {
+	if m != nil {
+		return m.ReceiptPrivateKey
+	}
+	return nil
+}
+
+func (m *CreateDomainRequest) GetSequencing() *SequencingConfig {
+	if m != nil {
+		return m.Sequencing
+	}
+	return nil
+}
+
 // DeleteDomainRequest deletes a domain
 type DeleteDomainRequest struct {
 	DomainId             string   `protobuf:"bytes,1,opt,name=domain_id,json=domainId" json:"domain_id,omitempty"`
@@ -354,7 +473,7 @@ func (m *DeleteDomainRequest) Reset()         { *m = DeleteDomainRequest{} }
 func (m *DeleteDomainRequest) String() string { return proto.CompactTextString(m) }
 func (*DeleteDomainRequest) ProtoMessage()    {}
 func (*DeleteDomainRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_admin_c3b05a971594dbf6, []int{5}
+	return fileDescriptor_admin_7e64f73bca4e160a, []int{6}
 }
 func (m *DeleteDomainRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_DeleteDomainRequest.Unmarshal(m, b)
@@ -393,7 +512,7 @@ func (m *UndeleteDomainRequest) Reset()         { *m = UndeleteDomainRequest{} }
 func (m *UndeleteDomainRequest) String() string { return proto.CompactTextString(m) }
 func (*UndeleteDomainRequest) ProtoMessage()    {}
 func (*UndeleteDomainRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_admin_c3b05a971594dbf6, []int{6}
+	return fileDescriptor_admin_7e64f73bca4e160a, []int{7}
 }
 func (m *UndeleteDomainRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_UndeleteDomainRequest.Unmarshal(m, b)
@@ -420,14 +539,217 @@ func (m *UndeleteDomainRequest) GetDomainId() string {
 	return ""
 }
 
+// RejectedMutation describes a mutation that the sequencer did not apply.
+type RejectedMutation struct {
+	// index is the map index the mutation tried to update.
+	Index []byte `protobuf:"bytes,1,opt,name=index,proto3" json:"index,omitempty"`
+	// mutation_hash is the ObjectHash of the rejected Entry.
+	MutationHash []byte `protobuf:"bytes,2,opt,name=mutation_hash,json=mutationHash,proto3" json:"mutation_hash,omitempty"`
+	// epoch is the epoch in which the mutation was processed.
+	Epoch int64 `protobuf:"varint,3,opt,name=epoch" json:"epoch,omitempty"`
+	// sequence is the position of the mutation in the list of mutations
+	// that produced epoch.
+	Sequence int64 `protobuf:"varint,4,opt,name=sequence" json:"sequence,omitempty"`
+	// reason describes why the mutation was rejected.
+	Reason               string   `protobuf:"bytes,5,opt,name=reason" json:"reason,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RejectedMutation) Reset()         { *m = RejectedMutation{} }
+func (m *RejectedMutation) String() string { return proto.CompactTextString(m) }
+func (*RejectedMutation) ProtoMessage()    {}
+func (*RejectedMutation) Descriptor() ([]byte, []int) {
+	return fileDescriptor_admin_7e64f73bca4e160a, []int{8}
+}
+func (m *RejectedMutation) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RejectedMutation.Unmarshal(m, b)
+}
+func (m *RejectedMutation) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RejectedMutation.Marshal(b, m, deterministic)
+}
+func (dst *RejectedMutation) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RejectedMutation.Merge(dst, src)
+}
+func (m *RejectedMutation) XXX_Size() int {
+	return xxx_messageInfo_RejectedMutation.Size(m)
+}
+func (m *RejectedMutation) XXX_DiscardUnknown() {
+	xxx_messageInfo_RejectedMutation.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RejectedMutation proto.InternalMessageInfo
+
+func (m *RejectedMutation) GetIndex() []byte {
+	if m != nil {
+		return m.Index
+	}
+	return nil
+}
+
+func (m *RejectedMutation) GetMutationHash() []byte {
+	if m != nil {
+		return m.MutationHash
+	}
+	return nil
+}
+
+func (m *RejectedMutation) GetEpoch() int64 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
+}
+
+func (m *RejectedMutation) GetSequence() int64 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
+func (m *RejectedMutation) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+// ListRejectedMutationsRequest requests the mutations that were rejected
+// while creating a given epoch.
+type ListRejectedMutationsRequest struct {
+	// domain_id is the domain identifier.
+	DomainId string `protobuf:"bytes,1,opt,name=domain_id,json=domainId" json:"domain_id,omitempty"`
+	// epoch specifies the epoch number.
+	Epoch int64 `protobuf:"varint,2,opt,name=epoch" json:"epoch,omitempty"`
+	// page_token defines the starting point for pagination.
+	// To request the next page, pass next_page_token from the previous response.
+	// To start at the beginning, simply omit page_token from the request.
+	PageToken string `protobuf:"bytes,3,opt,name=page_token,json=pageToken" json:"page_token,omitempty"`
+	// page_size is the maximum number of mutations to return in a single request.
+	// The server may choose a smaller page_size than the one requested.
+	PageSize             int32    `protobuf:"varint,4,opt,name=page_size,json=pageSize" json:"page_size,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListRejectedMutationsRequest) Reset()         { *m = ListRejectedMutationsRequest{} }
+func (m *ListRejectedMutationsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListRejectedMutationsRequest) ProtoMessage()    {}
+func (*ListRejectedMutationsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_admin_7e64f73bca4e160a, []int{9}
+}
+func (m *ListRejectedMutationsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListRejectedMutationsRequest.Unmarshal(m, b)
+}
+func (m *ListRejectedMutationsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListRejectedMutationsRequest.Marshal(b, m, deterministic)
+}
+func (dst *ListRejectedMutationsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListRejectedMutationsRequest.Merge(dst, src)
+}
+func (m *ListRejectedMutationsRequest) XXX_Size() int {
+	return xxx_messageInfo_ListRejectedMutationsRequest.Size(m)
+}
+func (m *ListRejectedMutationsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListRejectedMutationsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListRejectedMutationsRequest proto.InternalMessageInfo
+
+func (m *ListRejectedMutationsRequest) GetDomainId() string {
+	if m != nil {
+		return m.DomainId
+	}
+	return ""
+}
+
+func (m *ListRejectedMutationsRequest) GetEpoch() int64 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
+}
+
+func (m *ListRejectedMutationsRequest) GetPageToken() string {
+	if m != nil {
+		return m.PageToken
+	}
+	return ""
+}
+
+func (m *ListRejectedMutationsRequest) GetPageSize() int32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
+// ListRejectedMutationsResponse contains the mutations that were rejected
+// while creating an epoch.
+type ListRejectedMutationsResponse struct {
+	Mutations []*RejectedMutation `protobuf:"bytes,1,rep,name=mutations" json:"mutations,omitempty"`
+	// next_page_token is the next page token to query for pagination.
+	// An empty value means there are no more results to fetch.
+	NextPageToken        string   `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken" json:"next_page_token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListRejectedMutationsResponse) Reset()         { *m = ListRejectedMutationsResponse{} }
+func (m *ListRejectedMutationsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListRejectedMutationsResponse) ProtoMessage()    {}
+func (*ListRejectedMutationsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_admin_7e64f73bca4e160a, []int{10}
+}
+func (m *ListRejectedMutationsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListRejectedMutationsResponse.Unmarshal(m, b)
+}
+func (m *ListRejectedMutationsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListRejectedMutationsResponse.Marshal(b, m, deterministic)
+}
+func (dst *ListRejectedMutationsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListRejectedMutationsResponse.Merge(dst, src)
+}
+func (m *ListRejectedMutationsResponse) XXX_Size() int {
+	return xxx_messageInfo_ListRejectedMutationsResponse.Size(m)
+}
+func (m *ListRejectedMutationsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListRejectedMutationsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListRejectedMutationsResponse proto.InternalMessageInfo
+
+func (m *ListRejectedMutationsResponse) GetMutations() []*RejectedMutation {
+	if m != nil {
+		return m.Mutations
+	}
+	return nil
+}
+
+func (m *ListRejectedMutationsResponse) GetNextPageToken() string {
+	if m != nil {
+		return m.NextPageToken
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*Domain)(nil), "google.keytransparency.v1.Domain")
+	proto.RegisterType((*SequencingConfig)(nil), "google.keytransparency.v1.SequencingConfig")
 	proto.RegisterType((*ListDomainsRequest)(nil), "google.keytransparency.v1.ListDomainsRequest")
 	proto.RegisterType((*ListDomainsResponse)(nil), "google.keytransparency.v1.ListDomainsResponse")
 	proto.RegisterType((*GetDomainRequest)(nil), "google.keytransparency.v1.GetDomainRequest")
 	proto.RegisterType((*CreateDomainRequest)(nil), "google.keytransparency.v1.CreateDomainRequest")
 	proto.RegisterType((*DeleteDomainRequest)(nil), "google.keytransparency.v1.DeleteDomainRequest")
 	proto.RegisterType((*UndeleteDomainRequest)(nil), "google.keytransparency.v1.UndeleteDomainRequest")
+	proto.RegisterType((*RejectedMutation)(nil), "google.keytransparency.v1.RejectedMutation")
+	proto.RegisterType((*ListRejectedMutationsRequest)(nil), "google.keytransparency.v1.ListRejectedMutationsRequest")
+	proto.RegisterType((*ListRejectedMutationsResponse)(nil), "google.keytransparency.v1.ListRejectedMutationsResponse")
+	proto.RegisterEnum("google.keytransparency.v1.SequencingConfig_Mutator", SequencingConfig_Mutator_name, SequencingConfig_Mutator_value)
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -458,6 +780,9 @@ type KeyTransparencyAdminClient interface {
 	// UndeleteDomain marks a previously deleted domain as active if it has not
 	// already been garbage collected.
 	UndeleteDomain(ctx context.Context, in *UndeleteDomainRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	// ListRejectedMutations returns the mutations that the sequencer rejected
+	// while creating an epoch, along with the reason they were rejected.
+	ListRejectedMutations(ctx context.Context, in *ListRejectedMutationsRequest, opts ...grpc.CallOption) (*ListRejectedMutationsResponse, error)
 }
 
 type keyTransparencyAdminClient struct {
@@ -513,6 +838,15 @@ func (c *keyTransparencyAdminClient) UndeleteDomain(ctx context.Context, in *Und
 	return out, nil
 }
 
+func (c *keyTransparencyAdminClient) ListRejectedMutations(ctx context.Context, in *ListRejectedMutationsRequest, opts ...grpc.CallOption) (*ListRejectedMutationsResponse, error) {
+	out := new(ListRejectedMutationsResponse)
+	err := c.cc.Invoke(ctx, "/google.keytransparency.v1.KeyTransparencyAdmin/ListRejectedMutations", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for KeyTransparencyAdmin service
 
 type KeyTransparencyAdminServer interface {
@@ -532,6 +866,9 @@ type KeyTransparencyAdminServer interface {
 	// UndeleteDomain marks a previously deleted domain as active if it has not
 	// already been garbage collected.
 	UndeleteDomain(context.Context, *UndeleteDomainRequest) (*empty.Empty, error)
+	// ListRejectedMutations returns the mutations that the sequencer rejected
+	// while creating an epoch, along with the reason they were rejected.
+	ListRejectedMutations(context.Context, *ListRejectedMutationsRequest) (*ListRejectedMutationsResponse, error)
 }
 
 func RegisterKeyTransparencyAdminServer(s *grpc.Server, srv KeyTransparencyAdminServer) {
@@ -628,6 +965,24 @@ func _KeyTransparencyAdmin_UndeleteDomain_Handler(srv interface{}, ctx context.C
 	return interceptor(ctx, in, info, handler)
 }
 
+func _KeyTransparencyAdmin_ListRejectedMutations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRejectedMutationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeyTransparencyAdminServer).ListRejectedMutations(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/google.keytransparency.v1.KeyTransparencyAdmin/ListRejectedMutations",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeyTransparencyAdminServer).ListRejectedMutations(ctx, req.(*ListRejectedMutationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _KeyTransparencyAdmin_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "google.keytransparency.v1.KeyTransparencyAdmin",
 	HandlerType: (*KeyTransparencyAdminServer)(nil),
@@ -652,56 +1007,84 @@ var _KeyTransparencyAdmin_serviceDesc = grpc.ServiceDesc{
 			MethodName: "UndeleteDomain",
 			Handler:    _KeyTransparencyAdmin_UndeleteDomain_Handler,
 		},
+		{
+			MethodName: "ListRejectedMutations",
+			Handler:    _KeyTransparencyAdmin_ListRejectedMutations_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "v1/admin.proto",
 }
 
-func init() { proto.RegisterFile("v1/admin.proto", fileDescriptor_admin_c3b05a971594dbf6) }
-
-var fileDescriptor_admin_c3b05a971594dbf6 = []byte{
-	// 679 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x95, 0x4d, 0x6e, 0xd3, 0x40,
-	0x14, 0xc7, 0xe5, 0x84, 0xa6, 0xed, 0x24, 0x0d, 0x65, 0x52, 0x8a, 0x9b, 0x22, 0x48, 0xcd, 0x26,
-	0x2a, 0xc2, 0x26, 0x01, 0x09, 0xa9, 0x74, 0x53, 0x28, 0x1f, 0x55, 0x59, 0x54, 0x56, 0xd9, 0xb0,
-	0x89, 0x26, 0xf1, 0xc4, 0xb5, 0x6a, 0xcf, 0x98, 0xf1, 0xc4, 0xad, 0x05, 0x2c, 0x40, 0xdc, 0xa0,
-	0x57, 0xe0, 0x46, 0x5c, 0x81, 0x05, 0xc7, 0x40, 0xf6, 0x8c, 0x53, 0xc7, 0x49, 0xdc, 0x56, 0xac,
-	0x9c, 0xf1, 0x7b, 0xbf, 0xf7, 0xf9, 0x77, 0x06, 0xd4, 0xc3, 0x8e, 0x81, 0x2c, 0xcf, 0x21, 0xba,
-	0xcf, 0x28, 0xa7, 0x70, 0xc3, 0xa6, 0xd4, 0x76, 0xb1, 0x7e, 0x8a, 0x23, 0xce, 0x10, 0x09, 0x7c,
-	0xc4, 0x30, 0x19, 0x44, 0x7a, 0xd8, 0x69, 0xde, 0x17, 0x26, 0x03, 0xf9, 0x8e, 0x81, 0x08, 0xa1,
-	0x1c, 0x71, 0x87, 0x92, 0x40, 0x80, 0x4d, 0x09, 0x1a, 0xc9, 0xa9, 0x3f, 0x1a, 0x1a, 0x88, 0x44,
-	0xd2, 0xb4, 0x99, 0x37, 0x61, 0xcf, 0xe7, 0xa9, 0xf1, 0x41, 0xde, 0x68, 0x8d, 0x58, 0x12, 0x58,
-	0xda, 0xeb, 0x9c, 0x39, 0xae, 0xeb, 0xa0, 0xf4, 0xdc, 0x1c, 0xb0, 0xc8, 0xe7, 0xd4, 0x38, 0xc5,
-	0x51, 0xe0, 0xf7, 0xe5, 0x43, 0xd8, 0xb4, 0x5f, 0x25, 0x50, 0xd9, 0xa7, 0x1e, 0x72, 0x08, 0xdc,
-	0x04, 0xcb, 0x56, 0xf2, 0xab, 0xe7, 0x58, 0xaa, 0xd2, 0x52, 0xda, 0xcb, 0xe6, 0x92, 0x78, 0x71,
-	0x60, 0xc1, 0x16, 0x28, 0xbb, 0xd4, 0x56, 0x4b, 0x2d, 0xa5, 0x5d, 0xed, 0xd6, 0xf5, 0x71, 0x86,
-	0x63, 0x86, 0xb1, 0x19, 0x9b, 0x62, 0x0f, 0x0f, 0xf9, 0x6a, 0x79, 0xb6, 0x87, 0x87, 0x7c, 0xf8,
-	0x08, 0x94, 0x43, 0x36, 0x54, 0x6f, 0x25, 0x1e, 0x77, 0x74, 0x59, 0xc7, 0xd1, 0xa8, 0xef, 0x3a,
-	0x83, 0x43, 0x1c, 0x99, 0xb1, 0x15, 0xee, 0x82, 0x9a, 0x17, 0x97, 0x40, 0x38, 0x66, 0x21, 0x72,
-	0xd5, 0x85, 0xc4, 0x7b, 0x43, 0x97, 0x43, 0x4e, 0x7b, 0xd6, 0xf7, 0x65, 0xcf, 0x66, 0xd5, 0x73,
-	0xc8, 0x81, 0xf4, 0x4e, 0x68, 0x74, 0x7e, 0x49, 0x57, 0xae, 0xa6, 0xd1, 0xf9, 0x98, 0x56, 0xc1,
-	0xa2, 0x85, 0x5d, 0xcc, 0xb1, 0xa5, 0x2e, 0xb6, 0x94, 0xf6, 0x92, 0x99, 0x1e, 0xb5, 0x17, 0x00,
-	0x7e, 0x70, 0x02, 0x2e, 0x26, 0x15, 0x98, 0xf8, 0xf3, 0x08, 0x07, 0x1c, 0x6e, 0x81, 0x5a, 0x70,
-	0x42, 0xcf, 0x7a, 0x29, 0xa4, 0x24, 0x50, 0x35, 0x7e, 0xb7, 0x2f, 0x41, 0x13, 0x34, 0x26, 0xc0,
-	0xc0, 0xa7, 0x24, 0xc0, 0xf0, 0x25, 0x58, 0x14, 0xa3, 0x0d, 0x54, 0xa5, 0x55, 0x6e, 0x57, 0xbb,
-	0x5b, 0xfa, 0x5c, 0x15, 0xe9, 0x02, 0x36, 0x53, 0x42, 0x33, 0xc1, 0xea, 0x3b, 0x2c, 0x43, 0xa6,
-	0xa5, 0x14, 0x2e, 0x2f, 0x5f, 0x67, 0x69, 0xba, 0xce, 0xbf, 0x25, 0xd0, 0x78, 0xcd, 0x30, 0xe2,
-	0xf8, 0x06, 0x71, 0xf3, 0xbb, 0x2a, 0xfd, 0xd7, 0xae, 0xca, 0x37, 0xda, 0xd5, 0x2e, 0xb8, 0x1d,
-	0xb2, 0x61, 0xcf, 0x67, 0x4e, 0x88, 0x38, 0xee, 0x9d, 0xe2, 0x48, 0x0a, 0x6b, 0x6d, 0x2a, 0xc0,
-	0x1e, 0x89, 0xcc, 0x95, 0x90, 0x0d, 0x8f, 0x84, 0xef, 0x21, 0x8e, 0x62, 0xda, 0xa5, 0xf6, 0x04,
-	0xbd, 0x50, 0x44, 0xbb, 0xd4, 0x9e, 0xa4, 0x3d, 0xe4, 0x4f, 0xd0, 0x95, 0x22, 0xda, 0x43, 0xfe,
-	0x25, 0xad, 0x75, 0x41, 0x43, 0x4c, 0xfd, 0xfa, 0x93, 0xd6, 0x9e, 0x83, 0xbb, 0x1f, 0x89, 0x75,
-	0x43, 0xaa, 0x7b, 0xb1, 0x00, 0xd6, 0x0e, 0x71, 0x74, 0x9c, 0xd1, 0xd3, 0x5e, 0xfc, 0xc7, 0x05,
-	0xbf, 0x2b, 0xa0, 0x9a, 0x91, 0x25, 0x7c, 0x52, 0xa0, 0xbe, 0x69, 0xdd, 0x37, 0xf5, 0xeb, 0xba,
-	0x0b, 0xb5, 0x6b, 0x8d, 0x1f, 0xbf, 0xff, 0x5c, 0x94, 0x56, 0x60, 0xd5, 0x08, 0x3b, 0x86, 0x54,
-	0x31, 0xfc, 0x0a, 0x96, 0xc7, 0x2a, 0x86, 0x8f, 0x0b, 0x22, 0xe6, 0xb5, 0xde, 0xbc, 0xfa, 0x5b,
-	0xd1, 0x1e, 0x26, 0x19, 0x37, 0xe0, 0xbd, 0x4c, 0x46, 0xe3, 0xcb, 0x78, 0x52, 0xdf, 0x60, 0x04,
-	0x6a, 0x59, 0xb9, 0xc3, 0xa2, 0x96, 0x66, 0x7c, 0x17, 0xd7, 0xa9, 0x61, 0x3d, 0xa9, 0x61, 0x55,
-	0xcb, 0x76, 0xbd, 0xa3, 0x6c, 0xc3, 0x33, 0x50, 0xcb, 0xee, 0xbf, 0x30, 0xf5, 0x0c, 0xa1, 0x34,
-	0xd7, 0xa7, 0x44, 0xf6, 0x26, 0xbe, 0x1c, 0xd2, 0x9e, 0xb7, 0xe7, 0xf6, 0xfc, 0x53, 0x01, 0xf5,
-	0x49, 0x15, 0xc1, 0xa7, 0x05, 0xb9, 0x67, 0x0a, 0x6e, 0x6e, 0xf6, 0x76, 0x92, 0x5d, 0xdb, 0x6e,
-	0xcd, 0xc9, 0xbe, 0x33, 0x92, 0xe1, 0x5e, 0xbd, 0xff, 0xf4, 0xd6, 0x76, 0xf8, 0xc9, 0xa8, 0xaf,
-	0x0f, 0xa8, 0x67, 0xc8, 0xbb, 0x2c, 0x97, 0xdf, 0x18, 0x50, 0x26, 0xae, 0xcd, 0xb0, 0x93, 0xb7,
-	0xf5, 0x6c, 0xda, 0x13, 0xc9, 0x2b, 0xc9, 0xe3, 0xd9, 0xbf, 0x00, 0x00, 0x00, 0xff, 0xff, 0xeb,
-	0xcf, 0x8d, 0xfb, 0x92, 0x07, 0x00, 0x00,
+func init() { proto.RegisterFile("v1/admin.proto", fileDescriptor_admin_7e64f73bca4e160a) }
+
+var fileDescriptor_admin_7e64f73bca4e160a = []byte{
+	// 1065 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x56, 0x4f, 0x73, 0xdb, 0x44,
+	0x14, 0x47, 0x76, 0xe3, 0x3f, 0xcf, 0x8e, 0x93, 0x6e, 0xd2, 0xa0, 0xb8, 0x2d, 0xb8, 0x2a, 0xc3,
+	0x78, 0xc2, 0x20, 0x11, 0x97, 0x99, 0x30, 0x25, 0x97, 0xb4, 0x29, 0x34, 0x13, 0xca, 0x64, 0xb6,
+	0xe1, 0x00, 0x17, 0xcd, 0xc6, 0xda, 0xd8, 0x22, 0x92, 0x56, 0x48, 0x6b, 0x27, 0x6a, 0xe9, 0x01,
+	0x86, 0x2b, 0x17, 0x38, 0xf0, 0x09, 0xf8, 0x24, 0x0c, 0x9f, 0x80, 0x13, 0x77, 0xbe, 0x05, 0x97,
+	0xce, 0xae, 0x56, 0xae, 0xec, 0xc4, 0x4a, 0x32, 0x3d, 0xd9, 0xfb, 0xde, 0xfb, 0xbd, 0xf7, 0x7b,
+	0xab, 0xf7, 0x7e, 0xb3, 0xd0, 0x1a, 0x6f, 0x5a, 0xc4, 0xf1, 0xdd, 0xc0, 0x0c, 0x23, 0xc6, 0x19,
+	0x5a, 0x1f, 0x30, 0x36, 0xf0, 0xa8, 0x79, 0x42, 0x13, 0x1e, 0x91, 0x20, 0x0e, 0x49, 0x44, 0x83,
+	0x7e, 0x62, 0x8e, 0x37, 0xdb, 0x77, 0x52, 0x97, 0x45, 0x42, 0xd7, 0x22, 0x41, 0xc0, 0x38, 0xe1,
+	0x2e, 0x0b, 0xe2, 0x14, 0xd8, 0x56, 0x40, 0x4b, 0x9e, 0x8e, 0x46, 0xc7, 0x16, 0x09, 0x12, 0xe5,
+	0xba, 0x3d, 0xeb, 0xa2, 0x7e, 0xc8, 0x33, 0xe7, 0x7b, 0xb3, 0x4e, 0x67, 0x14, 0xc9, 0xc4, 0xca,
+	0xdf, 0xe2, 0x91, 0xeb, 0x79, 0x2e, 0xc9, 0xce, 0xed, 0x7e, 0x94, 0x84, 0x9c, 0x59, 0x27, 0x34,
+	0x89, 0xc3, 0x23, 0xf5, 0x93, 0xfa, 0x8c, 0x3f, 0xcb, 0x50, 0xd9, 0x65, 0x3e, 0x71, 0x03, 0x74,
+	0x1b, 0xea, 0x8e, 0xfc, 0x67, 0xbb, 0x8e, 0xae, 0x75, 0xb4, 0x6e, 0x1d, 0xd7, 0x52, 0xc3, 0x9e,
+	0x83, 0x3a, 0x50, 0xf6, 0xd8, 0x40, 0x2f, 0x75, 0xb4, 0x6e, 0xa3, 0xd7, 0x32, 0x27, 0x15, 0x0e,
+	0x23, 0x4a, 0xb1, 0x70, 0x89, 0x08, 0x9f, 0x84, 0x7a, 0xf9, 0xe2, 0x08, 0x9f, 0x84, 0xe8, 0x3e,
+	0x94, 0xc7, 0xd1, 0xb1, 0x7e, 0x43, 0x46, 0xdc, 0x34, 0x15, 0x8f, 0x83, 0xd1, 0x91, 0xe7, 0xf6,
+	0xf7, 0x69, 0x82, 0x85, 0x17, 0x6d, 0x43, 0xd3, 0x17, 0x14, 0x02, 0x4e, 0xa3, 0x31, 0xf1, 0xf4,
+	0x05, 0x19, 0xbd, 0x6e, 0xaa, 0x4b, 0xce, 0x7a, 0x36, 0x77, 0x55, 0xcf, 0xb8, 0xe1, 0xbb, 0xc1,
+	0x9e, 0x8a, 0x96, 0x68, 0x72, 0xf6, 0x06, 0x5d, 0xb9, 0x1c, 0x4d, 0xce, 0x26, 0x68, 0x1d, 0xaa,
+	0x0e, 0xf5, 0x28, 0xa7, 0x8e, 0x5e, 0xed, 0x68, 0xdd, 0x1a, 0xce, 0x8e, 0xa8, 0x07, 0x8d, 0x88,
+	0xf6, 0xa9, 0x1b, 0x72, 0xfb, 0x84, 0x26, 0x7a, 0x6d, 0x5e, 0x0b, 0xa0, 0xa2, 0xf6, 0x69, 0x82,
+	0xf6, 0x01, 0x62, 0xfa, 0xc3, 0x88, 0x06, 0x7d, 0x37, 0x18, 0xe8, 0x75, 0x09, 0xf9, 0xc8, 0x9c,
+	0x3b, 0x2c, 0xe6, 0xf3, 0x49, 0xf0, 0x63, 0x16, 0x1c, 0xbb, 0x03, 0x9c, 0x83, 0x1b, 0x7f, 0x6b,
+	0xb0, 0x3c, 0x1b, 0x80, 0x3e, 0x80, 0x96, 0xe8, 0xf6, 0x88, 0xf0, 0xfe, 0xd0, 0x8e, 0xdd, 0x17,
+	0x54, 0x7e, 0xb6, 0x05, 0x2c, 0xee, 0xe0, 0x91, 0x30, 0x3e, 0x77, 0x5f, 0x50, 0xf4, 0x0c, 0xaa,
+	0xfe, 0x88, 0x13, 0xce, 0x22, 0xf9, 0xf9, 0x5a, 0xbd, 0x07, 0xd7, 0x20, 0x61, 0x3e, 0x4b, 0xa1,
+	0x38, 0xcb, 0x81, 0x3e, 0x84, 0x25, 0xe2, 0x79, 0xec, 0x94, 0x3a, 0x36, 0x09, 0x43, 0xdb, 0x75,
+	0x62, 0xbd, 0xdc, 0x29, 0x77, 0xeb, 0x78, 0x51, 0x99, 0x77, 0xc2, 0x70, 0xcf, 0x89, 0x8d, 0x55,
+	0xa8, 0x2a, 0x2c, 0xaa, 0xc3, 0xc2, 0x93, 0xaf, 0x0f, 0xf1, 0xb7, 0xcb, 0xef, 0x18, 0x5b, 0x80,
+	0xbe, 0x72, 0x63, 0x9e, 0x8e, 0x5c, 0x8c, 0x45, 0xb5, 0x98, 0xa3, 0x7b, 0xd0, 0x8c, 0x87, 0xec,
+	0xd4, 0xce, 0x6e, 0x5f, 0x93, 0xb7, 0xdf, 0x10, 0xb6, 0xdd, 0xd4, 0x64, 0x60, 0x58, 0x99, 0x02,
+	0xc6, 0x21, 0x0b, 0x62, 0x8a, 0x3e, 0x87, 0x6a, 0x3a, 0xa3, 0xb1, 0xae, 0x75, 0xca, 0xdd, 0x46,
+	0xef, 0x5e, 0x41, 0x73, 0x29, 0x18, 0x67, 0x08, 0x03, 0xc3, 0xf2, 0x97, 0x54, 0xa5, 0xcc, 0xa8,
+	0x14, 0x6e, 0xc1, 0x2c, 0xcf, 0xd2, 0x79, 0x9e, 0xff, 0x97, 0x61, 0xe5, 0x71, 0x44, 0x09, 0xa7,
+	0xd7, 0xc8, 0x3b, 0x3b, 0xf4, 0xa5, 0xb7, 0x1a, 0xfa, 0xf2, 0xb5, 0x86, 0x7e, 0x1b, 0x96, 0xc6,
+	0xd1, 0xb1, 0x1d, 0x46, 0xee, 0x98, 0x70, 0x2a, 0xc7, 0x3b, 0xdd, 0xd0, 0xd5, 0x73, 0x09, 0x76,
+	0x82, 0x04, 0x2f, 0x8e, 0xa3, 0xe3, 0x83, 0x34, 0x56, 0x0c, 0xf9, 0x36, 0x2c, 0x79, 0x6c, 0x30,
+	0x85, 0x5e, 0x28, 0x42, 0x7b, 0x6c, 0x30, 0x8d, 0xf6, 0x49, 0x38, 0x85, 0xae, 0x14, 0xa1, 0x7d,
+	0x12, 0xe6, 0xd0, 0xbb, 0xb0, 0x92, 0x2d, 0x65, 0x3e, 0x43, 0xb5, 0x20, 0xc3, 0x4d, 0x05, 0xc8,
+	0x65, 0x99, 0x5e, 0xd3, 0xda, 0xdb, 0xad, 0x69, 0x0f, 0x56, 0xd2, 0x41, 0xb8, 0xfa, 0xc7, 0x37,
+	0x3e, 0x85, 0x5b, 0xdf, 0x04, 0xce, 0x75, 0x51, 0x7f, 0x68, 0xb0, 0x8c, 0xe9, 0xf7, 0xb4, 0xcf,
+	0xa9, 0x23, 0xf7, 0xcc, 0x65, 0x01, 0x5a, 0x85, 0x05, 0x37, 0x70, 0xe8, 0x99, 0x8c, 0x6e, 0xe2,
+	0xf4, 0x80, 0xee, 0xc3, 0xa2, 0xaf, 0x22, 0xec, 0x21, 0x89, 0x87, 0x72, 0xbc, 0x9a, 0xb8, 0x99,
+	0x19, 0x9f, 0x92, 0x78, 0x28, 0xa0, 0x34, 0x64, 0xfd, 0xa1, 0x9c, 0x9e, 0x32, 0x4e, 0x0f, 0xa8,
+	0x0d, 0x35, 0xd5, 0x1d, 0x95, 0x53, 0x51, 0xc6, 0x93, 0x33, 0x5a, 0x83, 0x4a, 0x44, 0x49, 0xcc,
+	0x02, 0xf9, 0xc5, 0xeb, 0x58, 0x9d, 0x8c, 0x5f, 0x35, 0xb8, 0x23, 0x56, 0x75, 0x96, 0x5d, 0x7c,
+	0xa5, 0x55, 0x98, 0xf0, 0x28, 0xe5, 0x79, 0xdc, 0x05, 0x08, 0xc9, 0x80, 0xda, 0x9c, 0x9d, 0xd0,
+	0x40, 0x52, 0xac, 0xe3, 0xba, 0xb0, 0x1c, 0x0a, 0x83, 0xc8, 0x28, 0xdd, 0x52, 0x03, 0x6f, 0x48,
+	0x0d, 0xac, 0x09, 0x83, 0xd0, 0x3f, 0xe3, 0x37, 0x0d, 0xee, 0xce, 0xe1, 0xa3, 0x44, 0x64, 0x0f,
+	0xea, 0xd9, 0x5d, 0x64, 0x32, 0x52, 0x34, 0x01, 0xb3, 0x89, 0xf0, 0x1b, 0xb4, 0x50, 0xc7, 0x80,
+	0x9e, 0x71, 0x3b, 0xc7, 0xb6, 0x24, 0xd9, 0x2e, 0x0a, 0xf3, 0x41, 0xc6, 0xb8, 0xf7, 0x6f, 0x05,
+	0x56, 0xf7, 0x69, 0x72, 0x98, 0x4b, 0xbd, 0x23, 0xde, 0x14, 0xe8, 0x27, 0x0d, 0x1a, 0x39, 0xa1,
+	0x43, 0x1f, 0x17, 0x10, 0x39, 0xaf, 0xa4, 0x6d, 0xf3, 0xaa, 0xe1, 0x69, 0xeb, 0xc6, 0xca, 0xcf,
+	0xff, 0xfc, 0xf7, 0x7b, 0x69, 0x11, 0x35, 0xac, 0xf1, 0xa6, 0xa5, 0x74, 0x11, 0xfd, 0x08, 0xf5,
+	0x89, 0x2e, 0xa2, 0xa2, 0x9b, 0x98, 0x55, 0xcf, 0xf6, 0xe5, 0xea, 0x6b, 0xbc, 0x2f, 0x2b, 0xae,
+	0xa3, 0x77, 0x73, 0x15, 0xad, 0x97, 0x93, 0x81, 0x78, 0x85, 0x12, 0x68, 0xe6, 0x05, 0x14, 0x15,
+	0xb5, 0x74, 0x81, 0xd2, 0x5e, 0x85, 0xc3, 0x9a, 0xe4, 0xb0, 0x6c, 0xe4, 0xbb, 0x7e, 0xa8, 0x6d,
+	0xa0, 0x53, 0x68, 0xe6, 0xd7, 0xb7, 0xb0, 0xf4, 0x05, 0x7b, 0xde, 0x5e, 0x3b, 0x27, 0x3a, 0x4f,
+	0xc4, 0xbb, 0x2d, 0xeb, 0x79, 0x63, 0x6e, 0xcf, 0xbf, 0x68, 0xd0, 0x9a, 0x16, 0x01, 0xf4, 0x49,
+	0x41, 0xed, 0x0b, 0xf5, 0x62, 0x6e, 0xf5, 0xae, 0xac, 0x6e, 0x6c, 0x74, 0xe6, 0x54, 0x7f, 0x38,
+	0x52, 0xe9, 0xd0, 0x5f, 0x1a, 0xdc, 0xba, 0x70, 0x55, 0xd0, 0xd6, 0x25, 0x73, 0x35, 0x6f, 0xd9,
+	0xdb, 0x9f, 0x5d, 0x1f, 0xa8, 0x46, 0x73, 0x4b, 0xd2, 0xde, 0x44, 0xd6, 0x1c, 0xda, 0x96, 0x94,
+	0x86, 0xd8, 0x7a, 0x29, 0x7f, 0x5f, 0x59, 0x91, 0xca, 0xf4, 0xe8, 0xe9, 0x77, 0x5f, 0x0c, 0x5c,
+	0x3e, 0x1c, 0x1d, 0x99, 0x7d, 0xe6, 0x5b, 0xea, 0xb1, 0x3c, 0x53, 0xde, 0xea, 0xb3, 0x28, 0x7d,
+	0x97, 0x8f, 0x37, 0x67, 0x7d, 0xf6, 0x80, 0xd9, 0xe9, 0x15, 0x56, 0xe4, 0xcf, 0x83, 0xd7, 0x03,
+	0x00, 0xac, 0xd6, 0xe9, 0xc4, 0xf3, 0x0b, 0x00, 0x00,
 }
@@ -14,9 +14,12 @@ associated with it.
 import proto "github.com/golang/protobuf/proto"
 import fmt "fmt"
 import math "math"
+import timestamp "github.com/golang/protobuf/ptypes/timestamp"
 import tink_go_proto "github.com/google/tink/proto/tink_go_proto"
 import trillian "github.com/google/trillian"
+import sigpb "github.com/google/trillian/crypto/sigpb"
 import _ "google.golang.org/genproto/googleapis/api/annotations"
+import status "google.golang.org/genproto/googleapis/rpc/status"
 
 import (
 	context "golang.org/x/net/context"
@@ -34,6 +37,36 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
+// State defines the processing state of a mutation.
+type MutationStatus_State int32
+
+const (
+	MutationStatus_UNKNOWN  MutationStatus_State = 0
+	MutationStatus_QUEUED   MutationStatus_State = 1
+	MutationStatus_APPLIED  MutationStatus_State = 2
+	MutationStatus_REJECTED MutationStatus_State = 3
+)
+
+var MutationStatus_State_name = map[int32]string{
+	0: "UNKNOWN",
+	1: "QUEUED",
+	2: "APPLIED",
+	3: "REJECTED",
+}
+var MutationStatus_State_value = map[string]int32{
+	"UNKNOWN":  0,
+	"QUEUED":   1,
+	"APPLIED":  2,
+	"REJECTED": 3,
+}
+
+func (x MutationStatus_State) String() string {
+	return proto.EnumName(MutationStatus_State_name, int32(x))
+}
+func (MutationStatus_State) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_keytransparency_1f9ecd1ae48c4eda, []int{24, 0}
+}
+
 // Committed represents the data committed to in a cryptographic commitment.
 // commitment = HMAC_SHA512_256(key, data)
 type Committed struct {
@@ -50,7 +83,7 @@ func (m *Committed) Reset()         { *m = Committed{} }
 func (m *Committed) String() string { return proto.CompactTextString(m) }
 func (*Committed) ProtoMessage()    {}
 func (*Committed) Descriptor() ([]byte, []int) {
-	return fileDescriptor_keytransparency_1a4dae9ae6f53062, []int{0}
+	return fileDescriptor_keytransparency_1f9ecd1ae48c4eda, []int{0}
 }
 func (m *Committed) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Committed.Unmarshal(m, b)
@@ -99,7 +132,7 @@ func (m *EntryUpdate) Reset()         { *m = EntryUpdate{} }
 func (m *EntryUpdate) String() string { return proto.CompactTextString(m) }
 func (*EntryUpdate) ProtoMessage()    {}
 func (*EntryUpdate) Descriptor() ([]byte, []int) {
-	return fileDescriptor_keytransparency_1a4dae9ae6f53062, []int{1}
+	return fileDescriptor_keytransparency_1f9ecd1ae48c4eda, []int{1}
 }
 func (m *EntryUpdate) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_EntryUpdate.Unmarshal(m, b)
@@ -160,7 +193,7 @@ func (m *Entry) Reset()         { *m = Entry{} }
 func (m *Entry) String() string { return proto.CompactTextString(m) }
 func (*Entry) ProtoMessage()    {}
 func (*Entry) Descriptor() ([]byte, []int) {
-	return fileDescriptor_keytransparency_1a4dae9ae6f53062, []int{2}
+	return fileDescriptor_keytransparency_1f9ecd1ae48c4eda, []int{2}
 }
 func (m *Entry) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Entry.Unmarshal(m, b)
@@ -234,7 +267,7 @@ func (m *MutationProof) Reset()         { *m = MutationProof{} }
 func (m *MutationProof) String() string { return proto.CompactTextString(m) }
 func (*MutationProof) ProtoMessage()    {}
 func (*MutationProof) Descriptor() ([]byte, []int) {
-	return fileDescriptor_keytransparency_1a4dae9ae6f53062, []int{3}
+	return fileDescriptor_keytransparency_1f9ecd1ae48c4eda, []int{3}
 }
 func (m *MutationProof) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_MutationProof.Unmarshal(m, b)
@@ -281,7 +314,7 @@ func (m *MapperMetadata) Reset()         { *m = MapperMetadata{} }
 func (m *MapperMetadata) String() string { return proto.CompactTextString(m) }
 func (*MapperMetadata) ProtoMessage()    {}
 func (*MapperMetadata) Descriptor() ([]byte, []int) {
-	return fileDescriptor_keytransparency_1a4dae9ae6f53062, []int{4}
+	return fileDescriptor_keytransparency_1f9ecd1ae48c4eda, []int{4}
 }
 func (m *MapperMetadata) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_MapperMetadata.Unmarshal(m, b)
@@ -328,7 +361,7 @@ func (m *GetEntryRequest) Reset()         { *m = GetEntryRequest{} }
 func (m *GetEntryRequest) String() string { return proto.CompactTextString(m) }
 func (*GetEntryRequest) ProtoMessage()    {}
 func (*GetEntryRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_keytransparency_1a4dae9ae6f53062, []int{5}
+	return fileDescriptor_keytransparency_1f9ecd1ae48c4eda, []int{5}
 }
 func (m *GetEntryRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GetEntryRequest.Unmarshal(m, b)
@@ -405,7 +438,7 @@ func (m *GetEntryResponse) Reset()         { *m = GetEntryResponse{} }
 func (m *GetEntryResponse) String() string { return proto.CompactTextString(m) }
 func (*GetEntryResponse) ProtoMessage()    {}
 func (*GetEntryResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_keytransparency_1a4dae9ae6f53062, []int{6}
+	return fileDescriptor_keytransparency_1f9ecd1ae48c4eda, []int{6}
 }
 func (m *GetEntryResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GetEntryResponse.Unmarshal(m, b)
@@ -474,6 +507,83 @@ func (m *GetEntryResponse) GetLogInclusion() [][]byte {
 	return nil
 }
 
+// GetEntryByRevisionRequest for a user's entry at a specific map revision.
+type GetEntryByRevisionRequest struct {
+	// domain_id identifies the domain in which the user and application live.
+	DomainId string `protobuf:"bytes,1,opt,name=domain_id,json=domainId" json:"domain_id,omitempty"`
+	// user_id is the user identifier. Most commonly an email address.
+	UserId string `protobuf:"bytes,2,opt,name=user_id,json=userId" json:"user_id,omitempty"`
+	// app_id is the identifier for the application.
+	AppId string `protobuf:"bytes,3,opt,name=app_id,json=appId" json:"app_id,omitempty"`
+	// revision is the map revision (epoch) at which to read the entry.
+	Revision int64 `protobuf:"varint,4,opt,name=revision" json:"revision,omitempty"`
+	// first_tree_size is the tree_size of the currently trusted log root.
+	// Omitting this field will omit the log consistency proof from the response.
+	FirstTreeSize        int64    `protobuf:"varint,5,opt,name=first_tree_size,json=firstTreeSize" json:"first_tree_size,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetEntryByRevisionRequest) Reset()         { *m = GetEntryByRevisionRequest{} }
+func (m *GetEntryByRevisionRequest) String() string { return proto.CompactTextString(m) }
+func (*GetEntryByRevisionRequest) ProtoMessage()    {}
+func (*GetEntryByRevisionRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_keytransparency_1f9ecd1ae48c4eda, []int{7}
+}
+func (m *GetEntryByRevisionRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetEntryByRevisionRequest.Unmarshal(m, b)
+}
+func (m *GetEntryByRevisionRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetEntryByRevisionRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetEntryByRevisionRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetEntryByRevisionRequest.Merge(dst, src)
+}
+func (m *GetEntryByRevisionRequest) XXX_Size() int {
+	return xxx_messageInfo_GetEntryByRevisionRequest.Size(m)
+}
+func (m *GetEntryByRevisionRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetEntryByRevisionRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetEntryByRevisionRequest proto.InternalMessageInfo
+
+func (m *GetEntryByRevisionRequest) GetDomainId() string {
+	if m != nil {
+		return m.DomainId
+	}
+	return ""
+}
+
+func (m *GetEntryByRevisionRequest) GetUserId() string {
+	if m != nil {
+		return m.UserId
+	}
+	return ""
+}
+
+func (m *GetEntryByRevisionRequest) GetAppId() string {
+	if m != nil {
+		return m.AppId
+	}
+	return ""
+}
+
+func (m *GetEntryByRevisionRequest) GetRevision() int64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
+
+func (m *GetEntryByRevisionRequest) GetFirstTreeSize() int64 {
+	if m != nil {
+		return m.FirstTreeSize
+	}
+	return 0
+}
+
 // ListEntryHistoryRequest gets a list of historical keys for a user.
 type ListEntryHistoryRequest struct {
 	// domain_id identifies the domain in which the user and application live.
@@ -488,7 +598,10 @@ type ListEntryHistoryRequest struct {
 	AppId string `protobuf:"bytes,4,opt,name=app_id,json=appId" json:"app_id,omitempty"`
 	// first_tree_size is the tree_size of the currently trusted log root.
 	// Omitting this field will omit the log consistency proof from the response.
-	FirstTreeSize        int64    `protobuf:"varint,5,opt,name=first_tree_size,json=firstTreeSize" json:"first_tree_size,omitempty"`
+	FirstTreeSize int64 `protobuf:"varint,5,opt,name=first_tree_size,json=firstTreeSize" json:"first_tree_size,omitempty"`
+	// sparse requests only the epochs in which the entry changed.
+	// When set, page_size is the maximum number of changes to return.
+	Sparse               bool     `protobuf:"varint,7,opt,name=sparse" json:"sparse,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -498,7 +611,7 @@ func (m *ListEntryHistoryRequest) Reset()         { *m = ListEntryHistoryRequest
 func (m *ListEntryHistoryRequest) String() string { return proto.CompactTextString(m) }
 func (*ListEntryHistoryRequest) ProtoMessage()    {}
 func (*ListEntryHistoryRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_keytransparency_1a4dae9ae6f53062, []int{7}
+	return fileDescriptor_keytransparency_1f9ecd1ae48c4eda, []int{8}
 }
 func (m *ListEntryHistoryRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ListEntryHistoryRequest.Unmarshal(m, b)
@@ -560,9 +673,21 @@ func (m *ListEntryHistoryRequest) GetFirstTreeSize() int64 {
 	return 0
 }
 
+func (m *ListEntryHistoryRequest) GetSparse() bool {
+	if m != nil {
+		return m.Sparse
+	}
+	return false
+}
+
 // ListEntryHistoryResponse requests a paginated history of keys for a user.
 type ListEntryHistoryResponse struct {
 	// values represents the list of keys this user_id has contained over time.
+	//
+	// For sparse requests, values contains the entry at start, the entry at
+	// each epoch in which it changed, and the entry at the epoch before each
+	// change. Two consecutive values are either for consecutive epochs or have
+	// the same leaf value, which proves that the entry did not change in between.
 	Values []*GetEntryResponse `protobuf:"bytes,1,rep,name=values" json:"values,omitempty"`
 	// next_start is the next page token to query for pagination.
 	// next_start is 0 when there are no more results to fetch.
@@ -576,7 +701,7 @@ func (m *ListEntryHistoryResponse) Reset()         { *m = ListEntryHistoryRespon
 func (m *ListEntryHistoryResponse) String() string { return proto.CompactTextString(m) }
 func (*ListEntryHistoryResponse) ProtoMessage()    {}
 func (*ListEntryHistoryResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_keytransparency_1a4dae9ae6f53062, []int{8}
+	return fileDescriptor_keytransparency_1f9ecd1ae48c4eda, []int{9}
 }
 func (m *ListEntryHistoryResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ListEntryHistoryResponse.Unmarshal(m, b)
@@ -632,7 +757,7 @@ func (m *UpdateEntryRequest) Reset()         { *m = UpdateEntryRequest{} }
 func (m *UpdateEntryRequest) String() string { return proto.CompactTextString(m) }
 func (*UpdateEntryRequest) ProtoMessage()    {}
 func (*UpdateEntryRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_keytransparency_1a4dae9ae6f53062, []int{9}
+	return fileDescriptor_keytransparency_1f9ecd1ae48c4eda, []int{10}
 }
 func (m *UpdateEntryRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_UpdateEntryRequest.Unmarshal(m, b)
@@ -691,17 +816,21 @@ func (m *UpdateEntryRequest) GetEntryUpdate() *EntryUpdate {
 // the Merkle Tree.
 type UpdateEntryResponse struct {
 	// proof contains a proof that the update has been included in the tree.
-	Proof                *GetEntryResponse `protobuf:"bytes,1,opt,name=proof" json:"proof,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
-	XXX_unrecognized     []byte            `json:"-"`
-	XXX_sizecache        int32             `json:"-"`
+	Proof *GetEntryResponse `protobuf:"bytes,1,opt,name=proof" json:"proof,omitempty"`
+	// receipt is the server's signed promise to include the update in an epoch
+	// before a deadline. receipt is omitted if the domain has no receipt key or
+	// if the update has already been applied.
+	Receipt              *MutationReceipt `protobuf:"bytes,2,opt,name=receipt" json:"receipt,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
 }
 
 func (m *UpdateEntryResponse) Reset()         { *m = UpdateEntryResponse{} }
 func (m *UpdateEntryResponse) String() string { return proto.CompactTextString(m) }
 func (*UpdateEntryResponse) ProtoMessage()    {}
 func (*UpdateEntryResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_keytransparency_1a4dae9ae6f53062, []int{10}
+	return fileDescriptor_keytransparency_1f9ecd1ae48c4eda, []int{11}
 }
 func (m *UpdateEntryResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_UpdateEntryResponse.Unmarshal(m, b)
@@ -728,6 +857,83 @@ func (m *UpdateEntryResponse) GetProof() *GetEntryResponse {
 	return nil
 }
 
+func (m *UpdateEntryResponse) GetReceipt() *MutationReceipt {
+	if m != nil {
+		return m.Receipt
+	}
+	return nil
+}
+
+// MutationReceipt is a promise by the server to include a mutation in an epoch
+// created no later than deadline.
+type MutationReceipt struct {
+	// domain_id is the domain the mutation was submitted to.
+	DomainId string `protobuf:"bytes,1,opt,name=domain_id,json=domainId" json:"domain_id,omitempty"`
+	// mutation_hash is the ObjectHash of the submitted Entry.
+	MutationHash []byte `protobuf:"bytes,2,opt,name=mutation_hash,json=mutationHash,proto3" json:"mutation_hash,omitempty"`
+	// deadline is the latest time at which the epoch containing the mutation
+	// will be created. deadline is derived from the domain's max_interval.
+	Deadline *timestamp.Timestamp `protobuf:"bytes,3,opt,name=deadline" json:"deadline,omitempty"`
+	// signature is over the serialized receipt with the signature field unset,
+	// made with the domain's receipt key.
+	Signature            *sigpb.DigitallySigned `protobuf:"bytes,4,opt,name=signature" json:"signature,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}               `json:"-"`
+	XXX_unrecognized     []byte                 `json:"-"`
+	XXX_sizecache        int32                  `json:"-"`
+}
+
+func (m *MutationReceipt) Reset()         { *m = MutationReceipt{} }
+func (m *MutationReceipt) String() string { return proto.CompactTextString(m) }
+func (*MutationReceipt) ProtoMessage()    {}
+func (*MutationReceipt) Descriptor() ([]byte, []int) {
+	return fileDescriptor_keytransparency_1f9ecd1ae48c4eda, []int{12}
+}
+func (m *MutationReceipt) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MutationReceipt.Unmarshal(m, b)
+}
+func (m *MutationReceipt) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MutationReceipt.Marshal(b, m, deterministic)
+}
+func (dst *MutationReceipt) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MutationReceipt.Merge(dst, src)
+}
+func (m *MutationReceipt) XXX_Size() int {
+	return xxx_messageInfo_MutationReceipt.Size(m)
+}
+func (m *MutationReceipt) XXX_DiscardUnknown() {
+	xxx_messageInfo_MutationReceipt.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MutationReceipt proto.InternalMessageInfo
+
+func (m *MutationReceipt) GetDomainId() string {
+	if m != nil {
+		return m.DomainId
+	}
+	return ""
+}
+
+func (m *MutationReceipt) GetMutationHash() []byte {
+	if m != nil {
+		return m.MutationHash
+	}
+	return nil
+}
+
+func (m *MutationReceipt) GetDeadline() *timestamp.Timestamp {
+	if m != nil {
+		return m.Deadline
+	}
+	return nil
+}
+
+func (m *MutationReceipt) GetSignature() *sigpb.DigitallySigned {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
 // GetEpochRequest identifies a particular epoch.
 type GetEpochRequest struct {
 	// domain_id is the domain for which epochs are being requested.
@@ -746,7 +952,7 @@ func (m *GetEpochRequest) Reset()         { *m = GetEpochRequest{} }
 func (m *GetEpochRequest) String() string { return proto.CompactTextString(m) }
 func (*GetEpochRequest) ProtoMessage()    {}
 func (*GetEpochRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_keytransparency_1a4dae9ae6f53062, []int{11}
+	return fileDescriptor_keytransparency_1f9ecd1ae48c4eda, []int{13}
 }
 func (m *GetEpochRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GetEpochRequest.Unmarshal(m, b)
@@ -803,7 +1009,7 @@ func (m *GetLatestEpochRequest) Reset()         { *m = GetLatestEpochRequest{} }
 func (m *GetLatestEpochRequest) String() string { return proto.CompactTextString(m) }
 func (*GetLatestEpochRequest) ProtoMessage()    {}
 func (*GetLatestEpochRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_keytransparency_1a4dae9ae6f53062, []int{12}
+	return fileDescriptor_keytransparency_1f9ecd1ae48c4eda, []int{14}
 }
 func (m *GetLatestEpochRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GetLatestEpochRequest.Unmarshal(m, b)
@@ -859,7 +1065,7 @@ func (m *Epoch) Reset()         { *m = Epoch{} }
 func (m *Epoch) String() string { return proto.CompactTextString(m) }
 func (*Epoch) ProtoMessage()    {}
 func (*Epoch) Descriptor() ([]byte, []int) {
-	return fileDescriptor_keytransparency_1a4dae9ae6f53062, []int{13}
+	return fileDescriptor_keytransparency_1f9ecd1ae48c4eda, []int{15}
 }
 func (m *Epoch) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Epoch.Unmarshal(m, b)
@@ -936,7 +1142,7 @@ func (m *ListMutationsRequest) Reset()         { *m = ListMutationsRequest{} }
 func (m *ListMutationsRequest) String() string { return proto.CompactTextString(m) }
 func (*ListMutationsRequest) ProtoMessage()    {}
 func (*ListMutationsRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_keytransparency_1a4dae9ae6f53062, []int{14}
+	return fileDescriptor_keytransparency_1f9ecd1ae48c4eda, []int{16}
 }
 func (m *ListMutationsRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ListMutationsRequest.Unmarshal(m, b)
@@ -1000,7 +1206,7 @@ func (m *ListMutationsResponse) Reset()         { *m = ListMutationsResponse{} }
 func (m *ListMutationsResponse) String() string { return proto.CompactTextString(m) }
 func (*ListMutationsResponse) ProtoMessage()    {}
 func (*ListMutationsResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_keytransparency_1a4dae9ae6f53062, []int{15}
+	return fileDescriptor_keytransparency_1f9ecd1ae48c4eda, []int{17}
 }
 func (m *ListMutationsResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ListMutationsResponse.Unmarshal(m, b)
@@ -1034,6 +1240,393 @@ func (m *ListMutationsResponse) GetNextPageToken() string {
 	return ""
 }
 
+// EntryIdentifier identifies a user entry within a domain.
+type EntryIdentifier struct {
+	// user_id is the user identifier. Most commonly an email address.
+	UserId string `protobuf:"bytes,1,opt,name=user_id,json=userId" json:"user_id,omitempty"`
+	// app_id is the identifier for the application.
+	AppId                string   `protobuf:"bytes,2,opt,name=app_id,json=appId" json:"app_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *EntryIdentifier) Reset()         { *m = EntryIdentifier{} }
+func (m *EntryIdentifier) String() string { return proto.CompactTextString(m) }
+func (*EntryIdentifier) ProtoMessage()    {}
+func (*EntryIdentifier) Descriptor() ([]byte, []int) {
+	return fileDescriptor_keytransparency_1f9ecd1ae48c4eda, []int{18}
+}
+func (m *EntryIdentifier) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_EntryIdentifier.Unmarshal(m, b)
+}
+func (m *EntryIdentifier) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_EntryIdentifier.Marshal(b, m, deterministic)
+}
+func (dst *EntryIdentifier) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EntryIdentifier.Merge(dst, src)
+}
+func (m *EntryIdentifier) XXX_Size() int {
+	return xxx_messageInfo_EntryIdentifier.Size(m)
+}
+func (m *EntryIdentifier) XXX_DiscardUnknown() {
+	xxx_messageInfo_EntryIdentifier.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EntryIdentifier proto.InternalMessageInfo
+
+func (m *EntryIdentifier) GetUserId() string {
+	if m != nil {
+		return m.UserId
+	}
+	return ""
+}
+
+func (m *EntryIdentifier) GetAppId() string {
+	if m != nil {
+		return m.AppId
+	}
+	return ""
+}
+
+// BatchGetEntriesRequest identifies a set of entries to fetch from the same
+// map revision.
+type BatchGetEntriesRequest struct {
+	// domain_id identifies the domain in which the users and applications live.
+	DomainId string `protobuf:"bytes,1,opt,name=domain_id,json=domainId" json:"domain_id,omitempty"`
+	// entries identifies the entries to fetch.
+	Entries []*EntryIdentifier `protobuf:"bytes,2,rep,name=entries" json:"entries,omitempty"`
+	// first_tree_size is the tree_size of the currently trusted log root.
+	// Omitting this field will omit the log consistency proof from the response.
+	FirstTreeSize        int64    `protobuf:"varint,3,opt,name=first_tree_size,json=firstTreeSize" json:"first_tree_size,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BatchGetEntriesRequest) Reset()         { *m = BatchGetEntriesRequest{} }
+func (m *BatchGetEntriesRequest) String() string { return proto.CompactTextString(m) }
+func (*BatchGetEntriesRequest) ProtoMessage()    {}
+func (*BatchGetEntriesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_keytransparency_1f9ecd1ae48c4eda, []int{19}
+}
+func (m *BatchGetEntriesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchGetEntriesRequest.Unmarshal(m, b)
+}
+func (m *BatchGetEntriesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchGetEntriesRequest.Marshal(b, m, deterministic)
+}
+func (dst *BatchGetEntriesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchGetEntriesRequest.Merge(dst, src)
+}
+func (m *BatchGetEntriesRequest) XXX_Size() int {
+	return xxx_messageInfo_BatchGetEntriesRequest.Size(m)
+}
+func (m *BatchGetEntriesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchGetEntriesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchGetEntriesRequest proto.InternalMessageInfo
+
+func (m *BatchGetEntriesRequest) GetDomainId() string {
+	if m != nil {
+		return m.DomainId
+	}
+	return ""
+}
+
+func (m *BatchGetEntriesRequest) GetEntries() []*EntryIdentifier {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+func (m *BatchGetEntriesRequest) GetFirstTreeSize() int64 {
+	if m != nil {
+		return m.FirstTreeSize
+	}
+	return 0
+}
+
+// BatchGetEntriesResponse returns a set of user entries and the roots that
+// they are all proven against.
+type BatchGetEntriesResponse struct {
+	// entries contains the requested entries, in the same order as the request.
+	// The smr, log_root, log_consistency and log_inclusion fields of each entry
+	// are left empty. The fields below apply to every entry.
+	Entries []*GetEntryResponse `protobuf:"bytes,1,rep,name=entries" json:"entries,omitempty"`
+	// smr contains the signed map head for the sparse Merkle Tree.
+	Smr *trillian.SignedMapRoot `protobuf:"bytes,2,opt,name=smr" json:"smr,omitempty"`
+	// log_root is the latest globally consistent log root.
+	LogRoot *trillian.SignedLogRoot `protobuf:"bytes,3,opt,name=log_root,json=logRoot" json:"log_root,omitempty"`
+	// log_consistency proves that log_root is consistent with previously seen roots.
+	LogConsistency [][]byte `protobuf:"bytes,4,rep,name=log_consistency,json=logConsistency,proto3" json:"log_consistency,omitempty"`
+	// log_inclusion proves that smr is part of log_root at index=srm.MapRevision.
+	LogInclusion         [][]byte `protobuf:"bytes,5,rep,name=log_inclusion,json=logInclusion,proto3" json:"log_inclusion,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BatchGetEntriesResponse) Reset()         { *m = BatchGetEntriesResponse{} }
+func (m *BatchGetEntriesResponse) String() string { return proto.CompactTextString(m) }
+func (*BatchGetEntriesResponse) ProtoMessage()    {}
+func (*BatchGetEntriesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_keytransparency_1f9ecd1ae48c4eda, []int{20}
+}
+func (m *BatchGetEntriesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchGetEntriesResponse.Unmarshal(m, b)
+}
+func (m *BatchGetEntriesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchGetEntriesResponse.Marshal(b, m, deterministic)
+}
+func (dst *BatchGetEntriesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchGetEntriesResponse.Merge(dst, src)
+}
+func (m *BatchGetEntriesResponse) XXX_Size() int {
+	return xxx_messageInfo_BatchGetEntriesResponse.Size(m)
+}
+func (m *BatchGetEntriesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchGetEntriesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchGetEntriesResponse proto.InternalMessageInfo
+
+func (m *BatchGetEntriesResponse) GetEntries() []*GetEntryResponse {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+func (m *BatchGetEntriesResponse) GetSmr() *trillian.SignedMapRoot {
+	if m != nil {
+		return m.Smr
+	}
+	return nil
+}
+
+func (m *BatchGetEntriesResponse) GetLogRoot() *trillian.SignedLogRoot {
+	if m != nil {
+		return m.LogRoot
+	}
+	return nil
+}
+
+func (m *BatchGetEntriesResponse) GetLogConsistency() [][]byte {
+	if m != nil {
+		return m.LogConsistency
+	}
+	return nil
+}
+
+func (m *BatchGetEntriesResponse) GetLogInclusion() [][]byte {
+	if m != nil {
+		return m.LogInclusion
+	}
+	return nil
+}
+
+// BatchUpdateEntriesRequest updates a set of user profiles.
+type BatchUpdateEntriesRequest struct {
+	// domain_id identifies the domain in which the users and applications live.
+	DomainId string `protobuf:"bytes,1,opt,name=domain_id,json=domainId" json:"domain_id,omitempty"`
+	// updates contains the individual updates. The domain_id of each update
+	// must be empty or equal to domain_id above.
+	Updates              []*UpdateEntryRequest `protobuf:"bytes,2,rep,name=updates" json:"updates,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
+	XXX_unrecognized     []byte                `json:"-"`
+	XXX_sizecache        int32                 `json:"-"`
+}
+
+func (m *BatchUpdateEntriesRequest) Reset()         { *m = BatchUpdateEntriesRequest{} }
+func (m *BatchUpdateEntriesRequest) String() string { return proto.CompactTextString(m) }
+func (*BatchUpdateEntriesRequest) ProtoMessage()    {}
+func (*BatchUpdateEntriesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_keytransparency_1f9ecd1ae48c4eda, []int{21}
+}
+func (m *BatchUpdateEntriesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchUpdateEntriesRequest.Unmarshal(m, b)
+}
+func (m *BatchUpdateEntriesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchUpdateEntriesRequest.Marshal(b, m, deterministic)
+}
+func (dst *BatchUpdateEntriesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchUpdateEntriesRequest.Merge(dst, src)
+}
+func (m *BatchUpdateEntriesRequest) XXX_Size() int {
+	return xxx_messageInfo_BatchUpdateEntriesRequest.Size(m)
+}
+func (m *BatchUpdateEntriesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchUpdateEntriesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchUpdateEntriesRequest proto.InternalMessageInfo
+
+func (m *BatchUpdateEntriesRequest) GetDomainId() string {
+	if m != nil {
+		return m.DomainId
+	}
+	return ""
+}
+
+func (m *BatchUpdateEntriesRequest) GetUpdates() []*UpdateEntryRequest {
+	if m != nil {
+		return m.Updates
+	}
+	return nil
+}
+
+// BatchUpdateEntriesResponse contains the outcome of each update in a batch.
+type BatchUpdateEntriesResponse struct {
+	// results contains the status of each update, in the same order as the
+	// request. An OK status means the update has been queued or was already
+	// applied.
+	Results              []*status.Status `protobuf:"bytes,1,rep,name=results" json:"results,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *BatchUpdateEntriesResponse) Reset()         { *m = BatchUpdateEntriesResponse{} }
+func (m *BatchUpdateEntriesResponse) String() string { return proto.CompactTextString(m) }
+func (*BatchUpdateEntriesResponse) ProtoMessage()    {}
+func (*BatchUpdateEntriesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_keytransparency_1f9ecd1ae48c4eda, []int{22}
+}
+func (m *BatchUpdateEntriesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BatchUpdateEntriesResponse.Unmarshal(m, b)
+}
+func (m *BatchUpdateEntriesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BatchUpdateEntriesResponse.Marshal(b, m, deterministic)
+}
+func (dst *BatchUpdateEntriesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BatchUpdateEntriesResponse.Merge(dst, src)
+}
+func (m *BatchUpdateEntriesResponse) XXX_Size() int {
+	return xxx_messageInfo_BatchUpdateEntriesResponse.Size(m)
+}
+func (m *BatchUpdateEntriesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_BatchUpdateEntriesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BatchUpdateEntriesResponse proto.InternalMessageInfo
+
+func (m *BatchUpdateEntriesResponse) GetResults() []*status.Status {
+	if m != nil {
+		return m.Results
+	}
+	return nil
+}
+
+// GetMutationStatusRequest identifies a submitted mutation.
+type GetMutationStatusRequest struct {
+	// domain_id identifies the domain the mutation was submitted to.
+	DomainId string `protobuf:"bytes,1,opt,name=domain_id,json=domainId" json:"domain_id,omitempty"`
+	// mutation_hash is the ObjectHash of the submitted Entry.
+	MutationHash         []byte   `protobuf:"bytes,2,opt,name=mutation_hash,json=mutationHash,proto3" json:"mutation_hash,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetMutationStatusRequest) Reset()         { *m = GetMutationStatusRequest{} }
+func (m *GetMutationStatusRequest) String() string { return proto.CompactTextString(m) }
+func (*GetMutationStatusRequest) ProtoMessage()    {}
+func (*GetMutationStatusRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_keytransparency_1f9ecd1ae48c4eda, []int{23}
+}
+func (m *GetMutationStatusRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetMutationStatusRequest.Unmarshal(m, b)
+}
+func (m *GetMutationStatusRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetMutationStatusRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetMutationStatusRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetMutationStatusRequest.Merge(dst, src)
+}
+func (m *GetMutationStatusRequest) XXX_Size() int {
+	return xxx_messageInfo_GetMutationStatusRequest.Size(m)
+}
+func (m *GetMutationStatusRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetMutationStatusRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetMutationStatusRequest proto.InternalMessageInfo
+
+func (m *GetMutationStatusRequest) GetDomainId() string {
+	if m != nil {
+		return m.DomainId
+	}
+	return ""
+}
+
+func (m *GetMutationStatusRequest) GetMutationHash() []byte {
+	if m != nil {
+		return m.MutationHash
+	}
+	return nil
+}
+
+// MutationStatus reports the processing state of a submitted mutation.
+type MutationStatus struct {
+	// state is the processing state of the mutation.
+	State MutationStatus_State `protobuf:"varint,1,opt,name=state,enum=google.keytransparency.v1.MutationStatus_State" json:"state,omitempty"`
+	// epoch is the epoch in which the mutation was applied or rejected.
+	Epoch int64 `protobuf:"varint,2,opt,name=epoch" json:"epoch,omitempty"`
+	// error describes why the mutation was rejected.
+	Error                string   `protobuf:"bytes,3,opt,name=error" json:"error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MutationStatus) Reset()         { *m = MutationStatus{} }
+func (m *MutationStatus) String() string { return proto.CompactTextString(m) }
+func (*MutationStatus) ProtoMessage()    {}
+func (*MutationStatus) Descriptor() ([]byte, []int) {
+	return fileDescriptor_keytransparency_1f9ecd1ae48c4eda, []int{24}
+}
+func (m *MutationStatus) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MutationStatus.Unmarshal(m, b)
+}
+func (m *MutationStatus) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MutationStatus.Marshal(b, m, deterministic)
+}
+func (dst *MutationStatus) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MutationStatus.Merge(dst, src)
+}
+func (m *MutationStatus) XXX_Size() int {
+	return xxx_messageInfo_MutationStatus.Size(m)
+}
+func (m *MutationStatus) XXX_DiscardUnknown() {
+	xxx_messageInfo_MutationStatus.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MutationStatus proto.InternalMessageInfo
+
+func (m *MutationStatus) GetState() MutationStatus_State {
+	if m != nil {
+		return m.State
+	}
+	return MutationStatus_UNKNOWN
+}
+
+func (m *MutationStatus) GetEpoch() int64 {
+	if m != nil {
+		return m.Epoch
+	}
+	return 0
+}
+
+func (m *MutationStatus) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*Committed)(nil), "google.keytransparency.v1.Committed")
 	proto.RegisterType((*EntryUpdate)(nil), "google.keytransparency.v1.EntryUpdate")
@@ -1042,15 +1635,25 @@ func init() {
 	proto.RegisterType((*MapperMetadata)(nil), "google.keytransparency.v1.MapperMetadata")
 	proto.RegisterType((*GetEntryRequest)(nil), "google.keytransparency.v1.GetEntryRequest")
 	proto.RegisterType((*GetEntryResponse)(nil), "google.keytransparency.v1.GetEntryResponse")
+	proto.RegisterType((*GetEntryByRevisionRequest)(nil), "google.keytransparency.v1.GetEntryByRevisionRequest")
 	proto.RegisterType((*ListEntryHistoryRequest)(nil), "google.keytransparency.v1.ListEntryHistoryRequest")
 	proto.RegisterType((*ListEntryHistoryResponse)(nil), "google.keytransparency.v1.ListEntryHistoryResponse")
 	proto.RegisterType((*UpdateEntryRequest)(nil), "google.keytransparency.v1.UpdateEntryRequest")
 	proto.RegisterType((*UpdateEntryResponse)(nil), "google.keytransparency.v1.UpdateEntryResponse")
+	proto.RegisterType((*MutationReceipt)(nil), "google.keytransparency.v1.MutationReceipt")
 	proto.RegisterType((*GetEpochRequest)(nil), "google.keytransparency.v1.GetEpochRequest")
 	proto.RegisterType((*GetLatestEpochRequest)(nil), "google.keytransparency.v1.GetLatestEpochRequest")
 	proto.RegisterType((*Epoch)(nil), "google.keytransparency.v1.Epoch")
 	proto.RegisterType((*ListMutationsRequest)(nil), "google.keytransparency.v1.ListMutationsRequest")
 	proto.RegisterType((*ListMutationsResponse)(nil), "google.keytransparency.v1.ListMutationsResponse")
+	proto.RegisterType((*EntryIdentifier)(nil), "google.keytransparency.v1.EntryIdentifier")
+	proto.RegisterType((*BatchGetEntriesRequest)(nil), "google.keytransparency.v1.BatchGetEntriesRequest")
+	proto.RegisterType((*BatchGetEntriesResponse)(nil), "google.keytransparency.v1.BatchGetEntriesResponse")
+	proto.RegisterType((*BatchUpdateEntriesRequest)(nil), "google.keytransparency.v1.BatchUpdateEntriesRequest")
+	proto.RegisterType((*BatchUpdateEntriesResponse)(nil), "google.keytransparency.v1.BatchUpdateEntriesResponse")
+	proto.RegisterType((*GetMutationStatusRequest)(nil), "google.keytransparency.v1.GetMutationStatusRequest")
+	proto.RegisterType((*MutationStatus)(nil), "google.keytransparency.v1.MutationStatus")
+	proto.RegisterEnum("google.keytransparency.v1.MutationStatus_State", MutationStatus_State_name, MutationStatus_State_value)
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -1079,12 +1682,24 @@ type KeyTransparencyClient interface {
 	// ListMutations returns a list of mutations in a specific epoch.
 	ListMutations(ctx context.Context, in *ListMutationsRequest, opts ...grpc.CallOption) (*ListMutationsResponse, error)
 	// ListMutationsStream is a streaming list of mutations in a specific epoch.
+	// All the mutations from page_token on are streamed; page_size is ignored.
 	ListMutationsStream(ctx context.Context, in *ListMutationsRequest, opts ...grpc.CallOption) (KeyTransparency_ListMutationsStreamClient, error)
 	// GetEntry returns a user's entry in the Merkle Tree.
 	//
 	// Entries contain signed commitments to a profile, which is also returned.
 	// TODO(gbelvin): Replace with GetUser
 	GetEntry(ctx context.Context, in *GetEntryRequest, opts ...grpc.CallOption) (*GetEntryResponse, error)
+	// GetEntryByRevision returns a user's entry in the Merkle Tree at a specific
+	// map revision.
+	//
+	// The response proves that the entry is part of the map root at that
+	// revision, and that the map root is included in the latest log root.
+	GetEntryByRevision(ctx context.Context, in *GetEntryByRevisionRequest, opts ...grpc.CallOption) (*GetEntryResponse, error)
+	// BatchGetEntries returns a set of user entries in the Merkle Tree.
+	//
+	// All entries are read from the same map revision and share a single set of
+	// map and log proofs.
+	BatchGetEntries(ctx context.Context, in *BatchGetEntriesRequest, opts ...grpc.CallOption) (*BatchGetEntriesResponse, error)
 	// ListEntryHistory returns a list of historic GetEntry values.
 	//
 	// Clients verify their account history by observing correct values for their
@@ -1095,6 +1710,15 @@ type KeyTransparencyClient interface {
 	// Returns the current user profile.
 	// Clients must retry until this function returns a proof containing the desired value.
 	UpdateEntry(ctx context.Context, in *UpdateEntryRequest, opts ...grpc.CallOption) (*UpdateEntryResponse, error)
+	// BatchUpdateEntries updates a set of user profiles.
+	//
+	// Each update is authorized, validated and queued independently. An
+	// unauthorized or invalid update does not prevent the others from being
+	// queued.
+	BatchUpdateEntries(ctx context.Context, in *BatchUpdateEntriesRequest, opts ...grpc.CallOption) (*BatchUpdateEntriesResponse, error)
+	// GetMutationStatus reports whether a submitted mutation is still queued,
+	// has been applied, or has been rejected.
+	GetMutationStatus(ctx context.Context, in *GetMutationStatusRequest, opts ...grpc.CallOption) (*MutationStatus, error)
 }
 
 type keyTransparencyClient struct {
@@ -1214,6 +1838,24 @@ func (c *keyTransparencyClient) GetEntry(ctx context.Context, in *GetEntryReques
 	return out, nil
 }
 
+func (c *keyTransparencyClient) GetEntryByRevision(ctx context.Context, in *GetEntryByRevisionRequest, opts ...grpc.CallOption) (*GetEntryResponse, error) {
+	out := new(GetEntryResponse)
+	err := c.cc.Invoke(ctx, "/google.keytransparency.v1.KeyTransparency/GetEntryByRevision", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keyTransparencyClient) BatchGetEntries(ctx context.Context, in *BatchGetEntriesRequest, opts ...grpc.CallOption) (*BatchGetEntriesResponse, error) {
+	out := new(BatchGetEntriesResponse)
+	err := c.cc.Invoke(ctx, "/google.keytransparency.v1.KeyTransparency/BatchGetEntries", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *keyTransparencyClient) ListEntryHistory(ctx context.Context, in *ListEntryHistoryRequest, opts ...grpc.CallOption) (*ListEntryHistoryResponse, error) {
 	out := new(ListEntryHistoryResponse)
 	err := c.cc.Invoke(ctx, "/google.keytransparency.v1.KeyTransparency/ListEntryHistory", in, out, opts...)
@@ -1232,6 +1874,24 @@ func (c *keyTransparencyClient) UpdateEntry(ctx context.Context, in *UpdateEntry
 	return out, nil
 }
 
+func (c *keyTransparencyClient) BatchUpdateEntries(ctx context.Context, in *BatchUpdateEntriesRequest, opts ...grpc.CallOption) (*BatchUpdateEntriesResponse, error) {
+	out := new(BatchUpdateEntriesResponse)
+	err := c.cc.Invoke(ctx, "/google.keytransparency.v1.KeyTransparency/BatchUpdateEntries", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *keyTransparencyClient) GetMutationStatus(ctx context.Context, in *GetMutationStatusRequest, opts ...grpc.CallOption) (*MutationStatus, error) {
+	out := new(MutationStatus)
+	err := c.cc.Invoke(ctx, "/google.keytransparency.v1.KeyTransparency/GetMutationStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for KeyTransparency service
 
 type KeyTransparencyServer interface {
@@ -1249,12 +1909,24 @@ type KeyTransparencyServer interface {
 	// ListMutations returns a list of mutations in a specific epoch.
 	ListMutations(context.Context, *ListMutationsRequest) (*ListMutationsResponse, error)
 	// ListMutationsStream is a streaming list of mutations in a specific epoch.
+	// All the mutations from page_token on are streamed; page_size is ignored.
 	ListMutationsStream(*ListMutationsRequest, KeyTransparency_ListMutationsStreamServer) error
 	// GetEntry returns a user's entry in the Merkle Tree.
 	//
 	// Entries contain signed commitments to a profile, which is also returned.
 	// TODO(gbelvin): Replace with GetUser
 	GetEntry(context.Context, *GetEntryRequest) (*GetEntryResponse, error)
+	// GetEntryByRevision returns a user's entry in the Merkle Tree at a specific
+	// map revision.
+	//
+	// The response proves that the entry is part of the map root at that
+	// revision, and that the map root is included in the latest log root.
+	GetEntryByRevision(context.Context, *GetEntryByRevisionRequest) (*GetEntryResponse, error)
+	// BatchGetEntries returns a set of user entries in the Merkle Tree.
+	//
+	// All entries are read from the same map revision and share a single set of
+	// map and log proofs.
+	BatchGetEntries(context.Context, *BatchGetEntriesRequest) (*BatchGetEntriesResponse, error)
 	// ListEntryHistory returns a list of historic GetEntry values.
 	//
 	// Clients verify their account history by observing correct values for their
@@ -1265,6 +1937,15 @@ type KeyTransparencyServer interface {
 	// Returns the current user profile.
 	// Clients must retry until this function returns a proof containing the desired value.
 	UpdateEntry(context.Context, *UpdateEntryRequest) (*UpdateEntryResponse, error)
+	// BatchUpdateEntries updates a set of user profiles.
+	//
+	// Each update is authorized, validated and queued independently. An
+	// unauthorized or invalid update does not prevent the others from being
+	// queued.
+	BatchUpdateEntries(context.Context, *BatchUpdateEntriesRequest) (*BatchUpdateEntriesResponse, error)
+	// GetMutationStatus reports whether a submitted mutation is still queued,
+	// has been applied, or has been rejected.
+	GetMutationStatus(context.Context, *GetMutationStatusRequest) (*MutationStatus, error)
 }
 
 func RegisterKeyTransparencyServer(s *grpc.Server, srv KeyTransparencyServer) {
@@ -1403,6 +2084,42 @@ func _KeyTransparency_GetEntry_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _KeyTransparency_GetEntryByRevision_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetEntryByRevisionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeyTransparencyServer).GetEntryByRevision(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/google.keytransparency.v1.KeyTransparency/GetEntryByRevision",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeyTransparencyServer).GetEntryByRevision(ctx, req.(*GetEntryByRevisionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KeyTransparency_BatchGetEntries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchGetEntriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeyTransparencyServer).BatchGetEntries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/google.keytransparency.v1.KeyTransparency/BatchGetEntries",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeyTransparencyServer).BatchGetEntries(ctx, req.(*BatchGetEntriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _KeyTransparency_ListEntryHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(ListEntryHistoryRequest)
 	if err := dec(in); err != nil {
@@ -1439,6 +2156,42 @@ func _KeyTransparency_UpdateEntry_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _KeyTransparency_BatchUpdateEntries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchUpdateEntriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeyTransparencyServer).BatchUpdateEntries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/google.keytransparency.v1.KeyTransparency/BatchUpdateEntries",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeyTransparencyServer).BatchUpdateEntries(ctx, req.(*BatchUpdateEntriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KeyTransparency_GetMutationStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMutationStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KeyTransparencyServer).GetMutationStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/google.keytransparency.v1.KeyTransparency/GetMutationStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KeyTransparencyServer).GetMutationStatus(ctx, req.(*GetMutationStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _KeyTransparency_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "google.keytransparency.v1.KeyTransparency",
 	HandlerType: (*KeyTransparencyServer)(nil),
@@ -1463,6 +2216,14 @@ var _KeyTransparency_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetEntry",
 			Handler:    _KeyTransparency_GetEntry_Handler,
 		},
+		{
+			MethodName: "GetEntryByRevision",
+			Handler:    _KeyTransparency_GetEntryByRevision_Handler,
+		},
+		{
+			MethodName: "BatchGetEntries",
+			Handler:    _KeyTransparency_BatchGetEntries_Handler,
+		},
 		{
 			MethodName: "ListEntryHistory",
 			Handler:    _KeyTransparency_ListEntryHistory_Handler,
@@ -1471,6 +2232,14 @@ var _KeyTransparency_serviceDesc = grpc.ServiceDesc{
 			MethodName: "UpdateEntry",
 			Handler:    _KeyTransparency_UpdateEntry_Handler,
 		},
+		{
+			MethodName: "BatchUpdateEntries",
+			Handler:    _KeyTransparency_BatchUpdateEntries_Handler,
+		},
+		{
+			MethodName: "GetMutationStatus",
+			Handler:    _KeyTransparency_GetMutationStatus_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -1488,90 +2257,125 @@ var _KeyTransparency_serviceDesc = grpc.ServiceDesc{
 }
 
 func init() {
-	proto.RegisterFile("v1/keytransparency.proto", fileDescriptor_keytransparency_1a4dae9ae6f53062)
-}
-
-var fileDescriptor_keytransparency_1a4dae9ae6f53062 = []byte{
-	// 1295 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x98, 0xcf, 0x6f, 0x1b, 0xc5,
-	0x17, 0xc0, 0xb5, 0x76, 0xec, 0xd8, 0xcf, 0xf9, 0x51, 0x4d, 0x93, 0x6f, 0xb6, 0xee, 0xb7, 0x10,
-	0x16, 0x48, 0x43, 0x51, 0xbd, 0x89, 0x2b, 0x54, 0x5a, 0x11, 0x55, 0x34, 0x24, 0x69, 0x94, 0x44,
-	0x54, 0x9b, 0x20, 0x21, 0x84, 0xb4, 0x9a, 0x7a, 0x27, 0xeb, 0x51, 0xd6, 0x3b, 0x9b, 0x9d, 0xb1,
-	0x55, 0x27, 0x84, 0x03, 0x12, 0xd0, 0x5b, 0x0f, 0xfd, 0x0f, 0x38, 0x73, 0xe3, 0xc4, 0x05, 0x09,
-	0xee, 0x1c, 0x10, 0xdc, 0xb8, 0xf2, 0x87, 0xa0, 0x99, 0x59, 0xff, 0x8c, 0xe3, 0xd8, 0x01, 0x71,
-	0xf2, 0xce, 0xdb, 0xf7, 0x66, 0x3e, 0xf3, 0x7e, 0xcd, 0xac, 0xc1, 0x6c, 0xac, 0xda, 0x47, 0xa4,
-	0x29, 0x62, 0x1c, 0xf2, 0x08, 0xc7, 0x24, 0xac, 0x34, 0x4b, 0x51, 0xcc, 0x04, 0x43, 0x37, 0x7c,
-	0xc6, 0xfc, 0x80, 0x94, 0xfa, 0xdf, 0x36, 0x56, 0x8b, 0xff, 0xd7, 0xaf, 0x6c, 0x1c, 0x51, 0x1b,
-	0x87, 0x21, 0x13, 0x58, 0x50, 0x16, 0x72, 0x6d, 0x58, 0x9c, 0x11, 0x31, 0x0d, 0x02, 0x8a, 0xc3,
-	0x64, 0xfc, 0xbf, 0xd6, 0xd8, 0xad, 0xe1, 0xc8, 0xc5, 0x11, 0x4d, 0xe4, 0x20, 0x68, 0x78, 0xd4,
-	0xb2, 0x69, 0xac, 0xda, 0xd8, 0xab, 0xd1, 0xc4, 0xc6, 0x5a, 0x85, 0xfc, 0x3a, 0xab, 0xd5, 0xa8,
-	0x10, 0xc4, 0x43, 0xd7, 0x20, 0x7d, 0x44, 0x9a, 0xa6, 0xb1, 0x68, 0x2c, 0x4f, 0x39, 0xf2, 0x11,
-	0x21, 0x98, 0xf0, 0xb0, 0xc0, 0x66, 0x4a, 0x89, 0xd4, 0xb3, 0xf5, 0xd2, 0x80, 0xc2, 0x46, 0x28,
-	0xe2, 0xe6, 0x27, 0x91, 0x87, 0x05, 0x41, 0x1f, 0x40, 0xae, 0x56, 0xd7, 0x64, 0x4a, 0xaf, 0x50,
-	0x5e, 0x2c, 0x5d, 0xb8, 0xa5, 0x92, 0xb2, 0x74, 0xda, 0x16, 0xe8, 0x31, 0xe4, 0x2b, 0x2d, 0x00,
-	0x33, 0xad, 0xcc, 0xdf, 0x1a, 0x62, 0xde, 0x86, 0x75, 0x3a, 0x66, 0xd6, 0x4f, 0x06, 0x64, 0xd4,
-	0xbc, 0x68, 0x0e, 0x32, 0x34, 0xf4, 0xc8, 0x73, 0x35, 0xd3, 0x94, 0xa3, 0x07, 0xe8, 0x35, 0x00,
-	0xad, 0x5c, 0x23, 0xa1, 0x30, 0xb3, 0xea, 0x55, 0x97, 0x04, 0xad, 0xc3, 0x2c, 0xae, 0x8b, 0x2a,
-	0x8b, 0xe9, 0x09, 0xf1, 0xdc, 0x23, 0xd2, 0xe4, 0xe6, 0xa4, 0x22, 0x29, 0xb6, 0x48, 0x2a, 0x71,
-	0x33, 0x12, 0xac, 0xa4, 0x1c, 0xb9, 0x43, 0x9a, 0x9c, 0x08, 0x67, 0xa6, 0x63, 0x22, 0x25, 0xa8,
-	0x08, 0xb9, 0x28, 0x26, 0x0d, 0xca, 0xea, 0xdc, 0xcc, 0xa9, 0x25, 0xda, 0x63, 0x09, 0xc0, 0xa9,
-	0x1f, 0x62, 0x51, 0x8f, 0x09, 0x37, 0x53, 0x8b, 0x69, 0x09, 0xd0, 0x91, 0x58, 0x2f, 0x0c, 0x98,
-	0xde, 0x4b, 0x3c, 0xf2, 0x34, 0x66, 0xec, 0xb0, 0xc7, 0xa9, 0xc6, 0xd8, 0x4e, 0x7d, 0x00, 0x10,
-	0x10, 0x7c, 0xe8, 0x46, 0x72, 0xae, 0x24, 0x28, 0xc5, 0x52, 0x3b, 0x5d, 0xf6, 0x70, 0xb4, 0x4b,
-	0xf0, 0xe1, 0x76, 0x58, 0x09, 0xea, 0x9c, 0xb2, 0xd0, 0xc9, 0x4b, 0x6d, 0xb5, 0xb0, 0xf5, 0x31,
-	0xcc, 0xec, 0xe1, 0x28, 0x22, 0xf1, 0x1e, 0x11, 0x58, 0xc6, 0x1b, 0xad, 0xc1, 0xcd, 0x2a, 0xf5,
-	0xab, 0x84, 0x0b, 0xf7, 0xb0, 0x1e, 0x04, 0x4d, 0xb7, 0xc2, 0x6a, 0x51, 0x40, 0x04, 0xf1, 0x5c,
-	0x4e, 0x8e, 0x15, 0x5d, 0xda, 0x31, 0x13, 0x95, 0x4d, 0xa9, 0xb1, 0xde, 0x52, 0xd8, 0x27, 0xc7,
-	0xd6, 0x37, 0x06, 0xcc, 0x6e, 0x11, 0xa1, 0x11, 0xc9, 0x71, 0x9d, 0x70, 0x81, 0x6e, 0x42, 0xde,
-	0x63, 0x35, 0x4c, 0x43, 0x97, 0x7a, 0xe6, 0xc4, 0xa2, 0xb1, 0x9c, 0x77, 0x72, 0x5a, 0xb0, 0xed,
-	0xa1, 0x05, 0x98, 0xac, 0x73, 0x12, 0xcb, 0x57, 0x86, 0x7a, 0x95, 0x95, 0xc3, 0x6d, 0x0f, 0xcd,
-	0x43, 0x16, 0x47, 0x91, 0x94, 0xa7, 0x94, 0x3c, 0x83, 0xa3, 0x68, 0xdb, 0x43, 0x4b, 0x30, 0x7b,
-	0x48, 0x63, 0x2e, 0x5c, 0x11, 0x13, 0xe2, 0x72, 0x7a, 0x42, 0x54, 0xf4, 0xd3, 0xce, 0xb4, 0x12,
-	0x1f, 0xc4, 0x84, 0xec, 0xd3, 0x13, 0x62, 0xfd, 0x99, 0x82, 0x6b, 0x1d, 0x10, 0x1e, 0xb1, 0x90,
-	0x13, 0x49, 0xd2, 0x88, 0x5b, 0x8e, 0xd2, 0x89, 0x9f, 0x6b, 0xc4, 0xda, 0x17, 0xbd, 0xb9, 0x99,
-	0xba, 0x52, 0x6e, 0xf6, 0x85, 0x22, 0x3d, 0x46, 0x28, 0xd0, 0x3b, 0x90, 0xe6, 0xb5, 0x58, 0xf9,
-	0xa7, 0x50, 0x5e, 0xe8, 0xd8, 0xec, 0x53, 0x3f, 0x24, 0xde, 0x1e, 0x8e, 0x1c, 0xc6, 0x84, 0x23,
-	0x75, 0x50, 0x19, 0x72, 0x01, 0xf3, 0xdd, 0x98, 0x31, 0x61, 0x66, 0x06, 0xeb, 0xef, 0x32, 0x5f,
-	0xe9, 0x4f, 0x06, 0xfa, 0x01, 0xdd, 0x86, 0x59, 0x69, 0x53, 0x61, 0x21, 0xa7, 0x5c, 0xc8, 0x4d,
-	0x98, 0x59, 0x95, 0x99, 0x33, 0x01, 0xf3, 0xd7, 0x3b, 0x52, 0xf4, 0x26, 0x4c, 0x4b, 0x45, 0xda,
-	0x62, 0x34, 0x27, 0x95, 0xda, 0x54, 0xc0, 0xfc, 0x36, 0xb7, 0xf5, 0xb3, 0x01, 0x0b, 0xbb, 0x94,
-	0x6b, 0xf7, 0x3e, 0xa1, 0x5c, 0xb0, 0x0b, 0xc2, 0x9d, 0x1d, 0x35, 0xdc, 0x73, 0x90, 0xe1, 0x02,
-	0xc7, 0x42, 0x79, 0x3e, 0xed, 0xe8, 0x81, 0x9c, 0x2b, 0xc2, 0x7e, 0x57, 0x9c, 0x33, 0x4e, 0x4e,
-	0x0a, 0x64, 0x88, 0xbb, 0x32, 0x64, 0xe2, 0x92, 0x0c, 0xc9, 0x0c, 0xca, 0x90, 0x2f, 0xc1, 0x3c,
-	0xbf, 0x85, 0x24, 0x51, 0xd6, 0x21, 0xdb, 0xc0, 0x41, 0x9d, 0x70, 0xd3, 0x58, 0x4c, 0x2f, 0x17,
-	0xca, 0xef, 0x0e, 0x49, 0x84, 0xfe, 0x2c, 0x73, 0x12, 0x53, 0x74, 0x0b, 0x20, 0x24, 0xcf, 0x85,
-	0xdb, 0xbd, 0xaf, 0xbc, 0x94, 0xec, 0x4b, 0x81, 0xf5, 0x87, 0x01, 0x48, 0x37, 0xd5, 0x8b, 0xab,
-	0x25, 0xf3, 0xdf, 0x54, 0x0b, 0xda, 0x86, 0x29, 0x22, 0x21, 0xdc, 0xba, 0x02, 0x4a, 0xb2, 0x70,
-	0xe9, 0xb2, 0x26, 0xa4, 0xf1, 0x9d, 0x02, 0xe9, 0x0c, 0xac, 0x4f, 0xe1, 0x7a, 0xcf, 0xae, 0x12,
-	0x8f, 0x7e, 0x08, 0x99, 0x4e, 0xd9, 0x8d, 0xe9, 0x50, 0x6d, 0x69, 0x05, 0xba, 0xb5, 0x44, 0xac,
-	0x52, 0x1d, 0xc9, 0x59, 0x73, 0x90, 0x21, 0x52, 0x39, 0x69, 0x5a, 0x7a, 0x30, 0xc8, 0x25, 0xa9,
-	0x41, 0xe9, 0xf1, 0x39, 0xcc, 0x6f, 0x11, 0xb1, 0x8b, 0x05, 0xe1, 0x43, 0xd6, 0x34, 0xfa, 0xd6,
-	0x1c, 0x75, 0xf6, 0xdf, 0xe4, 0x21, 0xa6, 0x78, 0x86, 0x4e, 0x97, 0x34, 0x85, 0xd4, 0x98, 0x4d,
-	0x21, 0x7d, 0xf5, 0xa6, 0x30, 0x31, 0x5a, 0x53, 0xc8, 0x0c, 0x68, 0x0a, 0x5f, 0x1b, 0x30, 0x27,
-	0x2b, 0xaa, 0x75, 0xb6, 0xf1, 0x7f, 0x10, 0xa5, 0x5b, 0x00, 0xaa, 0xf0, 0x05, 0x3b, 0x22, 0xa1,
-	0xda, 0x4f, 0xde, 0x51, 0xad, 0xe0, 0x40, 0x0a, 0x7a, 0xfb, 0xc2, 0x44, 0x6f, 0x5f, 0xb0, 0xbe,
-	0x35, 0x60, 0xbe, 0x8f, 0x23, 0x49, 0xc2, 0x4d, 0xc8, 0xb7, 0x4e, 0x4d, 0xae, 0xda, 0x5f, 0xa1,
-	0xbc, 0x3c, 0x24, 0x11, 0x7b, 0x0e, 0x69, 0xa7, 0x63, 0x2a, 0xa3, 0xac, 0x2a, 0xbb, 0x0b, 0x71,
-	0x52, 0x21, 0x4e, 0x4b, 0xf1, 0xd3, 0x16, 0x66, 0xf9, 0xd7, 0x02, 0xcc, 0xee, 0x90, 0xe6, 0x41,
-	0xd7, 0xbc, 0xe8, 0x0b, 0xc8, 0x6f, 0x11, 0xf1, 0x91, 0xda, 0x3e, 0xba, 0xa4, 0x0c, 0xb4, 0x56,
-	0xe2, 0xc6, 0xe2, 0x1b, 0x43, 0x94, 0xb5, 0xa6, 0xf5, 0xfa, 0x57, 0xbf, 0xff, 0xf5, 0x2a, 0x75,
-	0x03, 0x2d, 0xd8, 0x8d, 0x55, 0x5b, 0xbb, 0x98, 0xdb, 0xa7, 0x6d, 0xe7, 0x9f, 0xa1, 0x17, 0x06,
-	0xe4, 0x5a, 0x45, 0x84, 0xee, 0x5c, 0x52, 0x84, 0x5d, 0x59, 0x5f, 0x1c, 0x7a, 0x21, 0x91, 0x8a,
-	0x56, 0x49, 0xad, 0xbd, 0x8c, 0x96, 0x2e, 0x58, 0xdb, 0x56, 0x91, 0xe5, 0xf6, 0xa9, 0xfa, 0x3d,
-	0x43, 0xaf, 0x0c, 0x98, 0xe9, 0xad, 0x30, 0xb4, 0x32, 0x1c, 0xe8, 0x7c, 0x31, 0x8e, 0x80, 0x75,
-	0x57, 0x61, 0xdd, 0x46, 0x6f, 0x0f, 0xc7, 0x7a, 0x18, 0xa8, 0xc9, 0xd1, 0x4b, 0x4d, 0xa5, 0x6c,
-	0xf7, 0x45, 0x4c, 0x70, 0xed, 0x5f, 0x76, 0xd3, 0xa8, 0x3c, 0x5c, 0x2d, 0xbe, 0x62, 0xa0, 0xef,
-	0x0d, 0x98, 0xee, 0x49, 0x67, 0x64, 0x0f, 0x59, 0x64, 0x50, 0x01, 0x16, 0x57, 0x46, 0x37, 0xd0,
-	0x95, 0x62, 0xbd, 0xaf, 0x28, 0xcb, 0x68, 0x65, 0xb4, 0x60, 0xda, 0x9d, 0xda, 0xf8, 0xc1, 0x80,
-	0xeb, 0x3d, 0x73, 0x26, 0x5e, 0x1c, 0x1b, 0x7a, 0xe4, 0xca, 0xb4, 0x1e, 0x29, 0xd8, 0x07, 0xe8,
-	0xfe, 0xb8, 0xb0, 0x1d, 0x27, 0x7f, 0x97, 0xd4, 0x85, 0xfa, 0xae, 0xb8, 0x33, 0xd2, 0xe1, 0xa4,
-	0x29, 0xc7, 0x39, 0xc8, 0xac, 0x35, 0x05, 0x7a, 0x1f, 0xbd, 0x77, 0x11, 0x28, 0x8e, 0x22, 0x6e,
-	0x9f, 0xea, 0x93, 0xfc, 0xcc, 0x96, 0x67, 0x3b, 0xb7, 0x4f, 0x93, 0x13, 0xff, 0x0c, 0xfd, 0x62,
-	0xc0, 0xb5, 0xfe, 0x2b, 0x0b, 0x2a, 0x5f, 0xe2, 0xd7, 0x01, 0x57, 0xb4, 0xe2, 0xbd, 0xb1, 0x6c,
-	0x12, 0xf8, 0x0d, 0x05, 0xff, 0x08, 0xad, 0x5d, 0x09, 0xde, 0xae, 0x26, 0xbc, 0x3f, 0x1a, 0x50,
-	0xe8, 0xba, 0x20, 0xa0, 0xbb, 0x43, 0x58, 0xce, 0x5f, 0x8f, 0x8a, 0xa5, 0x51, 0xd5, 0x13, 0xea,
-	0x1d, 0x45, 0xbd, 0x51, 0xbc, 0x9a, 0xcb, 0x1f, 0xf6, 0x5c, 0x8b, 0x1e, 0x3f, 0xf9, 0x6c, 0xd3,
-	0xa7, 0xa2, 0x5a, 0x7f, 0x56, 0xaa, 0xb0, 0x9a, 0x9d, 0x7c, 0xae, 0xf7, 0x81, 0xd8, 0x15, 0x16,
-	0xeb, 0x6f, 0xf8, 0xf3, 0xff, 0x01, 0xb8, 0x3e, 0x73, 0xd5, 0x97, 0xf8, 0xb3, 0xac, 0xfa, 0xb9,
-	0xf7, 0x77, 0x00, 0x00, 0x00, 0xff, 0xff, 0x72, 0x21, 0xc9, 0xfa, 0x29, 0x10, 0x00, 0x00,
+	proto.RegisterFile("v1/keytransparency.proto", fileDescriptor_keytransparency_1f9ecd1ae48c4eda)
+}
+
+var fileDescriptor_keytransparency_1f9ecd1ae48c4eda = []byte{
+	// 1847 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xd4, 0x59, 0xcd, 0x6f, 0x23, 0x49,
+	0x15, 0xa7, 0xec, 0x38, 0xb6, 0x5f, 0x3e, 0xb7, 0x76, 0x66, 0xd2, 0xe3, 0x65, 0xd9, 0xd0, 0x0b,
+	0xb3, 0xd9, 0x59, 0xc6, 0x9d, 0x38, 0xfb, 0xc1, 0x0c, 0x8c, 0x56, 0x33, 0x89, 0x27, 0x93, 0x9d,
+	0x64, 0x66, 0xe8, 0x64, 0x84, 0x84, 0x56, 0xb2, 0x6a, 0xec, 0x8a, 0x5d, 0x4a, 0xbb, 0xbb, 0xa7,
+	0xab, 0x6c, 0xad, 0x27, 0x04, 0x09, 0x24, 0x60, 0x2f, 0x68, 0x0f, 0x7b, 0xe7, 0x00, 0xd7, 0x85,
+	0x0b, 0xe2, 0xc0, 0x05, 0x01, 0x27, 0xae, 0x08, 0x2e, 0x88, 0x2b, 0x7f, 0x08, 0xaa, 0x8f, 0xf6,
+	0xb7, 0x9d, 0x76, 0x40, 0x48, 0x7b, 0x49, 0x5c, 0xaf, 0xde, 0xab, 0xfa, 0xbd, 0xef, 0x57, 0x36,
+	0x58, 0xed, 0x2d, 0xe7, 0x94, 0x76, 0x44, 0x44, 0x7c, 0x1e, 0x92, 0x88, 0xfa, 0xd5, 0x4e, 0x31,
+	0x8c, 0x02, 0x11, 0xe0, 0xeb, 0xf5, 0x20, 0xa8, 0x7b, 0xb4, 0x38, 0xbc, 0xdb, 0xde, 0x2a, 0x58,
+	0xd5, 0xa8, 0x13, 0x8a, 0xc0, 0xe1, 0xac, 0x1e, 0x3e, 0xd7, 0x7f, 0xb5, 0x50, 0xe1, 0xab, 0x5a,
+	0xc8, 0x21, 0x21, 0x73, 0x88, 0xef, 0x07, 0x82, 0x08, 0x16, 0xf8, 0xdc, 0xec, 0xbe, 0x61, 0x76,
+	0xd5, 0xea, 0x79, 0xeb, 0xc4, 0x11, 0xac, 0x49, 0xb9, 0x20, 0xcd, 0xd0, 0x30, 0xac, 0x19, 0x86,
+	0x28, 0xac, 0x3a, 0x5c, 0x10, 0xd1, 0x8a, 0x25, 0x97, 0x45, 0xc4, 0x3c, 0x8f, 0x11, 0xdf, 0xac,
+	0xaf, 0xc5, 0xeb, 0x4a, 0x93, 0x84, 0x15, 0x12, 0x32, 0x43, 0x07, 0xc1, 0xfc, 0xd3, 0x58, 0xa6,
+	0xbd, 0xe5, 0x90, 0x5a, 0x93, 0x19, 0x19, 0x7b, 0x0b, 0xf2, 0x3b, 0x41, 0xb3, 0xc9, 0x84, 0xa0,
+	0x35, 0xbc, 0x0a, 0xe9, 0x53, 0xda, 0xb1, 0xd0, 0x3a, 0xda, 0x58, 0x74, 0xe5, 0x47, 0x8c, 0x61,
+	0xae, 0x46, 0x04, 0xb1, 0x52, 0x8a, 0xa4, 0x3e, 0xdb, 0x9f, 0x21, 0x58, 0x28, 0xfb, 0x22, 0xea,
+	0x3c, 0x0b, 0x6b, 0x44, 0x50, 0xfc, 0x5d, 0xc8, 0x35, 0x5b, 0x5a, 0x27, 0xc5, 0xb7, 0x50, 0x5a,
+	0x2f, 0x4e, 0x34, 0x53, 0x51, 0x49, 0xba, 0x5d, 0x09, 0x7c, 0x1f, 0xf2, 0xd5, 0x18, 0x80, 0x95,
+	0x56, 0xe2, 0xdf, 0x98, 0x22, 0xde, 0x05, 0xeb, 0xf6, 0xc4, 0xec, 0x3f, 0x22, 0xc8, 0xa8, 0x73,
+	0xf1, 0x15, 0xc8, 0x30, 0xbf, 0x46, 0x3f, 0x51, 0x27, 0x2d, 0xba, 0x7a, 0x81, 0xbf, 0x06, 0xa0,
+	0x99, 0x9b, 0xd4, 0x17, 0xd6, 0xbc, 0xda, 0xea, 0xa3, 0xe0, 0x1d, 0x58, 0x21, 0x2d, 0xd1, 0x08,
+	0x22, 0xf6, 0x92, 0xd6, 0x2a, 0xa7, 0xb4, 0xc3, 0xad, 0xac, 0x42, 0x52, 0x88, 0x91, 0x68, 0xdf,
+	0x16, 0x95, 0x21, 0x1f, 0xd1, 0x0e, 0xa7, 0xc2, 0x5d, 0xee, 0x89, 0x48, 0x0a, 0x2e, 0x40, 0x2e,
+	0x8c, 0x68, 0x9b, 0x05, 0x2d, 0x6e, 0xe5, 0xd4, 0x15, 0xdd, 0xb5, 0x04, 0xc0, 0x59, 0xdd, 0x27,
+	0xa2, 0x15, 0x51, 0x6e, 0xa5, 0xd6, 0xd3, 0x12, 0x40, 0x8f, 0x62, 0x7f, 0x8a, 0x60, 0xe9, 0xd0,
+	0x58, 0xe4, 0x69, 0x14, 0x04, 0x27, 0x03, 0x46, 0x45, 0x33, 0x1b, 0xf5, 0x36, 0x80, 0x47, 0xc9,
+	0x49, 0x25, 0x94, 0x67, 0x19, 0xa7, 0x14, 0x8a, 0xdd, 0x70, 0x39, 0x24, 0xe1, 0x01, 0x25, 0x27,
+	0xfb, 0x7e, 0xd5, 0x6b, 0x71, 0x16, 0xf8, 0x6e, 0x5e, 0x72, 0xab, 0x8b, 0xed, 0x27, 0xb0, 0x7c,
+	0x48, 0xc2, 0x90, 0x46, 0x87, 0x54, 0x10, 0xe9, 0x6f, 0x7c, 0x17, 0x5e, 0x6b, 0xb0, 0x7a, 0x83,
+	0x72, 0x51, 0x39, 0x69, 0x79, 0x5e, 0xa7, 0x52, 0x0d, 0x9a, 0xa1, 0x47, 0x05, 0xad, 0x55, 0x38,
+	0x7d, 0xa1, 0xd0, 0xa5, 0x5d, 0xcb, 0xb0, 0x3c, 0x90, 0x1c, 0x3b, 0x31, 0xc3, 0x11, 0x7d, 0x61,
+	0xff, 0x0c, 0xc1, 0xca, 0x1e, 0x15, 0x1a, 0x22, 0x7d, 0xd1, 0xa2, 0x5c, 0xe0, 0xd7, 0x20, 0x5f,
+	0x0b, 0x9a, 0x84, 0xf9, 0x15, 0x56, 0xb3, 0xe6, 0xd6, 0xd1, 0x46, 0xde, 0xcd, 0x69, 0xc2, 0x7e,
+	0x0d, 0xaf, 0x41, 0xb6, 0xc5, 0x69, 0x24, 0xb7, 0x90, 0xda, 0x9a, 0x97, 0xcb, 0xfd, 0x1a, 0xbe,
+	0x0a, 0xf3, 0x24, 0x0c, 0x25, 0x3d, 0xa5, 0xe8, 0x19, 0x12, 0x86, 0xfb, 0x35, 0x7c, 0x03, 0x56,
+	0x4e, 0x58, 0xc4, 0x45, 0x45, 0x44, 0x94, 0x56, 0x38, 0x7b, 0x49, 0x95, 0xf7, 0xd3, 0xee, 0x92,
+	0x22, 0x1f, 0x47, 0x94, 0x1e, 0xb1, 0x97, 0xd4, 0xfe, 0x57, 0x0a, 0x56, 0x7b, 0x40, 0x78, 0x18,
+	0xf8, 0x9c, 0x4a, 0x24, 0xed, 0x28, 0x36, 0x94, 0x0e, 0xfc, 0x5c, 0x3b, 0xd2, 0xb6, 0x18, 0x8c,
+	0xcd, 0xd4, 0xa5, 0x62, 0x73, 0xc8, 0x15, 0xe9, 0x19, 0x5c, 0x81, 0xdf, 0x86, 0x34, 0x6f, 0x46,
+	0xca, 0x3e, 0x0b, 0xa5, 0xb5, 0x9e, 0xcc, 0x11, 0xab, 0xfb, 0xb4, 0x76, 0x48, 0x42, 0x37, 0x08,
+	0x84, 0x2b, 0x79, 0x70, 0x09, 0x72, 0x5e, 0x50, 0xaf, 0x44, 0x41, 0x20, 0xac, 0xcc, 0x78, 0xfe,
+	0x83, 0xa0, 0xae, 0xf8, 0xb3, 0x9e, 0xfe, 0x80, 0xdf, 0x82, 0x15, 0x29, 0x53, 0x0d, 0x7c, 0xce,
+	0xb8, 0x90, 0x4a, 0x58, 0xf3, 0x2a, 0x32, 0x97, 0xbd, 0xa0, 0xbe, 0xd3, 0xa3, 0xe2, 0x37, 0x61,
+	0x49, 0x32, 0xb2, 0x18, 0xa3, 0x95, 0x55, 0x6c, 0x8b, 0x5e, 0x50, 0xef, 0xe2, 0xb6, 0xbf, 0x40,
+	0x70, 0x3d, 0xb6, 0xee, 0xfd, 0x8e, 0x4b, 0xdb, 0x4c, 0xa9, 0x33, 0xce, 0xe1, 0x68, 0xb2, 0xc3,
+	0x53, 0x13, 0x1c, 0x9e, 0xee, 0x77, 0x78, 0x01, 0x72, 0x91, 0x39, 0x5f, 0x19, 0x27, 0xed, 0x76,
+	0xd7, 0xe3, 0x82, 0x21, 0x33, 0x2e, 0x18, 0xfe, 0x89, 0x60, 0xed, 0x80, 0x71, 0x8d, 0xf7, 0x21,
+	0xe3, 0x22, 0x98, 0x10, 0x9d, 0xf3, 0x49, 0xa3, 0xf3, 0x0a, 0x64, 0xb8, 0x20, 0x91, 0x50, 0x3a,
+	0xa4, 0x5d, 0xbd, 0x90, 0x67, 0x85, 0xa4, 0xde, 0x17, 0x96, 0x19, 0x37, 0x27, 0x09, 0x12, 0x44,
+	0x9f, 0x7e, 0x73, 0x17, 0x04, 0xf4, 0x38, 0x1d, 0xf0, 0x35, 0x98, 0x97, 0xe1, 0xc7, 0xa9, 0xaa,
+	0x56, 0x39, 0xd7, 0xac, 0xec, 0x1f, 0x81, 0x35, 0xaa, 0x9a, 0x89, 0xf7, 0x1d, 0x98, 0x6f, 0x13,
+	0xaf, 0x45, 0xb9, 0x85, 0xd6, 0xd3, 0x1b, 0x0b, 0xa5, 0x77, 0xa6, 0xc4, 0xf3, 0x70, 0xb2, 0xb8,
+	0x46, 0x14, 0xbf, 0x0e, 0xe0, 0xd3, 0x4f, 0x44, 0xa5, 0x5f, 0xdf, 0xbc, 0xa4, 0x1c, 0x49, 0x82,
+	0xfd, 0x0f, 0x04, 0x58, 0xf7, 0x86, 0xc9, 0x49, 0x9f, 0xf9, 0xff, 0x24, 0x3d, 0xde, 0x87, 0x45,
+	0x2a, 0x41, 0x54, 0x5a, 0x0a, 0x90, 0x49, 0xa6, 0x1b, 0x17, 0xd5, 0x52, 0x0d, 0xdf, 0x5d, 0xa0,
+	0xbd, 0x85, 0xfd, 0x4b, 0x04, 0xaf, 0x0e, 0xa8, 0x65, 0x4c, 0x7a, 0x0f, 0x32, 0xbd, 0xf2, 0x31,
+	0xa3, 0x45, 0xb5, 0x24, 0xde, 0x85, 0x6c, 0x44, 0xab, 0x94, 0x85, 0xc2, 0x94, 0x99, 0x9b, 0x53,
+	0x0e, 0x89, 0x1b, 0x85, 0xab, 0x25, 0xdc, 0x58, 0xd4, 0xfe, 0x33, 0x82, 0x95, 0xa1, 0xcd, 0xe9,
+	0x89, 0xf7, 0x26, 0x2c, 0xc5, 0x2d, 0xa3, 0xd2, 0x20, 0xbc, 0x61, 0xda, 0xfc, 0x62, 0x4c, 0x7c,
+	0x48, 0x78, 0x03, 0xbf, 0x0f, 0xb9, 0x1a, 0x25, 0x35, 0x8f, 0xf9, 0xb4, 0x5b, 0xbe, 0x0c, 0xb8,
+	0x78, 0x64, 0x29, 0x1e, 0xc7, 0x23, 0x8b, 0xdb, 0xe5, 0xc5, 0xef, 0x42, 0xbe, 0xdb, 0xe1, 0x8c,
+	0xd9, 0xaf, 0x15, 0xf5, 0x58, 0xb4, 0xcb, 0xea, 0x4c, 0x10, 0xcf, 0xeb, 0xe8, 0xca, 0xe4, 0xf6,
+	0x18, 0x6d, 0x4f, 0x37, 0x8b, 0x30, 0xa8, 0x36, 0x12, 0xc5, 0xcd, 0x15, 0xc8, 0x50, 0xc9, 0x6c,
+	0xda, 0x90, 0x5e, 0x8c, 0x8b, 0x8e, 0xd4, 0xb8, 0x2a, 0xf0, 0x31, 0x5c, 0xdd, 0xa3, 0xe2, 0x80,
+	0x08, 0xca, 0xa7, 0xdc, 0x39, 0x6c, 0xb6, 0xa4, 0xa7, 0xff, 0x4d, 0x8e, 0x25, 0x0a, 0xcf, 0xd4,
+	0xe3, 0x4c, 0x99, 0x4f, 0xcd, 0x58, 0xe6, 0xd3, 0x97, 0x2f, 0xf3, 0x73, 0xc9, 0xca, 0x7c, 0x66,
+	0x4c, 0x99, 0xff, 0x29, 0x82, 0x2b, 0xb2, 0xb8, 0xc4, 0x71, 0xc6, 0xff, 0x0b, 0x2f, 0xbd, 0x0e,
+	0xa0, 0x6a, 0xa3, 0x08, 0x4e, 0xa9, 0x6f, 0x4a, 0xbc, 0xaa, 0x96, 0xc7, 0x92, 0x30, 0x58, 0x3a,
+	0xe7, 0x06, 0x4b, 0xa7, 0xfd, 0x73, 0x04, 0x57, 0x87, 0x70, 0x98, 0x74, 0x7c, 0x00, 0xf9, 0x38,
+	0x7e, 0xb9, 0x6a, 0x68, 0x0b, 0xa5, 0x8d, 0x04, 0xd9, 0xa4, 0x5a, 0xae, 0xdb, 0x13, 0x95, 0x5e,
+	0x56, 0x45, 0xae, 0x0f, 0x62, 0x56, 0x41, 0x5c, 0x92, 0xe4, 0xa7, 0x31, 0x4c, 0xfb, 0x1e, 0xac,
+	0xa8, 0x9c, 0xde, 0xaf, 0x51, 0x5f, 0xb0, 0x13, 0x46, 0xa3, 0x59, 0x8b, 0x99, 0xfd, 0x6b, 0x04,
+	0xd7, 0xee, 0x13, 0x51, 0x6d, 0x98, 0xfa, 0xc0, 0x28, 0x4f, 0x14, 0x88, 0xbb, 0x90, 0xa5, 0x9a,
+	0x5d, 0xcd, 0x94, 0xd3, 0xcb, 0xc6, 0x10, 0x48, 0x37, 0x16, 0x4d, 0x3c, 0x3f, 0xfd, 0x22, 0x05,
+	0x6b, 0x23, 0x28, 0x8d, 0xd1, 0xcb, 0x3d, 0x24, 0x97, 0xe8, 0x2b, 0x5d, 0x28, 0x5f, 0xaa, 0x54,
+	0xf8, 0x31, 0x82, 0xeb, 0xca, 0x1e, 0xbd, 0xa6, 0x90, 0xd4, 0x71, 0x7b, 0x90, 0xd5, 0xfd, 0x28,
+	0x76, 0xdc, 0xad, 0x29, 0xe6, 0x1a, 0x6d, 0xa5, 0x6e, 0x2c, 0x6d, 0x7f, 0x04, 0x85, 0x71, 0x10,
+	0x8c, 0x57, 0xbe, 0x25, 0xdb, 0x0a, 0x6f, 0x79, 0x22, 0xf6, 0x0a, 0x8e, 0xaf, 0x89, 0xc2, 0x6a,
+	0xf1, 0x48, 0xbd, 0x25, 0xdd, 0x98, 0xc5, 0xfe, 0x18, 0xac, 0x3d, 0xda, 0x4d, 0x28, 0xb3, 0x9b,
+	0x44, 0x9b, 0x24, 0x6d, 0xc4, 0xfe, 0x13, 0x82, 0xe5, 0xc1, 0xb3, 0x71, 0x59, 0x4d, 0x4c, 0x82,
+	0xaa, 0x03, 0x97, 0x4b, 0x4e, 0x82, 0x2c, 0xd5, 0x92, 0x0a, 0x3a, 0x75, 0xb5, 0x74, 0xaf, 0xb8,
+	0xa4, 0xfa, 0x8b, 0x8b, 0xa4, 0x46, 0x51, 0x10, 0xc5, 0xa3, 0xa3, 0x5a, 0xd8, 0xdf, 0x81, 0x8c,
+	0x92, 0xc5, 0x0b, 0x90, 0x7d, 0xf6, 0xf8, 0xd1, 0xe3, 0x27, 0xdf, 0x7f, 0xbc, 0xfa, 0x15, 0x0c,
+	0x30, 0xff, 0xbd, 0x67, 0xe5, 0x67, 0xe5, 0xdd, 0x55, 0x24, 0x37, 0xee, 0x3d, 0x7d, 0x7a, 0xb0,
+	0x5f, 0xde, 0x5d, 0x4d, 0xe1, 0x45, 0xc8, 0xb9, 0xe5, 0x8f, 0xca, 0x3b, 0xc7, 0xe5, 0xdd, 0xd5,
+	0x74, 0xe9, 0xf7, 0xaf, 0xc0, 0xca, 0x23, 0xda, 0x39, 0xee, 0xc3, 0x86, 0x7f, 0x08, 0xf9, 0x3d,
+	0x2a, 0x76, 0x95, 0x29, 0xf0, 0x05, 0x41, 0xaf, 0xb9, 0x8c, 0x49, 0x0b, 0x5f, 0x9f, 0xc2, 0xac,
+	0x39, 0xed, 0x37, 0x7e, 0xf2, 0xf7, 0x7f, 0x7f, 0x9e, 0xba, 0x8e, 0xd7, 0x9c, 0xf6, 0x96, 0xa3,
+	0xcd, 0xcd, 0x9d, 0xb3, 0xae, 0x23, 0xce, 0xf1, 0xa7, 0x08, 0x72, 0x71, 0xbb, 0xc4, 0x37, 0x2f,
+	0x48, 0xb9, 0xbe, 0xfe, 0x56, 0x98, 0xfa, 0x98, 0x94, 0x8c, 0x76, 0x51, 0xdd, 0xbd, 0x81, 0x6f,
+	0x4c, 0xb8, 0xdb, 0x51, 0x66, 0xe6, 0xce, 0x99, 0xfa, 0x7f, 0x8e, 0x3f, 0x47, 0xb0, 0x3c, 0xd8,
+	0x4b, 0xf1, 0xe6, 0x74, 0x40, 0xa3, 0x6d, 0x37, 0x01, 0xac, 0x5b, 0x0a, 0xd6, 0x5b, 0xf8, 0x9b,
+	0xd3, 0x61, 0xdd, 0xf1, 0xd4, 0xe1, 0xf8, 0x33, 0x8d, 0x4a, 0xc9, 0x1e, 0x89, 0x88, 0x92, 0xe6,
+	0xff, 0xd8, 0x4c, 0x49, 0xf1, 0x70, 0x75, 0xf9, 0x26, 0xc2, 0x5f, 0x20, 0x58, 0x1a, 0x68, 0x5c,
+	0x78, 0x5a, 0xdc, 0x8f, 0x6b, 0xb5, 0x85, 0xcd, 0xe4, 0x02, 0xba, 0x10, 0xd8, 0xdf, 0x56, 0x28,
+	0x4b, 0x78, 0x33, 0x99, 0x33, 0x9d, 0x5e, 0x17, 0xfc, 0x1d, 0x82, 0x57, 0x07, 0xce, 0x34, 0x56,
+	0x9c, 0x19, 0x74, 0xe2, 0x1e, 0x6c, 0x7f, 0xa8, 0xc0, 0xde, 0xc6, 0x1f, 0xcc, 0x0a, 0xb6, 0x67,
+	0xe4, 0x5f, 0x99, 0xbc, 0x50, 0xdf, 0x09, 0xdd, 0x4c, 0xd4, 0x8a, 0x34, 0xca, 0x59, 0xda, 0x96,
+	0x7d, 0x57, 0x01, 0xfd, 0x00, 0xbf, 0x37, 0x09, 0x28, 0x09, 0x43, 0xee, 0x9c, 0xe9, 0x8e, 0x7f,
+	0xee, 0xc8, 0x19, 0x80, 0x3b, 0x67, 0x66, 0x32, 0x38, 0xc7, 0x7f, 0x45, 0x80, 0x47, 0x5f, 0xcc,
+	0xf8, 0xdd, 0x04, 0x10, 0x46, 0x1e, 0xd8, 0xb3, 0x01, 0x7f, 0xa2, 0x80, 0xef, 0xe3, 0xbd, 0x8b,
+	0x2c, 0x1c, 0x3f, 0xab, 0x2f, 0x52, 0xe5, 0x37, 0x08, 0x56, 0x86, 0x46, 0x03, 0xbc, 0x35, 0x05,
+	0xd1, 0xf8, 0x61, 0xa7, 0x50, 0x9a, 0x45, 0xc4, 0xe8, 0xb2, 0xad, 0x74, 0xb9, 0x65, 0x6f, 0x4c,
+	0xd4, 0x45, 0x0b, 0xdc, 0x79, 0x6e, 0x0e, 0xb8, 0x83, 0x6e, 0xe2, 0xbf, 0x20, 0x58, 0x1d, 0x7e,
+	0x22, 0xe3, 0xd2, 0x05, 0x21, 0x3d, 0xe6, 0xab, 0x82, 0xc2, 0xf6, 0x4c, 0x32, 0x06, 0x72, 0x59,
+	0x41, 0xfe, 0x10, 0xdf, 0xbd, 0x54, 0xdc, 0x38, 0x0d, 0x83, 0xf7, 0x0f, 0x08, 0x16, 0xfa, 0x66,
+	0x03, 0x3c, 0xdb, 0x0c, 0x51, 0x28, 0x26, 0x65, 0x37, 0xa8, 0x1f, 0x29, 0xd4, 0xe5, 0xc2, 0xe5,
+	0xa2, 0xfd, 0xce, 0xc0, 0x33, 0x5c, 0x62, 0xc7, 0xa3, 0x83, 0xcb, 0xd4, 0xd8, 0x9f, 0x38, 0x6a,
+	0x15, 0xde, 0x9b, 0x51, 0xca, 0x28, 0xf4, 0xbe, 0x52, 0x68, 0xd3, 0x7e, 0x27, 0x51, 0xe4, 0xe8,
+	0x33, 0x64, 0xf0, 0xfc, 0x16, 0xc1, 0x2b, 0x23, 0x83, 0x12, 0xde, 0x9e, 0x9e, 0x80, 0x63, 0xc7,
+	0xaa, 0xc2, 0xdb, 0x89, 0x47, 0x1e, 0xfb, 0xb6, 0x42, 0xbb, 0x8d, 0xb7, 0x26, 0xa1, 0xed, 0x96,
+	0x41, 0xe7, 0x6c, 0x60, 0x1a, 0x3b, 0xbf, 0xff, 0xf0, 0x07, 0x0f, 0xea, 0x4c, 0x34, 0x5a, 0xcf,
+	0x8b, 0xd5, 0xa0, 0xe9, 0xe8, 0x1b, 0x87, 0x7f, 0xdf, 0x70, 0xaa, 0x41, 0xa4, 0x7f, 0xa1, 0x18,
+	0xfd, 0xed, 0xa3, 0x52, 0x0f, 0x2a, 0xfa, 0xa9, 0x3f, 0xaf, 0xfe, 0x6d, 0xff, 0x67, 0x00, 0x78,
+	0x75, 0xc2, 0xf4, 0x21, 0x19, 0x00, 0x00,
 }
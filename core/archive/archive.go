@@ -0,0 +1,200 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package archive moves the mutations of old revisions out of the mutation
+// storage into compact, checksummed segments, and serves them from there.
+//
+// Segments hold consecutive revisions and are written in revision order, so
+// the revisions of a domain that have been archived are always those up to
+// the last revision of its last segment.
+package archive
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/google/keytransparency/core/domain"
+	"github.com/google/keytransparency/core/mutator"
+
+	tpb "github.com/google/trillian"
+	tclient "github.com/google/trillian/client"
+)
+
+// Pruner deletes the mutations of archived revisions.
+type Pruner interface {
+	// DeleteRevisions deletes the mutations of revisions [start, end] of
+	// domainID.
+	DeleteRevisions(ctx context.Context, domainID string, start, end int64) error
+}
+
+// Archiver exports the mutations of old revisions into segments and prunes
+// them from the mutation storage.
+type Archiver struct {
+	tmap      tpb.TrillianMapClient
+	mutations mutator.MutationStorage
+	pruner    Pruner
+	segments  SegmentStore
+	// maxRevisions is the maximum number of revisions in a segment.
+	maxRevisions int64
+}
+
+// New returns an Archiver that reads revision timestamps from tmap, exports
+// the mutations in mutations to segments of at most maxRevisions revisions,
+// and prunes the exported revisions with pruner.
+func New(tmap tpb.TrillianMapClient, mutations mutator.MutationStorage, pruner Pruner,
+	segments SegmentStore, maxRevisions int64) *Archiver {
+	return &Archiver{
+		tmap:         tmap,
+		mutations:    mutations,
+		pruner:       pruner,
+		segments:     segments,
+		maxRevisions: maxRevisions,
+	}
+}
+
+// Archive exports the revisions of d whose map roots were created before
+// before, and that have not been archived yet, then prunes them from the
+// mutation storage. The latest revision of the map is never archived, since
+// the sequencer may still need it to recover. Archive returns the last
+// archived revision, or -1 if no revision is archived.
+//
+// Each segment is read back and verified before its revisions are pruned, so
+// an interrupted Archive can be run again.
+func (a *Archiver) Archive(ctx context.Context, d *domain.Domain, mapVerifier *tclient.MapVerifier, before time.Time) (int64, error) {
+	if a.maxRevisions <= 0 {
+		return 0, fmt.Errorf("archive: %v revisions per segment, want at least 1", a.maxRevisions)
+	}
+	segs, err := a.segments.List(ctx, d.DomainID)
+	if err != nil {
+		return 0, fmt.Errorf("archive: List(%v): %v", d.DomainID, err)
+	}
+	last := int64(-1)
+	if len(segs) > 0 {
+		last = segs[len(segs)-1].Last
+		// Finish pruning after an interrupted run.
+		if err := a.pruner.DeleteRevisions(ctx, d.DomainID, segs[0].First, last); err != nil {
+			return 0, fmt.Errorf("archive: DeleteRevisions(%v, %v, %v): %v", d.DomainID, segs[0].First, last, err)
+		}
+	}
+
+	end, err := a.cutoff(ctx, d, mapVerifier, last+1, before)
+	if err != nil {
+		return 0, err
+	}
+	for first := last + 1; first <= end; first += a.maxRevisions {
+		segEnd := first + a.maxRevisions - 1
+		if segEnd > end {
+			segEnd = end
+		}
+		if err := a.archiveSegment(ctx, d.DomainID, first, segEnd); err != nil {
+			return 0, err
+		}
+		last = segEnd
+	}
+	return last, nil
+}
+
+// archiveSegment writes revisions [first, last] of domainID to a segment,
+// verifies it, and prunes the revisions.
+func (a *Archiver) archiveSegment(ctx context.Context, domainID string, first, last int64) error {
+	seg := &Segment{DomainID: domainID, First: first}
+	for rev := first; rev <= last; rev++ {
+		msgs, err := a.mutations.ReadBatch(ctx, domainID, rev)
+		if err != nil {
+			return fmt.Errorf("archive: ReadBatch(%v, %v): %v", domainID, rev, err)
+		}
+		seg.Revisions = append(seg.Revisions, msgs)
+	}
+	data, err := seg.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("archive: encoding revisions %v to %v: %v", first, last, err)
+	}
+	if err := a.segments.Put(ctx, domainID, first, last, data); err != nil {
+		return fmt.Errorf("archive: Put(%v, %v, %v): %v", domainID, first, last, err)
+	}
+	if _, err := readSegment(ctx, a.segments, domainID, SegmentInfo{First: first, Last: last}); err != nil {
+		return err
+	}
+	if err := a.pruner.DeleteRevisions(ctx, domainID, first, last); err != nil {
+		return fmt.Errorf("archive: DeleteRevisions(%v, %v, %v): %v", domainID, first, last, err)
+	}
+	glog.Infof("Archived revisions %v to %v of domain %v: %v bytes", first, last, domainID, len(data))
+	return nil
+}
+
+// cutoff returns the last revision from start onwards whose map root was
+// created before before, excluding the latest revision. It returns start-1 if
+// there is none.
+func (a *Archiver) cutoff(ctx context.Context, d *domain.Domain, mapVerifier *tclient.MapVerifier, start int64, before time.Time) (int64, error) {
+	rootResp, err := a.tmap.GetSignedMapRoot(ctx, &tpb.GetSignedMapRootRequest{MapId: d.MapID})
+	if err != nil {
+		return 0, fmt.Errorf("GetSignedMapRoot(%v): %v", d.MapID, err)
+	}
+	latest, err := mapVerifier.VerifySignedMapRoot(rootResp.GetMapRoot())
+	if err != nil {
+		return 0, err
+	}
+	n := int64(latest.Revision) - start
+	if n <= 0 {
+		return start - 1, nil
+	}
+
+	// Map roots are created in revision order, so their timestamps increase.
+	var searchErr error
+	i := sort.Search(int(n), func(i int) bool {
+		if searchErr != nil {
+			return true
+		}
+		rev := start + int64(i)
+		resp, err := a.tmap.GetSignedMapRootByRevision(ctx, &tpb.GetSignedMapRootByRevisionRequest{
+			MapId:    d.MapID,
+			Revision: rev,
+		})
+		if err != nil {
+			searchErr = fmt.Errorf("GetSignedMapRootByRevision(%v): %v", rev, err)
+			return true
+		}
+		root, err := mapVerifier.VerifySignedMapRoot(resp.GetMapRoot())
+		if err != nil {
+			searchErr = err
+			return true
+		}
+		return !time.Unix(0, int64(root.TimestampNanos)).Before(before)
+	})
+	if searchErr != nil {
+		return 0, searchErr
+	}
+	return start + int64(i) - 1, nil
+}
+
+// readSegment reads and decodes the segment info of domainID, and checks that
+// it holds the revisions it is stored under.
+func readSegment(ctx context.Context, segments SegmentStore, domainID string, info SegmentInfo) (*Segment, error) {
+	data, err := segments.Get(ctx, domainID, info.First, info.Last)
+	if err != nil {
+		return nil, fmt.Errorf("archive: Get(%v, %v, %v): %v", domainID, info.First, info.Last, err)
+	}
+	seg := new(Segment)
+	if err := seg.UnmarshalBinary(data); err != nil {
+		return nil, fmt.Errorf("archive: segment %v to %v of domain %v: %v", info.First, info.Last, domainID, err)
+	}
+	if seg.DomainID != domainID || seg.First != info.First || seg.Last() != info.Last {
+		return nil, fmt.Errorf("archive: segment %v to %v of domain %v holds revisions %v to %v of domain %v",
+			info.First, info.Last, domainID, seg.First, seg.Last(), seg.DomainID)
+	}
+	return seg, nil
+}
@@ -0,0 +1,263 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/keytransparency/core/domain"
+	"github.com/google/keytransparency/core/fake"
+	"github.com/google/keytransparency/core/mutator"
+	"github.com/google/trillian/types"
+	"google.golang.org/grpc"
+
+	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
+	tpb "github.com/google/trillian"
+	tclient "github.com/google/trillian/client"
+	tcrypto "github.com/google/trillian/crypto"
+)
+
+const mapID = 1
+
+// start is the time of revision 0. Revision i is created i hours later.
+var start = time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// fakeMap serves signed map roots. Unimplemented methods panic.
+type fakeMap struct {
+	tpb.TrillianMapClient
+	roots []*tpb.SignedMapRoot
+}
+
+func (m *fakeMap) GetSignedMapRoot(context.Context, *tpb.GetSignedMapRootRequest, ...grpc.CallOption) (*tpb.GetSignedMapRootResponse, error) {
+	return &tpb.GetSignedMapRootResponse{MapRoot: m.roots[len(m.roots)-1]}, nil
+}
+
+func (m *fakeMap) GetSignedMapRootByRevision(_ context.Context, in *tpb.GetSignedMapRootByRevisionRequest, _ ...grpc.CallOption) (*tpb.GetSignedMapRootResponse, error) {
+	return &tpb.GetSignedMapRootResponse{MapRoot: m.roots[in.Revision]}, nil
+}
+
+// newMap returns a map holding revisions [0, latest].
+func newMap(t *testing.T, latest int) (*fakeMap, *tclient.MapVerifier) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey(): %v", err)
+	}
+	signer := tcrypto.NewSHA256Signer(key)
+	m := &fakeMap{}
+	for rev := 0; rev <= latest; rev++ {
+		smr, err := signer.SignMapRoot(&types.MapRootV1{
+			RootHash:       []byte(fmt.Sprintf("root%d", rev)),
+			TimestampNanos: uint64(start.Add(time.Duration(rev) * time.Hour).UnixNano()),
+			Revision:       uint64(rev),
+		})
+		if err != nil {
+			t.Fatalf("SignMapRoot(): %v", err)
+		}
+		m.roots = append(m.roots, smr)
+	}
+	return m, &tclient.MapVerifier{MapID: mapID, MapPubKey: key.Public(), SigHash: crypto.SHA256}
+}
+
+// newMutations returns a mutation storage holding revisions [0, latest],
+// where revision i holds i messages.
+func newMutations(ctx context.Context, t *testing.T, domainID string, latest int) (*fake.MutationStorage, [][]*mutator.QueueMessage) {
+	t.Helper()
+	m := fake.NewMutationStorage()
+	var revisions [][]*mutator.QueueMessage
+	for rev := 0; rev <= latest; rev++ {
+		msgs := []*mutator.QueueMessage{}
+		for i := 0; i < rev; i++ {
+			msgs = append(msgs, genMessage(100*rev+i, i%2 == 0))
+		}
+		if err := m.SequenceBatch(ctx, domainID, int64(rev), msgs); err != nil {
+			t.Fatalf("SequenceBatch(): %v", err)
+		}
+		revisions = append(revisions, msgs)
+	}
+	return m, revisions
+}
+
+func newDir(t *testing.T) (*Dir, func()) {
+	t.Helper()
+	path, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatalf("TempDir(): %v", err)
+	}
+	return NewDir(path), func() { os.RemoveAll(path) }
+}
+
+func TestArchive(t *testing.T) {
+	ctx := context.Background()
+	d := &domain.Domain{DomainID: "domain", MapID: mapID}
+	const latest = 10
+	tmap, mapVerifier := newMap(t, latest)
+	mutations, revisions := newMutations(ctx, t, d.DomainID, latest)
+	segments, done := newDir(t)
+	defer done()
+	a := New(tmap, mutations, mutations, segments, 3)
+
+	// Runs are cumulative.
+	for _, tc := range []struct {
+		desc     string
+		before   time.Time
+		wantLast int64
+		wantSegs []SegmentInfo
+	}{
+		{desc: "nothing old enough", before: start, wantLast: -1},
+		{desc: "first revisions", before: start.Add(90 * time.Minute), wantLast: 1,
+			wantSegs: []SegmentInfo{{0, 1}}},
+		{desc: "same cutoff", before: start.Add(2 * time.Hour), wantLast: 1,
+			wantSegs: []SegmentInfo{{0, 1}}},
+		{desc: "several segments", before: start.Add(8 * time.Hour), wantLast: 7,
+			wantSegs: []SegmentInfo{{0, 1}, {2, 4}, {5, 7}}},
+		{desc: "never the latest revision", before: start.Add(100 * time.Hour), wantLast: latest - 1,
+			wantSegs: []SegmentInfo{{0, 1}, {2, 4}, {5, 7}, {8, 9}}},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			last, err := a.Archive(ctx, d, mapVerifier, tc.before)
+			if err != nil {
+				t.Fatalf("Archive(): %v", err)
+			}
+			if last != tc.wantLast {
+				t.Errorf("Archive(): %v, want %v", last, tc.wantLast)
+			}
+			segs, err := segments.List(ctx, d.DomainID)
+			if err != nil {
+				t.Fatalf("List(): %v", err)
+			}
+			if !reflect.DeepEqual(segs, tc.wantSegs) {
+				t.Errorf("List(): %v, want %v", segs, tc.wantSegs)
+			}
+
+			for rev := range revisions {
+				msgs, err := mutations.ReadBatch(ctx, d.DomainID, int64(rev))
+				if err != nil {
+					t.Fatalf("ReadBatch(): %v", err)
+				}
+				want := len(revisions[rev])
+				if int64(rev) <= tc.wantLast {
+					want = 0
+				}
+				if got := len(msgs); got != want {
+					t.Errorf("ReadBatch(%v): %v messages left in storage, want %v", rev, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestArchiveResumesPruning(t *testing.T) {
+	ctx := context.Background()
+	d := &domain.Domain{DomainID: "domain", MapID: mapID}
+	tmap, mapVerifier := newMap(t, 5)
+	mutations, revisions := newMutations(ctx, t, d.DomainID, 5)
+	segments, done := newDir(t)
+	defer done()
+
+	// A segment was written but its revisions were not pruned.
+	data, err := (&Segment{DomainID: d.DomainID, Revisions: revisions[:3]}).MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary(): %v", err)
+	}
+	if err := segments.Put(ctx, d.DomainID, 0, 2, data); err != nil {
+		t.Fatalf("Put(): %v", err)
+	}
+	a := New(tmap, mutations, mutations, segments, 10)
+	if _, err := a.Archive(ctx, d, mapVerifier, start); err != nil {
+		t.Fatalf("Archive(): %v", err)
+	}
+	for rev := int64(0); rev <= 2; rev++ {
+		msgs, err := mutations.ReadBatch(ctx, d.DomainID, rev)
+		if err != nil {
+			t.Fatalf("ReadBatch(): %v", err)
+		}
+		if len(msgs) != 0 {
+			t.Errorf("ReadBatch(%v): %v messages left in storage, want 0", rev, len(msgs))
+		}
+	}
+}
+
+func TestStorage(t *testing.T) {
+	ctx := context.Background()
+	d := &domain.Domain{DomainID: "domain", MapID: mapID}
+	const latest = 6
+	tmap, mapVerifier := newMap(t, latest)
+	mutations, revisions := newMutations(ctx, t, d.DomainID, latest)
+	segments, done := newDir(t)
+	defer done()
+	if _, err := New(tmap, mutations, mutations, segments, 2).Archive(ctx, d, mapVerifier, start.Add(4*time.Hour)); err != nil {
+		t.Fatalf("Archive(): %v", err)
+	}
+	s := NewStorage(mutations, segments)
+
+	for rev := range revisions {
+		t.Run(fmt.Sprintf("revision %v", rev), func(t *testing.T) {
+			msgs, err := s.ReadBatch(ctx, d.DomainID, int64(rev))
+			if err != nil {
+				t.Fatalf("ReadBatch(): %v", err)
+			}
+			checkMessages(t, "ReadBatch()", msgs, revisions[rev])
+
+			// Read pages of 2 mutations.
+			var entries []*pb.Entry
+			next := int64(0)
+			for {
+				max, page, err := s.ReadPage(ctx, d.DomainID, int64(rev), next, 2)
+				if err != nil {
+					t.Fatalf("ReadPage(): %v", err)
+				}
+				if len(page) == 0 {
+					if max != 0 {
+						t.Errorf("ReadPage(%v): max %v on an empty page, want 0", next, max)
+					}
+					break
+				}
+				if want := next + int64(len(page)) - 1; max != want {
+					t.Errorf("ReadPage(%v): max %v, want %v", next, max, want)
+				}
+				entries = append(entries, page...)
+				next = max + 1
+			}
+			if len(entries) != len(revisions[rev]) {
+				t.Fatalf("ReadPage(): %v mutations, want %v", len(entries), len(revisions[rev]))
+			}
+			for i, e := range entries {
+				if !proto.Equal(e, revisions[rev][i].Mutation) {
+					t.Errorf("ReadPage()[%v]: %v, want %v", i, e, revisions[rev][i].Mutation)
+				}
+			}
+		})
+	}
+
+	// Unknown revisions and domains are still empty.
+	if msgs, err := s.ReadBatch(ctx, d.DomainID, latest+1); err != nil || len(msgs) != 0 {
+		t.Errorf("ReadBatch(%v): %v, %v, want no messages", latest+1, msgs, err)
+	}
+	if _, page, err := s.ReadPage(ctx, "other", 1, 0, 10); err != nil || len(page) != 0 {
+		t.Errorf("ReadPage(other): %v, %v, want no mutations", page, err)
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/keytransparency/core/mutator"
+
+	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
+)
+
+// magic starts every encoded segment and identifies the format version.
+const magic = "KTSEGv1\n"
+
+// ErrChecksum is returned when decoding a segment whose contents do not match
+// its checksum.
+var ErrChecksum = errors.New("archive: segment checksum mismatch")
+
+// Segment holds the mutations of a range of consecutive revisions of a
+// domain.
+type Segment struct {
+	DomainID string
+	// First is the first revision in the segment.
+	First int64
+	// Revisions holds the messages of revisions First, First+1, ... in
+	// sequence order. The queue IDs of the messages are not kept.
+	Revisions [][]*mutator.QueueMessage
+}
+
+// Last returns the last revision in the segment.
+func (s *Segment) Last() int64 {
+	return s.First + int64(len(s.Revisions)) - 1
+}
+
+// Contains returns whether revision is in the segment.
+func (s *Segment) Contains(revision int64) bool {
+	return revision >= s.First && revision <= s.Last()
+}
+
+// MarshalBinary encodes the segment as the magic string, the SHA-256 checksum
+// of the body, and the body: the zlib compressed domain ID, first revision
+// and, for each revision, its mutations and their committed data.
+func (s *Segment) MarshalBinary() ([]byte, error) {
+	var body bytes.Buffer
+	zw := zlib.NewWriter(&body)
+	w := &writer{w: zw}
+	w.bytes([]byte(s.DomainID))
+	w.uvarint(uint64(s.First))
+	w.uvarint(uint64(len(s.Revisions)))
+	for _, msgs := range s.Revisions {
+		w.uvarint(uint64(len(msgs)))
+		for _, msg := range msgs {
+			mData, err := proto.Marshal(msg.Mutation)
+			if err != nil {
+				return nil, err
+			}
+			w.bytes(mData)
+			w.optional(msg.ExtraData)
+		}
+	}
+	if w.err != nil {
+		return nil, w.err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(body.Bytes())
+	out := make([]byte, 0, len(magic)+len(sum)+body.Len())
+	out = append(out, magic...)
+	out = append(out, sum[:]...)
+	return append(out, body.Bytes()...), nil
+}
+
+// UnmarshalBinary decodes a segment encoded by MarshalBinary. It returns
+// ErrChecksum if data has been altered.
+func (s *Segment) UnmarshalBinary(data []byte) error {
+	if len(data) < len(magic)+sha256.Size || string(data[:len(magic)]) != magic {
+		return fmt.Errorf("archive: not a segment")
+	}
+	want := data[len(magic) : len(magic)+sha256.Size]
+	body := data[len(magic)+sha256.Size:]
+	if sum := sha256.Sum256(body); !bytes.Equal(sum[:], want) {
+		return ErrChecksum
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	plain, err := ioutil.ReadAll(zr)
+	if err != nil {
+		return err
+	}
+
+	r := &reader{r: bytes.NewReader(plain)}
+	domainID := r.bytes()
+	first := r.uvarint()
+	count := r.uvarint()
+	revisions := make([][]*mutator.QueueMessage, 0)
+	for i := uint64(0); i < count && r.err == nil; i++ {
+		n := r.uvarint()
+		msgs := make([]*mutator.QueueMessage, 0)
+		for j := uint64(0); j < n && r.err == nil; j++ {
+			msg := &mutator.QueueMessage{Mutation: new(pb.Entry)}
+			r.message(r.bytes(), msg.Mutation)
+			if cData := r.optional(); cData != nil {
+				msg.ExtraData = new(pb.Committed)
+				r.message(cData, msg.ExtraData)
+			}
+			msgs = append(msgs, msg)
+		}
+		revisions = append(revisions, msgs)
+	}
+	if r.err != nil {
+		return fmt.Errorf("archive: malformed segment: %v", r.err)
+	}
+	s.DomainID = string(domainID)
+	s.First = int64(first)
+	s.Revisions = revisions
+	return nil
+}
+
+// writer writes length prefixed fields and keeps the first error.
+type writer struct {
+	w   io.Writer
+	err error
+}
+
+func (w *writer) uvarint(v uint64) {
+	if w.err != nil {
+		return
+	}
+	var buf [binary.MaxVarintLen64]byte
+	_, w.err = w.w.Write(buf[:binary.PutUvarint(buf[:], v)])
+}
+
+func (w *writer) bytes(b []byte) {
+	w.uvarint(uint64(len(b)))
+	if w.err == nil {
+		_, w.err = w.w.Write(b)
+	}
+}
+
+// optional writes msg with its length plus one, or 0 if msg is nil.
+func (w *writer) optional(msg *pb.Committed) {
+	if msg == nil {
+		w.uvarint(0)
+		return
+	}
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		w.err = err
+		return
+	}
+	w.uvarint(uint64(len(data)) + 1)
+	if w.err == nil {
+		_, w.err = w.w.Write(data)
+	}
+}
+
+// reader reads the fields written by writer and keeps the first error.
+type reader struct {
+	r   *bytes.Reader
+	err error
+}
+
+func (r *reader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(r.r)
+	r.err = err
+	return v
+}
+
+func (r *reader) read(n uint64) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n > uint64(r.r.Len()) {
+		r.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	b := make([]byte, n)
+	_, r.err = io.ReadFull(r.r, b)
+	return b
+}
+
+func (r *reader) bytes() []byte {
+	return r.read(r.uvarint())
+}
+
+func (r *reader) optional() []byte {
+	n := r.uvarint()
+	if n == 0 {
+		return nil
+	}
+	return r.read(n - 1)
+}
+
+func (r *reader) message(data []byte, msg proto.Message) {
+	if r.err == nil {
+		r.err = proto.Unmarshal(data, msg)
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/keytransparency/core/mutator"
+
+	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
+)
+
+func genMessage(i int, committed bool) *mutator.QueueMessage {
+	msg := &mutator.QueueMessage{Mutation: &pb.Entry{
+		Index:      []byte(fmt.Sprintf("index%d", i)),
+		Commitment: []byte(fmt.Sprintf("mutation%d", i)),
+	}}
+	if committed {
+		msg.ExtraData = &pb.Committed{Key: []byte(fmt.Sprintf("key%d", i)), Data: []byte(fmt.Sprintf("data%d", i))}
+	}
+	return msg
+}
+
+func checkMessages(t *testing.T, desc string, got, want []*mutator.QueueMessage) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%v: %v messages, want %v", desc, len(got), len(want))
+	}
+	for i := range got {
+		if !proto.Equal(got[i].Mutation, want[i].Mutation) || !proto.Equal(got[i].ExtraData, want[i].ExtraData) {
+			t.Errorf("%v[%v]: %v, want %v", desc, i, got[i], want[i])
+		}
+	}
+}
+
+func TestSegmentRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		desc string
+		seg  *Segment
+	}{
+		{desc: "no revisions", seg: &Segment{DomainID: "domain", First: 3}},
+		{desc: "empty revisions", seg: &Segment{DomainID: "domain", Revisions: [][]*mutator.QueueMessage{{}, {}}}},
+		{desc: "mutations", seg: &Segment{
+			DomainID: "domain",
+			First:    10,
+			Revisions: [][]*mutator.QueueMessage{
+				{genMessage(1, true), genMessage(2, false)},
+				{},
+				{genMessage(3, true)},
+				{{Mutation: &pb.Entry{}, ExtraData: &pb.Committed{}}},
+			},
+		}},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			data, err := tc.seg.MarshalBinary()
+			if err != nil {
+				t.Fatalf("MarshalBinary(): %v", err)
+			}
+			got := new(Segment)
+			if err := got.UnmarshalBinary(data); err != nil {
+				t.Fatalf("UnmarshalBinary(): %v", err)
+			}
+			if got.DomainID != tc.seg.DomainID || got.First != tc.seg.First || got.Last() != tc.seg.Last() {
+				t.Errorf("UnmarshalBinary(): segment %v %v to %v, want %v %v to %v",
+					got.DomainID, got.First, got.Last(), tc.seg.DomainID, tc.seg.First, tc.seg.Last())
+			}
+			for i := range tc.seg.Revisions {
+				checkMessages(t, fmt.Sprintf("Revisions[%v]", i), got.Revisions[i], tc.seg.Revisions[i])
+			}
+		})
+	}
+}
+
+func TestSegmentCorrupt(t *testing.T) {
+	seg := &Segment{DomainID: "domain", Revisions: [][]*mutator.QueueMessage{{genMessage(1, true)}}}
+	data, err := seg.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary(): %v", err)
+	}
+	for _, tc := range []struct {
+		desc string
+		data []byte
+		want error
+	}{
+		{desc: "empty", data: []byte{}},
+		{desc: "bad magic", data: append([]byte("KTSEGv0\n"), data[len(magic):]...)},
+		{desc: "truncated", data: data[:len(data)-1], want: ErrChecksum},
+		{desc: "flipped bit", data: func() []byte {
+			d := append([]byte{}, data...)
+			d[len(d)-3] ^= 1
+			return d
+		}(), want: ErrChecksum},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := new(Segment).UnmarshalBinary(tc.data)
+			if err == nil {
+				t.Fatalf("UnmarshalBinary(): nil, want error")
+			}
+			if tc.want != nil && err != tc.want {
+				t.Errorf("UnmarshalBinary(): %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestDir(t *testing.T) {
+	ctx := context.Background()
+	path, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatalf("TempDir(): %v", err)
+	}
+	defer os.RemoveAll(path)
+	d := NewDir(path)
+
+	if segs, err := d.List(ctx, "domain/1"); err != nil || len(segs) != 0 {
+		t.Errorf("List() before Put: %v, %v, want none", segs, err)
+	}
+	for _, s := range []SegmentInfo{{First: 10, Last: 19}, {First: 0, Last: 9}, {First: 20, Last: 20}} {
+		data := []byte(fmt.Sprintf("segment %v", s.First))
+		if err := d.Put(ctx, "domain/1", s.First, s.Last, data); err != nil {
+			t.Fatalf("Put(%v): %v", s, err)
+		}
+	}
+	if err := d.Put(ctx, "domain/2", 0, 5, []byte("other")); err != nil {
+		t.Fatalf("Put(): %v", err)
+	}
+	// Files other than segments, such as those of an interrupted Put, are
+	// ignored.
+	if err := ioutil.WriteFile(filepath.Join(d.domainDir("domain/1"), "tmp-123"), nil, 0644); err != nil {
+		t.Fatalf("WriteFile(): %v", err)
+	}
+
+	segs, err := d.List(ctx, "domain/1")
+	if err != nil {
+		t.Fatalf("List(): %v", err)
+	}
+	if want := []SegmentInfo{{0, 9}, {10, 19}, {20, 20}}; !reflect.DeepEqual(segs, want) {
+		t.Errorf("List(): %v, want %v", segs, want)
+	}
+	got, err := d.Get(ctx, "domain/1", 10, 19)
+	if err != nil {
+		t.Fatalf("Get(): %v", err)
+	}
+	if want := "segment 10"; string(got) != want {
+		t.Errorf("Get(): %q, want %q", got, want)
+	}
+	if _, err := d.Get(ctx, "domain/1", 10, 18); err == nil {
+		t.Errorf("Get() of a missing segment: nil, want error")
+	}
+}
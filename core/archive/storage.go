@@ -0,0 +1,117 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/google/keytransparency/core/mutator"
+
+	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
+)
+
+// Storage is a mutator.MutationStorage that also serves the mutations of
+// archived revisions, from their segments.
+//
+// Revisions that the underlying storage returns no mutations for are looked
+// up in the segments. Since segments are written before their revisions are
+// pruned, a revision being archived concurrently is always found in one or
+// the other.
+type Storage struct {
+	mutator.MutationStorage
+	segments SegmentStore
+
+	mu sync.Mutex
+	// cached is the last segment read.
+	cached *Segment
+}
+
+// NewStorage returns a Storage that reads from mutations, and from segments
+// for the revisions that have been pruned from mutations.
+func NewStorage(mutations mutator.MutationStorage, segments SegmentStore) *Storage {
+	return &Storage{
+		MutationStorage: mutations,
+		segments:        segments,
+	}
+}
+
+// ReadPage returns up to pageSize mutations of domainID/revision with a
+// sequence number of at least start, and the highest sequence number read.
+func (s *Storage) ReadPage(ctx context.Context, domainID string, revision, start int64, pageSize int32) (int64, []*pb.Entry, error) {
+	max, entries, err := s.MutationStorage.ReadPage(ctx, domainID, revision, start, pageSize)
+	if err != nil || len(entries) > 0 {
+		return max, entries, err
+	}
+	seg, err := s.archived(ctx, domainID, revision)
+	if err != nil || seg == nil {
+		return max, entries, err
+	}
+
+	if start < 0 {
+		start = 0
+	}
+	msgs := seg.Revisions[revision-seg.First]
+	entries = make([]*pb.Entry, 0)
+	for i := start; i < int64(len(msgs)) && len(entries) < int(pageSize); i++ {
+		entries = append(entries, msgs[i].Mutation)
+	}
+	if len(entries) == 0 {
+		return 0, entries, nil
+	}
+	return start + int64(len(entries)) - 1, entries, nil
+}
+
+// ReadBatch returns the messages saved for domainID/revision.
+func (s *Storage) ReadBatch(ctx context.Context, domainID string, revision int64) ([]*mutator.QueueMessage, error) {
+	msgs, err := s.MutationStorage.ReadBatch(ctx, domainID, revision)
+	if err != nil || len(msgs) > 0 {
+		return msgs, err
+	}
+	seg, err := s.archived(ctx, domainID, revision)
+	if err != nil || seg == nil {
+		return msgs, err
+	}
+	return seg.Revisions[revision-seg.First], nil
+}
+
+// archived returns the segment holding revision, or nil if revision has not
+// been archived.
+func (s *Storage) archived(ctx context.Context, domainID string, revision int64) (*Segment, error) {
+	s.mu.Lock()
+	cached := s.cached
+	s.mu.Unlock()
+	if cached != nil && cached.DomainID == domainID && cached.Contains(revision) {
+		return cached, nil
+	}
+
+	infos, err := s.segments.List(ctx, domainID)
+	if err != nil {
+		return nil, err
+	}
+	i := sort.Search(len(infos), func(i int) bool { return infos[i].Last >= revision })
+	if i == len(infos) || infos[i].First > revision {
+		return nil, nil
+	}
+	seg, err := readSegment(ctx, s.segments, domainID, infos[i])
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.cached = seg
+	s.mu.Unlock()
+	return seg, nil
+}
@@ -0,0 +1,139 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// SegmentInfo identifies a stored segment by the range of revisions it holds.
+type SegmentInfo struct {
+	First, Last int64
+}
+
+// SegmentStore stores encoded segments.
+type SegmentStore interface {
+	// Put stores the segment holding revisions [first, last] of domainID.
+	Put(ctx context.Context, domainID string, first, last int64, data []byte) error
+	// Get returns the segment holding revisions [first, last] of domainID.
+	Get(ctx context.Context, domainID string, first, last int64) ([]byte, error)
+	// List returns the segments of domainID, ordered by first revision.
+	List(ctx context.Context, domainID string) ([]SegmentInfo, error)
+}
+
+// segmentExt is the file extension of segment files.
+const segmentExt = ".seg"
+
+// Dir stores segments as files in a directory, with one subdirectory per
+// domain.
+type Dir struct {
+	path string
+}
+
+// NewDir returns a SegmentStore that keeps segments under path.
+func NewDir(path string) *Dir {
+	return &Dir{path: path}
+}
+
+func (d *Dir) domainDir(domainID string) string {
+	return filepath.Join(d.path, url.PathEscape(domainID))
+}
+
+func (d *Dir) file(domainID string, first, last int64) string {
+	// Zero padding makes file names sort in revision order.
+	return filepath.Join(d.domainDir(domainID), fmt.Sprintf("%020d-%020d%s", first, last, segmentExt))
+}
+
+// Put writes data to a temporary file and renames it into place, so that
+// readers never see a partially written segment. The directories are synced
+// so that the segment is still there after a crash once Put returns, since the
+// archiver deletes the revisions it holds from the database.
+func (d *Dir) Put(ctx context.Context, domainID string, first, last int64, data []byte) error {
+	dir := d.domainDir(domainID)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		if err := syncDir(d.path); err != nil {
+			return err
+		}
+	}
+	f, err := ioutil.TempFile(dir, "tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), d.file(domainID, first, last)); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir commits the entries of the directory at path to stable storage.
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	if err := dir.Sync(); err != nil {
+		dir.Close()
+		return err
+	}
+	return dir.Close()
+}
+
+// Get reads the segment file holding revisions [first, last] of domainID.
+func (d *Dir) Get(ctx context.Context, domainID string, first, last int64) ([]byte, error) {
+	return ioutil.ReadFile(d.file(domainID, first, last))
+}
+
+// List returns the segment files of domainID. Other files are ignored.
+func (d *Dir) List(ctx context.Context, domainID string) ([]SegmentInfo, error) {
+	files, err := ioutil.ReadDir(d.domainDir(domainID))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var segs []SegmentInfo
+	for _, f := range files {
+		var s SegmentInfo
+		var ext string
+		if n, _ := fmt.Sscanf(f.Name(), "%d-%d%s", &s.First, &s.Last, &ext); n != 3 || ext != segmentExt {
+			continue
+		}
+		segs = append(segs, s)
+	}
+	sort.Slice(segs, func(i, j int) bool { return segs[i].First < segs[j].First })
+	return segs, nil
+}
@@ -29,6 +29,7 @@ import (
 
 	"github.com/google/keytransparency/core/mutator"
 	"github.com/google/keytransparency/core/mutator/entry"
+	"github.com/google/keytransparency/core/receipt"
 	"github.com/google/trillian"
 
 	"github.com/google/trillian/client/backoff"
@@ -60,6 +61,9 @@ var (
 	// ErrIncomplete occurs when the server indicates that requested epochs
 	// are not available.
 	ErrIncomplete = errors.New("incomplete account history")
+	// ErrRejected occurs when the server reports that it rejected a
+	// queued update. Use MutationStatus to find out why.
+	ErrRejected = errors.New("client: update rejected by the server")
 	// ErrLogEmpty occurs when the Log.TreeSize < 1 which indicates
 	// that the log of signed map roots is empty.
 	ErrLogEmpty = errors.New("log is empty - domain initialization failed")
@@ -80,6 +84,8 @@ type Verifier interface {
 	VerifyEpoch(epoch *pb.Epoch, trusted types.LogRootV1) (*types.LogRootV1, *types.MapRootV1, error)
 	// VerifySignedMapRoot verifies the signature on the SignedMapRoot.
 	VerifySignedMapRoot(smr *trillian.SignedMapRoot) (*types.MapRootV1, error)
+	// VerifyMutationReceipt verifies that a receipt was issued by the domain for mutation.
+	VerifyMutationReceipt(domainID string, mutation *pb.Entry, r *pb.MutationReceipt) error
 }
 
 // Client is a helper library for issuing updates to the key server.
@@ -221,6 +227,45 @@ func CompressHistory(profiles map[uint64][]byte) (map[uint64][]byte, error) {
 	return ret, nil
 }
 
+// CompressSparseHistory takes the map roots and entries of a sparse history,
+// ordered by epoch. Two consecutive entries must either be for consecutive
+// epochs or have the same leaf value, which proves that the entry did not
+// change in between.
+// CompressSparseHistory returns only the epochs where the associated data changed.
+// CompressSparseHistory returns an error if a gap between epochs is not proven.
+func CompressSparseHistory(roots []*types.MapRootV1, entries []*pb.GetEntryResponse) (map[uint64][]byte, error) {
+	if len(roots) != len(entries) {
+		return nil, fmt.Errorf("len(roots): %v != len(entries): %v", len(roots), len(entries))
+	}
+	var prevData, prevLeaf []byte
+	ret := make(map[uint64][]byte)
+	for i, e := range entries {
+		leaf := e.GetLeafProof().GetLeaf().GetLeafValue()
+		if i != 0 {
+			prev := roots[i-1].Revision
+			if roots[i].Revision <= prev {
+				glog.Errorf("Unordered history. Got epoch %v after %v", roots[i].Revision, prev)
+				return nil, ErrNonContiguous
+			}
+			// Gaps are only allowed if the entry did not change.
+			if roots[i].Revision != prev+1 && !bytes.Equal(leaf, prevLeaf) {
+				glog.Errorf("Unproven gap in history between epochs %v and %v", prev, roots[i].Revision)
+				return nil, ErrNonContiguous
+			}
+		}
+		prevLeaf = leaf
+
+		// Append to output when data changes.
+		data := e.GetCommitted().GetData()
+		if bytes.Equal(data, prevData) {
+			continue
+		}
+		prevData = data
+		ret[roots[i].Revision] = data
+	}
+	return ret, nil
+}
+
 // uint64Slice satisfies sort.Interface.
 type uint64Slice []uint64
 
@@ -250,6 +295,8 @@ func (c *Client) Update(ctx context.Context, u *tpb.User, signers []*tink.Keyset
 }
 
 // QueueMutation signs an entry.Mutation and sends it to the server.
+// If the server returns a mutation receipt, QueueMutation verifies it and
+// attaches it to m so that WaitForUserUpdate can hold the server to it.
 func (c *Client) QueueMutation(ctx context.Context, m *entry.Mutation, signers []*tink.KeysetHandle, opts ...grpc.CallOption) error {
 	req, err := m.SerializeAndSign(signers, int64(c.trusted.TreeSize))
 	if err != nil {
@@ -258,8 +305,39 @@ func (c *Client) QueueMutation(ctx context.Context, m *entry.Mutation, signers [
 
 	Vlog.Printf("Sending Update request...")
 	// TODO(gdbelvin): Change name from UpdateEntry to QueueUpdate.
-	_, err = c.cli.UpdateEntry(ctx, req, opts...)
-	return err
+	resp, err := c.cli.UpdateEntry(ctx, req, opts...)
+	if err != nil {
+		return err
+	}
+
+	m.SetReceipt(nil)
+	r := resp.GetReceipt()
+	if r == nil {
+		return nil
+	}
+	switch err := c.VerifyMutationReceipt(c.domainID, req.GetEntryUpdate().GetMutation(), r); err {
+	case nil:
+		m.SetReceipt(r)
+		Vlog.Printf("✓ Mutation receipt verified.")
+	case ErrNoReceiptKey:
+		glog.Infof("Ignoring mutation receipt: %v", err)
+	default:
+		return fmt.Errorf("VerifyMutationReceipt(): %v", err)
+	}
+	return nil
+}
+
+// MutationStatus returns whether m, which has been sent with QueueMutation,
+// is queued, applied or rejected.
+func (c *Client) MutationStatus(ctx context.Context, m *entry.Mutation, opts ...grpc.CallOption) (*pb.MutationStatus, error) {
+	hash, err := m.Hash()
+	if err != nil {
+		return nil, err
+	}
+	return c.cli.GetMutationStatus(ctx, &pb.GetMutationStatusRequest{
+		DomainId:     c.domainID,
+		MutationHash: hash,
+	}, opts...)
 }
 
 // CreateMutation fetches the current index and value for a user and prepares a mutation.
@@ -314,7 +392,9 @@ func (c *Client) WaitForUserUpdate(ctx context.Context, m *entry.Mutation) (*ent
 
 // waitOnceForUserUpdate waits for the STH to be updated, indicating the next epoch has been created,
 // it then queries the current value for the user and checks it against the requested mutation.
-// If the current value has not changed, WaitForUpdate returns ErrWait.
+// If the current value has not changed, WaitForUpdate returns ErrWait, or
+// receipt.ErrBrokenPromise if the mutation has a receipt and the current epoch
+// is past the receipt's deadline.
 // If the current value has changed, but does not match the requested mutation,
 // WaitForUpdate returns a new mutation, built with the current value and ErrRetry.
 // If the current value matches the request, no mutation and no error are returned.
@@ -340,10 +420,29 @@ func (c *Client) waitOnceForUserUpdate(ctx context.Context, m *entry.Mutation) (
 	if err != nil {
 		return m, err
 	}
+
 	switch {
 	case m.EqualsRequested(cntValue):
+		// The current epoch may be later than the epoch that applied the
+		// mutation, so it can't tell whether the deadline was met.
 		return nil, nil
 	case m.EqualsPrevious(cntValue):
+		// Check that the server is keeping its promise to apply the
+		// mutation.
+		if r := m.Receipt(); r != nil {
+			mapRoot, err := c.VerifySignedMapRoot(e.GetSmr())
+			if err != nil {
+				return m, err
+			}
+			if err := receipt.CheckDeadline(r, mapRoot); err != nil {
+				return m, err
+			}
+		}
+		// Stop waiting if the server has rejected the mutation.
+		if st, err := c.MutationStatus(ctx, m); err == nil && st.GetState() == pb.MutationStatus_REJECTED {
+			Vlog.Printf("✗ Update rejected: %v", st.GetError())
+			return m, ErrRejected
+		}
 		return m, ErrWait
 	default:
 		// Race condition: some change got in first.
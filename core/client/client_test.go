@@ -20,6 +20,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/keytransparency/core/mutator/entry"
+	"github.com/google/keytransparency/core/receipt"
 	"github.com/google/keytransparency/core/testutil"
 	"github.com/google/trillian"
 	"github.com/google/trillian/types"
@@ -113,6 +116,64 @@ func TestCompressHistory(t *testing.T) {
 	}
 }
 
+func TestCompressSparseHistory(t *testing.T) {
+	// entry returns an entry whose leaf value and profile data are both v.
+	entry := func(v string) *pb.GetEntryResponse {
+		return &pb.GetEntryResponse{
+			LeafProof: &trillian.MapLeafInclusion{Leaf: &trillian.MapLeaf{LeafValue: []byte(v)}},
+			Committed: &pb.Committed{Data: []byte(v)},
+		}
+	}
+	for _, tc := range []struct {
+		desc      string
+		revisions []uint64
+		values    []string
+		want      map[uint64][]byte
+		wantErr   error
+	}{
+		{
+			desc:      "Single",
+			revisions: []uint64{1},
+			values:    []string{"a"},
+			want:      map[uint64][]byte{1: []byte("a")},
+		},
+		{
+			desc:      "Proven gaps",
+			revisions: []uint64{0, 4, 5, 9},
+			values:    []string{"a", "a", "b", "b"},
+			want:      map[uint64][]byte{0: []byte("a"), 5: []byte("b")},
+		},
+		{
+			desc:      "Unproven gap",
+			revisions: []uint64{0, 5},
+			values:    []string{"a", "b"},
+			wantErr:   ErrNonContiguous,
+		},
+		{
+			desc:      "Unordered",
+			revisions: []uint64{5, 4},
+			values:    []string{"a", "a"},
+			wantErr:   ErrNonContiguous,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			roots := make([]*types.MapRootV1, 0, len(tc.revisions))
+			entries := make([]*pb.GetEntryResponse, 0, len(tc.values))
+			for i, r := range tc.revisions {
+				roots = append(roots, &types.MapRootV1{Revision: r})
+				entries = append(entries, entry(tc.values[i]))
+			}
+			got, err := CompressSparseHistory(roots, entries)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("CompressSparseHistory(): %#v, want %#v", got, tc.want)
+			}
+			if err != tc.wantErr {
+				t.Errorf("CompressSparseHistory(): %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
 func TestPaginateHistory(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
@@ -218,8 +279,127 @@ func TestPaginateHistory(t *testing.T) {
 	}
 }
 
+func TestWaitOnceForUserUpdate(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	interval := time.Second
+	index := make([]byte, 32)
+	prev := &pb.Entry{Index: index, Commitment: []byte("previous")}
+	prevLeaf, err := entry.ToLeafValue(prev)
+	if err != nil {
+		t.Fatalf("ToLeafValue(): %v", err)
+	}
+	prevHash, err := entry.Hash(prev)
+	if err != nil {
+		t.Fatalf("Hash(): %v", err)
+	}
+	// The mutation keeps the commitment of prev.
+	requestedLeaf, err := entry.ToLeafValue(&pb.Entry{Index: index, Previous: prevHash, Commitment: prev.Commitment})
+	if err != nil {
+		t.Fatalf("ToLeafValue(): %v", err)
+	}
+	// The server promised to apply the mutation by revision 2.
+	deadline, err := ptypes.TimestampProto(time.Unix(0, 0).Add(2 * interval))
+	if err != nil {
+		t.Fatalf("TimestampProto(): %v", err)
+	}
+
+	for _, tc := range []struct {
+		desc     string
+		revision byte
+		leaf     []byte
+		wantErr  error
+	}{
+		{desc: "queued", revision: 1, leaf: prevLeaf, wantErr: ErrWait},
+		{desc: "applied", revision: 2, leaf: requestedLeaf},
+		{desc: "late", revision: 3, leaf: prevLeaf, wantErr: receipt.ErrBrokenPromise},
+		// Polling after the deadline doesn't show that the mutation was
+		// applied late.
+		{desc: "applied, polled late", revision: 5, leaf: requestedLeaf},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			smr := &trillian.SignedMapRoot{MapRoot: []byte{tc.revision}}
+			srv := &fakeKeyServer{
+				latest: &pb.Epoch{Smr: smr},
+				entry: &pb.GetEntryResponse{
+					LeafProof: &trillian.MapLeafInclusion{Leaf: &trillian.MapLeaf{LeafValue: tc.leaf}},
+					Smr:       smr,
+				},
+			}
+			s, stop, err := testutil.NewFakeKT(srv)
+			if err != nil {
+				t.Fatalf("NewFakeKT(): %v", err)
+			}
+			defer stop()
+			c := Client{
+				Verifier:   &fakeVerifier{interval: interval},
+				cli:        s.Client,
+				RetryDelay: time.Millisecond,
+			}
+
+			m := entry.NewMutation(index, "domain", "app", "user")
+			if err := m.SetPrevious(prevLeaf, true); err != nil {
+				t.Fatalf("SetPrevious(): %v", err)
+			}
+			m.SetReceipt(&pb.MutationReceipt{Deadline: deadline})
+			if _, err := c.waitOnceForUserUpdate(ctx, m); err != tc.wantErr {
+				t.Errorf("waitOnceForUserUpdate(): %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifiedGetEntryByRevision(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	srv := &fakeKeyServer{
+		revisions: map[int64]*pb.GetEntryResponse{
+			1: {Smr: &trillian.SignedMapRoot{MapRoot: []byte{1}}},
+			2: {Smr: &trillian.SignedMapRoot{MapRoot: []byte{2}}},
+			// The server returns the map root of another revision.
+			3: {Smr: &trillian.SignedMapRoot{MapRoot: []byte{2}}},
+		},
+	}
+	s, stop, err := testutil.NewFakeKT(srv)
+	if err != nil {
+		t.Fatalf("NewFakeKT(): %v", err)
+	}
+	defer stop()
+
+	for _, tc := range []struct {
+		desc     string
+		revision int64
+		wantErr  bool
+	}{
+		{desc: "first", revision: 1},
+		{desc: "second", revision: 2},
+		{desc: "wrong map revision", revision: 3, wantErr: true},
+		{desc: "missing", revision: 4, wantErr: true},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			c := Client{
+				Verifier: &fakeVerifier{},
+				cli:      s.Client,
+			}
+			e, _, err := c.VerifiedGetEntryByRevision(ctx, "app", "user", tc.revision)
+			if got, want := err != nil, tc.wantErr; got != want {
+				t.Fatalf("VerifiedGetEntryByRevision(%v): %v, wantErr %v", tc.revision, err, want)
+			}
+			if err != nil {
+				return
+			}
+			if got, want := e.GetSmr().GetMapRoot()[0], byte(tc.revision); got != want {
+				t.Errorf("VerifiedGetEntryByRevision(%v).Smr revision: %v, want %v", tc.revision, got, want)
+			}
+		})
+	}
+}
+
 type fakeKeyServer struct {
 	revisions map[int64]*pb.GetEntryResponse
+	// latest and entry, if set, are returned by GetLatestEpoch and GetEntry.
+	latest *pb.Epoch
+	entry  *pb.GetEntryResponse
 }
 
 func (f *fakeKeyServer) ListEntryHistory(ctx context.Context, in *pb.ListEntryHistoryRequest) (*pb.ListEntryHistoryResponse, error) {
@@ -255,6 +435,9 @@ func (f *fakeKeyServer) GetEpoch(context.Context, *pb.GetEpochRequest) (*pb.Epoc
 }
 
 func (f *fakeKeyServer) GetLatestEpoch(context.Context, *pb.GetLatestEpochRequest) (*pb.Epoch, error) {
+	if f.latest != nil {
+		return f.latest, nil
+	}
 	return nil, status.Error(codes.Unimplemented, "not implemented")
 }
 
@@ -271,6 +454,21 @@ func (f *fakeKeyServer) ListMutationsStream(*pb.ListMutationsRequest, pb.KeyTran
 }
 
 func (f *fakeKeyServer) GetEntry(context.Context, *pb.GetEntryRequest) (*pb.GetEntryResponse, error) {
+	if f.entry != nil {
+		return f.entry, nil
+	}
+	return nil, status.Error(codes.Unimplemented, "not implemented")
+}
+
+func (f *fakeKeyServer) GetEntryByRevision(ctx context.Context, in *pb.GetEntryByRevisionRequest) (*pb.GetEntryResponse, error) {
+	e, ok := f.revisions[in.Revision]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "revision %v not found", in.Revision)
+	}
+	return e, nil
+}
+
+func (f *fakeKeyServer) BatchGetEntries(context.Context, *pb.BatchGetEntriesRequest) (*pb.BatchGetEntriesResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "not implemented")
 }
 
@@ -278,7 +476,19 @@ func (f *fakeKeyServer) UpdateEntry(context.Context, *pb.UpdateEntryRequest) (*p
 	return nil, status.Error(codes.Unimplemented, "not implemented")
 }
 
-type fakeVerifier struct{}
+func (f *fakeKeyServer) BatchUpdateEntries(context.Context, *pb.BatchUpdateEntriesRequest) (*pb.BatchUpdateEntriesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "not implemented")
+}
+
+func (f *fakeKeyServer) GetMutationStatus(context.Context, *pb.GetMutationStatusRequest) (*pb.MutationStatus, error) {
+	return nil, status.Error(codes.Unimplemented, "not implemented")
+}
+
+// fakeVerifier accepts every response. Map revision N is created at N times
+// interval after the Unix epoch, and is stored in a log of size N+1.
+type fakeVerifier struct {
+	interval time.Duration
+}
 
 func (f *fakeVerifier) Index(vrfProof []byte, domainID string, appID string, userID string) ([]byte, error) {
 	return make([]byte, 32), nil
@@ -291,9 +501,20 @@ func (f *fakeVerifier) VerifyGetEntryResponse(ctx context.Context, domainID stri
 
 func (f *fakeVerifier) VerifyEpoch(in *pb.Epoch, trusted types.LogRootV1) (*types.LogRootV1, *types.MapRootV1, error) {
 	smr, err := f.VerifySignedMapRoot(in.Smr)
-	return &types.LogRootV1{}, smr, err
+	if err != nil {
+		return nil, nil, err
+	}
+	return &types.LogRootV1{TreeSize: smr.Revision + 1}, smr, nil
+}
+
+func (f *fakeVerifier) VerifyMutationReceipt(domainID string, mutation *pb.Entry, r *pb.MutationReceipt) error {
+	return nil
 }
 
 func (f *fakeVerifier) VerifySignedMapRoot(smr *trillian.SignedMapRoot) (*types.MapRootV1, error) {
-	return &types.MapRootV1{Revision: uint64(smr.MapRoot[0])}, nil
+	revision := uint64(smr.MapRoot[0])
+	return &types.MapRootV1{
+		Revision:       revision,
+		TimestampNanos: revision * uint64(f.interval),
+	}, nil
 }
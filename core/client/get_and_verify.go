@@ -47,6 +47,77 @@ func (c *Client) VerifiedGetEntry(ctx context.Context, appID, userID string) (*p
 	return e, slr, nil
 }
 
+// VerifiedGetEntryByRevision fetches and verifies the results of
+// GetEntryByRevision. It also verifies that the returned map root is for the
+// requested revision.
+func (c *Client) VerifiedGetEntryByRevision(ctx context.Context, appID, userID string, revision int64) (*pb.GetEntryResponse, *types.LogRootV1, error) {
+	c.trustedLock.Lock()
+	defer c.trustedLock.Unlock()
+	e, err := c.cli.GetEntryByRevision(ctx, &pb.GetEntryByRevisionRequest{
+		DomainId:      c.domainID,
+		UserId:        userID,
+		AppId:         appID,
+		Revision:      revision,
+		FirstTreeSize: int64(c.trusted.TreeSize),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	smr, slr, err := c.VerifyGetEntryResponse(ctx, c.domainID, appID, userID, c.trusted, e)
+	if err != nil {
+		return nil, nil, err
+	}
+	if got, want := int64(smr.Revision), revision; got != want {
+		return nil, nil, fmt.Errorf("GetEntryByRevision(): smr.Revision: %v, want %v", got, want)
+	}
+	c.updateTrusted(slr)
+
+	return e, slr, nil
+}
+
+// BatchVerifiedGetEntries fetches and verifies the results of BatchGetEntries.
+// The returned entries are in the same order as ids, and each one contains the
+// map and log proofs shared by the batch.
+func (c *Client) BatchVerifiedGetEntries(ctx context.Context, ids []*pb.EntryIdentifier) ([]*pb.GetEntryResponse, *types.LogRootV1, error) {
+	c.trustedLock.Lock()
+	defer c.trustedLock.Unlock()
+	resp, err := c.cli.BatchGetEntries(ctx, &pb.BatchGetEntriesRequest{
+		DomainId:      c.domainID,
+		Entries:       ids,
+		FirstTreeSize: int64(c.trusted.TreeSize),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if got, want := len(resp.GetEntries()), len(ids); got != want {
+		return nil, nil, fmt.Errorf("BatchGetEntries(): len: %v, want %v", got, want)
+	}
+
+	var slr *types.LogRootV1
+	entries := make([]*pb.GetEntryResponse, 0, len(ids))
+	for i, e := range resp.GetEntries() {
+		entry := &pb.GetEntryResponse{
+			VrfProof:       e.GetVrfProof(),
+			Committed:      e.GetCommitted(),
+			LeafProof:      e.GetLeafProof(),
+			Smr:            resp.GetSmr(),
+			LogRoot:        resp.GetLogRoot(),
+			LogConsistency: resp.GetLogConsistency(),
+			LogInclusion:   resp.GetLogInclusion(),
+		}
+		_, slr, err = c.VerifyGetEntryResponse(ctx, c.domainID, ids[i].GetAppId(), ids[i].GetUserId(), c.trusted, entry)
+		if err != nil {
+			return nil, nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if slr != nil {
+		c.updateTrusted(slr)
+	}
+	return entries, slr, nil
+}
+
 // VerifiedGetLatestEpoch fetches the latest revision from the key server.
 // It also verifies the consistency from the last seen revision.
 // Returns the latest log root and the latest map root.
@@ -143,3 +214,45 @@ func (c *Client) VerifiedListHistory(ctx context.Context, appID, userID string,
 	}
 	return profiles, resp.NextStart, nil
 }
+
+// VerifiedListChanges performs one sparse list history operation, verifies
+// and returns the results. It returns the profile at start and at each epoch
+// in which it changed, up to count changes, and the next epoch to query.
+func (c *Client) VerifiedListChanges(ctx context.Context, appID, userID string, start int64, count int32) (map[uint64][]byte, int64, error) {
+	c.trustedLock.Lock()
+	defer c.trustedLock.Unlock()
+	resp, err := c.cli.ListEntryHistory(ctx, &pb.ListEntryHistoryRequest{
+		DomainId:      c.domainID,
+		UserId:        userID,
+		AppId:         appID,
+		FirstTreeSize: int64(c.trusted.TreeSize),
+		Start:         start,
+		PageSize:      count,
+		Sparse:        true,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var slr *types.LogRootV1
+	roots := make([]*types.MapRootV1, 0, len(resp.GetValues()))
+	for _, v := range resp.GetValues() {
+		var smr *types.MapRootV1
+		smr, slr, err = c.VerifyGetEntryResponse(ctx, c.domainID, appID, userID, c.trusted, v)
+		if err != nil {
+			return nil, 0, err
+		}
+		roots = append(roots, smr)
+	}
+	if len(roots) > 0 && int64(roots[0].Revision) != start {
+		return nil, 0, fmt.Errorf("ListEntryHistory(): first epoch: %v, want %v", roots[0].Revision, start)
+	}
+	profiles, err := CompressSparseHistory(roots, resp.GetValues())
+	if err != nil {
+		return nil, 0, err
+	}
+	if slr != nil {
+		c.updateTrusted(slr)
+	}
+	return profiles, resp.NextStart, nil
+}
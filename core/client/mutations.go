@@ -19,6 +19,7 @@ package client
 import (
 	"context"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/golang/glog"
@@ -35,13 +36,55 @@ type EpochMutations struct {
 	Mutations []*pb.MutationProof
 }
 
-// StreamEpochs repeatedly fetches epochs and sends them to out until GetEpoch
-// returns an error other than NotFound or until ctx.Done is closed.  When
-// GetEpoch returns NotFound, it waits one pollPeriod before trying again.
+// StreamEpochs sends epochs starting at startEpoch to out until the server
+// returns an error or until ctx.Done is closed. It uses GetEpochStream and
+// falls back to polling GetEpoch if the server does not implement it.
 func (c *Client) StreamEpochs(ctx context.Context, domainID string, startEpoch int64, out chan<- *pb.Epoch) error {
 	defer close(out)
+	next, err := c.streamEpochs(ctx, domainID, startEpoch, out)
+	if status.Code(err) != codes.Unimplemented {
+		return err
+	}
+	glog.Infof("GetEpochStream is unimplemented, polling GetEpoch instead")
+	return c.pollEpochs(ctx, domainID, startEpoch, next, out)
+}
+
+// streamEpochs receives epochs from GetEpochStream and sends them to out.
+// It returns the next epoch that has not yet been sent to out.
+func (c *Client) streamEpochs(ctx context.Context, domainID string, startEpoch int64, out chan<- *pb.Epoch) (int64, error) {
+	i := startEpoch
+	stream, err := c.cli.GetEpochStream(ctx, &pb.GetEpochRequest{
+		DomainId:      domainID,
+		Epoch:         startEpoch,
+		FirstTreeSize: startEpoch,
+	})
+	if err != nil {
+		return i, err
+	}
+	for {
+		epoch, err := stream.Recv()
+		if err == io.EOF {
+			return i, nil
+		} else if err != nil {
+			return i, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return i, ctx.Err()
+		case out <- epoch:
+			i++
+		}
+	}
+}
+
+// pollEpochs repeatedly fetches epochs starting at next and sends them to out
+// until GetEpoch returns an error other than NotFound or until ctx.Done is
+// closed.  When GetEpoch returns NotFound, it waits one pollPeriod before
+// trying again.
+func (c *Client) pollEpochs(ctx context.Context, domainID string, startEpoch, next int64, out chan<- *pb.Epoch) error {
 	wait := time.NewTicker(c.RetryDelay).C
-	for i := startEpoch; ; {
+	for i := next; ; {
 		// time out if we exceed the poll period:
 		epoch, err := c.cli.GetEpoch(ctx, &pb.GetEpochRequest{
 			DomainId:      domainID,
@@ -71,22 +114,57 @@ func (c *Client) StreamEpochs(ctx context.Context, domainID string, startEpoch i
 	}
 }
 
-// EpochMutations fetches all the mutations in an epoch
+// EpochMutations fetches all the mutations in an epoch. It uses
+// ListMutationsStream and falls back to paginating through ListMutations if
+// the server does not implement it.
 func (c *Client) EpochMutations(ctx context.Context, epoch *pb.Epoch) ([]*pb.MutationProof, error) {
 	mapRoot, err := c.VerifySignedMapRoot(epoch.GetSmr())
 	if err != nil {
 		return nil, err
 	}
+	mutations, err := c.streamMutations(ctx, epoch.GetDomainId(), int64(mapRoot.Revision))
+	if status.Code(err) != codes.Unimplemented {
+		return mutations, err
+	}
+	glog.Infof("ListMutationsStream is unimplemented, paginating ListMutations instead")
+	return c.listMutations(ctx, epoch.GetDomainId(), int64(mapRoot.Revision))
+}
+
+// streamMutations fetches all the mutations in an epoch with ListMutationsStream.
+func (c *Client) streamMutations(ctx context.Context, domainID string, epoch int64) ([]*pb.MutationProof, error) {
+	stream, err := c.cli.ListMutationsStream(ctx, &pb.ListMutationsRequest{
+		DomainId: domainID,
+		Epoch:    epoch,
+	})
+	if err != nil {
+		return nil, err
+	}
+	mutations := []*pb.MutationProof{}
+	for {
+		m, err := stream.Recv()
+		if err == io.EOF {
+			return mutations, nil
+		} else if status.Code(err) == codes.Unimplemented {
+			return nil, err
+		} else if err != nil {
+			return nil, fmt.Errorf("ListMutationsStream(%v): %v", domainID, err)
+		}
+		mutations = append(mutations, m)
+	}
+}
+
+// listMutations fetches all the mutations in an epoch with ListMutations.
+func (c *Client) listMutations(ctx context.Context, domainID string, epoch int64) ([]*pb.MutationProof, error) {
 	mutations := []*pb.MutationProof{}
 	token := ""
 	for {
 		resp, err := c.cli.ListMutations(ctx, &pb.ListMutationsRequest{
-			DomainId:  epoch.GetDomainId(),
-			Epoch:     int64(mapRoot.Revision),
+			DomainId:  domainID,
+			Epoch:     epoch,
 			PageToken: token,
 		})
 		if err != nil {
-			return nil, fmt.Errorf("GetMutations(%v): %v", epoch.GetDomainId(), err)
+			return nil, fmt.Errorf("GetMutations(%v): %v", domainID, err)
 		}
 		mutations = append(mutations, resp.GetMutations()...)
 		token = resp.GetNextPageToken()
@@ -16,6 +16,7 @@ package client
 
 import (
 	"context"
+	"crypto"
 	"errors"
 	"fmt"
 
@@ -24,6 +25,8 @@ import (
 	"github.com/google/keytransparency/core/crypto/vrf"
 	"github.com/google/keytransparency/core/crypto/vrf/p256"
 	"github.com/google/keytransparency/core/mutator/entry"
+	"github.com/google/keytransparency/core/receipt"
+	"github.com/google/trillian/crypto/keys/der"
 	"github.com/google/trillian/types"
 	"github.com/kr/pretty"
 
@@ -36,12 +39,16 @@ import (
 var (
 	// ErrNilProof occurs when the provided GetEntryResponse contains a nil proof.
 	ErrNilProof = errors.New("nil proof")
+	// ErrNoReceiptKey occurs when a mutation receipt cannot be verified
+	// because the domain's receipt key is unknown.
+	ErrNoReceiptKey = errors.New("no receipt key")
 )
 
 // RealVerifier is a client helper library for verifying request and responses.
 // Implements Verifier.
 type RealVerifier struct {
-	vrf vrf.PublicKey
+	vrf        vrf.PublicKey
+	receiptKey crypto.PublicKey
 	*tclient.MapVerifier
 	*tclient.LogVerifier
 }
@@ -75,7 +82,16 @@ func NewVerifierFromDomain(config *pb.Domain) (*RealVerifier, error) {
 		return nil, fmt.Errorf("error parsing vrf public key: %v", err)
 	}
 
-	return NewVerifier(vrfPubKey, mapVerifier, logVerifier), nil
+	v := NewVerifier(vrfPubKey, mapVerifier, logVerifier)
+
+	// Mutation receipt key
+	if config.GetReceiptKey() != nil {
+		v.receiptKey, err = der.UnmarshalPublicKey(config.GetReceiptKey().GetDer())
+		if err != nil {
+			return nil, fmt.Errorf("error parsing receipt public key: %v", err)
+		}
+	}
+	return v, nil
 }
 
 // Index computes the index from a VRF proof.
@@ -87,6 +103,15 @@ func (v *RealVerifier) Index(vrfProof []byte, domainID, appID, userID string) ([
 	return index[:], nil
 }
 
+// VerifyMutationReceipt verifies that r is a receipt for mutation in domainID,
+// signed by the domain's receipt key.
+func (v *RealVerifier) VerifyMutationReceipt(domainID string, mutation *pb.Entry, r *pb.MutationReceipt) error {
+	if v.receiptKey == nil {
+		return ErrNoReceiptKey
+	}
+	return receipt.Verify(v.receiptKey, domainID, mutation, r)
+}
+
 // VerifyGetEntryResponse verifies GetEntryResponse:
 //  - Verify commitment.
 //  - Verify VRF.
@@ -21,6 +21,8 @@ import (
 
 	"github.com/golang/protobuf/proto"
 	"github.com/google/trillian/crypto/keyspb"
+
+	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
 )
 
 // Domain stores configuration information for a single Key Transparency instance.
@@ -30,10 +32,29 @@ type Domain struct {
 	LogID    int64
 	VRF      *keyspb.PublicKey
 
-	VRFPriv                  proto.Message
+	VRFPriv proto.Message
+	// ReceiptKey and ReceiptPriv are used to sign mutation receipts.
+	// They are nil for domains that do not issue receipts.
+	ReceiptKey               *keyspb.PublicKey
+	ReceiptPriv              proto.Message
 	MinInterval, MaxInterval time.Duration
-	// TODO(gbelvin): specify mutation function
-	Deleted bool
+	// Sequencing holds the policies used to accept and sequence mutations.
+	// Defaults apply if it is nil. It is set when the domain is created and
+	// never changes.
+	Sequencing *pb.SequencingConfig
+	Deleted    bool
+}
+
+// DefaultMaxBatchSize limits the number of mutations that are sequenced per
+// epoch in domains that do not configure a max batch size.
+const DefaultMaxBatchSize = int32(1000)
+
+// BatchSize returns the maximum number of mutations per epoch of d.
+func (d *Domain) BatchSize() int32 {
+	if n := d.Sequencing.GetMaxBatchSize(); n > 0 {
+		return n
+	}
+	return DefaultMaxBatchSize
 }
 
 // Storage is an interface for storing multi-tenant configuration information.
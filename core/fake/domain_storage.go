@@ -16,6 +16,7 @@ package fake
 
 import (
 	"context"
+	"sort"
 
 	"github.com/google/keytransparency/core/domain"
 	"google.golang.org/grpc/codes"
@@ -34,28 +35,38 @@ func NewDomainStorage() *DomainStorage {
 	}
 }
 
-// List returns a list of active domains
+// List returns a list of active domains, sorted by domain ID.
 func (a *DomainStorage) List(ctx context.Context, deleted bool) ([]*domain.Domain, error) {
 	ret := make([]*domain.Domain, 0, len(a.domains))
 	for _, d := range a.domains {
-		ret = append(ret, d)
+		if d.Deleted && !deleted {
+			continue
+		}
+		c := *d
+		ret = append(ret, &c)
 	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].DomainID < ret[j].DomainID })
 	return ret, nil
 }
 
 // Write adds a new domain.
 func (a *DomainStorage) Write(ctx context.Context, d *domain.Domain) error {
-	a.domains[d.DomainID] = d
+	if _, ok := a.domains[d.DomainID]; ok {
+		return status.Errorf(codes.AlreadyExists, "Domain %v already exists", d.DomainID)
+	}
+	c := *d
+	a.domains[d.DomainID] = &c
 	return nil
 }
 
 // Read returns existing domains.
 func (a *DomainStorage) Read(ctx context.Context, ID string, showDeleted bool) (*domain.Domain, error) {
 	d, ok := a.domains[ID]
-	if !ok {
+	if !ok || (d.Deleted && !showDeleted) {
 		return nil, status.Errorf(codes.NotFound, "Domain %v not found", ID)
 	}
-	return d, nil
+	c := *d
+	return &c, nil
 }
 
 // SetDelete deletes or undeletes a domain.
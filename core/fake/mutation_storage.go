@@ -16,7 +16,11 @@ package fake
 
 import (
 	"context"
-	"fmt"
+	"sort"
+
+	"github.com/google/keytransparency/core/mutator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
 )
@@ -25,33 +29,48 @@ import (
 type MutationStorage struct {
 	// mtns is a map of domains to epoch numbers to a list of mutations.
 	mtns map[string]map[int64][]*pb.Entry
+	// changes is a map of domains to indexes to the sorted list of epochs in
+	// which that index changed.
+	changes map[string]map[string][]int64
+	// statuses is a map of domains to mutation hashes to mutation statuses.
+	statuses map[string]map[string]*pb.MutationStatus
+	// rejected is a map of domains to epoch numbers to a list of rejected
+	// mutations, in sequence order.
+	rejected map[string]map[int64][]*pb.RejectedMutation
+	// committed is a map of domains to epoch numbers to the committed data of
+	// each mutation, for batches written by SequenceBatch.
+	committed map[string]map[int64][]*pb.Committed
+	// sequenced is a map of domains to the highest sequenced revision.
+	sequenced map[string]int64
 }
 
 // NewMutationStorage returns a fake mutator.Mutation
 func NewMutationStorage() *MutationStorage {
 	return &MutationStorage{
-		mtns: make(map[string]map[int64][]*pb.Entry),
+		mtns:      make(map[string]map[int64][]*pb.Entry),
+		changes:   make(map[string]map[string][]int64),
+		statuses:  make(map[string]map[string]*pb.MutationStatus),
+		rejected:  make(map[string]map[int64][]*pb.RejectedMutation),
+		committed: make(map[string]map[int64][]*pb.Committed),
+		sequenced: make(map[string]int64),
 	}
 }
 
 // ReadPage paginates through the list of mutations
 func (m *MutationStorage) ReadPage(_ context.Context, domainID string, revision, start int64, pageSize int32) (int64, []*pb.Entry, error) {
-	domain, ok := m.mtns[domainID]
-	if !ok {
-		return 0, nil, fmt.Errorf("DomainID %v not found", domainID)
-	}
-	mutationList, ok := domain[revision]
-	if !ok {
-		return 0, nil, fmt.Errorf("DomainID: %v, revision %v not found", domainID, revision)
+	if start < 0 {
+		start = 0
 	}
-	if int(start) > len(mutationList) {
-		return start, nil, nil
+	mutationList := m.mtns[domainID][revision]
+	if int(start) >= len(mutationList) || pageSize <= 0 {
+		return 0, []*pb.Entry{}, nil
 	}
 	end := int(start) + int(pageSize)
 	if end > len(mutationList) {
 		end = len(mutationList)
 	}
-	return int64(end), mutationList[int(start):end], nil
+	// Return the maximum sequence number read, like the SQL implementation.
+	return int64(end - 1), mutationList[int(start):end], nil
 }
 
 // WriteBatch stores a set of mutations that are associated with a revision.
@@ -60,5 +79,144 @@ func (m *MutationStorage) WriteBatch(_ context.Context, domainID string, revisio
 		m.mtns[domainID] = make(map[int64][]*pb.Entry)
 	}
 	m.mtns[domainID][revision] = mutations
+	delete(m.committed[domainID], revision)
+	return nil
+}
+
+// SequenceBatch stores msgs and records revision as the highest sequenced
+// revision. The fake has no queue, so no messages are removed.
+func (m *MutationStorage) SequenceBatch(ctx context.Context, domainID string, revision int64, msgs []*mutator.QueueMessage) error {
+	if last := m.sequenced[domainID]; last != 0 && last != revision-1 {
+		return mutator.ErrStaleBatch
+	}
+	mutations := make([]*pb.Entry, 0, len(msgs))
+	committed := make([]*pb.Committed, 0, len(msgs))
+	for _, msg := range msgs {
+		mutations = append(mutations, msg.Mutation)
+		committed = append(committed, msg.ExtraData)
+	}
+	if err := m.WriteBatch(ctx, domainID, revision, mutations); err != nil {
+		return err
+	}
+	if _, ok := m.committed[domainID]; !ok {
+		m.committed[domainID] = make(map[int64][]*pb.Committed)
+	}
+	m.committed[domainID][revision] = committed
+	m.sequenced[domainID] = revision
+	return nil
+}
+
+// ReadBatch returns the messages stored for revision.
+func (m *MutationStorage) ReadBatch(_ context.Context, domainID string, revision int64) ([]*mutator.QueueMessage, error) {
+	committed := m.committed[domainID][revision]
+	msgs := make([]*mutator.QueueMessage, 0)
+	for i, e := range m.mtns[domainID][revision] {
+		msg := &mutator.QueueMessage{Mutation: e}
+		if i < len(committed) {
+			msg.ExtraData = committed[i]
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+// DeleteRevisions deletes the mutations of revisions [start, end].
+func (m *MutationStorage) DeleteRevisions(_ context.Context, domainID string, start, end int64) error {
+	for rev := range m.mtns[domainID] {
+		if rev >= start && rev <= end {
+			delete(m.mtns[domainID], rev)
+			delete(m.committed[domainID], rev)
+		}
+	}
+	return nil
+}
+
+// HighestSequencedRevision returns the highest revision recorded by SequenceBatch.
+func (m *MutationStorage) HighestSequencedRevision(_ context.Context, domainID string) (int64, error) {
+	return m.sequenced[domainID], nil
+}
+
+// WriteIndexChanges records the indexes that changed in revision.
+func (m *MutationStorage) WriteIndexChanges(_ context.Context, domainID string, revision int64, indexes [][]byte) error {
+	if _, ok := m.changes[domainID]; !ok {
+		m.changes[domainID] = make(map[string][]int64)
+	}
+	for _, index := range indexes {
+		revs := m.changes[domainID][string(index)]
+		i := sort.Search(len(revs), func(i int) bool { return revs[i] >= revision })
+		if i < len(revs) && revs[i] == revision {
+			continue
+		}
+		revs = append(revs, 0)
+		copy(revs[i+1:], revs[i:])
+		revs[i] = revision
+		m.changes[domainID][string(index)] = revs
+	}
+	return nil
+}
+
+// ListIndexChanges returns the revisions in [start, end] in which index changed.
+func (m *MutationStorage) ListIndexChanges(_ context.Context, domainID string, index []byte, start, end int64, limit int32) ([]int64, error) {
+	ret := []int64{}
+	for _, rev := range m.changes[domainID][string(index)] {
+		if len(ret) >= int(limit) {
+			break
+		}
+		if rev >= start && rev <= end {
+			ret = append(ret, rev)
+		}
+	}
+	return ret, nil
+}
+
+// WriteStatus records the status of the mutation identified by hash.
+func (m *MutationStorage) WriteStatus(_ context.Context, domainID string, hash []byte, s *pb.MutationStatus) error {
+	if _, ok := m.statuses[domainID]; !ok {
+		m.statuses[domainID] = make(map[string]*pb.MutationStatus)
+	}
+	m.statuses[domainID][string(hash)] = s
+	return nil
+}
+
+// ReadStatus returns the status of the mutation identified by hash.
+func (m *MutationStorage) ReadStatus(_ context.Context, domainID string, hash []byte) (*pb.MutationStatus, error) {
+	s, ok := m.statuses[domainID][string(hash)]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "mutation %x not found", hash)
+	}
+	return s, nil
+}
+
+// WriteRejected records the mutations that were rejected in revision.
+func (m *MutationStorage) WriteRejected(_ context.Context, domainID string, revision int64, rejected []*pb.RejectedMutation) error {
+	if _, ok := m.rejected[domainID]; !ok {
+		m.rejected[domainID] = make(map[int64][]*pb.RejectedMutation)
+	}
+	rms := m.rejected[domainID][revision]
+	for _, r := range rejected {
+		i := sort.Search(len(rms), func(i int) bool { return rms[i].GetSequence() >= r.GetSequence() })
+		if i < len(rms) && rms[i].GetSequence() == r.GetSequence() {
+			rms[i] = r
+			continue
+		}
+		rms = append(rms, nil)
+		copy(rms[i+1:], rms[i:])
+		rms[i] = r
+	}
+	m.rejected[domainID][revision] = rms
 	return nil
 }
+
+// ReadRejectedPage returns the mutations rejected in revision, starting at sequence start.
+func (m *MutationStorage) ReadRejectedPage(_ context.Context, domainID string, revision, start int64, pageSize int32) ([]*pb.RejectedMutation, error) {
+	ret := []*pb.RejectedMutation{}
+	for _, r := range m.rejected[domainID][revision] {
+		if len(ret) >= int(pageSize) {
+			break
+		}
+		if r.GetSequence() >= start {
+			ret = append(ret, r)
+		}
+	}
+	return ret, nil
+}
@@ -29,12 +29,12 @@ type keyID struct {
 	appID    string
 }
 
-// KeySets implements storage.UserManagerTable in memory.
+// KeySets implements storage.KeySets in memory.
 type KeySets struct {
 	keysets map[keyID]*tpb.KeySet
 }
 
-// NewKeySets produces a fake implementation of storage.UserManagerTable.
+// NewKeySets produces a fake implementation of storage.KeySets.
 func NewKeySets() *KeySets {
 	return &KeySets{
 		keysets: make(map[keyID]*tpb.KeySet),
@@ -53,3 +53,17 @@ func (k *KeySets) Get(ctx context.Context, instance int64, domainID, appID strin
 	}
 	return ks, nil
 }
+
+// Set saves a keyset. Keysets cannot be overwritten.
+func (k *KeySets) Set(ctx context.Context, instance int64, domainID, appID string, ks *tpb.KeySet) error {
+	id := keyID{
+		instance: instance,
+		domainID: domainID,
+		appID:    appID,
+	}
+	if _, ok := k.keysets[id]; ok {
+		return status.Errorf(codes.AlreadyExists, "KeySet %v/%v/%v already exists", instance, domainID, appID)
+	}
+	k.keysets[id] = ks
+	return nil
+}
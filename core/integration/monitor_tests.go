@@ -20,6 +20,7 @@ import (
 
 	"github.com/google/keytransparency/core/fake"
 	"github.com/google/keytransparency/core/monitor"
+	"github.com/google/keytransparency/core/mutator/entry"
 	"github.com/google/keytransparency/core/testutil"
 	"github.com/google/tink/go/tink"
 	"google.golang.org/grpc/codes"
@@ -96,7 +97,30 @@ func TestMonitor(ctx context.Context, env *Env, t *testing.T) {
 				},
 			},
 		},
+		{
+			// Several updates to the same user are applied in order.
+			epoch:   4,
+			signers: testutil.SignKeysetsFromPEMs(testPrivKey1),
+			userUpdates: []*tpb.User{
+				{
+					DomainId:       env.Domain.DomainId,
+					AppId:          "app1",
+					UserId:         "alice@test.com",
+					PublicKeyData:  []byte("alice-key2"),
+					AuthorizedKeys: testutil.VerifyKeysetFromPEMs(testPubKey1).Keyset(),
+				},
+				{
+					DomainId:       env.Domain.DomainId,
+					AppId:          "app1",
+					UserId:         "alice@test.com",
+					PublicKeyData:  []byte("alice-key3"),
+					AuthorizedKeys: testutil.VerifyKeysetFromPEMs(testPubKey1).Keyset(),
+				},
+			},
+		},
 	} {
+		// last holds the last mutation queued for each user in this epoch.
+		last := make(map[string]*entry.Mutation)
 		for _, u := range e.userUpdates {
 			opts := env.CallOpts(u.UserId)
 			cctx, cancel := context.WithTimeout(ctx, env.Timeout)
@@ -105,6 +129,16 @@ func TestMonitor(ctx context.Context, env *Env, t *testing.T) {
 			if err != nil {
 				t.Fatalf("CreateMutation(%v): %v", u.UserId, err)
 			}
+			if prev, ok := last[u.UserId]; ok {
+				// Build on the previous update, which has not been applied yet.
+				if m, err = prev.Next(); err != nil {
+					t.Fatalf("Next(): %v", err)
+				}
+				if err := m.SetCommitment(u.PublicKeyData); err != nil {
+					t.Fatalf("SetCommitment(): %v", err)
+				}
+			}
+			last[u.UserId] = m
 			if err := env.Client.QueueMutation(cctx, m, e.signers, opts...); err != nil {
 				t.Fatalf("QueueMutation(%v): %v", u.UserId, err)
 			}
@@ -125,7 +159,17 @@ func TestMonitor(ctx context.Context, env *Env, t *testing.T) {
 	}
 	cancel()
 
-	for i := int64(1); i < 4; i++ {
+	cctx, cancel = context.WithTimeout(ctx, env.Timeout)
+	defer cancel()
+	data, _, err := env.Client.GetEntry(cctx, "alice@test.com", "app1")
+	if err != nil {
+		t.Fatalf("GetEntry(): %v", err)
+	}
+	if got, want := string(data), "alice-key3"; got != want {
+		t.Errorf("GetEntry(alice): %v, want %v", got, want)
+	}
+
+	for i := int64(1); i < 5; i++ {
 		mresp, err := store.Get(i)
 		if err != nil {
 			t.Errorf("Could not read monitoring response for epoch %v: %v", i, err)
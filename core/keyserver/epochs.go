@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/golang/glog"
 	"google.golang.org/grpc/codes"
@@ -34,6 +35,11 @@ var (
 	defaultPageSize = int32(16) //32KB
 	// Maximum allowed requested page size to prevent DOS.
 	maxPageSize = int32(2048) // 8MB
+	// Number of mutations ListMutationsStream reads and proves at a time.
+	streamBatchSize = maxPageSize
+	// How often GetEpochStream polls for new epochs when the domain
+	// does not specify a MinInterval.
+	defaultEpochPollPeriod = 1 * time.Second
 )
 
 // GetLatestEpoch returns the latest epoch. The current epoch tracks the SignedLogRoot.
@@ -108,9 +114,60 @@ func (s *Server) getEpochByRevision(ctx context.Context, d *domain.Domain,
 	}, nil
 }
 
-// GetEpochStream is a streaming API similar to ListMutations.
-func (*Server) GetEpochStream(in *pb.GetEpochRequest, stream pb.KeyTransparency_GetEpochStreamServer) error {
-	return status.Error(codes.Unimplemented, "GetEpochStream is unimplemented")
+// GetEpochStream sends every epoch starting at in.Epoch and then continues to
+// send new epochs as they are published, until the client goes away.
+// Log inclusion and consistency proofs are relative to in.FirstTreeSize.
+func (s *Server) GetEpochStream(in *pb.GetEpochRequest, stream pb.KeyTransparency_GetEpochStreamServer) error {
+	if err := validateGetEpochRequest(in); err != nil {
+		glog.Errorf("validateGetEpochRequest(%v): %v", in, err)
+		return status.Error(codes.InvalidArgument, "Invalid request")
+	}
+	ctx := stream.Context()
+
+	// Lookup log and map info.
+	d, err := s.domains.Read(ctx, in.DomainId, false)
+	if err != nil {
+		glog.Errorf("GetEpochStream(): adminstorage.Read(%v): %v", in.DomainId, err)
+		return status.Errorf(codes.Internal, "Cannot fetch domain info")
+	}
+
+	// New epochs are not published more often than MinInterval.
+	period := d.MinInterval
+	if period <= 0 {
+		period = defaultEpochPollPeriod
+	}
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for epoch := in.GetEpoch(); ; {
+		logRoot, err := s.latestLogRoot(ctx, d)
+		if err != nil {
+			return err
+		}
+		if epoch < logRoot.GetTreeSize() {
+			logConsistency, err := s.logConsistency(ctx, d, in.GetFirstTreeSize(), logRoot)
+			if err != nil {
+				return err
+			}
+			for ; epoch < logRoot.GetTreeSize(); epoch++ {
+				resp, err := s.getEpochByRevision(ctx, d, logRoot, logConsistency, epoch)
+				if err != nil {
+					return err
+				}
+				if err := stream.Send(resp); err != nil {
+					glog.Errorf("GetEpochStream(): stream.Send(%v): %v", epoch, err)
+					return err
+				}
+			}
+		}
+
+		// Wait for the next epoch to be published.
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
 }
 
 // ListMutations returns the mutations that created an epoch.
@@ -130,26 +187,10 @@ func (s *Server) ListMutations(ctx context.Context, in *pb.ListMutationsRequest)
 	if err != nil {
 		return nil, err
 	}
-	// Read mutations from the database.
-	max, entries, err := s.mutations.ReadPage(ctx, d.DomainID, in.GetEpoch(), start, in.GetPageSize())
-	if err != nil {
-		glog.Errorf("ListMutations(): mutations.ReadRange(%v, %v, %v, %v): %v", d.MapID, in.GetEpoch(), start, in.GetPageSize(), err)
-		return nil, status.Error(codes.Internal, "Reading mutations range failed")
-	}
-	indexes := make([][]byte, 0, len(entries))
-	mutations := make([]*pb.MutationProof, 0, len(entries))
-	for _, e := range entries {
-		mutations = append(mutations, &pb.MutationProof{Mutation: e})
-		indexes = append(indexes, e.GetIndex())
-	}
-	// Get leaf proofs.
-	proofs, err := s.inclusionProofs(ctx, d, indexes, in.Epoch-1)
+	max, mutations, err := s.mutationProofs(ctx, d, in.GetEpoch(), start, in.GetPageSize())
 	if err != nil {
 		return nil, err
 	}
-	for i, p := range proofs {
-		mutations[i].LeafProof = p
-	}
 
 	nextPageToken := ""
 	if len(mutations) == int(in.PageSize) {
@@ -162,8 +203,72 @@ func (s *Server) ListMutations(ctx context.Context, in *pb.ListMutationsRequest)
 }
 
 // ListMutationsStream is a streaming list of mutations in a specific epoch.
-func (*Server) ListMutationsStream(in *pb.ListMutationsRequest, stream pb.KeyTransparency_ListMutationsStreamServer) error {
-	return status.Error(codes.Unimplemented, "ListMutationStream is unimplemented")
+// Mutations are sent in sequence order, starting at in.PageToken. Mutations
+// and their inclusion proofs are read in batches of streamBatchSize. The
+// stream is not paged, so in.PageSize is ignored.
+func (s *Server) ListMutationsStream(in *pb.ListMutationsRequest, stream pb.KeyTransparency_ListMutationsStreamServer) error {
+	if err := validateListMutationsRequest(in); err != nil {
+		glog.Errorf("validateListMutationsRequest(%v): %v", in, err)
+		return status.Error(codes.InvalidArgument, "Invalid request")
+	}
+	ctx := stream.Context()
+	// Lookup log and map info.
+	d, err := s.domains.Read(ctx, in.DomainId, false)
+	if err != nil {
+		glog.Errorf("ListMutationsStream(): adminstorage.Read(%v): %v", in.DomainId, err)
+		return status.Errorf(codes.Internal, "Cannot fetch domain info")
+	}
+
+	start, err := parseToken(in.PageToken)
+	if err != nil {
+		return err
+	}
+	for {
+		max, mutations, err := s.mutationProofs(ctx, d, in.GetEpoch(), start, streamBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, m := range mutations {
+			if err := stream.Send(m); err != nil {
+				glog.Errorf("ListMutationsStream(): stream.Send(): %v", err)
+				return err
+			}
+		}
+		if len(mutations) < int(streamBatchSize) {
+			return nil
+		}
+		start = max + 1
+	}
+}
+
+// mutationProofs returns up to pageSize mutations in epoch, starting at
+// sequence number start, along with their inclusion proofs in epoch-1.
+// It also returns the highest sequence number read.
+func (s *Server) mutationProofs(ctx context.Context, d *domain.Domain, epoch, start int64, pageSize int32) (int64, []*pb.MutationProof, error) {
+	// Read mutations from the database.
+	max, entries, err := s.mutations.ReadPage(ctx, d.DomainID, epoch, start, pageSize)
+	if err != nil {
+		glog.Errorf("mutations.ReadRange(%v, %v, %v, %v): %v", d.MapID, epoch, start, pageSize, err)
+		return 0, nil, status.Error(codes.Internal, "Reading mutations range failed")
+	}
+	indexes := make([][]byte, 0, len(entries))
+	mutations := make([]*pb.MutationProof, 0, len(entries))
+	for _, e := range entries {
+		mutations = append(mutations, &pb.MutationProof{Mutation: e})
+		indexes = append(indexes, e.GetIndex())
+	}
+	if len(entries) == 0 {
+		return max, mutations, nil
+	}
+	// Get leaf proofs.
+	proofs, err := s.inclusionProofs(ctx, d, indexes, epoch-1)
+	if err != nil {
+		return 0, nil, err
+	}
+	for i, p := range proofs {
+		mutations[i].LeafProof = p
+	}
+	return max, mutations, nil
 }
 
 // logInclusion returns the inclusion proof for a map revision in the log of map roots.
@@ -209,23 +314,32 @@ func (s *Server) latestLogRootProof(ctx context.Context, d *domain.Domain, first
 	if err != nil {
 		return nil, nil, err
 	}
-	// Consistency proof.
+	logConsistency, err := s.logConsistency(ctx, d, firstTreeSize, sth)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sth, logConsistency, nil
+}
+
+// logConsistency returns the consistency proof between firstTreeSize and sth.
+// If firstTreeSize is 0, no proof is returned.
+func (s *Server) logConsistency(ctx context.Context, d *domain.Domain, firstTreeSize int64, sth *tpb.SignedLogRoot) (*tpb.Proof, error) {
 	secondTreeSize := sth.GetTreeSize()
-	var logConsistency *tpb.GetConsistencyProofResponse
-	if firstTreeSize != 0 {
-		logConsistency, err = s.tlog.GetConsistencyProof(ctx,
-			&tpb.GetConsistencyProofRequest{
-				LogId:          d.LogID,
-				FirstTreeSize:  firstTreeSize,
-				SecondTreeSize: secondTreeSize,
-			})
-		if err != nil {
-			glog.Errorf("latestLogRootProof(): log.GetConsistency(%v, %v, %v): %v",
-				d.LogID, firstTreeSize, secondTreeSize, err)
-			return nil, nil, status.Errorf(codes.Internal, "Cannot fetch log consistency proof")
-		}
+	if firstTreeSize == 0 {
+		return nil, nil
+	}
+	logConsistency, err := s.tlog.GetConsistencyProof(ctx,
+		&tpb.GetConsistencyProofRequest{
+			LogId:          d.LogID,
+			FirstTreeSize:  firstTreeSize,
+			SecondTreeSize: secondTreeSize,
+		})
+	if err != nil {
+		glog.Errorf("latestLogRootProof(): log.GetConsistency(%v, %v, %v): %v",
+			d.LogID, firstTreeSize, secondTreeSize, err)
+		return nil, status.Errorf(codes.Internal, "Cannot fetch log consistency proof")
 	}
-	return sth, logConsistency.GetProof(), nil
+	return logConsistency.GetProof(), nil
 }
 
 // mapRevisionFor returns the latest map revision, given the latest sth.
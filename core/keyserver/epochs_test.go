@@ -24,8 +24,7 @@ import (
 
 	"github.com/golang/mock/gomock"
 	"github.com/golang/protobuf/proto"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"google.golang.org/grpc"
 
 	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
 	tpb "github.com/google/trillian"
@@ -62,11 +61,89 @@ func genMutations(start, end int) []*pb.Entry {
 	return mutations
 }
 
+// epochStream is a fake pb.KeyTransparency_GetEpochStreamServer that
+// cancels its context after receiving n epochs.
+type epochStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	cancel context.CancelFunc
+	n      int
+	epochs []*pb.Epoch
+}
+
+func (s *epochStream) Context() context.Context { return s.ctx }
+
+func (s *epochStream) Send(e *pb.Epoch) error {
+	s.epochs = append(s.epochs, e)
+	if len(s.epochs) >= s.n {
+		s.cancel()
+	}
+	return nil
+}
+
 func TestGetEpochStream(t *testing.T) {
-	srv := &Server{}
-	err := srv.GetEpochStream(nil, nil)
-	if got, want := status.Code(err), codes.Unimplemented; got != want {
-		t.Errorf("GetMutationsStream(_, _): %v, want %v", got, want)
+	ctx := context.Background()
+	for _, tc := range []struct {
+		desc      string
+		start     int64
+		treeSizes []int64
+		wantRevs  []int64
+	}{
+		{desc: "existing epochs", start: 1, treeSizes: []int64{4}, wantRevs: []int64{1, 2, 3}},
+		{desc: "new epochs", start: 1, treeSizes: []int64{2, 2, 4}, wantRevs: []int64{1, 2, 3}},
+		{desc: "future start", start: 3, treeSizes: []int64{1, 5}, wantRevs: []int64{3, 4}},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			defer cancel()
+			e, err := newMiniEnv(ctx, t)
+			if err != nil {
+				t.Fatalf("newMiniEnv(): %v", err)
+			}
+			defer e.Close()
+
+			for i, size := range tc.treeSizes {
+				call := e.s.Log.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).
+					Return(&tpb.GetLatestSignedLogRootResponse{
+						SignedLogRoot: &tpb.SignedLogRoot{TreeSize: size},
+					}, nil)
+				if i < len(tc.treeSizes)-1 {
+					call.Times(1)
+				} else {
+					call.AnyTimes()
+				}
+			}
+			e.s.Log.EXPECT().GetInclusionProof(gomock.Any(), gomock.Any()).
+				Return(&tpb.GetInclusionProofResponse{}, nil).AnyTimes()
+			for _, rev := range tc.wantRevs {
+				e.s.Map.EXPECT().GetSignedMapRootByRevision(gomock.Any(),
+					&tpb.GetSignedMapRootByRevisionRequest{
+						MapId:    mapID,
+						Revision: rev,
+					}).Return(&tpb.GetSignedMapRootResponse{
+					MapRoot: &tpb.SignedMapRoot{MapRoot: []byte(fmt.Sprintf("%v", rev))},
+				}, nil)
+			}
+
+			sctx, scancel := context.WithCancel(ctx)
+			defer scancel()
+			stream := &epochStream{ctx: sctx, cancel: scancel, n: len(tc.wantRevs)}
+			err = e.srv.GetEpochStream(&pb.GetEpochRequest{
+				DomainId: domainID,
+				Epoch:    tc.start,
+			}, stream)
+			if got, want := err, context.Canceled; got != want {
+				t.Errorf("GetEpochStream(): %v, want %v", got, want)
+			}
+			if got, want := len(stream.epochs), len(tc.wantRevs); got != want {
+				t.Fatalf("len(epochs): %v, want %v", got, want)
+			}
+			for i, rev := range tc.wantRevs {
+				if got, want := string(stream.epochs[i].GetSmr().GetMapRoot()), fmt.Sprintf("%v", rev); got != want {
+					t.Errorf("epochs[%v].Smr: %v, want %v", i, got, want)
+				}
+			}
+		})
 	}
 }
 
@@ -96,11 +173,11 @@ func TestListMutations(t *testing.T) {
 		wantNext   string
 		wantErr    bool
 	}{
-		{desc: "exact page", epoch: 1, token: "", pageSize: 6, start: 1, end: 6, wantNext: "7"},
+		{desc: "exact page", epoch: 1, token: "", pageSize: 6, start: 1, end: 6, wantNext: "6"},
 		{desc: "large page", epoch: 1, token: "", pageSize: 10, start: 1, end: 6, wantNext: ""},
-		{desc: "partial epoch 1", epoch: 1, token: "", pageSize: 4, start: 1, end: 4, wantNext: "5"},
+		{desc: "partial epoch 1", epoch: 1, token: "", pageSize: 4, start: 1, end: 4, wantNext: "4"},
 		{desc: "large page with token", epoch: 1, token: "2", pageSize: 10, start: 3, end: 6, wantNext: ""},
-		{desc: "smal page with token", epoch: 1, token: "2", pageSize: 2, start: 3, end: 4, wantNext: "5"},
+		{desc: "smal page with token", epoch: 1, token: "2", pageSize: 2, start: 3, end: 4, wantNext: "4"},
 		{desc: "invalid page token", epoch: 1, token: "some_token", pageSize: 0, wantNext: "", wantErr: true},
 	} {
 		t.Run(tc.desc, func(t *testing.T) {
@@ -150,3 +227,89 @@ func TestListMutations(t *testing.T) {
 		})
 	}
 }
+
+// mutationStream is a fake pb.KeyTransparency_ListMutationsStreamServer.
+type mutationStream struct {
+	grpc.ServerStream
+	ctx       context.Context
+	mutations []*pb.MutationProof
+}
+
+func (s *mutationStream) Context() context.Context { return s.ctx }
+
+func (s *mutationStream) Send(m *pb.MutationProof) error {
+	s.mutations = append(s.mutations, m)
+	return nil
+}
+
+func TestListMutationsStream(t *testing.T) {
+	ctx := context.Background()
+	fakeMutations := fake.NewMutationStorage()
+	if err := fakeMutations.WriteBatch(ctx, domainID, 1, genMutations(1, 10)); err != nil {
+		t.Fatalf("Test setup failed: %v", err)
+	}
+
+	defer func(size int32) { streamBatchSize = size }(streamBatchSize)
+	for _, tc := range []struct {
+		desc      string
+		token     string
+		pageSize  int32
+		batchSize int32
+		batches   [][2]int
+		start     int
+		wantErr   bool
+	}{
+		{desc: "one batch", batchSize: maxPageSize, batches: [][2]int{{1, 10}}, start: 1},
+		{desc: "exact batches", batchSize: 5, batches: [][2]int{{1, 5}, {6, 10}}, start: 1},
+		{desc: "partial batch", batchSize: 4, batches: [][2]int{{1, 4}, {5, 8}, {9, 10}}, start: 1},
+		{desc: "with token", token: "3", batchSize: 4, batches: [][2]int{{4, 7}, {8, 10}}, start: 4},
+		{desc: "page size ignored", pageSize: 3, batchSize: maxPageSize, batches: [][2]int{{1, 10}}, start: 1},
+		{desc: "no page size", batchSize: 4, batches: [][2]int{{1, 4}, {5, 8}, {9, 10}}, start: 1},
+		{desc: "invalid page token", token: "some_token", wantErr: true},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			streamBatchSize = tc.batchSize
+			ctx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+			defer cancel()
+			e, err := newMiniEnv(ctx, t)
+			if err != nil {
+				t.Fatalf("newMiniEnv(): %v", err)
+			}
+			defer e.Close()
+			e.srv.mutations = fakeMutations
+
+			for _, b := range tc.batches {
+				e.s.Map.EXPECT().GetLeavesByRevision(gomock.Any(),
+					&tpb.GetMapLeavesByRevisionRequest{
+						MapId: mapID,
+						Index: genIndexes(b[0], b[1]),
+					}).Return(&tpb.GetMapLeavesResponse{
+					MapLeafInclusion: genInclusions(b[0], b[1]),
+				}, nil)
+			}
+
+			stream := &mutationStream{ctx: ctx}
+			err = e.srv.ListMutationsStream(&pb.ListMutationsRequest{
+				DomainId:  domainID,
+				Epoch:     1,
+				PageToken: tc.token,
+				PageSize:  tc.pageSize,
+			}, stream)
+			if got, want := err != nil, tc.wantErr; got != want {
+				t.Fatalf("ListMutationsStream: %v, wantErr %v", err, want)
+			}
+			if err != nil {
+				return
+			}
+			mtns := genMutations(tc.start, 10)
+			if got, want := len(stream.mutations), len(mtns); got != want {
+				t.Fatalf("len(mutations):%v, want %v", got, want)
+			}
+			for i, mut := range stream.mutations {
+				if got, want := mut.Mutation, mtns[i]; !proto.Equal(got, want) {
+					t.Errorf("mutations[%v].Mutation:%v, want %v", i, got, want)
+				}
+			}
+		})
+	}
+}
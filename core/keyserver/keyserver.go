@@ -17,7 +17,6 @@ package keyserver
 
 import (
 	"context"
-	"database/sql"
 
 	"github.com/google/keytransparency/core/crypto/vrf"
 	"github.com/google/keytransparency/core/crypto/vrf/p256"
@@ -33,6 +32,7 @@ import (
 
 	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
 	tpb "github.com/google/trillian"
+	statuspb "google.golang.org/genproto/googleapis/rpc/status"
 )
 
 // Server holds internal state for the key server.
@@ -41,19 +41,27 @@ type Server struct {
 	tmap      tpb.TrillianMapClient
 	logAdmin  tpb.TrillianAdminClient
 	mapAdmin  tpb.TrillianAdminClient
-	mutator   mutator.Func
+	mutators  mutator.Registry
 	domains   domain.Storage
 	queue     mutator.MutationQueue
 	mutations mutator.MutationStorage
 	indexFunc indexFunc
+	// quota is nil unless SetLimits is called.
+	quota *quota
+	// authorizeUpdate is nil unless SetAuthorizer is called.
+	authorizeUpdate UpdateAuthorizer
 }
 
+// UpdateAuthorizer returns an error if the caller described by ctx may not make
+// update.
+type UpdateAuthorizer func(ctx context.Context, update *pb.UpdateEntryRequest) error
+
 // New creates a new instance of the key server.
 func New(tlog tpb.TrillianLogClient,
 	tmap tpb.TrillianMapClient,
 	logAdmin tpb.TrillianAdminClient,
 	mapAdmin tpb.TrillianAdminClient,
-	mutator mutator.Func,
+	mutators mutator.Registry,
 	domains domain.Storage,
 	queue mutator.MutationQueue,
 	mutations mutator.MutationStorage) *Server {
@@ -62,7 +70,7 @@ func New(tlog tpb.TrillianLogClient,
 		tmap:      tmap,
 		logAdmin:  logAdmin,
 		mapAdmin:  mapAdmin,
-		mutator:   mutator,
+		mutators:  mutators,
 		domains:   domains,
 		queue:     queue,
 		mutations: mutations,
@@ -70,6 +78,15 @@ func New(tlog tpb.TrillianLogClient,
 	}
 }
 
+// SetAuthorizer sets the authorizer of the updates of BatchUpdateEntries. Each
+// update is authorized separately, as if it had been sent on its own, so that
+// an unauthorized update does not fail the batch. Every update of a batch is
+// denied if no authorizer is set. It must be called before the server starts
+// serving.
+func (s *Server) SetAuthorizer(authz UpdateAuthorizer) {
+	s.authorizeUpdate = authz
+}
+
 // GetEntry returns a user's profile and proof that there is only one object for
 // this user and that it is the same one being provided to everyone else.
 // GetEntry also supports querying past values by setting the epoch field.
@@ -109,6 +126,50 @@ func (s *Server) GetEntry(ctx context.Context, in *pb.GetEntryRequest) (*pb.GetE
 	return resp, nil
 }
 
+// GetEntryByRevision returns a user's entry at a specific map revision, along
+// with proof that the map root of that revision is included in the latest log
+// root.
+func (s *Server) GetEntryByRevision(ctx context.Context, in *pb.GetEntryByRevisionRequest) (*pb.GetEntryResponse, error) {
+	domainID := in.GetDomainId()
+	if domainID == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Please specify a domain_id")
+	}
+	if in.GetRevision() < 0 {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"Revision is %v, want >= 0", in.GetRevision())
+	}
+
+	// Lookup log and map info.
+	d, err := s.domains.Read(ctx, domainID, false)
+	if status.Code(err) == codes.NotFound {
+		glog.Errorf("adminstorage.Read(%v): %v", domainID, err)
+		return nil, status.Errorf(codes.NotFound, "Domain %v not found", domainID)
+	} else if err != nil {
+		glog.Errorf("adminstorage.Read(%v): %v", domainID, err)
+		return nil, status.Errorf(codes.Internal, "Cannot fetch domain info")
+	}
+
+	// Fetch latest log root.
+	sth, consistencyProof, err := s.latestLogRootProof(ctx, d, in.GetFirstTreeSize())
+	if err != nil {
+		return nil, err
+	}
+	if in.GetRevision() >= sth.GetTreeSize() {
+		return nil, status.Errorf(codes.NotFound, "keyserver: Epoch %v has not been released yet", in.GetRevision())
+	}
+
+	entryProof, err := s.getEntryByRevision(ctx, sth, d, in.GetUserId(), in.GetAppId(), in.GetRevision())
+	if err != nil {
+		return nil, err
+	}
+	resp := &pb.GetEntryResponse{
+		LogRoot:        sth,
+		LogConsistency: consistencyProof.GetHashes(),
+	}
+	proto.Merge(resp, entryProof)
+	return resp, nil
+}
+
 // getEntryByRevision returns an entry and its proofs.
 // getEntryByRevision does NOT populate the following fields:
 // - LogRoot
@@ -139,17 +200,9 @@ func (s *Server) getEntryByRevision(ctx context.Context, sth *tpb.SignedLogRoot,
 	}
 	neighbors := getResp.MapLeafInclusion[0].GetInclusion()
 	leaf := getResp.MapLeafInclusion[0].GetLeaf().GetLeafValue()
-	extraData := getResp.MapLeafInclusion[0].GetLeaf().GetExtraData()
-
-	var committed *pb.Committed
-	if leaf != nil {
-		if extraData == nil {
-			return nil, status.Errorf(codes.Internal, "Missing commitment data")
-		}
-		committed = &pb.Committed{}
-		if err := proto.Unmarshal(extraData, committed); err != nil {
-			return nil, status.Errorf(codes.Internal, "Cannot read committed value")
-		}
+	committed, err := committedFor(getResp.MapLeafInclusion[0].GetLeaf())
+	if err != nil {
+		return nil, err
 	}
 
 	// SignedMapHead to SignedLogRoot inclusion proof.
@@ -181,6 +234,103 @@ func (s *Server) getEntryByRevision(ctx context.Context, sth *tpb.SignedLogRoot,
 	}, nil
 }
 
+// committedFor returns the commitment data stored alongside a map leaf.
+// committedFor returns nil if the leaf is empty.
+func committedFor(leaf *tpb.MapLeaf) (*pb.Committed, error) {
+	if leaf.GetLeafValue() == nil {
+		return nil, nil
+	}
+	extraData := leaf.GetExtraData()
+	if extraData == nil {
+		return nil, status.Errorf(codes.Internal, "Missing commitment data")
+	}
+	committed := &pb.Committed{}
+	if err := proto.Unmarshal(extraData, committed); err != nil {
+		return nil, status.Errorf(codes.Internal, "Cannot read committed value")
+	}
+	return committed, nil
+}
+
+// BatchGetEntries returns a set of user entries and their proofs. All entries
+// are read from the latest map revision in a single map request, and share the
+// same map root and log proofs.
+func (s *Server) BatchGetEntries(ctx context.Context, in *pb.BatchGetEntriesRequest) (*pb.BatchGetEntriesResponse, error) {
+	if err := validateBatchGetEntriesRequest(in); err != nil {
+		glog.Errorf("validateBatchGetEntriesRequest(): %v", err)
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid request: %v", err)
+	}
+
+	// Lookup log and map info.
+	d, err := s.domains.Read(ctx, in.GetDomainId(), false)
+	if err != nil {
+		glog.Errorf("adminstorage.Read(%v): %v", in.GetDomainId(), err)
+		return nil, status.Errorf(codes.Internal, "Cannot fetch domain info")
+	}
+
+	// Fetch latest revision.
+	sth, consistencyProof, err := s.latestLogRootProof(ctx, d, in.GetFirstTreeSize())
+	if err != nil {
+		return nil, err
+	}
+	revision, err := mapRevisionFor(sth)
+	if err != nil {
+		glog.Errorf("latestRevision(log %v, sth%v): %v", d.LogID, sth, err)
+		return nil, err
+	}
+
+	indexes := make([][]byte, 0, len(in.GetEntries()))
+	entries := make([]*pb.GetEntryResponse, 0, len(in.GetEntries()))
+	for _, id := range in.GetEntries() {
+		index, proof, err := s.indexFunc(ctx, d, id.GetAppId(), id.GetUserId())
+		if err != nil {
+			return nil, err
+		}
+		indexes = append(indexes, index[:])
+		entries = append(entries, &pb.GetEntryResponse{VrfProof: proof})
+	}
+
+	getResp, err := s.tmap.GetLeavesByRevision(ctx, &tpb.GetMapLeavesByRevisionRequest{
+		MapId:    d.MapID,
+		Index:    indexes,
+		Revision: revision,
+	})
+	if err != nil {
+		glog.Errorf("GetLeavesByRevision(%v, rev: %v): %v", d.MapID, revision, err)
+		return nil, status.Errorf(codes.Internal, "Failed fetching map leaves")
+	}
+	if got, want := len(getResp.GetMapLeafInclusion()), len(indexes); got != want {
+		glog.Errorf("GetLeavesByRevision() len: %v, want %v", got, want)
+		return nil, status.Errorf(codes.Internal, "Failed fetching map leaves")
+	}
+	for i, inclusion := range getResp.GetMapLeafInclusion() {
+		committed, err := committedFor(inclusion.GetLeaf())
+		if err != nil {
+			return nil, err
+		}
+		entries[i].Committed = committed
+		entries[i].LeafProof = &tpb.MapLeafInclusion{
+			Inclusion: inclusion.GetInclusion(),
+			Leaf: &tpb.MapLeaf{
+				LeafValue: inclusion.GetLeaf().GetLeafValue(),
+			},
+		}
+	}
+
+	// SignedMapHead to SignedLogRoot inclusion proof.
+	logInclusion, err := s.logInclusion(ctx, d, sth, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.BatchGetEntriesResponse{
+		Entries:        entries,
+		Smr:            getResp.GetMapRoot(),
+		LogRoot:        sth,
+		LogConsistency: consistencyProof.GetHashes(),
+		LogInclusion:   logInclusion.GetHashes(),
+	}, nil
+}
+
 // ListEntryHistory returns a list of EntryProofs covering a period of time.
 func (s *Server) ListEntryHistory(ctx context.Context, in *pb.ListEntryHistoryRequest) (*pb.ListEntryHistoryResponse, error) {
 	// Lookup log and map info.
@@ -209,6 +359,9 @@ func (s *Server) ListEntryHistory(ctx context.Context, in *pb.ListEntryHistoryRe
 		glog.Errorf("validateListEntryHistoryRequest(%v, %v): %v", in, currentEpoch, err)
 		return nil, status.Errorf(codes.InvalidArgument, "Invalid request")
 	}
+	if in.GetSparse() {
+		return s.listEntryChanges(ctx, d, sth, consistencyProof, currentEpoch, in)
+	}
 
 	// TODO(gbelvin): fetch all history from trillian at once.
 	// Get all GetEntryResponse for all epochs in the range [start, start + in.PageSize].
@@ -238,6 +391,63 @@ func (s *Server) ListEntryHistory(ctx context.Context, in *pb.ListEntryHistoryRe
 	}, nil
 }
 
+// listEntryChanges returns the entry at in.Start and at each of the next
+// in.PageSize epochs in which the entry changed. Each change is preceded by the
+// entry at the epoch before it. Because entries are hash chained, an entry
+// that has the same leaf value at two epochs did not change in between.
+func (s *Server) listEntryChanges(ctx context.Context, d *domain.Domain, sth *tpb.SignedLogRoot, consistencyProof *tpb.Proof,
+	currentEpoch int64, in *pb.ListEntryHistoryRequest) (*pb.ListEntryHistoryResponse, error) {
+	index, _, err := s.indexFunc(ctx, d, in.AppId, in.UserId)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := s.mutations.ListIndexChanges(ctx, d.DomainID, index[:], in.Start+1, currentEpoch, in.PageSize)
+	if err != nil {
+		glog.Errorf("mutations.ListIndexChanges(%v, %v, %v): %v", d.DomainID, in.Start+1, currentEpoch, err)
+		return nil, status.Errorf(codes.Internal, "Reading entry changes failed")
+	}
+
+	// If the page is full, it ends at the last change.
+	end := currentEpoch
+	if len(changes) == int(in.PageSize) {
+		end = changes[len(changes)-1]
+	}
+	nextStart := end + 1
+	if nextStart > currentEpoch {
+		nextStart = 0
+	}
+
+	revisions := []int64{in.Start}
+	for _, c := range changes {
+		if prev := c - 1; prev > revisions[len(revisions)-1] {
+			revisions = append(revisions, prev)
+		}
+		revisions = append(revisions, c)
+	}
+	if end > revisions[len(revisions)-1] {
+		revisions = append(revisions, end)
+	}
+
+	responses := make([]*pb.GetEntryResponse, 0, len(revisions))
+	for _, rev := range revisions {
+		resp, err := s.getEntryByRevision(ctx, sth, d, in.UserId, in.AppId, rev)
+		if err != nil {
+			glog.Errorf("getEntry failed for epoch %v: %v", rev, err)
+			return nil, status.Errorf(codes.Internal, "GetEntry failed")
+		}
+		proto.Merge(resp, &pb.GetEntryResponse{
+			LogRoot:        sth,
+			LogConsistency: consistencyProof.GetHashes(),
+		})
+		responses = append(responses, resp)
+	}
+
+	return &pb.ListEntryHistoryResponse{
+		Values:    responses,
+		NextStart: nextStart,
+	}, nil
+}
+
 // UpdateEntry updates a user's profile. If the user does not exist, a new
 // profile will be created.
 func (s *Server) UpdateEntry(ctx context.Context, in *pb.UpdateEntryRequest) (*pb.UpdateEntryResponse, error) {
@@ -263,6 +473,21 @@ func (s *Server) UpdateEntry(ctx context.Context, in *pb.UpdateEntryRequest) (*p
 		glog.Warningf("Invalid UpdateEntryRequest: %v", err)
 		return nil, status.Errorf(codes.InvalidArgument, "Invalid request")
 	}
+	if err := validateAppID(in.AppId, domain.Sequencing); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "App %v is not allowed in domain %v", in.AppId, in.DomainId)
+	}
+	mutate, err := s.mutators.Get(domain.Sequencing)
+	if err != nil {
+		glog.Errorf("mutators.Get(%v): %v", in.DomainId, err)
+		return nil, status.Errorf(codes.Internal, "Unsupported domain mutator")
+	}
+	// Refuse updates early when the queue is backed up. The place
+	// reserved for the update is released unless it is sent.
+	res, st := s.quota.check(ctx, domain, in.UserId)
+	if st != nil {
+		return nil, st.Err()
+	}
+	defer res.release()
 
 	// Query for the current epoch.
 	req := &pb.GetEntryRequest{
@@ -290,7 +515,7 @@ func (s *Server) UpdateEntry(ctx context.Context, in *pb.UpdateEntryRequest) (*p
 		glog.Errorf("entry.FromLeafValue: %v", err)
 		return nil, status.Errorf(codes.InvalidArgument, "invalid previous leaf value")
 	}
-	if _, err := s.mutator.Mutate(oldEntry, in.GetEntryUpdate().GetMutation()); err == mutator.ErrReplay {
+	if _, err := mutate.Mutate(oldEntry, in.GetEntryUpdate().GetMutation()); err == mutator.ErrReplay {
 		glog.Warningf("Discarding request due to replay")
 		// Return the response. The client should handle the replay case
 		// by comparing the returned response with the request. Check
@@ -301,12 +526,180 @@ func (s *Server) UpdateEntry(ctx context.Context, in *pb.UpdateEntryRequest) (*p
 		return nil, status.Errorf(codes.InvalidArgument, "Invalid mutation")
 	}
 
+	// Promise to include the mutation once the queue ahead of it is
+	// sequenced.
+	receipt, err := s.signReceipt(ctx, domain, in.GetEntryUpdate().GetMutation())
+	if err != nil {
+		glog.Errorf("signReceipt(): %v", err)
+		return nil, status.Errorf(codes.Internal, "Receipt signing error")
+	}
+
 	// Save mutation to the database.
 	if err := s.queue.Send(ctx, domain.DomainID, in.GetEntryUpdate()); err != nil {
 		glog.Errorf("mutations.Write failed: %v", err)
 		return nil, status.Errorf(codes.Internal, "Mutation write error")
 	}
-	return &pb.UpdateEntryResponse{Proof: resp}, nil
+	res.sent(in.GetEntryUpdate().GetMutation())
+	return &pb.UpdateEntryResponse{Proof: resp, Receipt: receipt}, nil
+}
+
+// BatchUpdateEntries validates and queues a set of updates. Each update is
+// handled independently: the status of each update is returned in the
+// response, and an invalid or unauthorized update does not prevent the others
+// from being queued. Each update is authorized by the authorizer set with
+// SetAuthorizer.
+func (s *Server) BatchUpdateEntries(ctx context.Context, in *pb.BatchUpdateEntriesRequest) (*pb.BatchUpdateEntriesResponse, error) {
+	if err := validateBatchUpdateEntriesRequest(in); err != nil {
+		glog.Warningf("Invalid BatchUpdateEntriesRequest: %v", err)
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid request: %v", err)
+	}
+	// Lookup log and map info.
+	domain, err := s.domains.Read(ctx, in.DomainId, false)
+	if err != nil {
+		glog.Errorf("adminstorage.Read(%v): %v", in.DomainId, err)
+		return nil, status.Errorf(codes.Internal, "Cannot fetch domain info")
+	}
+	vrfPriv, err := p256.NewFromWrappedKey(ctx, domain.VRFPriv)
+	if err != nil {
+		return nil, err
+	}
+	mutate, err := s.mutators.Get(domain.Sequencing)
+	if err != nil {
+		glog.Errorf("mutators.Get(%v): %v", in.DomainId, err)
+		return nil, status.Errorf(codes.Internal, "Unsupported domain mutator")
+	}
+
+	// Validate each update and collect the indexes of the valid ones.
+	results := make([]*status.Status, len(in.GetUpdates()))
+	indexes := make([][]byte, 0, len(in.GetUpdates()))
+	valid := make([]int, 0, len(in.GetUpdates()))
+	seen := make(map[string]bool)
+	for i, u := range in.GetUpdates() {
+		if st := s.authorize(ctx, in.DomainId, u); st != nil {
+			results[i] = st
+			continue
+		}
+		if u.GetDomainId() != "" && u.GetDomainId() != in.DomainId {
+			results[i] = status.Newf(codes.InvalidArgument, "domain_id %v does not match batch domain_id", u.GetDomainId())
+			continue
+		}
+		if err := validateUpdateEntryRequest(u, vrfPriv); err != nil {
+			glog.Warningf("Invalid UpdateEntryRequest: %v", err)
+			results[i] = status.New(codes.InvalidArgument, "Invalid request")
+			continue
+		}
+		if err := validateAppID(u.GetAppId(), domain.Sequencing); err != nil {
+			results[i] = status.Newf(codes.InvalidArgument, "App %v is not allowed in domain %v", u.GetAppId(), in.DomainId)
+			continue
+		}
+		index := u.GetEntryUpdate().GetMutation().GetIndex()
+		if seen[string(index)] {
+			results[i] = status.New(codes.InvalidArgument, "Duplicate update for the same entry")
+			continue
+		}
+		seen[string(index)] = true
+		indexes = append(indexes, index)
+		valid = append(valid, i)
+	}
+
+	if len(valid) > 0 {
+		// Read the current values of all valid updates at once.
+		sth, err := s.latestLogRoot(ctx, domain)
+		if err != nil {
+			return nil, err
+		}
+		revision, err := mapRevisionFor(sth)
+		if err != nil {
+			glog.Errorf("latestRevision(log %v, sth%v): %v", domain.LogID, sth, err)
+			return nil, err
+		}
+		leaves, err := s.inclusionProofs(ctx, domain, indexes, revision)
+		if err != nil {
+			return nil, err
+		}
+
+		for j, i := range valid {
+			u := in.GetUpdates()[i]
+			results[i] = s.queueUpdate(ctx, domain, u.GetUserId(), mutate, leaves[j].GetLeaf().GetLeafValue(), u.GetEntryUpdate())
+		}
+	}
+
+	resp := &pb.BatchUpdateEntriesResponse{
+		Results: make([]*statuspb.Status, 0, len(results)),
+	}
+	for _, r := range results {
+		resp.Results = append(resp.Results, r.Proto())
+	}
+	return resp, nil
+}
+
+// authorize returns a PermissionDenied status unless u, an update of a batch
+// for domainID, is authorized.
+func (s *Server) authorize(ctx context.Context, domainID string, u *pb.UpdateEntryRequest) *status.Status {
+	if s.authorizeUpdate == nil {
+		return status.New(codes.PermissionDenied, "Batch updates are not authorized by this server")
+	}
+	// Updates are always applied to the domain of the batch.
+	update := &pb.UpdateEntryRequest{
+		DomainId:    domainID,
+		UserId:      u.GetUserId(),
+		AppId:       u.GetAppId(),
+		EntryUpdate: u.GetEntryUpdate(),
+	}
+	if err := s.authorizeUpdate(ctx, update); err != nil {
+		glog.V(2).Infof("BatchUpdateEntries: update of %v/%v: %v", u.GetAppId(), u.GetUserId(), err)
+		return status.New(codes.PermissionDenied, status.Convert(err).Message())
+	}
+	return nil
+}
+
+// queueUpdate checks that mutate can apply update to oldLeafB and saves it to
+// the mutation queue of d.
+func (s *Server) queueUpdate(ctx context.Context, d *domain.Domain, userID string, mutate mutator.Func, oldLeafB []byte, update *pb.EntryUpdate) *status.Status {
+	oldEntry, err := entry.FromLeafValue(oldLeafB)
+	if err != nil {
+		glog.Errorf("entry.FromLeafValue: %v", err)
+		return status.New(codes.InvalidArgument, "invalid previous leaf value")
+	}
+	if _, err := mutate.Mutate(oldEntry, update.GetMutation()); err == mutator.ErrReplay {
+		glog.Warningf("Discarding request due to replay")
+		// The update has already been applied.
+		return status.New(codes.OK, "")
+	} else if err != nil {
+		glog.Warningf("Invalid mutation: %v", err)
+		return status.New(codes.InvalidArgument, "Invalid mutation")
+	}
+
+	res, st := s.quota.check(ctx, d, userID)
+	if st != nil {
+		return st
+	}
+	defer res.release()
+
+	// Save mutation to the database.
+	if err := s.queue.Send(ctx, d.DomainID, update); err != nil {
+		glog.Errorf("mutations.Write failed: %v", err)
+		return status.New(codes.Internal, "Mutation write error")
+	}
+	res.sent(update.GetMutation())
+	return status.New(codes.OK, "")
+}
+
+// GetMutationStatus returns whether a submitted mutation is queued, applied,
+// or rejected.
+func (s *Server) GetMutationStatus(ctx context.Context, in *pb.GetMutationStatusRequest) (*pb.MutationStatus, error) {
+	if err := validateGetMutationStatusRequest(in); err != nil {
+		glog.Warningf("Invalid GetMutationStatusRequest: %v", err)
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid request: %v", err)
+	}
+	st, err := s.mutations.ReadStatus(ctx, in.DomainId, in.MutationHash)
+	if status.Code(err) == codes.NotFound {
+		return nil, status.Errorf(codes.NotFound, "Mutation %x not found", in.MutationHash)
+	} else if err != nil {
+		glog.Errorf("mutations.ReadStatus(%v, %x): %v", in.DomainId, in.MutationHash, err)
+		return nil, status.Errorf(codes.Internal, "Cannot read mutation status")
+	}
+	return st, nil
 }
 
 // GetDomain returns all info tied to the specified domain.
@@ -320,7 +713,7 @@ func (s *Server) GetDomain(ctx context.Context, in *pb.GetDomainRequest) (*pb.Do
 		return nil, status.Errorf(codes.InvalidArgument, "Please specify a domain_id")
 	}
 	domain, err := s.domains.Read(ctx, in.DomainId, false)
-	if err == sql.ErrNoRows {
+	if status.Code(err) == codes.NotFound {
 		glog.Errorf("adminstorage.Read(%v): %v", in.DomainId, err)
 		return nil, status.Errorf(codes.NotFound, "Domain %v not found", in.DomainId)
 	} else if err != nil {
@@ -346,6 +739,7 @@ func (s *Server) GetDomain(ctx context.Context, in *pb.GetDomainRequest) (*pb.Do
 		Vrf:         domain.VRF,
 		MinInterval: ptypes.DurationProto(domain.MinInterval),
 		MaxInterval: ptypes.DurationProto(domain.MaxInterval),
+		ReceiptKey:  domain.ReceiptKey,
 	}, nil
 }
 
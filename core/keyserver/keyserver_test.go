@@ -15,12 +15,14 @@
 package keyserver
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/golang/protobuf/proto"
 	"github.com/google/keytransparency/core/domain"
 	"github.com/google/keytransparency/core/fake"
 	"github.com/google/trillian/testonly"
@@ -152,3 +154,309 @@ func TestLatestRevision(t *testing.T) {
 	}
 
 }
+
+func TestBatchGetEntries(t *testing.T) {
+	ctx := context.Background()
+	committed := &pb.Committed{Key: []byte("key"), Data: []byte("data")}
+	extraData, err := proto.Marshal(committed)
+	if err != nil {
+		t.Fatalf("proto.Marshal(): %v", err)
+	}
+
+	for _, tc := range []struct {
+		desc       string
+		leaves     []*tpb.MapLeafInclusion
+		wantErr    codes.Code
+		wantValues [][]byte
+	}{
+		{
+			desc: "empty and set leaves",
+			leaves: []*tpb.MapLeafInclusion{
+				{Leaf: &tpb.MapLeaf{}},
+				{Leaf: &tpb.MapLeaf{LeafValue: []byte("leaf"), ExtraData: extraData}},
+			},
+			wantValues: [][]byte{nil, []byte("leaf")},
+		},
+		{
+			desc: "missing commitment",
+			leaves: []*tpb.MapLeafInclusion{
+				{Leaf: &tpb.MapLeaf{}},
+				{Leaf: &tpb.MapLeaf{LeafValue: []byte("leaf")}},
+			},
+			wantErr: codes.Internal,
+		},
+		{
+			desc:    "wrong number of leaves",
+			leaves:  []*tpb.MapLeafInclusion{{Leaf: &tpb.MapLeaf{}}},
+			wantErr: codes.Internal,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+			defer cancel()
+			e, err := newMiniEnv(ctx, t)
+			if err != nil {
+				t.Fatalf("newMiniEnv(): %v", err)
+			}
+			defer e.Close()
+			e.s.Log.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).
+				Return(&tpb.GetLatestSignedLogRootResponse{
+					SignedLogRoot: &tpb.SignedLogRoot{TreeSize: 2},
+				}, nil)
+			// All entries must be fetched in one request.
+			e.s.Map.EXPECT().GetLeavesByRevision(gomock.Any(),
+				&tpb.GetMapLeavesByRevisionRequest{
+					MapId:    mapID,
+					Index:    [][]byte{make([]byte, 32), make([]byte, 32)},
+					Revision: 1,
+				}).
+				Return(&tpb.GetMapLeavesResponse{
+					MapLeafInclusion: tc.leaves,
+					MapRoot:          &tpb.SignedMapRoot{MapRoot: []byte("root")},
+				}, nil)
+			if tc.wantErr == codes.OK {
+				e.s.Log.EXPECT().GetInclusionProof(gomock.Any(), gomock.Any()).
+					Return(&tpb.GetInclusionProofResponse{}, nil)
+			}
+
+			resp, err := e.srv.BatchGetEntries(ctx, &pb.BatchGetEntriesRequest{
+				DomainId: domainID,
+				Entries: []*pb.EntryIdentifier{
+					{UserId: "alice", AppId: "app"},
+					{UserId: "bob", AppId: "app"},
+				},
+			})
+			if got, want := status.Code(err), tc.wantErr; got != want {
+				t.Fatalf("BatchGetEntries(): %v, want %v", err, want)
+			}
+			if err != nil {
+				return
+			}
+			if got, want := resp.GetSmr().GetMapRoot(), []byte("root"); !bytes.Equal(got, want) {
+				t.Errorf("BatchGetEntries().Smr: %s, want %s", got, want)
+			}
+			if got, want := len(resp.GetEntries()), len(tc.wantValues); got != want {
+				t.Fatalf("len(BatchGetEntries().Entries): %v, want %v", got, want)
+			}
+			for i, entry := range resp.GetEntries() {
+				if got, want := entry.GetLeafProof().GetLeaf().GetLeafValue(), tc.wantValues[i]; !bytes.Equal(got, want) {
+					t.Errorf("Entries[%v].LeafValue: %s, want %s", i, got, want)
+				}
+				if got, want := entry.GetCommitted() != nil, tc.wantValues[i] != nil; got != want {
+					t.Errorf("Entries[%v].Committed: %v, want present: %v", i, entry.GetCommitted(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetEntryByRevision(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		desc     string
+		domainID string
+		revision int64
+		// checked is set if the request is checked before the log
+		// root is fetched.
+		checked bool
+		wantErr codes.Code
+	}{
+		{desc: "first revision", domainID: domainID, revision: 0},
+		{desc: "latest revision", domainID: domainID, revision: 2},
+		{desc: "negative revision", domainID: domainID, revision: -1, checked: true, wantErr: codes.InvalidArgument},
+		{desc: "future revision", domainID: domainID, revision: 3, wantErr: codes.NotFound},
+		{desc: "missing domain", domainID: "missing", revision: 0, checked: true, wantErr: codes.NotFound},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+			defer cancel()
+			e, err := newMiniEnv(ctx, t)
+			if err != nil {
+				t.Fatalf("newMiniEnv(): %v", err)
+			}
+			defer e.Close()
+			if !tc.checked {
+				e.s.Log.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).
+					Return(&tpb.GetLatestSignedLogRootResponse{
+						SignedLogRoot: &tpb.SignedLogRoot{TreeSize: 3},
+					}, nil)
+			}
+			if tc.wantErr == codes.OK {
+				e.s.Map.EXPECT().GetLeavesByRevision(gomock.Any(),
+					&tpb.GetMapLeavesByRevisionRequest{
+						MapId:    mapID,
+						Index:    [][]byte{make([]byte, 32)},
+						Revision: tc.revision,
+					}).
+					Return(&tpb.GetMapLeavesResponse{
+						MapLeafInclusion: []*tpb.MapLeafInclusion{{}},
+					}, nil)
+				e.s.Log.EXPECT().GetInclusionProof(gomock.Any(),
+					&tpb.GetInclusionProofRequest{
+						LeafIndex: tc.revision,
+						TreeSize:  3,
+					}).
+					Return(&tpb.GetInclusionProofResponse{}, nil)
+			}
+
+			resp, err := e.srv.GetEntryByRevision(ctx, &pb.GetEntryByRevisionRequest{
+				DomainId: tc.domainID,
+				Revision: tc.revision,
+			})
+			if got, want := status.Code(err), tc.wantErr; got != want {
+				t.Fatalf("GetEntryByRevision(): %v, want %v", err, want)
+			}
+			if err != nil {
+				return
+			}
+			if got, want := resp.GetLogRoot().GetTreeSize(), int64(3); got != want {
+				t.Errorf("GetEntryByRevision().LogRoot.TreeSize: %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestListEntryHistorySparse(t *testing.T) {
+	ctx := context.Background()
+	fakeMutations := fake.NewMutationStorage()
+	for _, rev := range []int64{2, 5} {
+		if err := fakeMutations.WriteIndexChanges(ctx, domainID, rev, [][]byte{make([]byte, 32)}); err != nil {
+			t.Fatalf("WriteIndexChanges(): %v", err)
+		}
+	}
+
+	for _, tc := range []struct {
+		desc          string
+		start         int64
+		pageSize      int32
+		wantRevisions []int64
+		wantNext      int64
+	}{
+		{desc: "all changes", start: 0, pageSize: 10, wantRevisions: []int64{0, 1, 2, 4, 5, 6}},
+		{desc: "one change", start: 0, pageSize: 1, wantRevisions: []int64{0, 1, 2}, wantNext: 3},
+		{desc: "adjacent change", start: 1, pageSize: 1, wantRevisions: []int64{1, 2}, wantNext: 3},
+		{desc: "no changes", start: 6, pageSize: 10, wantRevisions: []int64{6}},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+			defer cancel()
+			e, err := newMiniEnv(ctx, t)
+			if err != nil {
+				t.Fatalf("newMiniEnv(): %v", err)
+			}
+			defer e.Close()
+			e.srv.mutations = fakeMutations
+			e.s.Log.EXPECT().GetLatestSignedLogRoot(gomock.Any(), gomock.Any()).
+				Return(&tpb.GetLatestSignedLogRootResponse{
+					SignedLogRoot: &tpb.SignedLogRoot{TreeSize: 7},
+				}, nil)
+			for _, rev := range tc.wantRevisions {
+				e.s.Map.EXPECT().GetLeavesByRevision(gomock.Any(),
+					&tpb.GetMapLeavesByRevisionRequest{
+						MapId:    mapID,
+						Index:    [][]byte{make([]byte, 32)},
+						Revision: rev,
+					}).
+					Return(&tpb.GetMapLeavesResponse{
+						MapLeafInclusion: []*tpb.MapLeafInclusion{{}},
+					}, nil)
+			}
+			e.s.Log.EXPECT().GetInclusionProof(gomock.Any(), gomock.Any()).
+				Return(&tpb.GetInclusionProofResponse{}, nil).Times(len(tc.wantRevisions))
+
+			resp, err := e.srv.ListEntryHistory(ctx, &pb.ListEntryHistoryRequest{
+				DomainId: domainID,
+				Start:    tc.start,
+				PageSize: tc.pageSize,
+				Sparse:   true,
+			})
+			if err != nil {
+				t.Fatalf("ListEntryHistory(): %v", err)
+			}
+			if got, want := len(resp.GetValues()), len(tc.wantRevisions); got != want {
+				t.Errorf("len(ListEntryHistory().Values): %v, want %v", got, want)
+			}
+			if got, want := resp.GetNextStart(), tc.wantNext; got != want {
+				t.Errorf("ListEntryHistory().NextStart: %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestGetMutationStatus(t *testing.T) {
+	ctx := context.Background()
+	mutations := fake.NewMutationStorage()
+	srv := &Server{mutations: mutations}
+	applied := bytes.Repeat([]byte{1}, 32)
+	if err := mutations.WriteStatus(ctx, domainID, applied, &pb.MutationStatus{
+		State: pb.MutationStatus_APPLIED,
+		Epoch: 3,
+	}); err != nil {
+		t.Fatalf("WriteStatus(): %v", err)
+	}
+
+	for _, tc := range []struct {
+		desc     string
+		hash     []byte
+		wantCode codes.Code
+		want     *pb.MutationStatus
+	}{
+		{desc: "applied", hash: applied, want: &pb.MutationStatus{State: pb.MutationStatus_APPLIED, Epoch: 3}},
+		{desc: "unknown", hash: bytes.Repeat([]byte{2}, 32), wantCode: codes.NotFound},
+		{desc: "short hash", hash: []byte("hash"), wantCode: codes.InvalidArgument},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := srv.GetMutationStatus(ctx, &pb.GetMutationStatusRequest{
+				DomainId:     domainID,
+				MutationHash: tc.hash,
+			})
+			if status.Code(err) != tc.wantCode {
+				t.Fatalf("GetMutationStatus(): %v, want %v", err, tc.wantCode)
+			}
+			if err == nil && !proto.Equal(got, tc.want) {
+				t.Errorf("GetMutationStatus(): %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	ctx := context.Background()
+	const domainID = "domain"
+	deny := func(context.Context, *pb.UpdateEntryRequest) error {
+		return status.Errorf(codes.Unauthenticated, "no identity")
+	}
+	for _, tc := range []struct {
+		desc     string
+		authz    UpdateAuthorizer
+		wantCode codes.Code
+	}{
+		{desc: "no authorizer", wantCode: codes.PermissionDenied},
+		{desc: "denied", authz: deny, wantCode: codes.PermissionDenied},
+		{desc: "authorized", authz: func(context.Context, *pb.UpdateEntryRequest) error { return nil }, wantCode: codes.OK},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			var got *pb.UpdateEntryRequest
+			s := &Server{}
+			if tc.authz != nil {
+				s.SetAuthorizer(func(ctx context.Context, u *pb.UpdateEntryRequest) error {
+					got = u
+					return tc.authz(ctx, u)
+				})
+			}
+			// The domain of each update is ignored in favor of the
+			// domain of the batch.
+			u := &pb.UpdateEntryRequest{DomainId: "other", AppId: "app", UserId: "user"}
+			st := s.authorize(ctx, domainID, u)
+			if code := st.Code(); code != tc.wantCode {
+				t.Errorf("authorize(): %v, want %v", st.Err(), tc.wantCode)
+			}
+			if tc.authz == nil {
+				return
+			}
+			if got.GetDomainId() != domainID || got.GetAppId() != "app" || got.GetUserId() != "user" {
+				t.Errorf("authorized %v, want update of %v/app/user", got, domainID)
+			}
+		})
+	}
+}
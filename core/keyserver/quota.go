@@ -0,0 +1,419 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keyserver
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/keytransparency/core/domain"
+	"github.com/google/keytransparency/core/mutator"
+	"github.com/google/keytransparency/core/mutator/entry"
+
+	"github.com/golang/glog"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
+)
+
+const (
+	// depthMaxAge is how long a queue depth read from the queue is used
+	// before it is read again. Sends by this server are counted in between.
+	depthMaxAge = time.Second
+	// pendingExpiry is how long a mutation is counted as pending if its
+	// status is never written, for instance because it was dropped.
+	pendingExpiry = time.Hour
+	// sweepInterval is how often the pending mutations of all the users of
+	// a domain are checked, so that users who do not send again are
+	// forgotten.
+	sweepInterval = time.Minute
+)
+
+var (
+	throttledCTR = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kt_keyserver_updates_throttled",
+		Help: "Number of updates refused because a queue limit was reached, by limit.",
+	}, []string{"domain", "limit"})
+	queueDepthGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kt_keyserver_queue_depth",
+		Help: "Number of mutations in the queue of a domain, as last seen by the key server.",
+	}, []string{"domain"})
+	pendingUsersGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kt_keyserver_pending_users",
+		Help: "Number of users with mutations sent by this key server that are waiting to be sequenced.",
+	}, []string{"domain"})
+)
+
+func init() {
+	prometheus.MustRegister(throttledCTR)
+	prometheus.MustRegister(queueDepthGauge)
+	prometheus.MustRegister(pendingUsersGauge)
+}
+
+// Limits bounds the number of mutations waiting to be sequenced, so that a
+// burst of updates, or a single misbehaving client, cannot build a backlog
+// that delays every other update past the maximum merge delay. Zero disables
+// a limit.
+type Limits struct {
+	// MaxQueueDepth is the maximum number of mutations in the queue of a
+	// domain.
+	MaxQueueDepth int64
+	// MaxPendingPerUser is the maximum number of mutations of a user that
+	// are waiting to be sequenced. Pending mutations are tracked by each
+	// key server, so with several servers a user may have up to
+	// MaxPendingPerUser mutations pending on each.
+	MaxPendingPerUser int
+}
+
+// quota enforces Limits on the updates sent to the mutation queue.
+type quota struct {
+	limits    Limits
+	queue     mutator.MutationQueue
+	mutations mutator.MutationStorage
+	clock     func() time.Time
+
+	mu sync.Mutex
+	// depths holds the last depth read from the queue of each domain.
+	depths map[string]*queueDepth
+	// pending holds the mutations of each user of each domain that have
+	// been sent, or have a place reserved, and whose status has not been
+	// written yet.
+	pending map[string]map[string][]*pendingMutation
+	// swept holds the time the last sweep of each domain started.
+	swept map[string]time.Time
+	// sweeps counts the sweeps running in the background.
+	sweeps sync.WaitGroup
+}
+
+type queueDepth struct {
+	depth int64
+	read  time.Time
+}
+
+type pendingMutation struct {
+	// hash is nil until the mutation is sent.
+	hash []byte
+	sent time.Time
+}
+
+func newQuota(limits Limits, queue mutator.MutationQueue, mutations mutator.MutationStorage) *quota {
+	return &quota{
+		limits:    limits,
+		queue:     queue,
+		mutations: mutations,
+		clock:     time.Now,
+		depths:    make(map[string]*queueDepth),
+		pending:   make(map[string]map[string][]*pendingMutation),
+		swept:     make(map[string]time.Time),
+	}
+}
+
+// SetLimits enforces limits on the updates sent by UpdateEntry and
+// BatchUpdateEntries. It must be called before the server starts serving.
+func (s *Server) SetLimits(limits Limits) {
+	s.quota = newQuota(limits, s.queue, s.mutations)
+}
+
+// reservation holds the place of an update of a user in the limits until the
+// update is sent, or the place is released.
+type reservation struct {
+	q        *quota
+	domainID string
+	userID   string
+	// depth is the queue depth that was incremented for the update, if any.
+	depth *queueDepth
+	// pending is the place reserved for the update, if any.
+	pending *pendingMutation
+	done    bool
+}
+
+// check reserves a place for an update of userID in the queue of d. It returns
+// a ResourceExhausted status if the update would exceed a limit. Errors
+// reading the queue or the mutation statuses are logged and do not refuse
+// updates.
+//
+// The caller must call sent on the returned reservation once the update is
+// sent, or release if it is not. Both accept a nil reservation.
+func (q *quota) check(ctx context.Context, d *domain.Domain, userID string) (*reservation, *status.Status) {
+	if q == nil {
+		return nil, nil
+	}
+	if q.limits.MaxQueueDepth > 0 {
+		q.readDepth(ctx, d.DomainID)
+	}
+	var done map[string]bool
+	if max := q.limits.MaxPendingPerUser; max > 0 {
+		q.startSweep(d.DomainID)
+		q.mu.Lock()
+		pending := q.pending[d.DomainID][userID]
+		hashes := sentHashes(pending)
+		q.mu.Unlock()
+		// Statuses are only read if the user would be refused
+		// otherwise, so at most max of them.
+		if len(pending) >= max {
+			done = q.resolved(ctx, d.DomainID, hashes)
+		}
+	}
+
+	now := q.clock()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	depth := q.depths[d.DomainID]
+	if max := q.limits.MaxQueueDepth; max > 0 && depth != nil && depth.depth >= max {
+		throttledCTR.WithLabelValues(d.DomainID, "queue_depth").Inc()
+		return nil, resourceExhausted(retryDelay(d),
+			"The queue of domain %v is full, please retry later", d.DomainID)
+	}
+	if max := q.limits.MaxPendingPerUser; max > 0 {
+		if n := q.prune(d.DomainID, userID, done, now); n >= max {
+			throttledCTR.WithLabelValues(d.DomainID, "user_pending").Inc()
+			return nil, resourceExhausted(retryDelay(d),
+				"User has %v updates waiting to be applied, please retry once they are", n)
+		}
+	}
+
+	r := &reservation{q: q, domainID: d.DomainID, userID: userID}
+	if q.limits.MaxQueueDepth > 0 && depth != nil {
+		// Sends are counted until the depth is read again.
+		depth.depth++
+		r.depth = depth
+	}
+	if q.limits.MaxPendingPerUser > 0 {
+		users, ok := q.pending[d.DomainID]
+		if !ok {
+			users = make(map[string][]*pendingMutation)
+			q.pending[d.DomainID] = users
+		}
+		r.pending = &pendingMutation{sent: now}
+		users[userID] = append(users[userID], r.pending)
+		pendingUsersGauge.WithLabelValues(d.DomainID).Set(float64(len(users)))
+	}
+	return r, nil
+}
+
+// sent records that the update was sent to the queue as mutation.
+func (r *reservation) sent(mutation *pb.Entry) {
+	if r == nil {
+		return
+	}
+	hash, err := entry.Hash(mutation)
+	if err != nil {
+		glog.Errorf("entry.Hash(): %v", err)
+		r.release()
+		return
+	}
+	r.sentHash(hash)
+}
+
+// sentHash records that the update was sent to the queue as the mutation
+// identified by hash.
+func (r *reservation) sentHash(hash []byte) {
+	q := r.q
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if r.done {
+		return
+	}
+	r.done = true
+	if r.pending == nil {
+		return
+	}
+	for _, p := range q.pending[r.domainID][r.userID] {
+		if p != r.pending && bytes.Equal(p.hash, hash) {
+			// The same mutation was sent again.
+			q.remove(r.domainID, r.userID, r.pending)
+			return
+		}
+	}
+	r.pending.hash = hash
+}
+
+// release returns the place held by r, if the update has not been sent.
+func (r *reservation) release() {
+	if r == nil {
+		return
+	}
+	q := r.q
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if r.done {
+		return
+	}
+	r.done = true
+	if r.depth != nil && q.depths[r.domainID] == r.depth {
+		r.depth.depth--
+	}
+	if r.pending != nil {
+		q.remove(r.domainID, r.userID, r.pending)
+	}
+}
+
+// readDepth reads the depth of the queue of domainID if the last depth read
+// is older than depthMaxAge. The depth is forgotten if it can't be read.
+func (q *quota) readDepth(ctx context.Context, domainID string) {
+	now := q.clock()
+	q.mu.Lock()
+	d, ok := q.depths[domainID]
+	fresh := ok && now.Sub(d.read) < depthMaxAge
+	q.mu.Unlock()
+	if fresh {
+		return
+	}
+
+	stats, err := q.queue.Stats(ctx, domainID)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err != nil {
+		glog.Errorf("queue.Stats(%v): %v", domainID, err)
+		delete(q.depths, domainID)
+		return
+	}
+	queueDepthGauge.WithLabelValues(domainID).Set(float64(stats.Depth))
+	q.depths[domainID] = &queueDepth{depth: stats.Depth, read: now}
+}
+
+// startSweep starts a sweep of domainID in the background, at most once per
+// sweepInterval. Sweeps read the status of every pending mutation of the
+// domain, so they are not run by the update that starts them.
+func (q *quota) startSweep(domainID string) {
+	now := q.clock()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if now.Sub(q.swept[domainID]) < sweepInterval {
+		return
+	}
+	q.swept[domainID] = now
+	q.sweeps.Add(1)
+	go func() {
+		defer q.sweeps.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), sweepInterval)
+		defer cancel()
+		q.sweep(ctx, domainID, now)
+	}()
+}
+
+// sweep forgets the pending mutations of every user of domainID that are
+// resolved, or expired at now.
+func (q *quota) sweep(ctx context.Context, domainID string, now time.Time) {
+	q.mu.Lock()
+	var hashes [][]byte
+	for _, pending := range q.pending[domainID] {
+		hashes = append(hashes, sentHashes(pending)...)
+	}
+	q.mu.Unlock()
+
+	done := q.resolved(ctx, domainID, hashes)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for userID := range q.pending[domainID] {
+		q.prune(domainID, userID, done, now)
+	}
+}
+
+// sentHashes returns the hashes of the mutations in pending that have been
+// sent.
+func sentHashes(pending []*pendingMutation) [][]byte {
+	var hashes [][]byte
+	for _, p := range pending {
+		if p.hash != nil {
+			hashes = append(hashes, p.hash)
+		}
+	}
+	return hashes
+}
+
+// resolved returns the hashes in hashes whose status is no longer QUEUED.
+func (q *quota) resolved(ctx context.Context, domainID string, hashes [][]byte) map[string]bool {
+	done := make(map[string]bool)
+	for _, hash := range hashes {
+		st, err := q.mutations.ReadStatus(ctx, domainID, hash)
+		switch {
+		case status.Code(err) == codes.NotFound:
+		case err != nil:
+			glog.Errorf("mutations.ReadStatus(%v, %x): %v", domainID, hash, err)
+		case st.GetState() != pb.MutationStatus_QUEUED:
+			done[string(hash)] = true
+		}
+	}
+	return done
+}
+
+// prune forgets the mutations of userID that are in done or older than
+// pendingExpiry, and returns the number of mutations left. q.mu must be held.
+func (q *quota) prune(domainID, userID string, done map[string]bool, now time.Time) int {
+	users := q.pending[domainID]
+	kept := make([]*pendingMutation, 0, len(users[userID]))
+	for _, p := range users[userID] {
+		if now.Sub(p.sent) > pendingExpiry || (p.hash != nil && done[string(p.hash)]) {
+			continue
+		}
+		kept = append(kept, p)
+	}
+	q.setPending(domainID, userID, kept)
+	return len(kept)
+}
+
+// remove forgets pending, a mutation of userID. q.mu must be held.
+func (q *quota) remove(domainID, userID string, pending *pendingMutation) {
+	kept := make([]*pendingMutation, 0)
+	for _, p := range q.pending[domainID][userID] {
+		if p != pending {
+			kept = append(kept, p)
+		}
+	}
+	q.setPending(domainID, userID, kept)
+}
+
+// setPending replaces the pending mutations of userID. q.mu must be held.
+func (q *quota) setPending(domainID, userID string, pending []*pendingMutation) {
+	users, ok := q.pending[domainID]
+	if !ok {
+		return
+	}
+	if len(pending) == 0 {
+		delete(users, userID)
+	} else {
+		users[userID] = pending
+	}
+	pendingUsersGauge.WithLabelValues(domainID).Set(float64(len(users)))
+}
+
+// retryDelay returns how long clients should wait before retrying an update
+// refused by a limit. The sequencer drains the queue at least once per
+// MinInterval.
+func retryDelay(d *domain.Domain) time.Duration {
+	if d.MinInterval > 0 {
+		return d.MinInterval
+	}
+	return time.Second
+}
+
+// resourceExhausted returns a ResourceExhausted status that asks the client to
+// retry after delay.
+func resourceExhausted(delay time.Duration, format string, a ...interface{}) *status.Status {
+	st := status.Newf(codes.ResourceExhausted, format, a...)
+	withRetry, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: ptypes.DurationProto(delay)})
+	if err != nil {
+		glog.Errorf("WithDetails(): %v", err)
+		return st
+	}
+	return withRetry
+}
@@ -0,0 +1,324 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keyserver
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/keytransparency/core/domain"
+	"github.com/google/keytransparency/core/fake"
+	"github.com/google/keytransparency/core/mutator"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
+)
+
+// fakeQueue reports a fixed queue depth. Unimplemented methods panic.
+type fakeQueue struct {
+	mutator.MutationQueue
+	depth int64
+	err   error
+	reads int
+}
+
+func (q *fakeQueue) Stats(context.Context, string) (*mutator.QueueStats, error) {
+	q.reads++
+	return &mutator.QueueStats{Depth: q.depth}, q.err
+}
+
+// countingStatuses counts the statuses read from a MutationStorage.
+type countingStatuses struct {
+	mutator.MutationStorage
+	reads int
+}
+
+func (m *countingStatuses) ReadStatus(ctx context.Context, domainID string, hash []byte) (*pb.MutationStatus, error) {
+	m.reads++
+	return m.MutationStorage.ReadStatus(ctx, domainID, hash)
+}
+
+// fakeClock is a settable time source.
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func newTestQuota(limits Limits, queue mutator.MutationQueue, mutations mutator.MutationStorage) (*quota, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	q := newQuota(limits, queue, mutations)
+	q.clock = clock.Now
+	return q, clock
+}
+
+// mayUpdate returns the status of check for an update of userID, and releases
+// the place reserved for it. It waits for the sweep started by check, if any.
+func mayUpdate(ctx context.Context, q *quota, d *domain.Domain, userID string) *status.Status {
+	r, st := q.check(ctx, d, userID)
+	r.release()
+	q.sweeps.Wait()
+	return st
+}
+
+// send sends an update of userID as the mutation identified by hash.
+func send(ctx context.Context, t *testing.T, q *quota, d *domain.Domain, userID, hash string) {
+	t.Helper()
+	r, st := q.check(ctx, d, userID)
+	if st != nil {
+		t.Fatalf("check(%v): %v, want nil", userID, st.Err())
+	}
+	r.sentHash([]byte(hash))
+	q.sweeps.Wait()
+}
+
+// checkRefused checks that st refuses an update with a retry delay of want.
+func checkRefused(t *testing.T, desc string, st *status.Status, want time.Duration) {
+	t.Helper()
+	if got := st.Code(); got != codes.ResourceExhausted {
+		t.Errorf("%v: check(): %v, want %v", desc, st.Err(), codes.ResourceExhausted)
+		return
+	}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok {
+			got, err := ptypes.Duration(info.RetryDelay)
+			if err != nil {
+				t.Fatalf("%v: ptypes.Duration(): %v", desc, err)
+			}
+			if got != want {
+				t.Errorf("%v: retry delay %v, want %v", desc, got, want)
+			}
+			return
+		}
+	}
+	t.Errorf("%v: check(): no RetryInfo in %v", desc, st.Details())
+}
+
+func TestQueueDepthLimit(t *testing.T) {
+	ctx := context.Background()
+	d := &domain.Domain{DomainID: domainID, MinInterval: 3 * time.Second}
+	queue := &fakeQueue{depth: 2}
+	q, clock := newTestQuota(Limits{MaxQueueDepth: 3}, queue, fake.NewMutationStorage())
+
+	if st := mayUpdate(ctx, q, d, "alice"); st != nil {
+		t.Errorf("check() below the limit: %v, want nil", st.Err())
+	}
+	// Sends are counted until the depth is read again.
+	send(ctx, t, q, d, "alice", "hash1")
+	checkRefused(t, "full queue", mayUpdate(ctx, q, d, "bob"), 3*time.Second)
+	if queue.reads != 1 {
+		t.Errorf("Stats() called %v times, want 1", queue.reads)
+	}
+
+	// The sequencer drained the queue.
+	queue.depth = 0
+	clock.now = clock.now.Add(depthMaxAge)
+	if st := mayUpdate(ctx, q, d, "bob"); st != nil {
+		t.Errorf("check() after the queue drained: %v, want nil", st.Err())
+	}
+
+	// Updates are not refused when the depth cannot be read.
+	queue.depth, queue.err = 10, errors.New("unavailable")
+	clock.now = clock.now.Add(depthMaxAge)
+	if st := mayUpdate(ctx, q, d, "bob"); st != nil {
+		t.Errorf("check() with a failing queue: %v, want nil", st.Err())
+	}
+}
+
+func TestPendingPerUserLimit(t *testing.T) {
+	ctx := context.Background()
+	d := &domain.Domain{DomainID: domainID}
+	mutations := fake.NewMutationStorage()
+	q, clock := newTestQuota(Limits{MaxPendingPerUser: 2}, &fakeQueue{}, mutations)
+
+	send(ctx, t, q, d, "alice", "a1")
+	send(ctx, t, q, d, "alice", "a1") // Sent again.
+	if st := mayUpdate(ctx, q, d, "alice"); st != nil {
+		t.Errorf("check() with one pending mutation: %v, want nil", st.Err())
+	}
+	send(ctx, t, q, d, "alice", "a2")
+	checkRefused(t, "two pending mutations", mayUpdate(ctx, q, d, "alice"), time.Second)
+	if st := mayUpdate(ctx, q, d, "bob"); st != nil {
+		t.Errorf("check() for another user: %v, want nil", st.Err())
+	}
+
+	// Steps are cumulative.
+	for _, tc := range []struct {
+		desc    string
+		hash    string
+		state   pb.MutationStatus_State
+		send    string
+		refused bool
+	}{
+		{desc: "still queued", hash: "a1", state: pb.MutationStatus_QUEUED, refused: true},
+		{desc: "applied", hash: "a1", state: pb.MutationStatus_APPLIED},
+		{desc: "sent another", send: "a3", refused: true},
+		{desc: "rejected", hash: "a2", state: pb.MutationStatus_REJECTED},
+	} {
+		if tc.hash != "" {
+			if err := mutations.WriteStatus(ctx, domainID, []byte(tc.hash), &pb.MutationStatus{State: tc.state}); err != nil {
+				t.Fatalf("WriteStatus(): %v", err)
+			}
+		}
+		if tc.send != "" {
+			send(ctx, t, q, d, "alice", tc.send)
+		}
+		st := mayUpdate(ctx, q, d, "alice")
+		if tc.refused {
+			checkRefused(t, tc.desc, st, time.Second)
+		} else if st != nil {
+			t.Errorf("%v: check(): %v, want nil", tc.desc, st.Err())
+		}
+	}
+	if got := len(q.pending[domainID]["alice"]); got != 1 {
+		t.Errorf("%v pending mutations tracked, want 1", got)
+	}
+
+	// Mutations whose status is never written expire.
+	send(ctx, t, q, d, "carol", "c1")
+	send(ctx, t, q, d, "carol", "c2")
+	clock.now = clock.now.Add(pendingExpiry + time.Second)
+	if st := mayUpdate(ctx, q, d, "carol"); st != nil {
+		t.Errorf("check() after expiry: %v, want nil", st.Err())
+	}
+	if _, ok := q.pending[domainID]["carol"]; ok {
+		t.Errorf("expired mutations are still tracked")
+	}
+}
+
+func TestPendingStatusReads(t *testing.T) {
+	ctx := context.Background()
+	d := &domain.Domain{DomainID: domainID}
+	mutations := &countingStatuses{MutationStorage: fake.NewMutationStorage()}
+	q, _ := newTestQuota(Limits{MaxPendingPerUser: 2}, &fakeQueue{}, mutations)
+
+	// Statuses are only read by updates that would exceed the limit.
+	for _, tc := range []struct {
+		desc      string
+		send      string
+		wantReads int
+	}{
+		{desc: "no pending mutations", wantReads: 0},
+		{desc: "one pending mutation", send: "a1", wantReads: 0},
+		{desc: "limit reached", send: "a2", wantReads: 2},
+	} {
+		if tc.send != "" {
+			send(ctx, t, q, d, "alice", tc.send)
+		}
+		mutations.reads = 0
+		mayUpdate(ctx, q, d, "alice")
+		if got := mutations.reads; got != tc.wantReads {
+			t.Errorf("%v: ReadStatus() called %v times, want %v", tc.desc, got, tc.wantReads)
+		}
+	}
+}
+
+func TestSweep(t *testing.T) {
+	ctx := context.Background()
+	d := &domain.Domain{DomainID: domainID}
+	mutations := fake.NewMutationStorage()
+	q, clock := newTestQuota(Limits{MaxPendingPerUser: 2}, &fakeQueue{}, mutations)
+
+	// Users who do not send again are forgotten by sweeps.
+	send(ctx, t, q, d, "carol", "c1")
+	send(ctx, t, q, d, "dave", "d1")
+	if err := mutations.WriteStatus(ctx, domainID, []byte("d1"), &pb.MutationStatus{State: pb.MutationStatus_APPLIED}); err != nil {
+		t.Fatalf("WriteStatus(): %v", err)
+	}
+	for _, tc := range []struct {
+		desc    string
+		advance time.Duration
+		want    []string
+	}{
+		{desc: "before the next sweep", want: []string{"carol", "dave"}},
+		{desc: "resolved", advance: sweepInterval, want: []string{"carol"}},
+		{desc: "expired", advance: pendingExpiry, want: []string{}},
+	} {
+		clock.now = clock.now.Add(tc.advance)
+		if st := mayUpdate(ctx, q, d, "bob"); st != nil {
+			t.Fatalf("%v: check(): %v, want nil", tc.desc, st.Err())
+		}
+		got := make([]string, 0)
+		for userID := range q.pending[domainID] {
+			got = append(got, userID)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%v: users with pending mutations: %v, want %v", tc.desc, got, tc.want)
+		}
+	}
+}
+
+func TestConcurrentUpdates(t *testing.T) {
+	ctx := context.Background()
+	d := &domain.Domain{DomainID: domainID}
+	const max = 3
+	q, _ := newTestQuota(Limits{MaxPendingPerUser: max}, &fakeQueue{}, fake.NewMutationStorage())
+
+	// A burst of updates from one user, none of which has been sent yet.
+	const updates = 20
+	results := make(chan *reservation, updates)
+	var wg sync.WaitGroup
+	for i := 0; i < updates; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, st := q.check(ctx, d, "alice")
+			if st != nil {
+				r = nil
+			}
+			results <- r
+		}()
+	}
+	wg.Wait()
+	close(results)
+	var reserved []*reservation
+	for r := range results {
+		if r != nil {
+			reserved = append(reserved, r)
+		}
+	}
+	if got := len(reserved); got != max {
+		t.Fatalf("check() allowed %v concurrent updates, want %v", got, max)
+	}
+
+	// Updates that fail or are replays release their place.
+	reserved[0].release()
+	reserved[1].release()
+	reserved[2].sentHash([]byte("a1"))
+	reserved[2].release() // Released after being sent: no effect.
+	if got := len(q.pending[domainID]["alice"]); got != 1 {
+		t.Errorf("%v pending mutations tracked, want 1", got)
+	}
+	if st := mayUpdate(ctx, q, d, "alice"); st != nil {
+		t.Errorf("check() after releasing: %v, want nil", st.Err())
+	}
+}
+
+func TestNoLimits(t *testing.T) {
+	var q *quota
+	r, st := q.check(context.Background(), &domain.Domain{DomainID: domainID}, "alice")
+	if st != nil {
+		t.Errorf("check() without limits: %v, want nil", st.Err())
+	}
+	r.sent(&pb.Entry{})
+	r.release()
+}
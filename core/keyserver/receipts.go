@@ -0,0 +1,55 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keyserver
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/keytransparency/core/domain"
+	"github.com/google/keytransparency/core/receipt"
+	"github.com/google/trillian/crypto/keys"
+
+	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
+	tcrypto "github.com/google/trillian/crypto"
+)
+
+// signReceipt returns a receipt promising that mutation, which is about to be
+// sent to the queue of d, will be included in an epoch once the mutations
+// queued ahead of it are. signReceipt returns nil if d does not have a receipt
+// key.
+func (s *Server) signReceipt(ctx context.Context, d *domain.Domain, mutation *pb.Entry) (*pb.MutationReceipt, error) {
+	if d.ReceiptPriv == nil {
+		return nil, nil
+	}
+	stats, err := s.queue.Stats(ctx, d.DomainID)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := keys.NewSigner(ctx, d.ReceiptPriv)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(receiptDelay(d, stats.Depth))
+	return receipt.New(tcrypto.NewSHA256Signer(signer), d.DomainID, mutation, deadline)
+}
+
+// receiptDelay returns how long the sequencer may take to apply a mutation
+// sent to the queue of d behind depth other mutations. The sequencer creates
+// an epoch at least once every MaxInterval, with up to BatchSize mutations.
+func receiptDelay(d *domain.Domain, depth int64) time.Duration {
+	epochs := depth/int64(d.BatchSize()) + 1
+	return time.Duration(epochs) * d.MaxInterval
+}
@@ -0,0 +1,93 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keyserver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/keytransparency/core/domain"
+	"github.com/google/keytransparency/core/receipt"
+	"github.com/google/trillian/crypto/keys"
+	"github.com/google/trillian/crypto/keys/der"
+	"github.com/google/trillian/crypto/keyspb"
+
+	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
+	_ "github.com/google/trillian/crypto/keys/der/proto" // Register PrivateKey ProtoHandler
+)
+
+func TestSignReceipt(t *testing.T) {
+	ctx := context.Background()
+	priv, err := der.NewProtoFromSpec(&keyspb.Specification{
+		Params: &keyspb.Specification_EcdsaParams{
+			EcdsaParams: &keyspb.Specification_ECDSA{Curve: keyspb.Specification_ECDSA_P256},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewProtoFromSpec(): %v", err)
+	}
+	signer, err := keys.NewSigner(ctx, priv)
+	if err != nil {
+		t.Fatalf("NewSigner(): %v", err)
+	}
+	mutation := &pb.Entry{Index: []byte("index")}
+
+	for _, tc := range []struct {
+		desc        string
+		d           *domain.Domain
+		depth       int64
+		depthErr    error
+		wantReceipt bool
+		wantErr     bool
+		wantDelay   time.Duration
+	}{
+		{desc: "no receipt key", d: &domain.Domain{DomainID: "domain", MaxInterval: time.Hour}},
+		{desc: "receipt key", d: &domain.Domain{DomainID: "domain", MaxInterval: time.Hour, ReceiptPriv: priv},
+			wantReceipt: true, wantDelay: time.Hour},
+		{desc: "queue backed up", d: &domain.Domain{DomainID: "domain", MaxInterval: time.Hour, ReceiptPriv: priv,
+			Sequencing: &pb.SequencingConfig{MaxBatchSize: 10}}, depth: 25, wantReceipt: true, wantDelay: 3 * time.Hour},
+		{desc: "queue unavailable", d: &domain.Domain{DomainID: "domain", MaxInterval: time.Hour, ReceiptPriv: priv},
+			depthErr: errors.New("unavailable"), wantErr: true},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			s := &Server{queue: &fakeQueue{depth: tc.depth, err: tc.depthErr}}
+			start := time.Now()
+			r, err := s.signReceipt(ctx, tc.d, mutation)
+			end := time.Now()
+			if got, want := err != nil, tc.wantErr; got != want {
+				t.Fatalf("signReceipt(): %v, want err: %v", err, want)
+			}
+			if got, want := r != nil, tc.wantReceipt; got != want {
+				t.Fatalf("signReceipt(): %v, want receipt: %v", r, want)
+			}
+			if r == nil {
+				return
+			}
+			if err := receipt.Verify(signer.Public(), tc.d.DomainID, mutation, r); err != nil {
+				t.Errorf("Verify(): %v", err)
+			}
+			deadline, err := ptypes.Timestamp(r.GetDeadline())
+			if err != nil {
+				t.Fatalf("Timestamp(): %v", err)
+			}
+			if min, max := start.Add(tc.wantDelay), end.Add(tc.wantDelay); deadline.Before(min) || deadline.After(max) {
+				t.Errorf("deadline: %v, want between %v and %v", deadline, min, max)
+			}
+		})
+	}
+}
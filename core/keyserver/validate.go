@@ -18,6 +18,7 @@ package keyserver
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"time"
@@ -50,8 +51,22 @@ var (
 	ErrInvalidStart = errors.New("invalid start epoch")
 	// ErrInvalidPageSize occurs when the page size is < 0.
 	ErrInvalidPageSize = errors.New("Invalid page size")
+	// ErrInvalidBatchSize occurs when a batch request is empty or too large.
+	ErrInvalidBatchSize = errors.New("invalid batch size")
+	// ErrDuplicateEntry occurs when a batch request contains the same entry
+	// more than once.
+	ErrDuplicateEntry = errors.New("duplicate entry")
+	// ErrInvalidMutationHash occurs when a mutation hash is not the size of
+	// an ObjectHash.
+	ErrInvalidMutationHash = errors.New("invalid mutation hash")
+	// ErrAppIDNotAllowed occurs when a domain does not accept updates for
+	// an app id.
+	ErrAppIDNotAllowed = errors.New("app id not allowed in domain")
 )
 
+// Maximum number of entries in a single batch request.
+var maxBatchSize = 1000
+
 // validateKey verifies:
 // - appID is present.
 // - Key is valid for its format.
@@ -95,6 +110,21 @@ func validateUpdateEntryRequest(in *pb.UpdateEntryRequest, vrfPriv vrf.PrivateKe
 	return validateKey(in.GetUserId(), in.GetAppId(), committed.GetData())
 }
 
+// validateAppID verifies that appID is listed in the allowed app ids of c,
+// if c restricts them.
+func validateAppID(appID string, c *pb.SequencingConfig) error {
+	allowed := c.GetAllowedAppIds()
+	if len(allowed) == 0 {
+		return nil
+	}
+	for _, a := range allowed {
+		if a == appID {
+			return nil
+		}
+	}
+	return ErrAppIDNotAllowed
+}
+
 // validateListEntryHistoryRequest ensures that start epoch is in range [1,
 // currentEpoch] and sets the page size if it is 0 or larger than what the server
 // can return (due to reaching currentEpoch).
@@ -140,3 +170,57 @@ func validateListMutationsRequest(in *pb.ListMutationsRequest) error {
 	}
 	return nil
 }
+
+// validateBatchGetEntriesRequest verifies that
+// - domain_id is present.
+// - The number of entries is in [1, maxBatchSize].
+// - Each entry has an app_id.
+// - No entry is requested more than once.
+func validateBatchGetEntriesRequest(in *pb.BatchGetEntriesRequest) error {
+	if in.GetDomainId() == "" {
+		return fmt.Errorf("missing domain_id")
+	}
+	if n := len(in.GetEntries()); n == 0 || n > maxBatchSize {
+		return ErrInvalidBatchSize
+	}
+	type entryKey struct{ userID, appID string }
+	seen := make(map[entryKey]bool)
+	for _, id := range in.GetEntries() {
+		if id.GetAppId() == "" {
+			return ErrNoAppID
+		}
+		key := entryKey{userID: id.GetUserId(), appID: id.GetAppId()}
+		if seen[key] {
+			return ErrDuplicateEntry
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// validateBatchUpdateEntriesRequest verifies that
+// - domain_id is present.
+// - The number of updates is in [1, maxBatchSize].
+// Individual updates are validated separately.
+func validateBatchUpdateEntriesRequest(in *pb.BatchUpdateEntriesRequest) error {
+	if in.GetDomainId() == "" {
+		return fmt.Errorf("missing domain_id")
+	}
+	if n := len(in.GetUpdates()); n == 0 || n > maxBatchSize {
+		return ErrInvalidBatchSize
+	}
+	return nil
+}
+
+// validateGetMutationStatusRequest verifies that
+// - domain_id is present.
+// - mutation_hash is the size of an ObjectHash.
+func validateGetMutationStatusRequest(in *pb.GetMutationStatusRequest) error {
+	if in.GetDomainId() == "" {
+		return fmt.Errorf("missing domain_id")
+	}
+	if len(in.GetMutationHash()) != sha256.Size {
+		return ErrInvalidMutationHash
+	}
+	return nil
+}
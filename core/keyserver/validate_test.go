@@ -117,3 +117,96 @@ func TestValidateUpdateEntryRequest(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateAppID(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		appID   string
+		config  *pb.SequencingConfig
+		wantErr error
+	}{
+		{desc: "no config", appID: "app"},
+		{desc: "no restriction", appID: "app", config: &pb.SequencingConfig{MaxBatchSize: 10}},
+		{desc: "allowed", appID: "app", config: &pb.SequencingConfig{AllowedAppIds: []string{"other", "app"}}},
+		{desc: "not allowed", appID: "app", config: &pb.SequencingConfig{AllowedAppIds: []string{"other"}}, wantErr: ErrAppIDNotAllowed},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got, want := validateAppID(tc.appID, tc.config), tc.wantErr; got != want {
+				t.Errorf("validateAppID(): %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateBatchGetEntriesRequest(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		entries []*pb.EntryIdentifier
+		wantErr error
+	}{
+		{desc: "empty", wantErr: ErrInvalidBatchSize},
+		{desc: "one", entries: []*pb.EntryIdentifier{{UserId: "a", AppId: "app"}}},
+		{desc: "two", entries: []*pb.EntryIdentifier{{UserId: "a", AppId: "app"}, {UserId: "b", AppId: "app"}}},
+		{desc: "missing app", entries: []*pb.EntryIdentifier{{UserId: "a"}}, wantErr: ErrNoAppID},
+		{desc: "duplicate", entries: []*pb.EntryIdentifier{{UserId: "a", AppId: "app"}, {UserId: "a", AppId: "app"}}, wantErr: ErrDuplicateEntry},
+		{desc: "too many", entries: make([]*pb.EntryIdentifier, maxBatchSize+1), wantErr: ErrInvalidBatchSize},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := validateBatchGetEntriesRequest(&pb.BatchGetEntriesRequest{
+				DomainId: "domain",
+				Entries:  tc.entries,
+			})
+			if got, want := err, tc.wantErr; got != want {
+				t.Errorf("validateBatchGetEntriesRequest(): %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateBatchUpdateEntriesRequest(t *testing.T) {
+	for _, tc := range []struct {
+		desc     string
+		domainID string
+		updates  []*pb.UpdateEntryRequest
+		wantErr  bool
+	}{
+		{desc: "empty", domainID: "domain", wantErr: true},
+		{desc: "missing domain", updates: []*pb.UpdateEntryRequest{{}}, wantErr: true},
+		{desc: "too many", domainID: "domain", updates: make([]*pb.UpdateEntryRequest, maxBatchSize+1), wantErr: true},
+		{desc: "ok", domainID: "domain", updates: []*pb.UpdateEntryRequest{{}, {}}},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := validateBatchUpdateEntriesRequest(&pb.BatchUpdateEntriesRequest{
+				DomainId: tc.domainID,
+				Updates:  tc.updates,
+			})
+			if got, want := err != nil, tc.wantErr; got != want {
+				t.Errorf("validateBatchUpdateEntriesRequest(): %v, wantErr %v", err, want)
+			}
+		})
+	}
+}
+
+func TestValidateGetMutationStatusRequest(t *testing.T) {
+	for _, tc := range []struct {
+		desc     string
+		domainID string
+		hash     []byte
+		wantErr  bool
+	}{
+		{desc: "missing domain", hash: make([]byte, 32), wantErr: true},
+		{desc: "missing hash", domainID: "domain", wantErr: true},
+		{desc: "short hash", domainID: "domain", hash: make([]byte, 16), wantErr: true},
+		{desc: "ok", domainID: "domain", hash: make([]byte, 32)},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := validateGetMutationStatusRequest(&pb.GetMutationStatusRequest{
+				DomainId:     tc.domainID,
+				MutationHash: tc.hash,
+			})
+			if got, want := err != nil, tc.wantErr; got != want {
+				t.Errorf("validateGetMutationStatusRequest(): %v, wantErr %v", err, want)
+			}
+		})
+	}
+}
@@ -16,13 +16,16 @@ package monitor
 
 import (
 	"context"
+	"crypto"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/google/keytransparency/core/client"
 	"github.com/google/keytransparency/core/monitorstorage"
 
 	"github.com/google/trillian"
+	"github.com/google/trillian/crypto/keys/der"
 	"github.com/google/trillian/types"
 
 	"github.com/golang/glog"
@@ -40,6 +43,12 @@ type Monitor struct {
 	mapVerifier *tclient.MapVerifier
 	signer      *tcrypto.Signer
 	store       monitorstorage.Interface
+
+	// Mutation receipts that have not been fulfilled yet.
+	domainID   string
+	receiptKey crypto.PublicKey
+	receiptsMu sync.Mutex
+	receipts   map[string]*pb.MutationReceipt
 }
 
 // NewFromDomain produces a new monitor from a Domain object.
@@ -61,7 +70,18 @@ func NewFromDomain(cli pb.KeyTransparencyClient,
 		return nil, fmt.Errorf("could not create kt client: %v", err)
 	}
 
-	return New(ktClient, logVerifier, mapVerifier, signer, store)
+	m, err := New(ktClient, logVerifier, mapVerifier, signer, store)
+	if err != nil {
+		return nil, err
+	}
+	m.domainID = config.GetDomainId()
+	if config.GetReceiptKey() != nil {
+		m.receiptKey, err = der.UnmarshalPublicKey(config.GetReceiptKey().GetDer())
+		if err != nil {
+			return nil, fmt.Errorf("could not parse receipt key: %v", err)
+		}
+	}
+	return m, nil
 }
 
 // New creates a new instance of the monitor.
@@ -76,6 +96,7 @@ func New(cli *client.Client,
 		mapVerifier: mapVerifier,
 		signer:      signer,
 		store:       store,
+		receipts:    make(map[string]*pb.MutationReceipt),
 	}, nil
 }
 
@@ -151,6 +172,8 @@ func (m *Monitor) ProcessLoop(ctx context.Context, domainID string, trusted type
 				return err
 			}
 		}
+		// Broken receipts do not invalidate the epoch, but are reported.
+		errList = append(errList, m.checkReceipts(mutations, mapRootB)...)
 
 		// Save result.
 		if err := m.store.Set(int64(mapRootB.Revision), &monitorstorage.Result{
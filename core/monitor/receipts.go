@@ -0,0 +1,82 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitor
+
+import (
+	"errors"
+
+	"github.com/golang/glog"
+	"github.com/google/keytransparency/core/mutator/entry"
+	"github.com/google/keytransparency/core/receipt"
+	"github.com/google/trillian/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
+)
+
+// ErrNoReceiptKey occurs when a receipt is added to a monitor that does not
+// know the domain's receipt key.
+var ErrNoReceiptKey = errors.New("monitor: no receipt key")
+
+// AddReceipt verifies a mutation receipt and tracks it until the mutation
+// is observed in an epoch. ProcessLoop reports an error for each receipt
+// whose mutation is not included in an epoch created before the receipt's
+// deadline.
+func (m *Monitor) AddReceipt(mutation *pb.Entry, r *pb.MutationReceipt) error {
+	if m.receiptKey == nil {
+		return ErrNoReceiptKey
+	}
+	if err := receipt.Verify(m.receiptKey, m.domainID, mutation, r); err != nil {
+		return err
+	}
+	m.receiptsMu.Lock()
+	defer m.receiptsMu.Unlock()
+	m.receipts[string(r.GetMutationHash())] = r
+	return nil
+}
+
+// checkReceipts marks the receipts of muts as fulfilled, and returns an error
+// for each receipt that was not fulfilled before mapRoot was created.
+// Fulfilled and broken receipts are no longer tracked.
+func (m *Monitor) checkReceipts(muts []*pb.MutationProof, mapRoot *types.MapRootV1) []error {
+	m.receiptsMu.Lock()
+	defer m.receiptsMu.Unlock()
+	errs := ErrList{}
+
+	for _, mut := range muts {
+		hash, err := entry.Hash(mut.GetMutation())
+		if err != nil {
+			glog.Infof("entry.Hash(): %v", err)
+			continue
+		}
+		r, ok := m.receipts[string(hash)]
+		if !ok {
+			continue
+		}
+		delete(m.receipts, string(hash))
+		if err := receipt.CheckDeadline(r, mapRoot); err != nil {
+			errs.AppendStatus(status.Newf(codes.DeadlineExceeded, "mutation applied in epoch %v: %v", mapRoot.Revision, err).WithDetails(r))
+		}
+	}
+	// Receipts whose mutations are still missing.
+	for hash, r := range m.receipts {
+		if err := receipt.CheckDeadline(r, mapRoot); err != nil {
+			delete(m.receipts, hash)
+			errs.AppendStatus(status.Newf(codes.DeadlineExceeded, "mutation missing from epoch %v: %v", mapRoot.Revision, err).WithDetails(r))
+		}
+	}
+	return errs
+}
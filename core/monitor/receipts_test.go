@@ -0,0 +1,132 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitor
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/google/keytransparency/core/receipt"
+	"github.com/google/trillian/types"
+
+	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
+	tcrypto "github.com/google/trillian/crypto"
+)
+
+func TestCheckReceipts(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey(): %v", err)
+	}
+	signer := tcrypto.NewSHA256Signer(key)
+	deadline := time.Unix(1000, 0)
+	before := &types.MapRootV1{Revision: 1, TimestampNanos: uint64(deadline.Add(-time.Second).UnixNano())}
+	after := &types.MapRootV1{Revision: 2, TimestampNanos: uint64(deadline.Add(time.Second).UnixNano())}
+	mut1 := &pb.Entry{Index: []byte("one")}
+	mut2 := &pb.Entry{Index: []byte("two")}
+
+	for _, tc := range []struct {
+		desc     string
+		epochs   []*types.MapRootV1
+		muts     [][]*pb.Entry // Mutations in each epoch.
+		wantErrs []int         // Number of errors for each epoch.
+	}{
+		{
+			desc:     "applied on time",
+			epochs:   []*types.MapRootV1{before},
+			muts:     [][]*pb.Entry{{mut1, mut2}},
+			wantErrs: []int{0},
+		},
+		{
+			desc:     "pending before deadline",
+			epochs:   []*types.MapRootV1{before},
+			muts:     [][]*pb.Entry{{mut1}},
+			wantErrs: []int{0},
+		},
+		{
+			desc:     "applied late",
+			epochs:   []*types.MapRootV1{before, after},
+			muts:     [][]*pb.Entry{{mut1}, {mut2}},
+			wantErrs: []int{0, 1},
+		},
+		{
+			desc:     "missing after deadline",
+			epochs:   []*types.MapRootV1{after, after},
+			muts:     [][]*pb.Entry{{}, {mut1, mut2}},
+			wantErrs: []int{2, 0},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			m := &Monitor{
+				domainID:   "domain",
+				receiptKey: key.Public(),
+				receipts:   make(map[string]*pb.MutationReceipt),
+			}
+			for _, mut := range []*pb.Entry{mut1, mut2} {
+				r, err := receipt.New(signer, "domain", mut, deadline)
+				if err != nil {
+					t.Fatalf("receipt.New(): %v", err)
+				}
+				if err := m.AddReceipt(mut, r); err != nil {
+					t.Fatalf("AddReceipt(): %v", err)
+				}
+			}
+			for i, mapRoot := range tc.epochs {
+				muts := make([]*pb.MutationProof, 0, len(tc.muts[i]))
+				for _, mut := range tc.muts[i] {
+					muts = append(muts, &pb.MutationProof{Mutation: mut})
+				}
+				errs := m.checkReceipts(muts, mapRoot)
+				if got, want := len(errs), tc.wantErrs[i]; got != want {
+					t.Errorf("checkReceipts(epoch %v): %v, want %v errors", i, errs, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAddReceipt(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey(): %v", err)
+	}
+	mut := &pb.Entry{Index: []byte("one")}
+	r, err := receipt.New(tcrypto.NewSHA256Signer(key), "domain", mut, time.Now())
+	if err != nil {
+		t.Fatalf("receipt.New(): %v", err)
+	}
+	for _, tc := range []struct {
+		desc    string
+		m       *Monitor
+		mut     *pb.Entry
+		wantErr bool
+	}{
+		{desc: "valid", m: &Monitor{domainID: "domain", receiptKey: key.Public()}, mut: mut},
+		{desc: "no receipt key", m: &Monitor{domainID: "domain"}, mut: mut, wantErr: true},
+		{desc: "wrong domain", m: &Monitor{domainID: "other", receiptKey: key.Public()}, mut: mut, wantErr: true},
+		{desc: "wrong mutation", m: &Monitor{domainID: "domain", receiptKey: key.Public()}, mut: &pb.Entry{}, wantErr: true},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			tc.m.receipts = make(map[string]*pb.MutationReceipt)
+			err := tc.m.AddReceipt(tc.mut, r)
+			if got, want := err != nil, tc.wantErr; got != want {
+				t.Errorf("AddReceipt(): %v, want err: %v", err, want)
+			}
+		})
+	}
+}
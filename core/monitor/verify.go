@@ -89,19 +89,22 @@ func (e *ErrList) Proto() []*statuspb.Status {
 	return errs
 }
 
+// verifyMutations verifies that applying muts to the map at oldRoot produces
+// expectedNewRoot. Mutations are applied the same way the sequencer applies
+// them: mutations to the same index are applied in order, each against the
+// result of the last valid mutation before it, and invalid mutations leave the
+// index unchanged.
 func (m *Monitor) verifyMutations(muts []*pb.MutationProof, oldRoot *trillian.SignedMapRoot, expectedNewRoot *types.MapRootV1) []error {
 	errs := ErrList{}
 	mutator := entry.New()
 	oldProofNodes := make(map[string][]byte)
-	newLeaves := make([]merkle.HStar2LeafHash, 0, len(muts))
+	// newValues holds the latest value of each index that a valid mutation
+	// changed, and order holds those indexes in the order they first changed.
+	newValues := make(map[string]*pb.Entry)
+	order := make([][]byte, 0, len(muts))
 	glog.Infof("verifyMutations() called with %v mutations.", len(muts))
 
 	for _, mut := range muts {
-		oldLeaf, err := entry.FromLeafValue(mut.GetLeafProof().GetLeaf().GetLeafValue())
-		if err != nil {
-			errs.AppendStatus(status.Newf(codes.DataLoss, "could not decode leaf: %v", err).WithDetails(mut.GetLeafProof().GetLeaf()))
-		}
-
 		// verify that the provided leaf’s inclusion proof goes to epoch e-1:
 		index := mut.GetLeafProof().GetLeaf().GetIndex()
 		if err := m.mapVerifier.VerifyMapLeafInclusion(oldRoot, mut.GetLeafProof()); err != nil {
@@ -109,13 +112,51 @@ func (m *Monitor) verifyMutations(muts []*pb.MutationProof, oldRoot *trillian.Si
 			errs.AppendStatus(status.Newf(codes.DataLoss, "invalid  map inclusion proof: %v", err).WithDetails(mut.GetLeafProof()))
 		}
 
-		// compute the new leaf
+		// store the proof hashes locally to recompute the tree below:
+		leafNodeID := storage.NewNodeIDFromPrefixSuffix(index, storage.Suffix{}, m.mapVerifier.Hasher.BitLen())
+		sibIDs := leafNodeID.Siblings()
+		proofs := mut.GetLeafProof().GetInclusion()
+		for level, sibID := range sibIDs {
+			proof := proofs[level]
+			if p, ok := oldProofNodes[sibID.String()]; ok {
+				// sanity check: for each mut overlapping proof nodes should be
+				// equal:
+				if !bytes.Equal(p, proof) {
+					// this is really odd and should never happen
+					errs.appendErr(ErrInconsistentProofs)
+				}
+			} else {
+				if len(proof) > 0 {
+					oldProofNodes[sibID.String()] = proof
+				}
+			}
+		}
+
+		// compute the new leaf, skipping invalid mutations like the
+		// sequencer does.
+		oldLeaf, ok := newValues[string(index)]
+		if !ok {
+			var err error
+			oldLeaf, err = entry.FromLeafValue(mut.GetLeafProof().GetLeaf().GetLeafValue())
+			if err != nil {
+				errs.AppendStatus(status.Newf(codes.DataLoss, "could not decode leaf: %v", err).WithDetails(mut.GetLeafProof().GetLeaf()))
+				continue
+			}
+		}
 		newValue, err := mutator.Mutate(oldLeaf, mut.GetMutation())
 		if err != nil {
 			glog.Infof("Mutation did not verify: %v", err)
-			errs.AppendStatus(status.Newf(codes.DataLoss, "invalid mutation: %v", err).WithDetails(mut.GetMutation()))
+			continue
 		}
-		leafNodeID := storage.NewNodeIDFromPrefixSuffix(index, storage.Suffix{}, m.mapVerifier.Hasher.BitLen())
+		if !ok {
+			order = append(order, index)
+		}
+		newValues[string(index)] = newValue.(*pb.Entry)
+	}
+
+	newLeaves := make([]merkle.HStar2LeafHash, 0, len(order))
+	for _, index := range order {
+		newValue := newValues[string(index)]
 		leaf, err := entry.ToLeafValue(newValue)
 		if err != nil {
 			glog.Infof("Failed to serialize: %v", err)
@@ -129,29 +170,11 @@ func (m *Monitor) verifyMutations(muts []*pb.MutationProof, oldRoot *trillian.Si
 		if err != nil {
 			errs.appendErr(err)
 		}
+		leafNodeID := storage.NewNodeIDFromPrefixSuffix(index, storage.Suffix{}, m.mapVerifier.Hasher.BitLen())
 		newLeaves = append(newLeaves, merkle.HStar2LeafHash{
 			Index:    leafNodeID.BigInt(),
 			LeafHash: leafHash,
 		})
-
-		// store the proof hashes locally to recompute the tree below:
-		sibIDs := leafNodeID.Siblings()
-		proofs := mut.GetLeafProof().GetInclusion()
-		for level, sibID := range sibIDs {
-			proof := proofs[level]
-			if p, ok := oldProofNodes[sibID.String()]; ok {
-				// sanity check: for each mut overlapping proof nodes should be
-				// equal:
-				if !bytes.Equal(p, proof) {
-					// this is really odd and should never happen
-					errs.appendErr(ErrInconsistentProofs)
-				}
-			} else {
-				if len(proof) > 0 {
-					oldProofNodes[sibID.String()] = proof
-				}
-			}
-		}
 	}
 
 	if err := m.validateMapRoot(expectedNewRoot, newLeaves, oldProofNodes); err != nil {
@@ -18,6 +18,7 @@ package entry
 import (
 	"fmt"
 
+	"github.com/benlaurie/objecthash/go/objecthash"
 	"github.com/golang/glog"
 	"github.com/golang/protobuf/proto"
 
@@ -50,3 +51,17 @@ func ToLeafValue(update proto.Message) ([]byte, error) {
 
 	return proto.Marshal(e)
 }
+
+// Hash returns the ObjectHash of the CommonJSON representation of e.
+// This is the hash that Entry.Previous uses to refer to an entry.
+func Hash(e *pb.Entry) ([]byte, error) {
+	ej, err := objecthash.CommonJSONify(e)
+	if err != nil {
+		return nil, err
+	}
+	h, err := objecthash.ObjectHash(ej)
+	if err != nil {
+		return nil, err
+	}
+	return h[:], nil
+}
@@ -38,6 +38,7 @@ type Mutation struct {
 
 	prevEntry *pb.Entry
 	entry     *pb.Entry
+	receipt   *pb.MutationReceipt
 }
 
 // NewMutation creates a mutation object from a previous value which can be modified.
@@ -76,6 +77,7 @@ func (m *Mutation) SetPrevious(oldValue []byte, copyPrevious bool) error {
 
 	m.prevEntry = prevEntry
 	m.entry.Previous = hash[:]
+	m.receipt = nil
 	if copyPrevious {
 		m.entry.AuthorizedKeys = prevEntry.GetAuthorizedKeys()
 		m.entry.Commitment = prevEntry.GetCommitment()
@@ -83,6 +85,22 @@ func (m *Mutation) SetPrevious(oldValue []byte, copyPrevious bool) error {
 	return nil
 }
 
+// Next returns a new mutation to the same entry that builds on the value m
+// sets. Use Next after m has been signed to queue several updates to an entry
+// without waiting for m to be applied. The sequencer applies such a chain in
+// the order in which it was queued.
+func (m *Mutation) Next() (*Mutation, error) {
+	value, err := ToLeafValue(m.entry)
+	if err != nil {
+		return nil, err
+	}
+	next := NewMutation(m.entry.GetIndex(), m.DomainID, m.AppID, m.UserID)
+	if err := next.SetPrevious(value, true); err != nil {
+		return nil, err
+	}
+	return next, nil
+}
+
 // SetCommitment updates entry to be a commitment to data.
 func (m *Mutation) SetCommitment(data []byte) error {
 	// Commit to profile.
@@ -174,6 +192,23 @@ func (m *Mutation) sign(signers []*tink.KeysetHandle) (*pb.Entry, error) {
 	return m.entry, nil
 }
 
+// Hash returns the ObjectHash of the mutation's entry. Once the mutation has
+// been signed, Hash identifies the mutation in GetMutationStatus.
+func (m *Mutation) Hash() ([]byte, error) {
+	return Hash(m.entry)
+}
+
+// SetReceipt records the receipt the server issued for this mutation.
+func (m *Mutation) SetReceipt(r *pb.MutationReceipt) {
+	m.receipt = r
+}
+
+// Receipt returns the receipt the server issued for this mutation, or nil if
+// no receipt was issued since the mutation was last modified.
+func (m *Mutation) Receipt() *pb.MutationReceipt {
+	return m.receipt
+}
+
 // EqualsRequested verifies that an update was successfully applied.
 // Returns nil if newLeaf is equal to the entry in this mutation.
 func (m *Mutation) EqualsRequested(leafValue proto.Message) bool {
@@ -77,3 +77,46 @@ func TestCreateAndVerify(t *testing.T) {
 		}
 	}
 }
+
+func TestNext(t *testing.T) {
+	signers := testutil.SignKeysetsFromPEMs(testPrivKey1)
+	m := NewMutation([]byte("index"), domainID, "app1", "alice")
+	if err := m.SetCommitment([]byte("foo")); err != nil {
+		t.Fatalf("SetCommitment(): %v", err)
+	}
+	if err := m.ReplaceAuthorizedKeys(testutil.VerifyKeysetFromPEMs(testPubKey1).Keyset()); err != nil {
+		t.Fatalf("ReplaceAuthorizedKeys(): %v", err)
+	}
+	first, err := m.SerializeAndSign(signers, 0)
+	if err != nil {
+		t.Fatalf("SerializeAndSign(): %v", err)
+	}
+
+	next, err := m.Next()
+	if err != nil {
+		t.Fatalf("Next(): %v", err)
+	}
+	if err := next.SetCommitment([]byte("bar")); err != nil {
+		t.Fatalf("SetCommitment(): %v", err)
+	}
+	second, err := next.SerializeAndSign(signers, 0)
+	if err != nil {
+		t.Fatalf("SerializeAndSign(): %v", err)
+	}
+
+	// Apply the chain in order, as the sequencer does.
+	value, err := New().Mutate(nil, first.GetEntryUpdate().GetMutation())
+	if err != nil {
+		t.Fatalf("Mutate(first): %v", err)
+	}
+	value, err = New().Mutate(value, second.GetEntryUpdate().GetMutation())
+	if err != nil {
+		t.Fatalf("Mutate(second): %v", err)
+	}
+	if !next.EqualsRequested(value) {
+		t.Errorf("EqualsRequested(): false")
+	}
+	if !next.EqualsPrevious(first.GetEntryUpdate().GetMutation()) {
+		t.Errorf("EqualsPrevious(): false")
+	}
+}
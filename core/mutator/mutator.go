@@ -20,6 +20,7 @@ package mutator
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/golang/protobuf/proto"
@@ -46,6 +47,10 @@ var (
 	// ErrUnauthorized occurs when the mutation has not been signed by a key in the
 	// previous entry.
 	ErrUnauthorized = errors.New("mutation: unauthorized")
+	// ErrStaleBatch occurs when a batch can't be sequenced because another
+	// receiver, such as the receiver of a newer master, has sequenced its
+	// revision or its messages.
+	ErrStaleBatch = errors.New("mutation: batch or revision already sequenced")
 )
 
 // Func verifies mutations and transforms values in the map.
@@ -55,8 +60,24 @@ type Func interface {
 	Mutate(value, mutation proto.Message) (proto.Message, error)
 }
 
+// Registry holds the mutation functions that domains can select in their
+// sequencing config.
+type Registry map[pb.SequencingConfig_Mutator]Func
+
+// Get returns the mutation function selected by c.
+// A nil config selects the ENTRY mutator.
+func (r Registry) Get(c *pb.SequencingConfig) (Func, error) {
+	f, ok := r[c.GetMutator()]
+	if !ok {
+		return nil, fmt.Errorf("mutator: %v is not supported", c.GetMutator())
+	}
+	return f, nil
+}
+
 // QueueMessage represents a change to a user, and associated data.
 type QueueMessage struct {
+	// ID is assigned by the queue. IDs increase in the order messages
+	// were sent to a domain's queue.
 	ID        int64
 	Mutation  *pb.Entry
 	ExtraData *pb.Committed
@@ -65,11 +86,28 @@ type QueueMessage struct {
 // MutationQueue provides (at minimum) a roughly time ordered queue that can support
 // multiple writers.  Replays, drops, and duplicate delivery must be tolerated by
 // receivers.
+//
+// A batch delivered to a receiver is claimed until receiveFunc returns.
+// It is removed from the queue if receiveFunc succeeds, and delivered again
+// otherwise, or once ReceiverOptions.ClaimTimeout has passed if the receiver
+// stopped without returning. Starting a receiver releases the batches claimed
+// by earlier receivers of the domain, so only the domain's master may start one.
 type MutationQueue interface {
 	// Send submits an item to the queue
 	Send(ctx context.Context, domainID string, mutation *pb.EntryUpdate) error
 	// NewReceiver starts receiving messages sent to the queue. As batches become ready, receiveFunc will be called.
 	NewReceiver(ctx context.Context, last time.Time, domainID string, receiveFunc ReceiveFunc, ropts ReceiverOptions) Receiver
+	// Stats describes the items waiting in the queue of domainID.
+	Stats(ctx context.Context, domainID string) (*QueueStats, error)
+}
+
+// QueueStats describes the items waiting in a queue.
+type QueueStats struct {
+	// Depth is the number of items in the queue.
+	Depth int64
+	// Oldest is the time the oldest item was sent, or the zero time if
+	// the queue is empty.
+	Oldest time.Time
 }
 
 // ReceiveFunc receives updates from the queue.
@@ -95,6 +133,13 @@ type ReceiverOptions struct {
 	// MaxPeriod is the maximum allowed time between batches.
 	// If no data has been received in this period, an empty batch will be sent.
 	MaxPeriod time.Duration
+	// ClaimTimeout is how long a delivered batch stays claimed by a
+	// receiver that neither acknowledges nor releases it. Zero selects an
+	// implementation default.
+	ClaimTimeout time.Duration
+	// MMDBlown, if set, is called when more than MaxPeriod has passed since
+	// the last batch was received at last. It is called once per late batch.
+	MMDBlown func(last time.Time)
 }
 
 // MutationStorage reads and writes mutations to the database.
@@ -103,6 +148,42 @@ type MutationStorage interface {
 	// pageSize specifies the maximum number of items to return.
 	// Returns the maximum sequence number returned.
 	ReadPage(ctx context.Context, domainID string, revision, start int64, pageSize int32) (int64, []*pb.Entry, error)
-	// WriteBatch saves the mutations in the database under domainID/revision.
+	// WriteBatch saves the mutations in the database under domainID/revision,
+	// replacing any mutations previously saved there.
 	WriteBatch(ctx context.Context, domainID string, revision int64, mutation []*pb.Entry) error
+	// SequenceBatch saves the mutations of msgs, along with their committed
+	// data, under domainID/revision, replacing any mutations previously
+	// saved there. In the same transaction, it removes msgs from the queue of
+	// domainID and records revision as the highest sequenced revision of
+	// domainID. msgs must be a batch delivered by the queue.
+	// SequenceBatch fails with ErrStaleBatch, and saves nothing, unless
+	// revision follows the highest sequenced revision of domainID or none
+	// has been recorded, and every message of msgs is still queued.
+	SequenceBatch(ctx context.Context, domainID string, revision int64, msgs []*QueueMessage) error
+	// ReadBatch returns the messages saved for domainID/revision in sequence
+	// order. ExtraData is nil for mutations saved without committed data.
+	ReadBatch(ctx context.Context, domainID string, revision int64) ([]*QueueMessage, error)
+	// HighestSequencedRevision returns the highest revision recorded by
+	// SequenceBatch for domainID, or 0 if there is none.
+	HighestSequencedRevision(ctx context.Context, domainID string) (int64, error)
+	// WriteIndexChanges records that the map leaves at indexes were changed
+	// in domainID/revision. Recording a change again has no effect.
+	WriteIndexChanges(ctx context.Context, domainID string, revision int64, indexes [][]byte) error
+	// ListIndexChanges returns the revisions in the interval [start, end] in
+	// which the map leaf at index was changed, in ascending order.
+	// limit specifies the maximum number of revisions to return.
+	ListIndexChanges(ctx context.Context, domainID string, index []byte, start, end int64, limit int32) ([]int64, error)
+	// WriteStatus records the processing state of the mutation identified by
+	// hash, the ObjectHash of the mutation's Entry.
+	WriteStatus(ctx context.Context, domainID string, hash []byte, status *pb.MutationStatus) error
+	// ReadStatus returns the processing state of the mutation identified by hash.
+	// ReadStatus returns a NotFound error if the mutation is unknown.
+	ReadStatus(ctx context.Context, domainID string, hash []byte) (*pb.MutationStatus, error)
+	// WriteRejected records the mutations that were rejected in domainID/revision.
+	// Recording a rejected mutation again replaces the earlier record.
+	WriteRejected(ctx context.Context, domainID string, revision int64, rejected []*pb.RejectedMutation) error
+	// ReadRejectedPage returns the mutations rejected in domainID/revision
+	// with a sequence number of at least start, in sequence order.
+	// pageSize specifies the maximum number of items to return.
+	ReadRejectedPage(ctx context.Context, domainID string, revision, start int64, pageSize int32) ([]*pb.RejectedMutation, error)
 }
@@ -0,0 +1,117 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package receipt creates and verifies mutation receipts.
+//
+// A mutation receipt is a signed promise from the key server that a mutation
+// will be included in an epoch created no later than the receipt's deadline.
+package receipt
+
+import (
+	"bytes"
+	"crypto"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/keytransparency/core/mutator/entry"
+	"github.com/google/trillian/types"
+
+	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
+	tcrypto "github.com/google/trillian/crypto"
+)
+
+var (
+	// ErrWrongDomain occurs when a receipt was issued for a different domain.
+	ErrWrongDomain = errors.New("receipt: wrong domain")
+	// ErrWrongMutation occurs when a receipt was issued for a different mutation.
+	ErrWrongMutation = errors.New("receipt: wrong mutation")
+	// ErrBrokenPromise occurs when a mutation has not been included in an
+	// epoch created before the receipt's deadline.
+	ErrBrokenPromise = errors.New("receipt: mutation not included before deadline")
+)
+
+// New returns a receipt, signed by signer, promising that mutation will be
+// included in an epoch of domainID created no later than deadline.
+func New(signer *tcrypto.Signer, domainID string, mutation *pb.Entry, deadline time.Time) (*pb.MutationReceipt, error) {
+	hash, err := entry.Hash(mutation)
+	if err != nil {
+		return nil, fmt.Errorf("entry.Hash(): %v", err)
+	}
+	deadlinePB, err := ptypes.TimestampProto(deadline)
+	if err != nil {
+		return nil, err
+	}
+	r := &pb.MutationReceipt{
+		DomainId:     domainID,
+		MutationHash: hash,
+		Deadline:     deadlinePB,
+	}
+	data, err := signedData(r)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := signer.Sign(data)
+	if err != nil {
+		return nil, fmt.Errorf("Sign(): %v", err)
+	}
+	r.Signature = sig
+	return r, nil
+}
+
+// Verify verifies that r is correctly signed by pubKey and that r was issued
+// for mutation in domainID.
+func Verify(pubKey crypto.PublicKey, domainID string, mutation *pb.Entry, r *pb.MutationReceipt) error {
+	if got, want := r.GetDomainId(), domainID; got != want {
+		return ErrWrongDomain
+	}
+	hash, err := entry.Hash(mutation)
+	if err != nil {
+		return fmt.Errorf("entry.Hash(): %v", err)
+	}
+	if !bytes.Equal(r.GetMutationHash(), hash) {
+		return ErrWrongMutation
+	}
+	data, err := signedData(r)
+	if err != nil {
+		return err
+	}
+	return tcrypto.Verify(pubKey, crypto.SHA256, data, r.GetSignature())
+}
+
+// CheckDeadline returns ErrBrokenPromise if an epoch with mapRoot is past
+// the deadline of r. CheckDeadline should be called with the map root of the
+// epoch that includes the mutation of r, or with the map root of an epoch that
+// does not include it yet.
+func CheckDeadline(r *pb.MutationReceipt, mapRoot *types.MapRootV1) error {
+	deadline, err := ptypes.Timestamp(r.GetDeadline())
+	if err != nil {
+		return fmt.Errorf("invalid deadline: %v", err)
+	}
+	if time.Unix(0, int64(mapRoot.TimestampNanos)).After(deadline) {
+		return ErrBrokenPromise
+	}
+	return nil
+}
+
+// signedData returns the bytes that are signed by a receipt's signature.
+func signedData(r *pb.MutationReceipt) ([]byte, error) {
+	return proto.Marshal(&pb.MutationReceipt{
+		DomainId:     r.GetDomainId(),
+		MutationHash: r.GetMutationHash(),
+		Deadline:     r.GetDeadline(),
+	})
+}
@@ -0,0 +1,93 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package receipt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/google/trillian/types"
+
+	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
+	tcrypto "github.com/google/trillian/crypto"
+)
+
+func TestVerify(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey(): %v", err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey(): %v", err)
+	}
+	signer := tcrypto.NewSHA256Signer(key)
+	mutation := &pb.Entry{Index: []byte("index"), Commitment: []byte("commitment")}
+	r, err := New(signer, "domain", mutation, time.Now())
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+
+	for _, tc := range []struct {
+		desc     string
+		pubKey   interface{}
+		domainID string
+		mutation *pb.Entry
+		wantErr  bool
+	}{
+		{desc: "valid", pubKey: key.Public(), domainID: "domain", mutation: mutation},
+		{desc: "wrong key", pubKey: otherKey.Public(), domainID: "domain", mutation: mutation, wantErr: true},
+		{desc: "wrong domain", pubKey: key.Public(), domainID: "other", mutation: mutation, wantErr: true},
+		{desc: "wrong mutation", pubKey: key.Public(), domainID: "domain", mutation: &pb.Entry{Index: []byte("index")}, wantErr: true},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := Verify(tc.pubKey, tc.domainID, tc.mutation, r)
+			if got, want := err != nil, tc.wantErr; got != want {
+				t.Errorf("Verify(): %v, want err: %v", err, want)
+			}
+		})
+	}
+}
+
+func TestCheckDeadline(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey(): %v", err)
+	}
+	deadline := time.Unix(1000, 0)
+	r, err := New(tcrypto.NewSHA256Signer(key), "domain", &pb.Entry{}, deadline)
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	for _, tc := range []struct {
+		desc    string
+		epoch   time.Time
+		wantErr error
+	}{
+		{desc: "before deadline", epoch: deadline.Add(-time.Second)},
+		{desc: "at deadline", epoch: deadline},
+		{desc: "after deadline", epoch: deadline.Add(time.Nanosecond), wantErr: ErrBrokenPromise},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			mapRoot := &types.MapRootV1{TimestampNanos: uint64(tc.epoch.UnixNano())}
+			if err := CheckDeadline(r, mapRoot); err != tc.wantErr {
+				t.Errorf("CheckDeadline(): %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
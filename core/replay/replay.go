@@ -0,0 +1,211 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package replay rebuilds the map of a domain from its stored mutations and
+// compares the result with the map roots stored by the map server.
+package replay
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/golang/glog"
+	"github.com/google/keytransparency/core/domain"
+	"github.com/google/keytransparency/core/mutator"
+	"github.com/google/keytransparency/core/mutator/entry"
+	"github.com/google/trillian/merkle"
+	"github.com/google/trillian/merkle/hashers"
+	"github.com/google/trillian/storage"
+
+	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
+	tpb "github.com/google/trillian"
+	tclient "github.com/google/trillian/client"
+)
+
+// pageSize is the number of mutations read from storage at a time.
+var pageSize = int32(1000)
+
+// DivergenceError reports the first revision whose recomputed map root
+// differs from the map root stored by the map server.
+type DivergenceError struct {
+	Revision int64
+	// Got is the recomputed root hash, Want is the stored root hash.
+	Got, Want []byte
+}
+
+func (e *DivergenceError) Error() string {
+	return fmt.Sprintf("replay: revision %v: recomputed root %x, stored root %x", e.Revision, e.Got, e.Want)
+}
+
+// Replayer recomputes map roots from stored mutations.
+type Replayer struct {
+	tmap      tpb.TrillianMapClient
+	mutations mutator.MutationStorage
+	mutators  mutator.Registry
+}
+
+// New returns a Replayer that reads mutations from mutations, applies them
+// with the mutator each domain is configured with, and compares the
+// results with the map roots in tmap.
+func New(tmap tpb.TrillianMapClient, mutations mutator.MutationStorage, mutators mutator.Registry) *Replayer {
+	return &Replayer{
+		tmap:      tmap,
+		mutations: mutations,
+		mutators:  mutators,
+	}
+}
+
+// Replay applies the mutations of every revision of d, up to the latest
+// revision of its map, to an in-memory sparse Merkle tree hashed with the
+// hasher of the map. It returns the latest revision if every recomputed root
+// matches the stored one, and a *DivergenceError for the first revision that
+// does not.
+//
+// Replay keeps the latest value of every map leaf in memory.
+func (r *Replayer) Replay(ctx context.Context, d *domain.Domain, mapVerifier *tclient.MapVerifier) (int64, error) {
+	mutate, err := r.mutators.Get(d.Sequencing)
+	if err != nil {
+		return 0, err
+	}
+	rootResp, err := r.tmap.GetSignedMapRoot(ctx, &tpb.GetSignedMapRootRequest{MapId: d.MapID})
+	if err != nil {
+		return 0, fmt.Errorf("GetSignedMapRoot(%v): %v", d.MapID, err)
+	}
+	latest, err := mapVerifier.VerifySignedMapRoot(rootResp.GetMapRoot())
+	if err != nil {
+		return 0, err
+	}
+
+	t := newTree(mapVerifier.MapID, mapVerifier.Hasher)
+	values := make(map[string]*pb.Entry)
+	for rev := int64(0); rev <= int64(latest.Revision); rev++ {
+		muts, err := r.readRevision(ctx, d.DomainID, rev)
+		if err != nil {
+			return 0, err
+		}
+		leaves := applyMutations(mutate, values, muts)
+		got, err := t.update(leaves)
+		if err != nil {
+			return 0, fmt.Errorf("replay: revision %v: %v", rev, err)
+		}
+
+		rootResp, err := r.tmap.GetSignedMapRootByRevision(ctx, &tpb.GetSignedMapRootByRevisionRequest{
+			MapId:    d.MapID,
+			Revision: rev,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("GetSignedMapRootByRevision(%v): %v", rev, err)
+		}
+		mapRoot, err := mapVerifier.VerifySignedMapRoot(rootResp.GetMapRoot())
+		if err != nil {
+			return 0, err
+		}
+		if !bytes.Equal(got, mapRoot.RootHash) {
+			return 0, &DivergenceError{Revision: rev, Got: got, Want: mapRoot.RootHash}
+		}
+		glog.V(2).Infof("Replay: revision %v of domain %v matches, %v mutations", rev, d.DomainID, len(muts))
+	}
+	return int64(latest.Revision), nil
+}
+
+// readRevision returns all the mutations of revision rev.
+func (r *Replayer) readRevision(ctx context.Context, domainID string, rev int64) ([]*pb.Entry, error) {
+	var muts []*pb.Entry
+	start := int64(0)
+	for {
+		max, page, err := r.mutations.ReadPage(ctx, domainID, rev, start, pageSize)
+		if err != nil {
+			return nil, fmt.Errorf("ReadPage(%v, %v, %v): %v", domainID, rev, start, err)
+		}
+		muts = append(muts, page...)
+		if int32(len(page)) < pageSize {
+			return muts, nil
+		}
+		start = max + 1
+	}
+}
+
+// applyMutations applies muts to values the way the sequencer does: mutations
+// to the same index are applied in order, each against the result of the last
+// valid mutation before it, and invalid mutations leave the index unchanged.
+// It returns the new leaf values of the indexes that changed.
+func applyMutations(mutate mutator.Func, values map[string]*pb.Entry, muts []*pb.Entry) map[string][]byte {
+	changed := make(map[string][]byte)
+	for _, m := range muts {
+		index := string(m.GetIndex())
+		newValue, err := mutate.Mutate(values[index], m)
+		if err != nil {
+			glog.V(2).Infof("Replay: skipping invalid mutation of index %x: %v", m.GetIndex(), err)
+			continue
+		}
+		newEntry, ok := newValue.(*pb.Entry)
+		if !ok {
+			glog.V(2).Infof("Replay: skipping mutation of index %x: %T is not an Entry", m.GetIndex(), newValue)
+			continue
+		}
+		leafValue, err := entry.ToLeafValue(newEntry)
+		if err != nil {
+			glog.V(2).Infof("Replay: skipping mutation of index %x: ToLeafValue(): %v", m.GetIndex(), err)
+			continue
+		}
+		values[index] = newEntry
+		changed[index] = leafValue
+	}
+	return changed
+}
+
+// tree is an in-memory sparse Merkle tree. It keeps every node it computes so
+// that each revision only rehashes the paths of the leaves that changed.
+type tree struct {
+	treeID int64
+	hasher hashers.MapHasher
+	nodes  map[string][]byte
+}
+
+func newTree(treeID int64, hasher hashers.MapHasher) *tree {
+	return &tree{
+		treeID: treeID,
+		hasher: hasher,
+		nodes:  make(map[string][]byte),
+	}
+}
+
+// update sets the leaves of the tree to the values in leaves, keyed by index,
+// and returns the new root hash.
+func (t *tree) update(leaves map[string][]byte) ([]byte, error) {
+	bitLen := t.hasher.BitLen()
+	hs2Leaves := make([]merkle.HStar2LeafHash, 0, len(leaves))
+	for index, value := range leaves {
+		leafHash, err := t.hasher.HashLeaf(t.treeID, []byte(index), value)
+		if err != nil {
+			return nil, err
+		}
+		leafNodeID := storage.NewNodeIDFromPrefixSuffix([]byte(index), storage.Suffix{}, bitLen)
+		hs2Leaves = append(hs2Leaves, merkle.HStar2LeafHash{
+			Index:    leafNodeID.BigInt(),
+			LeafHash: leafHash,
+		})
+	}
+	hs2 := merkle.NewHStar2(t.treeID, t.hasher)
+	return hs2.HStar2Nodes([]byte{}, bitLen, hs2Leaves,
+		func(depth int, index *big.Int) ([]byte, error) {
+			return t.nodes[storage.NewNodeIDFromBigInt(depth, index, bitLen).String()], nil
+		},
+		func(depth int, index *big.Int, hash []byte) error {
+			t.nodes[storage.NewNodeIDFromBigInt(depth, index, bitLen).String()] = hash
+			return nil
+		})
+}
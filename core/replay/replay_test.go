@@ -0,0 +1,176 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replay
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/keytransparency/core/domain"
+	"github.com/google/keytransparency/core/fake"
+	"github.com/google/keytransparency/core/mutator"
+	"github.com/google/keytransparency/core/mutator/entry"
+	"github.com/google/trillian/merkle/coniks"
+	"github.com/google/trillian/types"
+	"google.golang.org/grpc"
+
+	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
+	tpb "github.com/google/trillian"
+	tclient "github.com/google/trillian/client"
+	tcrypto "github.com/google/trillian/crypto"
+)
+
+const mapID = 1
+
+// fakeMutator accepts every mutation, except those committing to "bad".
+type fakeMutator struct{}
+
+func (fakeMutator) Mutate(value, mutation proto.Message) (proto.Message, error) {
+	m := mutation.(*pb.Entry)
+	if bytes.Equal(m.GetCommitment(), []byte("bad")) {
+		return nil, mutator.ErrUnauthorized
+	}
+	return m, nil
+}
+
+// fakeMap serves signed map roots. Unimplemented methods panic.
+type fakeMap struct {
+	tpb.TrillianMapClient
+	roots []*tpb.SignedMapRoot
+}
+
+func (m *fakeMap) GetSignedMapRoot(context.Context, *tpb.GetSignedMapRootRequest, ...grpc.CallOption) (*tpb.GetSignedMapRootResponse, error) {
+	return &tpb.GetSignedMapRootResponse{MapRoot: m.roots[len(m.roots)-1]}, nil
+}
+
+func (m *fakeMap) GetSignedMapRootByRevision(_ context.Context, in *tpb.GetSignedMapRootByRevisionRequest, _ ...grpc.CallOption) (*tpb.GetSignedMapRootResponse, error) {
+	return &tpb.GetSignedMapRootResponse{MapRoot: m.roots[in.Revision]}, nil
+}
+
+func index(name string) []byte {
+	i := sha256.Sum256([]byte(name))
+	return i[:]
+}
+
+// newMap returns a map holding revisions [0, len(revisions)], where revision
+// i+1 applies revisions[i]. Each root is computed from scratch over all the
+// leaves of its revision.
+func newMap(t *testing.T, revisions [][]*pb.Entry) (*fakeMap, *tclient.MapVerifier) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey(): %v", err)
+	}
+	signer := tcrypto.NewSHA256Signer(key)
+	m := &fakeMap{}
+	leaves := make(map[string][]byte)
+	for rev := 0; rev <= len(revisions); rev++ {
+		if rev > 0 {
+			for _, e := range revisions[rev-1] {
+				if bytes.Equal(e.GetCommitment(), []byte("bad")) {
+					continue
+				}
+				leafValue, err := entry.ToLeafValue(e)
+				if err != nil {
+					t.Fatalf("ToLeafValue(): %v", err)
+				}
+				leaves[string(e.GetIndex())] = leafValue
+			}
+		}
+		rootHash, err := newTree(mapID, coniks.Default).update(leaves)
+		if err != nil {
+			t.Fatalf("update(): %v", err)
+		}
+		smr, err := signer.SignMapRoot(&types.MapRootV1{RootHash: rootHash, Revision: uint64(rev)})
+		if err != nil {
+			t.Fatalf("SignMapRoot(): %v", err)
+		}
+		m.roots = append(m.roots, smr)
+	}
+	return m, &tclient.MapVerifier{MapID: mapID, Hasher: coniks.Default, MapPubKey: key.Public(), SigHash: crypto.SHA256}
+}
+
+func TestReplay(t *testing.T) {
+	// Read several pages per revision.
+	defer func(p int32) { pageSize = p }(pageSize)
+	pageSize = 1
+	ctx := context.Background()
+	d := &domain.Domain{DomainID: "domain", MapID: mapID}
+	revisions := [][]*pb.Entry{
+		{{Index: index("alice"), Commitment: []byte("a1")}, {Index: index("bob"), Commitment: []byte("b1")}},
+		{{Index: index("alice"), Commitment: []byte("a2")}, {Index: index("alice"), Commitment: []byte("a3")}},
+		{{Index: index("carol"), Commitment: []byte("c1")}, {Index: index("bob"), Commitment: []byte("bad")}},
+		{},
+		{{Index: index("bob"), Commitment: []byte("b2")}},
+	}
+	for _, tc := range []struct {
+		desc    string
+		corrupt func(m *fake.MutationStorage)
+		wantRev int64 // The diverging revision, or 0 if none.
+	}{
+		{desc: "consistent", corrupt: func(*fake.MutationStorage) {}},
+		{desc: "altered mutation", wantRev: 2, corrupt: func(m *fake.MutationStorage) {
+			m.WriteBatch(ctx, d.DomainID, 2, []*pb.Entry{
+				{Index: index("alice"), Commitment: []byte("a2")}, {Index: index("alice"), Commitment: []byte("x")},
+			})
+		}},
+		{desc: "missing mutation", wantRev: 5, corrupt: func(m *fake.MutationStorage) {
+			m.WriteBatch(ctx, d.DomainID, 5, []*pb.Entry{})
+		}},
+		{desc: "extra mutation", wantRev: 4, corrupt: func(m *fake.MutationStorage) {
+			m.WriteBatch(ctx, d.DomainID, 4, []*pb.Entry{{Index: index("dave"), Commitment: []byte("d1")}})
+		}},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			tmap, mapVerifier := newMap(t, revisions)
+			mutations := fake.NewMutationStorage()
+			if err := mutations.WriteBatch(ctx, d.DomainID, 0, []*pb.Entry{}); err != nil {
+				t.Fatalf("WriteBatch(): %v", err)
+			}
+			for i, muts := range revisions {
+				if err := mutations.WriteBatch(ctx, d.DomainID, int64(i+1), muts); err != nil {
+					t.Fatalf("WriteBatch(): %v", err)
+				}
+			}
+			tc.corrupt(mutations)
+
+			r := New(tmap, mutations, mutator.Registry{pb.SequencingConfig_ENTRY: fakeMutator{}})
+			latest, err := r.Replay(ctx, d, mapVerifier)
+			if tc.wantRev == 0 {
+				if err != nil {
+					t.Fatalf("Replay(): %v", err)
+				}
+				if got, want := latest, int64(len(revisions)); got != want {
+					t.Errorf("Replay(): %v, want %v", got, want)
+				}
+				return
+			}
+			derr, ok := err.(*DivergenceError)
+			if !ok {
+				t.Fatalf("Replay(): %v, want DivergenceError", err)
+			}
+			if got, want := derr.Revision, tc.wantRev; got != want {
+				t.Errorf("Replay() diverged at revision %v, want %v", got, want)
+			}
+		})
+	}
+}
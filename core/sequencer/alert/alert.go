@@ -0,0 +1,224 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package alert delivers emergency alerts raised by the sequencer when a
+// domain can no longer keep its promises to clients.
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kind identifies the emergency an alert reports.
+type Kind string
+
+const (
+	// LogAppendFailed occurs when a map root cannot be added to the log.
+	LogAppendFailed Kind = "log_append_failed"
+	// MMDBlown occurs when a domain goes longer than its max interval
+	// without an epoch.
+	MMDBlown Kind = "mmd_blown"
+	// MapRootInvalid occurs when a map root fails verification.
+	MapRootInvalid Kind = "map_root_invalid"
+	// ReceiverFailed occurs when sequencing of a domain can't start, for
+	// instance because an incomplete epoch can't be reconciled.
+	ReceiverFailed Kind = "receiver_failed"
+)
+
+// Alert describes an emergency in a domain.
+type Alert struct {
+	Kind     Kind   `json:"kind"`
+	DomainID string `json:"domain_id"`
+	// Revision is the map revision concerned, or -1 if it is not known.
+	Revision int64     `json:"revision"`
+	Time     time.Time `json:"time"`
+	Error    string    `json:"error,omitempty"`
+	// Details holds additional information specific to Kind.
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// Sink delivers alerts.
+type Sink interface {
+	// Fire delivers a. Fire may be called concurrently.
+	Fire(ctx context.Context, a *Alert) error
+}
+
+// multi fires alerts to several sinks.
+type multi []Sink
+
+// Multi returns a Sink that fires each alert to all of sinks.
+func Multi(sinks ...Sink) Sink {
+	return multi(sinks)
+}
+
+func (m multi) Fire(ctx context.Context, a *Alert) error {
+	var errs []string
+	for _, s := range m {
+		if err := s.Fire(ctx, a); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("alert: %v of %v sinks failed: %v", len(errs), len(m), strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// timeout bounds the time spent firing alerts to a sink.
+type timeout struct {
+	s       Sink
+	timeout time.Duration
+}
+
+// WithTimeout returns a Sink that fires each alert to s with a context that
+// expires after d. Fire returns once d has elapsed even if s has not, so that
+// a hung sink cannot hold up sequencing; s is then left to finish in the
+// background.
+func WithTimeout(s Sink, d time.Duration) Sink {
+	return &timeout{s: s, timeout: d}
+}
+
+func (t *timeout) Fire(ctx context.Context, a *Alert) error {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- t.s.Fire(ctx, a) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("alert: %v not delivered: %v", a.Kind, ctx.Err())
+	}
+}
+
+// webhook POSTs alerts as JSON to a URL.
+type webhook struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhook returns a Sink that POSTs each alert, encoded as JSON, to url.
+func NewWebhook(url string, timeout time.Duration) Sink {
+	return &webhook{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+func (w *webhook) Fire(ctx context.Context, a *Alert) error {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("alert: POST %v: %v", w.url, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("alert: POST %v: %v", w.url, resp.Status)
+	}
+	return nil
+}
+
+// execHook runs a command for each alert.
+type execHook struct {
+	path string
+	args []string
+}
+
+// NewExec returns a Sink that runs path with args for each alert. The alert
+// is written to the command's stdin as JSON, and its kind, domain and revision
+// are set in the KT_ALERT_KIND, KT_ALERT_DOMAIN and KT_ALERT_REVISION
+// environment variables.
+func NewExec(path string, args ...string) Sink {
+	return &execHook{path: path, args: args}
+}
+
+func (e *execHook) Fire(ctx context.Context, a *Alert) error {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, e.path, e.args...)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("KT_ALERT_KIND=%v", a.Kind),
+		fmt.Sprintf("KT_ALERT_DOMAIN=%v", a.DomainID),
+		fmt.Sprintf("KT_ALERT_REVISION=%v", a.Revision))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("alert: %v: %v: %s", e.path, err, out)
+	}
+	return nil
+}
+
+// file appends alerts to a file.
+type file struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFile returns a Sink that appends each alert to the file at path, as one
+// line of JSON.
+func NewFile(path string) Sink {
+	return &file{path: path}
+}
+
+func (f *file) Fire(_ context.Context, a *Alert) error {
+	line, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := out.Write(append(line, '\n')); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// ReadFile returns the alerts written to path by a sink returned by NewFile.
+func ReadFile(path string) ([]*Alert, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var alerts []*Alert
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for dec.More() {
+		a := &Alert{}
+		if err := dec.Decode(a); err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, nil
+}
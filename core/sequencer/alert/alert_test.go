@@ -0,0 +1,208 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newAlert(kind Kind, revision int64) *Alert {
+	return &Alert{
+		Kind:     kind,
+		DomainID: "domain",
+		Revision: revision,
+		Time:     time.Unix(1500000000, 0).UTC(),
+		Error:    "injected",
+		Details:  map[string]string{"log_id": "2"},
+	}
+}
+
+func tempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "alert")
+	if err != nil {
+		t.Fatalf("TempDir(): %v", err)
+	}
+	return dir
+}
+
+func TestFile(t *testing.T) {
+	ctx := context.Background()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "alerts")
+
+	want := []*Alert{newAlert(LogAppendFailed, 3), newAlert(MMDBlown, -1)}
+	sink := NewFile(path)
+	for _, a := range want {
+		if err := sink.Fire(ctx, a); err != nil {
+			t.Fatalf("Fire(): %v", err)
+		}
+	}
+	got, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile(): %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadFile(): %v, want %v", got, want)
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		desc    string
+		status  int
+		wantErr bool
+	}{
+		{desc: "ok", status: http.StatusOK},
+		{desc: "no content", status: http.StatusNoContent},
+		{desc: "server error", status: http.StatusInternalServerError, wantErr: true},
+		{desc: "redirect", status: http.StatusNotModified, wantErr: true},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			var got *Alert
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					t.Errorf("Method: %v, want POST", r.Method)
+				}
+				got = &Alert{}
+				if err := json.NewDecoder(r.Body).Decode(got); err != nil {
+					t.Errorf("Decode(): %v", err)
+				}
+				w.WriteHeader(tc.status)
+			}))
+			defer srv.Close()
+
+			want := newAlert(MapRootInvalid, 5)
+			err := NewWebhook(srv.URL, time.Second).Fire(ctx, want)
+			if got, want := err != nil, tc.wantErr; got != want {
+				t.Errorf("Fire(): %v, want err %v", err, want)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("POSTed %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestExec(t *testing.T) {
+	ctx := context.Background()
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+
+	want := newAlert(LogAppendFailed, 7)
+	sink := NewExec("sh", "-c", `cat > "$0"; echo "$KT_ALERT_KIND $KT_ALERT_DOMAIN $KT_ALERT_REVISION" > "$0.env"`, out)
+	if err := sink.Fire(ctx, want); err != nil {
+		t.Fatalf("Fire(): %v", err)
+	}
+	got, err := ReadFile(out)
+	if err != nil {
+		t.Fatalf("ReadFile(): %v", err)
+	}
+	if !reflect.DeepEqual(got, []*Alert{want}) {
+		t.Errorf("stdin: %v, want %v", got, want)
+	}
+	env, err := ioutil.ReadFile(out + ".env")
+	if err != nil {
+		t.Fatalf("ReadFile(): %v", err)
+	}
+	if got, want := strings.TrimSpace(string(env)), "log_append_failed domain 7"; got != want {
+		t.Errorf("env: %q, want %q", got, want)
+	}
+
+	if err := NewExec("sh", "-c", "exit 1").Fire(ctx, want); err == nil {
+		t.Errorf("Fire() with a failing command: nil, want error")
+	}
+}
+
+// sinkFunc adapts a function to a Sink.
+type sinkFunc func(ctx context.Context, a *Alert) error
+
+func (f sinkFunc) Fire(ctx context.Context, a *Alert) error { return f(ctx, a) }
+
+func TestMulti(t *testing.T) {
+	ctx := context.Background()
+	var calls int
+	ok := sinkFunc(func(context.Context, *Alert) error { calls++; return nil })
+	fail := sinkFunc(func(context.Context, *Alert) error { calls++; return errors.New("unreachable") })
+	for _, tc := range []struct {
+		desc    string
+		sinks   []Sink
+		wantErr bool
+	}{
+		{desc: "none"},
+		{desc: "all succeed", sinks: []Sink{ok, ok}},
+		{desc: "one fails", sinks: []Sink{fail, ok}, wantErr: true},
+		{desc: "all fail", sinks: []Sink{fail, fail}, wantErr: true},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			calls = 0
+			err := Multi(tc.sinks...).Fire(ctx, newAlert(MMDBlown, -1))
+			if got, want := err != nil, tc.wantErr; got != want {
+				t.Errorf("Fire(): %v, want err %v", err, want)
+			}
+			// Every sink is fired, even after one fails.
+			if got, want := calls, len(tc.sinks); got != want {
+				t.Errorf("Fire() fired %v sinks, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	ctx := context.Background()
+	hung := make(chan struct{})
+	defer close(hung)
+	for _, tc := range []struct {
+		desc    string
+		sink    Sink
+		wantErr bool
+	}{
+		{desc: "delivered", sink: sinkFunc(func(context.Context, *Alert) error { return nil })},
+		{desc: "failed", sink: sinkFunc(func(context.Context, *Alert) error { return errors.New("unreachable") }), wantErr: true},
+		{desc: "cancelled", sink: sinkFunc(func(ctx context.Context, _ *Alert) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}), wantErr: true},
+		{desc: "ignores the context", sink: sinkFunc(func(context.Context, *Alert) error {
+			<-hung
+			return nil
+		}), wantErr: true},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			start := time.Now()
+			err := WithTimeout(tc.sink, 50*time.Millisecond).Fire(ctx, newAlert(MMDBlown, -1))
+			if got, want := err != nil, tc.wantErr; got != want {
+				t.Errorf("Fire(): %v, want err %v", err, want)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("Fire() took %v, want it bounded by the timeout", elapsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package election decides which of several sequencer replicas is allowed to
+// sequence a domain.
+package election
+
+import (
+	"context"
+)
+
+// Election tracks mastership of a single resource, such as a domain.
+type Election interface {
+	// Campaign tries to acquire mastership, or to extend it if this instance
+	// is already the master. Campaign returns whether this instance is the
+	// master. Campaign must be called periodically to retain mastership.
+	Campaign(ctx context.Context) (bool, error)
+	// IsMaster returns whether this instance currently holds mastership.
+	IsMaster(ctx context.Context) (bool, error)
+	// Resign gives up mastership so that another instance can take over
+	// without waiting for the current mastership to expire.
+	Resign(ctx context.Context) error
+}
+
+// Factory creates elections.
+type Factory interface {
+	// NewElection returns an election for resourceID.
+	NewElection(ctx context.Context, resourceID string) (Election, error)
+}
+
+// NoopFactory creates elections in which every instance is always the master.
+// NoopFactory is suitable when only a single sequencer replica runs.
+type NoopFactory struct{}
+
+// NewElection returns an election that is always won.
+func (NoopFactory) NewElection(context.Context, string) (Election, error) {
+	return noopElection{}, nil
+}
+
+type noopElection struct{}
+
+func (noopElection) Campaign(context.Context) (bool, error) { return true, nil }
+func (noopElection) IsMaster(context.Context) (bool, error) { return true, nil }
+func (noopElection) Resign(context.Context) error           { return nil }
@@ -0,0 +1,175 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sequencer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/keytransparency/core/domain"
+	"github.com/google/keytransparency/core/sequencer/election"
+
+	"github.com/golang/glog"
+
+	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
+	tpb "github.com/google/trillian"
+	tclient "github.com/google/trillian/client"
+)
+
+// reconcile finishes the epochs of d that were left incomplete, e.g. because
+// the sequencer crashed while creating them.
+//
+// Mutations are sequenced into a revision before the map revision is
+// created, so an epoch is incomplete when the highest sequenced revision is
+// missing from the map, or when its map root is missing from the log.
+// reconcile creates the missing map revision from the sequenced mutations.
+// For each revision missing from the log, it replays the saved mutations
+// against the previous revision to recover the changed leaves and the outcome
+// of each mutation, and then repeats the remaining steps of creating the epoch.
+func (s *Sequencer) reconcile(ctx context.Context, d *domain.Domain, e election.Election, log mapRootLog, mapVerifier *tclient.MapVerifier) error {
+	logRoot, err := log.UpdateRoot(ctx)
+	if err != nil {
+		return fmt.Errorf("UpdateRoot(%v): %v", d.LogID, err)
+	}
+	rootResp, err := s.tmap.GetSignedMapRoot(ctx, &tpb.GetSignedMapRootRequest{MapId: d.MapID})
+	if err != nil {
+		return fmt.Errorf("GetSignedMapRoot(%v): %v", d.MapID, err)
+	}
+	mapRoot, err := s.verifyMapRoot(ctx, d, mapVerifier, rootResp.GetMapRoot(), -1)
+	if err != nil {
+		return err
+	}
+	sequenced, err := s.mutations.HighestSequencedRevision(ctx, d.DomainID)
+	if err != nil {
+		return fmt.Errorf("HighestSequencedRevision(%v): %v", d.DomainID, err)
+	}
+
+	latest := int64(mapRoot.Revision)
+	switch {
+	case sequenced == latest+1:
+		glog.Warningf("Reconcile: creating map revision %v of domain %v", sequenced, d.DomainID)
+		if err := s.rollForward(ctx, d, e, mapVerifier, sequenced); err != nil {
+			return fmt.Errorf("creating revision %v of domain %v: %v", sequenced, d.DomainID, err)
+		}
+		latest = sequenced
+	case sequenced > latest+1:
+		return fmt.Errorf("revision %v of domain %v is sequenced, but map %v is at revision %v",
+			sequenced, d.DomainID, d.MapID, latest)
+	}
+
+	// The log holds the map roots of revisions [0, TreeSize).
+	next := int64(logRoot.TreeSize)
+	if next > latest+1 {
+		return fmt.Errorf("log %v has %v map roots, but map %v is at revision %v",
+			d.LogID, next, d.MapID, latest)
+	}
+	for rev := next; rev <= latest; rev++ {
+		glog.Warningf("Reconcile: finishing incomplete epoch %v of domain %v", rev, d.DomainID)
+		if err := s.recoverEpoch(ctx, d, e, log, mapVerifier, rev); err != nil {
+			return fmt.Errorf("recovering revision %v of domain %v: %v", rev, d.DomainID, err)
+		}
+	}
+	return nil
+}
+
+// rollForward creates map revision rev, the revision after the latest, from the
+// mutations sequenced into it.
+func (s *Sequencer) rollForward(ctx context.Context, d *domain.Domain, e election.Election, mapVerifier *tclient.MapVerifier, rev int64) error {
+	mutate, err := s.mutators.Get(d.Sequencing)
+	if err != nil {
+		return err
+	}
+	msgs, err := s.mutations.ReadBatch(ctx, d.DomainID, rev)
+	if err != nil {
+		return fmt.Errorf("ReadBatch(%v, %v): %v", d.DomainID, rev, err)
+	}
+	indexes := make([][]byte, 0, len(msgs))
+	for _, m := range msgs {
+		if m.ExtraData == nil {
+			return fmt.Errorf("mutation of index %x has no committed data", m.Mutation.GetIndex())
+		}
+		indexes = append(indexes, m.Mutation.GetIndex())
+	}
+	getResp, err := s.tmap.GetLeaves(ctx, &tpb.GetMapLeavesRequest{
+		MapId: d.MapID,
+		Index: indexes,
+	})
+	if err != nil {
+		return err
+	}
+	leaves := make([]*tpb.MapLeaf, 0, len(getResp.MapLeafInclusion))
+	for _, m := range getResp.MapLeafInclusion {
+		leaves = append(leaves, m.Leaf)
+	}
+	newLeaves, _, err := s.applyMutations(mutate, msgs, leaves)
+	if err != nil {
+		return err
+	}
+	_, err = s.setLeaves(ctx, d, e, mapVerifier, rev, newLeaves)
+	return err
+}
+
+// recoverEpoch finishes creating map revision rev from the mutations saved for it.
+func (s *Sequencer) recoverEpoch(ctx context.Context, d *domain.Domain, e election.Election, log mapRootLog, mapVerifier *tclient.MapVerifier, rev int64) error {
+	mutate, err := s.mutators.Get(d.Sequencing)
+	if err != nil {
+		return err
+	}
+	msgs, err := s.mutations.ReadBatch(ctx, d.DomainID, rev)
+	if err != nil {
+		return fmt.Errorf("ReadBatch(%v, %v): %v", d.DomainID, rev, err)
+	}
+	for _, m := range msgs {
+		if m.ExtraData == nil {
+			// Only the indexes of the new leaves are needed, and the
+			// map already holds their values.
+			m.ExtraData = &pb.Committed{}
+		}
+	}
+
+	// Replay the mutations against the leaves of the previous revision.
+	indexes := make([][]byte, 0, len(msgs))
+	for _, m := range msgs {
+		indexes = append(indexes, m.Mutation.GetIndex())
+	}
+	getResp, err := s.tmap.GetLeavesByRevision(ctx, &tpb.GetMapLeavesByRevisionRequest{
+		MapId:    d.MapID,
+		Index:    indexes,
+		Revision: rev - 1,
+	})
+	if err != nil {
+		return fmt.Errorf("GetLeavesByRevision(%v): %v", rev-1, err)
+	}
+	leaves := make([]*tpb.MapLeaf, 0, len(getResp.MapLeafInclusion))
+	for _, m := range getResp.MapLeafInclusion {
+		leaves = append(leaves, m.Leaf)
+	}
+	newLeaves, rejected, err := s.applyMutations(mutate, msgs, leaves)
+	if err != nil {
+		return err
+	}
+
+	rootResp, err := s.tmap.GetSignedMapRootByRevision(ctx, &tpb.GetSignedMapRootByRevisionRequest{
+		MapId:    d.MapID,
+		Revision: rev,
+	})
+	if err != nil {
+		return fmt.Errorf("GetSignedMapRootByRevision(%v): %v", rev, err)
+	}
+	if _, err := s.verifyMapRoot(ctx, d, mapVerifier, rootResp.GetMapRoot(), rev); err != nil {
+		return err
+	}
+	return s.finishEpoch(ctx, d, e, log, rev, rootResp.GetMapRoot(), msgs, newLeaves, rejected)
+}
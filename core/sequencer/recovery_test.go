@@ -0,0 +1,360 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sequencer
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/keytransparency/core/domain"
+	"github.com/google/keytransparency/core/fake"
+	"github.com/google/keytransparency/core/mutator"
+	"github.com/google/keytransparency/core/mutator/entry"
+	"github.com/google/trillian/types"
+	"google.golang.org/grpc"
+
+	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
+	tpb "github.com/google/trillian"
+	tclient "github.com/google/trillian/client"
+	tcrypto "github.com/google/trillian/crypto"
+)
+
+var errInjected = errors.New("injected fault")
+
+// alwaysMaster is an election this sequencer always holds.
+var alwaysMaster = &fakeElection{master: true}
+
+// expiringElection is held for the first n calls to IsMaster.
+type expiringElection struct {
+	fakeElection
+	n int
+}
+
+func (e *expiringElection) IsMaster(context.Context) (bool, error) {
+	e.n--
+	return e.n >= 0, nil
+}
+
+// fakeMap is an in-memory Trillian map that keeps every revision.
+// Unimplemented methods panic.
+type fakeMap struct {
+	tpb.TrillianMapClient
+	signer        *tcrypto.Signer
+	roots         []*tpb.SignedMapRoot
+	leaves        []map[string]*tpb.MapLeaf
+	failSetLeaves bool
+}
+
+func newFakeMap(t *testing.T) (*fakeMap, *tclient.MapVerifier) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey(): %v", err)
+	}
+	m := &fakeMap{signer: tcrypto.NewSHA256Signer(key)}
+	if err := m.commit(map[string]*tpb.MapLeaf{}); err != nil {
+		t.Fatalf("commit(): %v", err)
+	}
+	return m, &tclient.MapVerifier{MapPubKey: key.Public(), SigHash: crypto.SHA256}
+}
+
+// commit creates a new revision with leaves.
+func (m *fakeMap) commit(leaves map[string]*tpb.MapLeaf) error {
+	rev := len(m.roots)
+	rootHash := sha256.Sum256([]byte(fmt.Sprint(rev)))
+	smr, err := m.signer.SignMapRoot(&types.MapRootV1{RootHash: rootHash[:], Revision: uint64(rev)})
+	if err != nil {
+		return err
+	}
+	m.roots = append(m.roots, smr)
+	m.leaves = append(m.leaves, leaves)
+	return nil
+}
+
+func (m *fakeMap) getLeaves(rev int64, indexes [][]byte) *tpb.GetMapLeavesResponse {
+	resp := &tpb.GetMapLeavesResponse{}
+	for _, index := range indexes {
+		l, ok := m.leaves[rev][string(index)]
+		if !ok {
+			l = &tpb.MapLeaf{Index: index}
+		}
+		resp.MapLeafInclusion = append(resp.MapLeafInclusion, &tpb.MapLeafInclusion{Leaf: l})
+	}
+	return resp
+}
+
+func (m *fakeMap) GetSignedMapRoot(context.Context, *tpb.GetSignedMapRootRequest, ...grpc.CallOption) (*tpb.GetSignedMapRootResponse, error) {
+	return &tpb.GetSignedMapRootResponse{MapRoot: m.roots[len(m.roots)-1]}, nil
+}
+
+func (m *fakeMap) GetSignedMapRootByRevision(_ context.Context, in *tpb.GetSignedMapRootByRevisionRequest, _ ...grpc.CallOption) (*tpb.GetSignedMapRootResponse, error) {
+	return &tpb.GetSignedMapRootResponse{MapRoot: m.roots[in.Revision]}, nil
+}
+
+func (m *fakeMap) GetLeaves(_ context.Context, in *tpb.GetMapLeavesRequest, _ ...grpc.CallOption) (*tpb.GetMapLeavesResponse, error) {
+	return m.getLeaves(int64(len(m.roots)-1), in.Index), nil
+}
+
+func (m *fakeMap) GetLeavesByRevision(_ context.Context, in *tpb.GetMapLeavesByRevisionRequest, _ ...grpc.CallOption) (*tpb.GetMapLeavesResponse, error) {
+	return m.getLeaves(in.Revision, in.Index), nil
+}
+
+func (m *fakeMap) SetLeaves(_ context.Context, in *tpb.SetMapLeavesRequest, _ ...grpc.CallOption) (*tpb.SetMapLeavesResponse, error) {
+	if m.failSetLeaves {
+		return nil, errInjected
+	}
+	leaves := make(map[string]*tpb.MapLeaf)
+	for k, v := range m.leaves[len(m.leaves)-1] {
+		leaves[k] = v
+	}
+	for _, l := range in.Leaves {
+		leaves[string(l.Index)] = l
+	}
+	if err := m.commit(leaves); err != nil {
+		return nil, err
+	}
+	return &tpb.SetMapLeavesResponse{MapRoot: m.roots[len(m.roots)-1]}, nil
+}
+
+// fakeLog is an in-memory log of map roots.
+type fakeLog struct {
+	leaves  [][]byte
+	failAdd bool
+}
+
+func (l *fakeLog) UpdateRoot(context.Context) (*types.LogRootV1, error) {
+	return &types.LogRootV1{TreeSize: uint64(len(l.leaves))}, nil
+}
+
+func (l *fakeLog) AddSequencedLeafAndWait(_ context.Context, data []byte, index int64) error {
+	if l.failAdd {
+		return errInjected
+	}
+	if index < int64(len(l.leaves)) {
+		if !bytes.Equal(l.leaves[index], data) {
+			return fmt.Errorf("leaf %v already set to different data", index)
+		}
+		return nil
+	}
+	if index != int64(len(l.leaves)) {
+		return fmt.Errorf("leaf %v added to log of size %v", index, len(l.leaves))
+	}
+	l.leaves = append(l.leaves, data)
+	return nil
+}
+
+// faultyMutations fails the selected writes. It models the queue as a list
+// of messages that SequenceBatch removes.
+type faultyMutations struct {
+	*fake.MutationStorage
+	queue                 []*mutator.QueueMessage
+	failSequenceBatch     bool
+	failWriteIndexChanges bool
+	failWriteStatus       bool
+}
+
+func (m *faultyMutations) SequenceBatch(ctx context.Context, domainID string, revision int64, msgs []*mutator.QueueMessage) error {
+	if m.failSequenceBatch {
+		return errInjected
+	}
+	if err := m.MutationStorage.SequenceBatch(ctx, domainID, revision, msgs); err != nil {
+		return err
+	}
+	m.queue = m.queue[len(msgs):]
+	return nil
+}
+
+func (m *faultyMutations) WriteIndexChanges(ctx context.Context, domainID string, revision int64, indexes [][]byte) error {
+	if m.failWriteIndexChanges {
+		return errInjected
+	}
+	return m.MutationStorage.WriteIndexChanges(ctx, domainID, revision, indexes)
+}
+
+func (m *faultyMutations) WriteStatus(ctx context.Context, domainID string, hash []byte, s *pb.MutationStatus) error {
+	if m.failWriteStatus {
+		return errInjected
+	}
+	return m.MutationStorage.WriteStatus(ctx, domainID, hash, s)
+}
+
+// TestReconcile injects a fault at each step of creating an epoch, then
+// reconciles and receives what is left in the queue, as a restarted sequencer
+// would.
+func TestReconcile(t *testing.T) {
+	ctx := context.Background()
+	d := &domain.Domain{DomainID: "domain", MapID: 1, LogID: 2}
+	index1, index2 := []byte("index1"), []byte("index2")
+
+	for _, tc := range []struct {
+		desc    string
+		fault   func(tmap *fakeMap, log *fakeLog, mutations *faultyMutations)
+		wantErr bool
+	}{
+		{desc: "no fault", fault: func(*fakeMap, *fakeLog, *faultyMutations) {}},
+		{desc: "SequenceBatch", fault: func(_ *fakeMap, _ *fakeLog, m *faultyMutations) { m.failSequenceBatch = true }, wantErr: true},
+		{desc: "SetLeaves", fault: func(tmap *fakeMap, _ *fakeLog, _ *faultyMutations) { tmap.failSetLeaves = true }, wantErr: true},
+		{desc: "WriteIndexChanges", fault: func(_ *fakeMap, _ *fakeLog, m *faultyMutations) { m.failWriteIndexChanges = true }, wantErr: true},
+		{desc: "WriteStatus", fault: func(_ *fakeMap, _ *fakeLog, m *faultyMutations) { m.failWriteStatus = true }, wantErr: true},
+		{desc: "AddSequencedLeaf", fault: func(_ *fakeMap, log *fakeLog, _ *faultyMutations) { log.failAdd = true }, wantErr: true},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			tmap, mapVerifier := newFakeMap(t)
+			log := &fakeLog{leaves: [][]byte{tmap.roots[0].GetMapRoot()}}
+			msgs := []*mutator.QueueMessage{
+				{ID: 1, Mutation: &pb.Entry{Index: index1, Commitment: []byte("a")}, ExtraData: &pb.Committed{}},
+				{ID: 2, Mutation: &pb.Entry{Index: index2, Commitment: []byte("bad")}, ExtraData: &pb.Committed{}},
+				{ID: 3, Mutation: &pb.Entry{Index: index1, Previous: []byte("a"), Commitment: []byte("b")}, ExtraData: &pb.Committed{}},
+			}
+			mutations := &faultyMutations{MutationStorage: fake.NewMutationStorage(), queue: msgs}
+			s := &Sequencer{tmap: tmap, mutators: fakeMutators, mutations: mutations, lastEpoch: make(map[string]time.Time)}
+
+			tc.fault(tmap, log, mutations)
+			err := s.createEpoch(ctx, d, alwaysMaster, log, mapVerifier, mutations.queue)
+			if got := err != nil; got != tc.wantErr {
+				t.Fatalf("createEpoch(): %v, want err: %v", err, tc.wantErr)
+			}
+
+			// Restart: clear the fault, reconcile, and receive what
+			// is left in the queue.
+			tmap.failSetLeaves = false
+			log.failAdd = false
+			mutations.failSequenceBatch = false
+			mutations.failWriteIndexChanges = false
+			mutations.failWriteStatus = false
+			if err := s.reconcile(ctx, d, alwaysMaster, log, mapVerifier); err != nil {
+				t.Fatalf("reconcile(): %v", err)
+			}
+			if err := s.createEpoch(ctx, d, alwaysMaster, log, mapVerifier, mutations.queue); err != nil {
+				t.Fatalf("createEpoch(): %v", err)
+			}
+
+			// The log holds every map root.
+			if got, want := len(log.leaves), len(tmap.roots); got != want {
+				t.Fatalf("log size: %v, want %v", got, want)
+			}
+			for i, root := range tmap.roots {
+				if !bytes.Equal(log.leaves[i], root.GetMapRoot()) {
+					t.Errorf("log leaf %v is not map root %v", i, i)
+				}
+			}
+			// Every mutation was applied exactly once, in revision 1.
+			_, stored, err := mutations.ReadPage(ctx, d.DomainID, 1, 0, MaxBatchSize)
+			if err != nil {
+				t.Fatalf("ReadPage(): %v", err)
+			}
+			if got, want := len(stored), len(msgs); got != want {
+				t.Errorf("ReadPage(): %v mutations, want %v", got, want)
+			}
+			for i, want := range []pb.MutationStatus_State{
+				pb.MutationStatus_APPLIED,
+				pb.MutationStatus_REJECTED,
+				pb.MutationStatus_APPLIED,
+			} {
+				hash, err := entry.Hash(msgs[i].Mutation)
+				if err != nil {
+					t.Fatalf("entry.Hash(): %v", err)
+				}
+				got, err := mutations.ReadStatus(ctx, d.DomainID, hash)
+				if err != nil {
+					t.Fatalf("ReadStatus(%v): %v", i, err)
+				}
+				if got.GetState() != want || got.GetEpoch() != 1 {
+					t.Errorf("ReadStatus(%v): %v, want %v in epoch 1", i, got, want)
+				}
+			}
+			revs, err := mutations.ListIndexChanges(ctx, d.DomainID, index1, 0, 10, 10)
+			if err != nil {
+				t.Fatalf("ListIndexChanges(): %v", err)
+			}
+			if want := []int64{1}; !reflect.DeepEqual(revs, want) {
+				t.Errorf("ListIndexChanges(): %v, want %v", revs, want)
+			}
+			value, err := entry.FromLeafValue(tmap.getLeaves(int64(len(tmap.roots)-1), [][]byte{index1}).MapLeafInclusion[0].Leaf.LeafValue)
+			if err != nil {
+				t.Fatalf("FromLeafValue(): %v", err)
+			}
+			if got, want := string(value.GetCommitment()), "b"; got != want {
+				t.Errorf("leaf commitment: %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// TestCreateEpochLosesMastership verifies that a sequencer that loses
+// mastership while creating an epoch stops writing to the map and the log, and
+// that the next master finishes the epoch.
+func TestCreateEpochLosesMastership(t *testing.T) {
+	ctx := context.Background()
+	d := &domain.Domain{DomainID: "domain", MapID: 1, LogID: 2}
+	for _, tc := range []struct {
+		desc      string
+		checks    int
+		wantRoots int
+	}{
+		{desc: "before SetLeaves", checks: 0, wantRoots: 1},
+		{desc: "before AddSequencedLeaf", checks: 1, wantRoots: 2},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			tmap, mapVerifier := newFakeMap(t)
+			log := &fakeLog{leaves: [][]byte{tmap.roots[0].GetMapRoot()}}
+			msgs := []*mutator.QueueMessage{
+				{ID: 1, Mutation: &pb.Entry{Index: []byte("index1"), Commitment: []byte("a")}, ExtraData: &pb.Committed{}},
+			}
+			mutations := &faultyMutations{MutationStorage: fake.NewMutationStorage(), queue: msgs}
+			s := &Sequencer{tmap: tmap, mutators: fakeMutators, mutations: mutations, lastEpoch: make(map[string]time.Time)}
+
+			old := &expiringElection{n: tc.checks}
+			if err := s.createEpoch(ctx, d, old, log, mapVerifier, mutations.queue); err != errNotMaster {
+				t.Fatalf("createEpoch(): %v, want %v", err, errNotMaster)
+			}
+			if got := len(tmap.roots); got != tc.wantRoots {
+				t.Errorf("map has %v revisions, want %v", got, tc.wantRoots)
+			}
+			if got := len(log.leaves); got != 1 {
+				t.Errorf("log has %v map roots, want 1", got)
+			}
+
+			// The next master finishes the epoch.
+			if err := s.reconcile(ctx, d, alwaysMaster, log, mapVerifier); err != nil {
+				t.Fatalf("reconcile(): %v", err)
+			}
+			if got, want := len(log.leaves), 2; got != want {
+				t.Errorf("log has %v map roots after reconcile, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestReconcileLogAhead(t *testing.T) {
+	ctx := context.Background()
+	d := &domain.Domain{DomainID: "domain", MapID: 1, LogID: 2}
+	tmap, mapVerifier := newFakeMap(t)
+	log := &fakeLog{leaves: [][]byte{tmap.roots[0].GetMapRoot(), []byte("unknown")}}
+	s := &Sequencer{tmap: tmap, mutators: fakeMutators, mutations: fake.NewMutationStorage(), lastEpoch: make(map[string]time.Time)}
+	if err := s.reconcile(ctx, d, alwaysMaster, log, mapVerifier); err == nil {
+		t.Errorf("reconcile(): nil, want error when the log is ahead of the map")
+	}
+}
@@ -17,13 +17,17 @@ package sequencer
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
+	"sync"
 	"time"
 
 	"github.com/google/keytransparency/core/domain"
 	"github.com/google/keytransparency/core/mutator"
 	"github.com/google/keytransparency/core/mutator/entry"
+	"github.com/google/keytransparency/core/sequencer/alert"
+	"github.com/google/keytransparency/core/sequencer/election"
 
 	"github.com/golang/glog"
 	"github.com/golang/protobuf/proto"
@@ -32,82 +36,159 @@ import (
 	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
 	tpb "github.com/google/trillian"
 	tclient "github.com/google/trillian/client"
+	"github.com/google/trillian/types"
 )
 
 var (
-	mutationsCTR = prometheus.NewCounter(prometheus.CounterOpts{
+	mutationsCTR = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name: "kt_signer_mutations",
 		Help: "Number of mutations the signer has processed.",
-	})
-	indexCTR = prometheus.NewCounter(prometheus.CounterOpts{
+	}, []string{"domain"})
+	indexCTR = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name: "kt_signer_mutations_unique",
 		Help: "Number of mutations the signer has processed post per epoch dedupe.",
-	})
-	mapUpdateHist = prometheus.NewHistogram(prometheus.HistogramOpts{
+	}, []string{"domain"})
+	rejectedCTR = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kt_signer_mutations_rejected",
+		Help: "Number of mutations the signer has rejected, by reason.",
+	}, []string{"domain", "reason"})
+	mapUpdateHist = prometheus.NewHistogramVec(prometheus.HistogramOpts{
 		Name:    "kt_signer_map_update_seconds",
 		Help:    "Seconds waiting for map update",
 		Buckets: []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, math.Inf(1)},
-	})
-	createEpochHist = prometheus.NewHistogram(prometheus.HistogramOpts{
+	}, []string{"domain"})
+	createEpochHist = prometheus.NewHistogramVec(prometheus.HistogramOpts{
 		Name:    "kt_signer_create_epoch_seconds",
 		Help:    "Seconds spent generating epoch",
 		Buckets: []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, math.Inf(1)},
-	})
+	}, []string{"domain"})
+	masterGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kt_signer_is_master",
+		Help: "Set to 1 while this signer is the master for a domain, 0 otherwise.",
+	}, []string{"domain"})
+	queueDepthGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kt_signer_queue_depth",
+		Help: "Number of mutations waiting in the queue of a domain.",
+	}, []string{"domain"})
+	queueAgeGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kt_signer_queue_oldest_age_seconds",
+		Help: "Seconds since the oldest mutation in the queue of a domain was sent, 0 if the queue is empty.",
+	}, []string{"domain"})
+	epochAgeGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kt_signer_seconds_since_epoch",
+		Help: "Seconds since the latest epoch of a domain was created.",
+	}, []string{"domain"})
+	mmdViolations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kt_signer_mmd_violations",
+		Help: "Number of times a receiver went longer than the domain's max interval without an epoch.",
+	}, []string{"domain"})
 )
 
-// MaxBatchSize limits the number of mutations that will be processed per epoch.
-const MaxBatchSize = int32(1000)
+// MaxBatchSize limits the number of mutations that will be processed per epoch
+// in domains that do not configure a max batch size.
+const MaxBatchSize = domain.DefaultMaxBatchSize
+
+// errNotMaster occurs when a receiver of a domain tries to create an epoch
+// after this sequencer has lost mastership of the domain.
+var errNotMaster = errors.New("sequencer: not the master for this domain")
 
 func init() {
 	prometheus.MustRegister(mutationsCTR)
 	prometheus.MustRegister(indexCTR)
+	prometheus.MustRegister(rejectedCTR)
 	prometheus.MustRegister(mapUpdateHist)
 	prometheus.MustRegister(createEpochHist)
+	prometheus.MustRegister(masterGauge)
+	prometheus.MustRegister(queueDepthGauge)
+	prometheus.MustRegister(queueAgeGauge)
+	prometheus.MustRegister(epochAgeGauge)
+	prometheus.MustRegister(mmdViolations)
+}
+
+// mapRootLog appends signed map roots to the log of a domain.
+// It is implemented by *tclient.LogClient.
+type mapRootLog interface {
+	// UpdateRoot returns the latest verified root of the log.
+	UpdateRoot(ctx context.Context) (*types.LogRootV1, error)
+	// AddSequencedLeafAndWait adds data to the log at index and waits for
+	// it to be integrated.
+	AddSequencedLeafAndWait(ctx context.Context, data []byte, index int64) error
 }
 
 // Sequencer processes mutations and sends them to the trillian map.
 type Sequencer struct {
-	domains     domain.Storage
-	logAdmin    tpb.TrillianAdminClient
-	tlog        tpb.TrillianLogClient
-	mapAdmin    tpb.TrillianAdminClient
-	tmap        tpb.TrillianMapClient
-	mutatorFunc mutator.Func
-	mutations   mutator.MutationStorage
-	queue       mutator.MutationQueue
-	receivers   map[string]mutator.Receiver
+	domains   domain.Storage
+	logAdmin  tpb.TrillianAdminClient
+	tlog      tpb.TrillianLogClient
+	mapAdmin  tpb.TrillianAdminClient
+	tmap      tpb.TrillianMapClient
+	mutators  mutator.Registry
+	mutations mutator.MutationStorage
+	queue     mutator.MutationQueue
+	receivers map[string]mutator.Receiver
+	// newReceiver starts the receiver of a domain. It is NewReceiver,
+	// except in tests.
+	newReceiver func(ctx context.Context, d *domain.Domain) (mutator.Receiver, error)
+	// running holds the configuration each receiver was started with.
+	running     map[string]*domain.Domain
+	electionFac election.Factory
+	elections   map[string]election.Election
+	alerts      alert.Sink
+	// epochMu guards lastEpoch, the time of the latest epoch of each
+	// domain with a running receiver.
+	epochMu   sync.Mutex
+	lastEpoch map[string]time.Time
 }
 
 // New creates a new instance of the signer.
+// Emergency alerts are fired to alerts. If alerts is nil, they are only logged.
 func New(tlog tpb.TrillianLogClient,
 	logAdmin tpb.TrillianAdminClient,
 	tmap tpb.TrillianMapClient,
 	mapAdmin tpb.TrillianAdminClient,
-	mutatorFunc mutator.Func,
+	mutators mutator.Registry,
 	domains domain.Storage,
 	mutations mutator.MutationStorage,
-	queue mutator.MutationQueue) *Sequencer {
-	return &Sequencer{
+	queue mutator.MutationQueue,
+	electionFac election.Factory,
+	alerts alert.Sink) *Sequencer {
+	s := &Sequencer{
 		domains:     domains,
 		tlog:        tlog,
 		logAdmin:    logAdmin,
 		tmap:        tmap,
 		mapAdmin:    mapAdmin,
-		mutatorFunc: mutatorFunc,
+		mutators:    mutators,
 		mutations:   mutations,
 		queue:       queue,
 		receivers:   make(map[string]mutator.Receiver),
+		running:     make(map[string]*domain.Domain),
+		electionFac: electionFac,
+		elections:   make(map[string]election.Election),
+		alerts:      alerts,
+		lastEpoch:   make(map[string]time.Time),
 	}
+	s.newReceiver = s.NewReceiver
+	return s
 }
 
-// Close stops all receivers and releases resources.
+// Close stops all receivers, resigns mastership, and releases resources.
 func (s *Sequencer) Close() {
 	for _, r := range s.receivers {
 		r.Close()
 	}
+	for domainID, e := range s.elections {
+		if err := e.Resign(context.Background()); err != nil {
+			glog.Errorf("Resign(%v): %v", domainID, err)
+		}
+	}
 }
 
-// ListenForNewDomains starts receivers for all domains and periodically checks for new domains.
+// ListenForNewDomains starts receivers for all domains and periodically checks
+// for changes to the list of domains. Receivers are stopped when their domain
+// is deleted, and restarted when their domain is undeleted or its epoch
+// intervals change. A domain whose receiver fails to start is retried at the
+// next refresh, without affecting the other domains.
 func (s *Sequencer) ListenForNewDomains(ctx context.Context, refresh time.Duration) error {
 	ticker := time.NewTicker(refresh)
 	defer func() { ticker.Stop() }()
@@ -119,25 +200,182 @@ func (s *Sequencer) ListenForNewDomains(ctx context.Context, refresh time.Durati
 			if err != nil {
 				return fmt.Errorf("admin.List(): %v", err)
 			}
-			for _, d := range domains {
-				if _, ok := s.receivers[d.DomainID]; !ok {
-					glog.Infof("StartSigning domain: %v", d.DomainID)
-					r, err := s.NewReceiver(ctx, d)
-					if err != nil {
-						return err
-					}
-					s.receivers[d.DomainID] = r
-				}
-			}
+			s.updateDomains(ctx, domains)
+			s.updateMetrics(ctx, time.Now())
 		case <-ctx.Done():
 			return ctx.Err()
 		}
 	}
 }
 
+// updateDomains reconciles the running receivers with domains, the list of
+// domains that are not deleted. Errors are handled per domain, so that a
+// domain that can't be sequenced does not stop the others.
+func (s *Sequencer) updateDomains(ctx context.Context, domains []*domain.Domain) {
+	active := make(map[string]bool)
+	for _, d := range domains {
+		active[d.DomainID] = true
+	}
+	for domainID := range s.elections {
+		if !active[domainID] {
+			glog.Infof("Domain %v deleted", domainID)
+			s.stopDomain(ctx, domainID)
+		}
+	}
+	for _, d := range domains {
+		if err := s.updateMastership(ctx, d); err != nil {
+			glog.Errorf("updateMastership(%v): %v", d.DomainID, err)
+		}
+	}
+}
+
+// stopDomain stops the receiver of domainID and resigns mastership of it.
+func (s *Sequencer) stopDomain(ctx context.Context, domainID string) {
+	s.stopReceiver(domainID)
+	if e, ok := s.elections[domainID]; ok {
+		if err := e.Resign(ctx); err != nil {
+			glog.Errorf("Resign(%v): %v", domainID, err)
+		}
+		delete(s.elections, domainID)
+	}
+	masterGauge.WithLabelValues(domainID).Set(0)
+}
+
+// stopReceiver stops the receiver of domainID, if it is running.
+func (s *Sequencer) stopReceiver(domainID string) {
+	if r, ok := s.receivers[domainID]; ok {
+		glog.Infof("StopSigning domain: %v", domainID)
+		r.Close()
+		delete(s.receivers, domainID)
+		delete(s.running, domainID)
+		s.epochMu.Lock()
+		delete(s.lastEpoch, domainID)
+		s.epochMu.Unlock()
+		queueDepthGauge.DeleteLabelValues(domainID)
+		queueAgeGauge.DeleteLabelValues(domainID)
+		epochAgeGauge.DeleteLabelValues(domainID)
+	}
+}
+
+// setLastEpoch records t as the time of the latest epoch of domainID.
+func (s *Sequencer) setLastEpoch(domainID string, t time.Time) {
+	s.epochMu.Lock()
+	defer s.epochMu.Unlock()
+	s.lastEpoch[domainID] = t
+}
+
+// updateMetrics sets the queue and epoch gauges of the domains this sequencer
+// is running receivers for.
+func (s *Sequencer) updateMetrics(ctx context.Context, now time.Time) {
+	for domainID := range s.receivers {
+		stats, err := s.queue.Stats(ctx, domainID)
+		if err != nil {
+			glog.Errorf("queue.Stats(%v): %v", domainID, err)
+		} else {
+			queueDepthGauge.WithLabelValues(domainID).Set(float64(stats.Depth))
+			queueAgeGauge.WithLabelValues(domainID).Set(secondsSince(now, stats.Oldest))
+		}
+		s.epochMu.Lock()
+		last, ok := s.lastEpoch[domainID]
+		s.epochMu.Unlock()
+		if ok {
+			epochAgeGauge.WithLabelValues(domainID).Set(secondsSince(now, last))
+		}
+	}
+}
+
+// secondsSince returns the number of seconds from t to now, or 0 if t is the
+// zero time.
+func secondsSince(now, t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return now.Sub(t).Seconds()
+}
+
+// receiverChanged returns true if a receiver started for old must be
+// restarted to apply the configuration of d. The sequencing config of a
+// domain never changes, so it is not compared.
+func receiverChanged(old, d *domain.Domain) bool {
+	return old.MapID != d.MapID ||
+		old.LogID != d.LogID ||
+		old.MinInterval != d.MinInterval ||
+		old.MaxInterval != d.MaxInterval
+}
+
+// election returns the election for domainID, creating it if needed.
+func (s *Sequencer) election(ctx context.Context, domainID string) (election.Election, error) {
+	if e, ok := s.elections[domainID]; ok {
+		return e, nil
+	}
+	e, err := s.electionFac.NewElection(ctx, domainID)
+	if err != nil {
+		return nil, fmt.Errorf("NewElection(%v): %v", domainID, err)
+	}
+	s.elections[domainID] = e
+	return e, nil
+}
+
+// updateMastership campaigns for mastership of d. It starts a receiver for d
+// when this sequencer becomes the master, and stops it when mastership is lost.
+// A running receiver is restarted if the configuration of d has changed.
+//
+// If the receiver fails to start, for instance because an incomplete epoch
+// can't be reconciled, an alert is fired and d is left without a receiver,
+// so that the next call tries again.
+func (s *Sequencer) updateMastership(ctx context.Context, d *domain.Domain) error {
+	e, err := s.election(ctx, d.DomainID)
+	if err != nil {
+		return err
+	}
+	master, err := e.Campaign(ctx)
+	if err != nil {
+		// Mastership can't be renewed, so it will expire soon.
+		glog.Errorf("Campaign(%v): %v", d.DomainID, err)
+		master = false
+	}
+	if master {
+		masterGauge.WithLabelValues(d.DomainID).Set(1)
+	} else {
+		masterGauge.WithLabelValues(d.DomainID).Set(0)
+	}
+
+	if !master {
+		s.stopReceiver(d.DomainID)
+		return nil
+	}
+	if _, ok := s.receivers[d.DomainID]; ok {
+		old, ok := s.running[d.DomainID]
+		if ok && !receiverChanged(old, d) {
+			return nil
+		}
+		glog.Infof("Domain %v changed, restarting", d.DomainID)
+		s.stopReceiver(d.DomainID)
+	}
+	glog.Infof("StartSigning domain: %v", d.DomainID)
+	r, err := s.newReceiver(ctx, d)
+	if err != nil {
+		s.fireAlert(ctx, &alert.Alert{
+			Kind:     alert.ReceiverFailed,
+			DomainID: d.DomainID,
+			Revision: -1,
+			Error:    err.Error(),
+		})
+		return fmt.Errorf("NewReceiver(%v): %v", d.DomainID, err)
+	}
+	s.receivers[d.DomainID] = r
+	s.running[d.DomainID] = d
+	return nil
+}
+
 // NewReceiver creates a new receiver for a domain.
 // New epochs will be created at least once per maxInterval and as often as minInterval.
+// Epochs are only created while this sequencer is the master for the domain.
 func (s *Sequencer) NewReceiver(ctx context.Context, d *domain.Domain) (mutator.Receiver, error) {
+	e, err := s.election(ctx, d.DomainID)
+	if err != nil {
+		return nil, err
+	}
 	cctx, cancel := context.WithTimeout(ctx, d.MinInterval)
 	defer cancel()
 	mapTree, err := s.mapAdmin.GetTree(cctx, &tpb.GetTreeRequest{TreeId: d.MapID})
@@ -155,11 +393,12 @@ func (s *Sequencer) NewReceiver(ctx context.Context, d *domain.Domain) (mutator.
 	}
 	cancel()
 	// Fetch last time from previous map head (as stored in the map server)
-	mapRoot, err := mapVerifier.VerifySignedMapRoot(rootResp.GetMapRoot())
+	mapRoot, err := s.verifyMapRoot(ctx, d, mapVerifier, rootResp.GetMapRoot(), -1)
 	if err != nil {
 		return nil, err
 	}
 	last := time.Unix(0, int64(mapRoot.TimestampNanos))
+	s.setLastEpoch(d.DomainID, last)
 
 	logTree, err := s.logAdmin.GetTree(ctx, &tpb.GetTreeRequest{TreeId: d.LogID})
 	if err != nil {
@@ -170,15 +409,68 @@ func (s *Sequencer) NewReceiver(ctx context.Context, d *domain.Domain) (mutator.
 		return nil, err
 	}
 
+	// Finish any epoch that a previous master left incomplete before
+	// sequencing resumes.
+	if err := s.reconcile(ctx, d, e, logClient, mapVerifier); err != nil {
+		return nil, err
+	}
+	reconciled := true
+
 	return s.queue.NewReceiver(ctx, last, d.DomainID, func(mutations []*mutator.QueueMessage) error {
-		return s.createEpoch(ctx, d, logClient, mapVerifier, mutations)
+		// Check mastership right before sequencing, since it may have
+		// expired since the last campaign.
+		if err := checkMaster(ctx, e); err != nil {
+			return err
+		}
+		if !reconciled {
+			if err := s.reconcile(ctx, d, e, logClient, mapVerifier); err != nil {
+				return err
+			}
+			reconciled = true
+		}
+		if err := s.createEpoch(ctx, d, e, logClient, mapVerifier, mutations); err != nil {
+			// The epoch may be incomplete.
+			reconciled = false
+			return err
+		}
+		return nil
 	}, mutator.ReceiverOptions{
-		MaxBatchSize: MaxBatchSize,
+		MaxBatchSize: d.BatchSize(),
 		Period:       d.MinInterval,
 		MaxPeriod:    d.MaxInterval,
+		// A batch that takes longer than MaxInterval to sequence has
+		// blown the MMD anyway, so it is delivered again.
+		ClaimTimeout: d.MaxInterval,
+		MMDBlown: func(last time.Time) {
+			mmdViolations.WithLabelValues(d.DomainID).Inc()
+			s.fireAlert(ctx, &alert.Alert{
+				Kind:     alert.MMDBlown,
+				DomainID: d.DomainID,
+				Revision: -1,
+				Details: map[string]string{
+					"last_epoch":   last.Format(time.RFC3339Nano),
+					"max_interval": d.MaxInterval.String(),
+				},
+			})
+		},
 	}), nil
 }
 
+// checkMaster returns errNotMaster unless this sequencer holds mastership in e.
+// Mastership can expire at any time, so it is checked before each write that
+// a newer master could conflict with. Sequenced batches are also fenced by
+// SequenceBatch.
+func checkMaster(ctx context.Context, e election.Election) error {
+	master, err := e.IsMaster(ctx)
+	if err != nil {
+		return err
+	}
+	if !master {
+		return errNotMaster
+	}
+	return nil
+}
+
 // toArray returns the first 32 bytes from b.
 // If b is less than 32 bytes long, the output is zero padded.
 func toArray(b []byte) [32]byte {
@@ -187,11 +479,19 @@ func toArray(b []byte) [32]byte {
 	return i
 }
 
-// applyMutations takes the set of mutations and applies them to given leafs.
-// Multiple mutations for the same leaf will be applied to provided leaf.
-// The last valid mutation for each leaf is included in the output.
-// Returns a list of map leaves that should be updated.
-func (s *Sequencer) applyMutations(mutations []*mutator.QueueMessage, leaves []*tpb.MapLeaf) ([]*tpb.MapLeaf, error) {
+// applyMutations takes the set of mutations and applies them with mutate to
+// given leafs.
+//
+// Mutations for the same leaf are applied in queue order, each against the
+// result of the last valid mutation before it, so that a chain of updates to
+// one leaf can be sequenced in a single epoch. An invalid mutation leaves the
+// leaf unchanged. When several mutations build on the same value, only the
+// first of them in queue order is applied; the others no longer match the
+// leaf's previous hash and are rejected.
+//
+// Returns a list of map leaves that should be updated, and the reason each
+// mutation was rejected, or nil if it was applied.
+func (s *Sequencer) applyMutations(mutate mutator.Func, mutations []*mutator.QueueMessage, leaves []*tpb.MapLeaf) ([]*tpb.MapLeaf, []error, error) {
 	// Put leaves in a map from index to leaf value.
 	leafMap := make(map[[32]byte]*tpb.MapLeaf)
 	for _, l := range leaves {
@@ -199,26 +499,40 @@ func (s *Sequencer) applyMutations(mutations []*mutator.QueueMessage, leaves []*
 	}
 
 	retMap := make(map[[32]byte]*tpb.MapLeaf)
-	for _, m := range mutations {
+	values := make(map[[32]byte]*pb.Entry) // The latest value of each leaf in retMap.
+	rejected := make([]error, len(mutations))
+	for i, m := range mutations {
 		index := m.Mutation.GetIndex()
-		var oldValue *pb.Entry // If no map leaf was found, oldValue will be nil.
-		if leaf, ok := leafMap[toArray(index)]; ok {
-			var err error
-			oldValue, err = entry.FromLeafValue(leaf.GetLeafValue())
-			if err != nil {
-				glog.Warningf("entry.FromLeafValue(%v): %v", leaf.GetLeafValue(), err)
-				continue
+		oldValue, ok := values[toArray(index)]
+		if !ok {
+			// If no map leaf was found, oldValue will be nil.
+			if leaf, ok := leafMap[toArray(index)]; ok {
+				var err error
+				oldValue, err = entry.FromLeafValue(leaf.GetLeafValue())
+				if err != nil {
+					glog.Warningf("entry.FromLeafValue(%v): %v", leaf.GetLeafValue(), err)
+					rejected[i] = err
+					continue
+				}
 			}
 		}
 
-		newValue, err := s.mutatorFunc.Mutate(oldValue, m.Mutation)
+		newValue, err := mutate.Mutate(oldValue, m.Mutation)
 		if err != nil {
 			glog.Warningf("Mutate(): %v", err)
+			rejected[i] = err
 			continue // A bad mutation should not make the whole batch fail.
 		}
-		leafValue, err := entry.ToLeafValue(newValue)
+		newEntry, ok := newValue.(*pb.Entry)
+		if !ok {
+			glog.Warningf("Mutate(): %T is not an Entry", newValue)
+			rejected[i] = fmt.Errorf("mutation: result is not an Entry")
+			continue
+		}
+		leafValue, err := entry.ToLeafValue(newEntry)
 		if err != nil {
 			glog.Warningf("ToLeafValue(): %v", err)
+			rejected[i] = err
 			continue
 		}
 
@@ -226,9 +540,11 @@ func (s *Sequencer) applyMutations(mutations []*mutator.QueueMessage, leaves []*
 		extraData, err := proto.Marshal(m.ExtraData)
 		if err != nil {
 			glog.Warningf("Marshal(committed proto): %v", err)
+			rejected[i] = err
 			continue
 		}
 
+		values[toArray(index)] = newEntry
 		retMap[toArray(index)] = &tpb.MapLeaf{
 			Index:     index,
 			LeafValue: leafValue,
@@ -240,23 +556,119 @@ func (s *Sequencer) applyMutations(mutations []*mutator.QueueMessage, leaves []*
 	for _, v := range retMap {
 		ret = append(ret, v)
 	}
-	return ret, nil
+	return ret, rejected, nil
+}
+
+// writeStatuses records whether each mutation was applied or rejected in
+// revision. rejected contains the reason each mutation was rejected, or nil.
+func (s *Sequencer) writeStatuses(ctx context.Context, domainID string, revision int64, msgs []*mutator.QueueMessage, rejected []error) error {
+	for i, m := range msgs {
+		hash, err := entry.Hash(m.Mutation)
+		if err != nil {
+			glog.Errorf("entry.Hash(): %v", err)
+			continue
+		}
+		status := &pb.MutationStatus{State: pb.MutationStatus_APPLIED, Epoch: revision}
+		if err := rejected[i]; err != nil {
+			status = &pb.MutationStatus{State: pb.MutationStatus_REJECTED, Epoch: revision, Error: err.Error()}
+		}
+		if err := s.mutations.WriteStatus(ctx, domainID, hash, status); err != nil {
+			return fmt.Errorf("WriteStatus(%v, %x): %v", domainID, hash, err)
+		}
+	}
+	return nil
+}
+
+// rejectReason returns a short label that describes why a mutation was
+// rejected, for use in metrics.
+func rejectReason(err error) string {
+	switch err {
+	case mutator.ErrReplay:
+		return "replay"
+	case mutator.ErrSize:
+		return "size"
+	case mutator.ErrPreviousHash:
+		return "previous_hash"
+	case mutator.ErrInvalidSig:
+		return "invalid_signature"
+	case mutator.ErrUnauthorized:
+		return "unauthorized"
+	default:
+		return "other"
+	}
 }
 
-// createEpoch signs the current map head.
-func (s *Sequencer) createEpoch(ctx context.Context, d *domain.Domain, logClient *tclient.LogClient, mapVerifier *tclient.MapVerifier, msgs []*mutator.QueueMessage) error {
+// countRejected counts the mutations of domainID that were rejected, by
+// reason. It is called once per sequenced batch, so that retries of the rest of
+// the epoch don't count them again.
+func countRejected(domainID string, rejected []error) {
+	for _, err := range rejected {
+		if err != nil {
+			rejectedCTR.WithLabelValues(domainID, rejectReason(err)).Inc()
+		}
+	}
+}
+
+// writeRejected records the mutations that were rejected in revision, along
+// with the reason they were rejected.
+func (s *Sequencer) writeRejected(ctx context.Context, domainID string, revision int64, msgs []*mutator.QueueMessage, rejected []error) error {
+	rms := make([]*pb.RejectedMutation, 0)
+	for i, m := range msgs {
+		if rejected[i] == nil {
+			continue
+		}
+		hash, err := entry.Hash(m.Mutation)
+		if err != nil {
+			// Mutations are identified by their hash, so there is
+			// nothing to record.
+			glog.Errorf("entry.Hash(): %v", err)
+			continue
+		}
+		rms = append(rms, &pb.RejectedMutation{
+			Index:        m.Mutation.GetIndex(),
+			MutationHash: hash,
+			Epoch:        revision,
+			Sequence:     int64(i),
+			Reason:       rejected[i].Error(),
+		})
+	}
+	if len(rms) == 0 {
+		return nil
+	}
+	glog.Warningf("CreateEpoch: rejected %v mutations in revision %v", len(rms), revision)
+	if err := s.mutations.WriteRejected(ctx, domainID, revision, rms); err != nil {
+		return fmt.Errorf("WriteRejected(%v, %v): %v", domainID, revision, err)
+	}
+	return nil
+}
+
+// createEpoch applies msgs to the map and signs the new map head.
+//
+// msgs are sequenced into the next revision before the map revision is
+// created: they are saved and removed from the queue in one transaction, so
+// that every map revision has its mutations saved and no mutation is applied
+// twice. If creating the map revision fails, reconcile creates it from the
+// saved mutations. The remaining steps are performed by finishEpoch, which
+// reconcile repeats if they fail. Writes to the map and the log are only made
+// while this sequencer is the master in e.
+func (s *Sequencer) createEpoch(ctx context.Context, d *domain.Domain, e election.Election, log mapRootLog, mapVerifier *tclient.MapVerifier, msgs []*mutator.QueueMessage) error {
 	glog.Infof("CreateEpoch: starting sequencing run with %d mutations", len(msgs))
 	start := time.Now()
+	mutate, err := s.mutators.Get(d.Sequencing)
+	if err != nil {
+		return err
+	}
 	// Get the current root.
 	rootResp, err := s.tmap.GetSignedMapRoot(ctx, &tpb.GetSignedMapRootRequest{MapId: d.MapID})
 	if err != nil {
 		return fmt.Errorf("GetSignedMapRoot(%v): %v", d.MapID, err)
 	}
-	mapRoot, err := mapVerifier.VerifySignedMapRoot(rootResp.GetMapRoot())
+	mapRoot, err := s.verifyMapRoot(ctx, d, mapVerifier, rootResp.GetMapRoot(), -1)
 	if err != nil {
 		return err
 	}
 	glog.V(3).Infof("CreateEpoch: Previous SignedMapRoot: {Revision: %v}", mapRoot.Revision)
+	revision := int64(mapRoot.Revision) + 1
 
 	// Get current leaf values.
 	indexes := make([][]byte, 0, len(msgs))
@@ -283,49 +695,134 @@ func (s *Sequencer) createEpoch(ctx context.Context, d *domain.Domain, logClient
 	}
 
 	// Apply mutations to values.
-	newLeaves, err := s.applyMutations(msgs, leaves)
+	newLeaves, rejected, err := s.applyMutations(mutate, msgs, leaves)
 	if err != nil {
 		return err
 	}
 	glog.V(2).Infof("CreateEpoch: applied %v mutations to %v leaves", len(msgs), len(leaves))
 
+	// Write mutations associated with this epoch.
+	if err := s.mutations.SequenceBatch(ctx, d.DomainID, revision, msgs); err != nil {
+		return fmt.Errorf("SequenceBatch(%v, %v): %v", d.DomainID, revision, err)
+	}
+	countRejected(d.DomainID, rejected)
+
 	// Set new leaf values.
 	mapSetStart := time.Now()
+	smr, err := s.setLeaves(ctx, d, e, mapVerifier, revision, newLeaves)
+	mapSetEnd := time.Now()
+	if err != nil {
+		return err
+	}
+	if err := s.finishEpoch(ctx, d, e, log, revision, smr, msgs, newLeaves, rejected); err != nil {
+		return err
+	}
+
+	s.setLastEpoch(d.DomainID, mapSetEnd)
+	mutationsCTR.WithLabelValues(d.DomainID).Add(float64(len(msgs)))
+	indexCTR.WithLabelValues(d.DomainID).Add(float64(len(indexes)))
+	mapUpdateHist.WithLabelValues(d.DomainID).Observe(mapSetEnd.Sub(mapSetStart).Seconds())
+	createEpochHist.WithLabelValues(d.DomainID).Observe(time.Since(start).Seconds())
+	glog.Infof("CreatedEpoch: rev: %v with %v mutations", revision, len(msgs))
+	return nil
+}
+
+// setLeaves creates map revision with newLeaves, if this sequencer is the
+// master in e.
+func (s *Sequencer) setLeaves(ctx context.Context, d *domain.Domain, e election.Election, mapVerifier *tclient.MapVerifier,
+	revision int64, newLeaves []*tpb.MapLeaf) (*tpb.SignedMapRoot, error) {
+	if err := checkMaster(ctx, e); err != nil {
+		return nil, err
+	}
 	setResp, err := s.tmap.SetLeaves(ctx, &tpb.SetMapLeavesRequest{
 		MapId:  d.MapID,
 		Leaves: newLeaves,
 	})
-	mapSetEnd := time.Now()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	mapRoot, err = mapVerifier.VerifySignedMapRoot(setResp.GetMapRoot())
+	mapRoot, err := s.verifyMapRoot(ctx, d, mapVerifier, setResp.GetMapRoot(), revision)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	glog.V(2).Infof("CreateEpoch: SetLeaves:{Revision: %v, RootHash: %x}", mapRoot.Revision, mapRoot.RootHash)
+	if got := int64(mapRoot.Revision); got != revision {
+		// Only the master writes to the map, so this should not happen.
+		return nil, fmt.Errorf("SetLeaves created revision %v, want %v", got, revision)
 	}
-	glog.V(2).Infof("CreateEpoch: SetLeaves:{Revision: %v}", mapRoot.Revision)
+	return setResp.GetMapRoot(), nil
+}
 
-	// Write mutations associated with this epoch.
-	mutations := make([]*pb.Entry, 0, len(msgs))
-	for _, msg := range msgs {
-		mutations = append(mutations, msg.Mutation)
+// finishEpoch performs the steps of creating an epoch that follow the creation
+// of map revision: it records which leaves changed and the outcome of each
+// mutation, and puts the signed map root in the log. Each step may be
+// repeated, so finishEpoch can be retried until it succeeds. The map root is
+// put in the log last, so that reconcile retries the epoch until every
+// mutation's status is written, and only if this sequencer is the master in e.
+func (s *Sequencer) finishEpoch(ctx context.Context, d *domain.Domain, e election.Election, log mapRootLog, revision int64, smr *tpb.SignedMapRoot,
+	msgs []*mutator.QueueMessage, newLeaves []*tpb.MapLeaf, rejected []error) error {
+	// Record which map leaves changed in this epoch.
+	changed := make([][]byte, 0, len(newLeaves))
+	for _, l := range newLeaves {
+		changed = append(changed, l.Index)
 	}
-	if err := s.mutations.WriteBatch(ctx, d.DomainID, int64(mapRoot.Revision), mutations); err != nil {
-		glog.Fatalf("Could not write mutations for revision %v: %v", mapRoot.Revision, err)
+	if err := s.mutations.WriteIndexChanges(ctx, d.DomainID, revision, changed); err != nil {
+		return fmt.Errorf("WriteIndexChanges(%v, %v): %v", d.DomainID, revision, err)
+	}
+	// Record the outcome of each mutation.
+	if err := s.writeStatuses(ctx, d.DomainID, revision, msgs, rejected); err != nil {
+		return err
+	}
+	if err := s.writeRejected(ctx, d.DomainID, revision, msgs, rejected); err != nil {
 		return err
 	}
 
 	// Put SignedMapHead in an append only log.
-	if err := logClient.AddSequencedLeafAndWait(ctx, setResp.GetMapRoot().GetMapRoot(), int64(mapRoot.Revision)); err != nil {
-		glog.Fatalf("AddSequencedLeaf(logID: %v, rev: %v): %v", d.LogID, mapRoot.Revision, err)
-		// TODO(gdbelvin): If the log doesn't do this, we need to generate an emergency alert.
+	if err := checkMaster(ctx, e); err != nil {
 		return err
 	}
-
-	mutationsCTR.Add(float64(len(msgs)))
-	indexCTR.Add(float64(len(indexes)))
-	mapUpdateHist.Observe(mapSetEnd.Sub(mapSetStart).Seconds())
-	createEpochHist.Observe(time.Since(start).Seconds())
-	glog.Infof("CreatedEpoch: rev: %v with %v mutations, root: %x", mapRoot.Revision, len(msgs), mapRoot.RootHash)
+	if err := log.AddSequencedLeafAndWait(ctx, smr.GetMapRoot(), revision); err != nil {
+		// Clients can't verify the map revision until its root is in
+		// the log, so the epoch is late until this succeeds.
+		s.fireAlert(ctx, &alert.Alert{
+			Kind:     alert.LogAppendFailed,
+			DomainID: d.DomainID,
+			Revision: revision,
+			Error:    err.Error(),
+			Details:  map[string]string{"log_id": fmt.Sprint(d.LogID)},
+		})
+		return fmt.Errorf("AddSequencedLeaf(logID: %v, rev: %v): %v", d.LogID, revision, err)
+	}
 	return nil
 }
+
+// verifyMapRoot verifies smr, a map root of d, and fires an alert if it does
+// not verify. revision is the revision smr should have, or -1 if it is not
+// known.
+func (s *Sequencer) verifyMapRoot(ctx context.Context, d *domain.Domain, mapVerifier *tclient.MapVerifier,
+	smr *tpb.SignedMapRoot, revision int64) (*types.MapRootV1, error) {
+	mapRoot, err := mapVerifier.VerifySignedMapRoot(smr)
+	if err != nil {
+		s.fireAlert(ctx, &alert.Alert{
+			Kind:     alert.MapRootInvalid,
+			DomainID: d.DomainID,
+			Revision: revision,
+			Error:    err.Error(),
+			Details:  map[string]string{"map_id": fmt.Sprint(d.MapID)},
+		})
+		return nil, err
+	}
+	return mapRoot, nil
+}
+
+// fireAlert logs a and fires it to the alert sink.
+func (s *Sequencer) fireAlert(ctx context.Context, a *alert.Alert) {
+	a.Time = time.Now()
+	glog.Errorf("ALERT %v: domain %v, revision %v: %v %v", a.Kind, a.DomainID, a.Revision, a.Error, a.Details)
+	if s.alerts == nil {
+		return
+	}
+	if err := s.alerts.Fire(ctx, a); err != nil {
+		glog.Errorf("Fire(%v): %v", a.Kind, err)
+	}
+}
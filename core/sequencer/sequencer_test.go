@@ -0,0 +1,493 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sequencer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/keytransparency/core/domain"
+	"github.com/google/keytransparency/core/fake"
+	"github.com/google/keytransparency/core/mutator"
+	"github.com/google/keytransparency/core/mutator/entry"
+	"github.com/google/keytransparency/core/sequencer/alert"
+	"github.com/google/keytransparency/core/sequencer/election"
+
+	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
+	tpb "github.com/google/trillian"
+)
+
+// fakeMutator accepts every mutation, except those committing to "bad" and
+// those whose previous field is set but does not match the commitment of the
+// current value.
+type fakeMutator struct{}
+
+func (fakeMutator) Mutate(value, mutation proto.Message) (proto.Message, error) {
+	m := mutation.(*pb.Entry)
+	if bytes.Equal(m.GetCommitment(), []byte("bad")) {
+		return nil, mutator.ErrUnauthorized
+	}
+	if m.GetPrevious() != nil && !bytes.Equal(m.GetPrevious(), value.(*pb.Entry).GetCommitment()) {
+		return nil, mutator.ErrPreviousHash
+	}
+	return m, nil
+}
+
+var fakeMutators = mutator.Registry{pb.SequencingConfig_ENTRY: fakeMutator{}}
+
+func TestApplyMutationsStatus(t *testing.T) {
+	ctx := context.Background()
+	domainID := "domain"
+	index1 := []byte("index1")
+	index2 := []byte("index2")
+	msgs := []*mutator.QueueMessage{
+		{Mutation: &pb.Entry{Index: index1, Commitment: []byte("a")}, ExtraData: &pb.Committed{}},
+		{Mutation: &pb.Entry{Index: index1, Commitment: []byte("bad")}, ExtraData: &pb.Committed{}},
+		{Mutation: &pb.Entry{Index: index2, Commitment: []byte("c")}, ExtraData: &pb.Committed{}},
+		{Mutation: &pb.Entry{Index: index1, Commitment: []byte("d")}, ExtraData: &pb.Committed{}},
+	}
+	mutations := fake.NewMutationStorage()
+	s := &Sequencer{mutations: mutations}
+
+	leaves, rejected, err := s.applyMutations(fakeMutator{}, msgs, nil)
+	if err != nil {
+		t.Fatalf("applyMutations(): %v", err)
+	}
+	if got, want := len(leaves), 2; got != want {
+		t.Errorf("applyMutations(): %v leaves, want %v", got, want)
+	}
+	if err := s.writeStatuses(ctx, domainID, 5, msgs, rejected); err != nil {
+		t.Fatalf("writeStatuses(): %v", err)
+	}
+
+	for i, want := range []*pb.MutationStatus{
+		{State: pb.MutationStatus_APPLIED, Epoch: 5},
+		{State: pb.MutationStatus_REJECTED, Epoch: 5, Error: mutator.ErrUnauthorized.Error()},
+		{State: pb.MutationStatus_APPLIED, Epoch: 5},
+		{State: pb.MutationStatus_APPLIED, Epoch: 5},
+	} {
+		hash, err := entry.Hash(msgs[i].Mutation)
+		if err != nil {
+			t.Fatalf("entry.Hash(): %v", err)
+		}
+		got, err := mutations.ReadStatus(ctx, domainID, hash)
+		if err != nil {
+			t.Fatalf("ReadStatus(%v): %v", i, err)
+		}
+		if !proto.Equal(got, want) {
+			t.Errorf("ReadStatus(%v): %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestApplyMutationsChain(t *testing.T) {
+	index := []byte("index")
+	oldLeaf, err := entry.ToLeafValue(&pb.Entry{Index: index, Commitment: []byte("a")})
+	if err != nil {
+		t.Fatalf("ToLeafValue(): %v", err)
+	}
+	leaves := []*tpb.MapLeaf{{Index: index, LeafValue: oldLeaf}}
+
+	for _, tc := range []struct {
+		desc         string
+		commitments  [][2]string // previous, commitment pairs in queue order.
+		wantRejected []error
+		wantValue    string
+	}{
+		{
+			desc:         "chain",
+			commitments:  [][2]string{{"a", "b"}, {"b", "c"}, {"c", "d"}},
+			wantRejected: []error{nil, nil, nil},
+			wantValue:    "d",
+		},
+		{
+			desc:         "first sibling wins",
+			commitments:  [][2]string{{"a", "b"}, {"a", "c"}, {"b", "d"}},
+			wantRejected: []error{nil, mutator.ErrPreviousHash, nil},
+			wantValue:    "d",
+		},
+		{
+			desc:         "invalid link leaves value unchanged",
+			commitments:  [][2]string{{"a", "b"}, {"b", "bad"}, {"b", "c"}},
+			wantRejected: []error{nil, mutator.ErrUnauthorized, nil},
+			wantValue:    "c",
+		},
+		{
+			desc:         "out of order",
+			commitments:  [][2]string{{"b", "c"}, {"a", "b"}},
+			wantRejected: []error{mutator.ErrPreviousHash, nil},
+			wantValue:    "b",
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			msgs := make([]*mutator.QueueMessage, 0, len(tc.commitments))
+			for _, c := range tc.commitments {
+				msgs = append(msgs, &mutator.QueueMessage{Mutation: &pb.Entry{
+					Index:      index,
+					Previous:   []byte(c[0]),
+					Commitment: []byte(c[1]),
+				}, ExtraData: &pb.Committed{}})
+			}
+			s := &Sequencer{}
+			newLeaves, rejected, err := s.applyMutations(fakeMutator{}, msgs, leaves)
+			if err != nil {
+				t.Fatalf("applyMutations(): %v", err)
+			}
+			for i, want := range tc.wantRejected {
+				if got := rejected[i]; got != want {
+					t.Errorf("rejected[%v]: %v, want %v", i, got, want)
+				}
+			}
+			if got, want := len(newLeaves), 1; got != want {
+				t.Fatalf("applyMutations(): %v leaves, want %v", got, want)
+			}
+			value, err := entry.FromLeafValue(newLeaves[0].GetLeafValue())
+			if err != nil {
+				t.Fatalf("FromLeafValue(): %v", err)
+			}
+			if got, want := string(value.GetCommitment()), tc.wantValue; got != want {
+				t.Errorf("leaf commitment: %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestWriteRejected(t *testing.T) {
+	ctx := context.Background()
+	domainID := "domain"
+	msgs := []*mutator.QueueMessage{
+		{Mutation: &pb.Entry{Index: []byte("index1"), Commitment: []byte("a")}, ExtraData: &pb.Committed{}},
+		{Mutation: &pb.Entry{Index: []byte("index1"), Commitment: []byte("b")}, ExtraData: &pb.Committed{}},
+		{Mutation: &pb.Entry{Index: []byte("index2"), Commitment: []byte("bad")}, ExtraData: &pb.Committed{}},
+	}
+	mutations := fake.NewMutationStorage()
+	s := &Sequencer{mutations: mutations}
+
+	_, rejected, err := s.applyMutations(fakeMutator{}, msgs, nil)
+	if err != nil {
+		t.Fatalf("applyMutations(): %v", err)
+	}
+	if err := s.writeRejected(ctx, domainID, 5, msgs, rejected); err != nil {
+		t.Fatalf("writeRejected(): %v", err)
+	}
+
+	hash, err := entry.Hash(msgs[2].Mutation)
+	if err != nil {
+		t.Fatalf("entry.Hash(): %v", err)
+	}
+	want := []*pb.RejectedMutation{{
+		Index:        []byte("index2"),
+		MutationHash: hash,
+		Epoch:        5,
+		Sequence:     2,
+		Reason:       mutator.ErrUnauthorized.Error(),
+	}}
+	got, err := mutations.ReadRejectedPage(ctx, domainID, 5, 0, 10)
+	if err != nil {
+		t.Fatalf("ReadRejectedPage(): %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ReadRejectedPage(): %v, want %v", got, want)
+	}
+	for i := range got {
+		if !proto.Equal(got[i], want[i]) {
+			t.Errorf("ReadRejectedPage()[%d]: %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	// The epoch is retried until the rejected mutations are recorded.
+	s.mutations = failingRejected{mutations}
+	if err := s.writeRejected(ctx, domainID, 5, msgs, rejected); err == nil {
+		t.Errorf("writeRejected() with failing storage: nil, want error")
+	}
+}
+
+// failingRejected fails to record rejected mutations.
+type failingRejected struct {
+	mutator.MutationStorage
+}
+
+func (failingRejected) WriteRejected(context.Context, string, int64, []*pb.RejectedMutation) error {
+	return errors.New("write failed")
+}
+
+func TestRejectReason(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want string
+	}{
+		{err: mutator.ErrReplay, want: "replay"},
+		{err: mutator.ErrUnauthorized, want: "unauthorized"},
+		{err: mutator.ErrInvalidSig, want: "invalid_signature"},
+		{err: errors.New("entry: invalid leaf"), want: "other"},
+	} {
+		if got := rejectReason(tc.err); got != tc.want {
+			t.Errorf("rejectReason(%v): %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
+
+type fakeElection struct {
+	master   bool
+	resigned bool
+}
+
+func (e *fakeElection) Campaign(context.Context) (bool, error) { return e.master, nil }
+func (e *fakeElection) IsMaster(context.Context) (bool, error) { return e.master, nil }
+func (e *fakeElection) Resign(context.Context) error {
+	e.resigned = true
+	return nil
+}
+
+type fakeReceiver struct{ closed bool }
+
+func (r *fakeReceiver) Close()                                  { r.closed = true }
+func (r *fakeReceiver) FlushN(ctx context.Context, n int) error { return nil }
+
+func TestUpdateMastershipStandby(t *testing.T) {
+	ctx := context.Background()
+	d := &domain.Domain{DomainID: "domain"}
+	e := &fakeElection{master: false}
+	r := &fakeReceiver{}
+	s := &Sequencer{
+		receivers: map[string]mutator.Receiver{d.DomainID: r},
+		elections: map[string]election.Election{d.DomainID: e},
+	}
+
+	// Losing mastership stops the receiver.
+	if err := s.updateMastership(ctx, d); err != nil {
+		t.Fatalf("updateMastership(): %v", err)
+	}
+	if !r.closed {
+		t.Errorf("receiver not closed after losing mastership")
+	}
+	if _, ok := s.receivers[d.DomainID]; ok {
+		t.Errorf("receiver still registered after losing mastership")
+	}
+
+	// A standby does not start a receiver.
+	if err := s.updateMastership(ctx, d); err != nil {
+		t.Fatalf("updateMastership(): %v", err)
+	}
+	if _, ok := s.receivers[d.DomainID]; ok {
+		t.Errorf("standby started a receiver")
+	}
+
+	s.Close()
+	if !e.resigned {
+		t.Errorf("Close() did not resign mastership")
+	}
+}
+
+func TestUpdateDomainsDeleted(t *testing.T) {
+	ctx := context.Background()
+	deleted := &domain.Domain{DomainID: "deleted"}
+	kept := &domain.Domain{DomainID: "kept"}
+	deletedE, keptE := &fakeElection{master: true}, &fakeElection{master: true}
+	deletedR, keptR := &fakeReceiver{}, &fakeReceiver{}
+	s := &Sequencer{
+		receivers: map[string]mutator.Receiver{deleted.DomainID: deletedR, kept.DomainID: keptR},
+		running:   map[string]*domain.Domain{deleted.DomainID: deleted, kept.DomainID: kept},
+		elections: map[string]election.Election{deleted.DomainID: deletedE, kept.DomainID: keptE},
+	}
+
+	s.updateDomains(ctx, []*domain.Domain{kept})
+	if !deletedR.closed {
+		t.Errorf("receiver of deleted domain not closed")
+	}
+	if !deletedE.resigned {
+		t.Errorf("mastership of deleted domain not resigned")
+	}
+	if _, ok := s.elections[deleted.DomainID]; ok {
+		t.Errorf("election of deleted domain still registered")
+	}
+	if keptR.closed || keptE.resigned {
+		t.Errorf("unchanged domain was stopped")
+	}
+	if got := s.receivers[kept.DomainID]; got != keptR {
+		t.Errorf("receiver of unchanged domain replaced")
+	}
+}
+
+func TestUpdateDomainsReceiverFailed(t *testing.T) {
+	ctx := context.Background()
+	domains := []*domain.Domain{{DomainID: "a"}, {DomainID: "broken"}, {DomainID: "c"}}
+	sink := &recordingSink{}
+	fixed := false
+	s := &Sequencer{
+		receivers: make(map[string]mutator.Receiver),
+		running:   make(map[string]*domain.Domain),
+		elections: map[string]election.Election{
+			"a":      &fakeElection{master: true},
+			"broken": &fakeElection{master: true},
+			"c":      &fakeElection{master: true},
+		},
+		alerts: sink,
+	}
+	s.newReceiver = func(_ context.Context, d *domain.Domain) (mutator.Receiver, error) {
+		if d.DomainID == "broken" && !fixed {
+			return nil, errors.New("log 2 has 3 map roots, but map 1 is at revision 1")
+		}
+		return &fakeReceiver{}, nil
+	}
+
+	s.updateDomains(ctx, domains)
+	for _, domainID := range []string{"a", "c"} {
+		if _, ok := s.receivers[domainID]; !ok {
+			t.Errorf("no receiver for %v after another domain failed", domainID)
+		}
+	}
+	if _, ok := s.receivers["broken"]; ok {
+		t.Errorf("receiver registered for a domain that failed to reconcile")
+	}
+	if got := len(sink.alerts); got != 1 {
+		t.Fatalf("updateDomains() fired %v alerts, want 1", got)
+	}
+	if a := sink.alerts[0]; a.Kind != alert.ReceiverFailed || a.DomainID != "broken" || a.Error == "" {
+		t.Errorf("updateDomains() fired %+v, want a ReceiverFailed alert for broken", a)
+	}
+
+	// The failed domain is retried at the next refresh.
+	fixed = true
+	s.updateDomains(ctx, domains)
+	if _, ok := s.receivers["broken"]; !ok {
+		t.Errorf("receiver of broken not started once it can be reconciled")
+	}
+}
+
+func TestReceiverChanged(t *testing.T) {
+	old := &domain.Domain{DomainID: "domain", MapID: 1, LogID: 2, MinInterval: time.Second, MaxInterval: time.Minute}
+	for _, tc := range []struct {
+		desc string
+		d    domain.Domain
+		want bool
+	}{
+		{desc: "same", d: *old},
+		{desc: "deleted flag", d: domain.Domain{DomainID: "domain", MapID: 1, LogID: 2, MinInterval: time.Second, MaxInterval: time.Minute, Deleted: true}},
+		{desc: "min interval", d: domain.Domain{DomainID: "domain", MapID: 1, LogID: 2, MinInterval: 2 * time.Second, MaxInterval: time.Minute}, want: true},
+		{desc: "max interval", d: domain.Domain{DomainID: "domain", MapID: 1, LogID: 2, MinInterval: time.Second, MaxInterval: time.Hour}, want: true},
+		{desc: "map", d: domain.Domain{DomainID: "domain", MapID: 3, LogID: 2, MinInterval: time.Second, MaxInterval: time.Minute}, want: true},
+		{desc: "immutable sequencing", d: domain.Domain{DomainID: "domain", MapID: 1, LogID: 2, MinInterval: time.Second, MaxInterval: time.Minute,
+			Sequencing: &pb.SequencingConfig{MaxBatchSize: 10}}},
+	} {
+		if got := receiverChanged(old, &tc.d); got != tc.want {
+			t.Errorf("%v: receiverChanged(): %v, want %v", tc.desc, got, tc.want)
+		}
+	}
+}
+
+func TestBatchSize(t *testing.T) {
+	for _, tc := range []struct {
+		desc       string
+		sequencing *pb.SequencingConfig
+		want       int32
+	}{
+		{desc: "no config", want: MaxBatchSize},
+		{desc: "default", sequencing: &pb.SequencingConfig{}, want: MaxBatchSize},
+		{desc: "configured", sequencing: &pb.SequencingConfig{MaxBatchSize: 10}, want: 10},
+	} {
+		d := &domain.Domain{Sequencing: tc.sequencing}
+		if got := d.BatchSize(); got != tc.want {
+			t.Errorf("%v: BatchSize(): %v, want %v", tc.desc, got, tc.want)
+		}
+	}
+}
+
+func TestCreateEpochUnsupportedMutator(t *testing.T) {
+	s := &Sequencer{mutators: fakeMutators}
+	d := &domain.Domain{DomainID: "domain", Sequencing: &pb.SequencingConfig{Mutator: 100}}
+	if err := s.createEpoch(context.Background(), d, alwaysMaster, nil, nil, nil); err == nil {
+		t.Errorf("createEpoch() with an unsupported mutator: nil, want error")
+	}
+}
+
+// recordingSink records the alerts fired to it.
+type recordingSink struct {
+	alerts []*alert.Alert
+}
+
+func (r *recordingSink) Fire(_ context.Context, a *alert.Alert) error {
+	r.alerts = append(r.alerts, a)
+	return nil
+}
+
+func TestFinishEpochLogAppendFailed(t *testing.T) {
+	ctx := context.Background()
+	sink := &recordingSink{}
+	s := &Sequencer{mutations: fake.NewMutationStorage(), alerts: sink}
+	d := &domain.Domain{DomainID: "domain", LogID: 2}
+	smr := &tpb.SignedMapRoot{MapRoot: []byte("root")}
+	for _, tc := range []struct {
+		desc       string
+		failAdd    bool
+		wantAlerts int
+	}{
+		{desc: "appended"},
+		{desc: "append failed", failAdd: true, wantAlerts: 1},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			sink.alerts = nil
+			log := &fakeLog{failAdd: tc.failAdd}
+			err := s.finishEpoch(ctx, d, alwaysMaster, log, 0, smr, nil, nil, nil)
+			if got, want := err != nil, tc.failAdd; got != want {
+				t.Errorf("finishEpoch(): %v, want err %v", err, want)
+			}
+			if got, want := len(sink.alerts), tc.wantAlerts; got != want {
+				t.Fatalf("finishEpoch() fired %v alerts, want %v", got, want)
+			}
+			if tc.wantAlerts == 0 {
+				return
+			}
+			a := sink.alerts[0]
+			if a.Kind != alert.LogAppendFailed || a.DomainID != d.DomainID || a.Revision != 0 || a.Details["log_id"] != "2" {
+				t.Errorf("finishEpoch() fired %+v, want a LogAppendFailed alert for revision 0 of %v", a, d.DomainID)
+			}
+			if a.Time.IsZero() {
+				t.Errorf("finishEpoch() fired an alert without a time")
+			}
+		})
+	}
+}
+
+func TestVerifyMapRootInvalid(t *testing.T) {
+	ctx := context.Background()
+	tmap, mapVerifier := newFakeMap(t)
+	sink := &recordingSink{}
+	s := &Sequencer{alerts: sink}
+	d := &domain.Domain{DomainID: "domain", MapID: 1}
+
+	if _, err := s.verifyMapRoot(ctx, d, mapVerifier, tmap.roots[0], 0); err != nil {
+		t.Fatalf("verifyMapRoot(valid): %v", err)
+	}
+	if got := len(sink.alerts); got != 0 {
+		t.Fatalf("verifyMapRoot(valid) fired %v alerts, want 0", got)
+	}
+
+	bad := proto.Clone(tmap.roots[0]).(*tpb.SignedMapRoot)
+	bad.Signature = []byte("forged")
+	if _, err := s.verifyMapRoot(ctx, d, mapVerifier, bad, 3); err == nil {
+		t.Errorf("verifyMapRoot(forged): nil, want error")
+	}
+	if got := len(sink.alerts); got != 1 {
+		t.Fatalf("verifyMapRoot(forged) fired %v alerts, want 1", got)
+	}
+	if a := sink.alerts[0]; a.Kind != alert.MapRootInvalid || a.Revision != 3 {
+		t.Errorf("verifyMapRoot(forged) fired %+v, want a MapRootInvalid alert for revision 3", a)
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storagetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/keytransparency/core/domain"
+	"github.com/google/trillian/crypto/keyspb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
+)
+
+// DomainStorageFactory returns an empty domain.Storage and a function that
+// releases it.
+type DomainStorageFactory func(ctx context.Context, t *testing.T) (domain.Storage, func())
+
+// DomainStorageTester verifies implementations of domain.Storage.
+type DomainStorageTester struct {
+	NewStorage DomainStorageFactory
+}
+
+// RunAllTests runs all the domain.Storage tests.
+func (tester DomainStorageTester) RunAllTests(t *testing.T) {
+	ctx := context.Background()
+	for _, test := range []struct {
+		name string
+		fn   func(context.Context, *testing.T, domain.Storage)
+	}{
+		{"TestList", testDomainList},
+		{"TestWriteReadDelete", testDomainWriteReadDelete},
+		{"TestNotFound", testDomainNotFound},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s, done := tester.NewStorage(ctx, t)
+			defer done()
+			test.fn(ctx, t, s)
+		})
+	}
+}
+
+func genDomain(domainID string) *domain.Domain {
+	return &domain.Domain{
+		DomainID:    domainID,
+		MapID:       1,
+		LogID:       2,
+		VRF:         &keyspb.PublicKey{Der: []byte("pubkeybytes")},
+		VRFPriv:     &keyspb.PrivateKey{Der: []byte("privkeybytes")},
+		MinInterval: 1 * time.Second,
+		MaxInterval: 5 * time.Second,
+	}
+}
+
+// testDomainList verifies that List returns domains sorted by domain ID, with
+// deleted domains only if requested.
+func testDomainList(ctx context.Context, t *testing.T, s domain.Storage) {
+	d1 := genDomain("domain1")
+	d2 := genDomain("domain2")
+	d2.ReceiptKey = &keyspb.PublicKey{Der: []byte("receiptpubkeybytes")}
+	d2.ReceiptPriv = &keyspb.PrivateKey{Der: []byte("receiptprivkeybytes")}
+	d2.MinInterval, d2.MaxInterval = 5*time.Hour, 500*time.Hour
+	d3 := genDomain("domain3")
+	d3.Sequencing = &pb.SequencingConfig{
+		MaxBatchSize:  10,
+		AllowedAppIds: []string{"app1", "app2"},
+	}
+	for _, d := range []*domain.Domain{d3, d1, d2} {
+		if err := s.Write(ctx, d); err != nil {
+			t.Fatalf("Write(%v): %v", d.DomainID, err)
+		}
+	}
+	if err := s.SetDelete(ctx, d2.DomainID, true); err != nil {
+		t.Fatalf("SetDelete(%v): %v", d2.DomainID, err)
+	}
+	deleted := *d2
+	deleted.Deleted = true
+
+	for _, tc := range []struct {
+		showDeleted bool
+		want        []*domain.Domain
+	}{
+		{showDeleted: false, want: []*domain.Domain{d1, d3}},
+		{showDeleted: true, want: []*domain.Domain{d1, &deleted, d3}},
+	} {
+		domains, err := s.List(ctx, tc.showDeleted)
+		if err != nil {
+			t.Errorf("List(%v): %v", tc.showDeleted, err)
+			continue
+		}
+		if got, want := domains, tc.want; !cmp.Equal(got, want, cmp.Comparer(proto.Equal)) {
+			t.Errorf("List(%v): %#v, want %#v, diff: \n%v", tc.showDeleted, got, want, cmp.Diff(got, want))
+		}
+	}
+}
+
+func testDomainWriteReadDelete(ctx context.Context, t *testing.T, s domain.Storage) {
+	for _, tc := range []struct {
+		desc                 string
+		write                bool
+		wantWriteErr         bool
+		setDelete, isDeleted bool
+		readDeleted          bool
+		wantReadErr          bool
+	}{
+		{desc: "Success", write: true},
+		{desc: "Duplicate DomainID", write: true, wantWriteErr: true},
+		{desc: "Delete", setDelete: true, isDeleted: true, readDeleted: false, wantReadErr: true},
+		{desc: "Read deleted", setDelete: true, isDeleted: true, readDeleted: true, wantReadErr: false},
+		{desc: "Undelete", setDelete: true, isDeleted: false, readDeleted: false, wantReadErr: false},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			d := genDomain("testdomain")
+			if tc.write {
+				err := s.Write(ctx, d)
+				if got, want := err != nil, tc.wantWriteErr; got != want {
+					t.Errorf("Write(): %v, want err: %v", err, want)
+					return
+				}
+				if err != nil {
+					return
+				}
+			}
+			if tc.setDelete {
+				if err := s.SetDelete(ctx, d.DomainID, tc.isDeleted); err != nil {
+					t.Errorf("SetDelete(%v, %v): %v", d.DomainID, tc.isDeleted, err)
+					return
+				}
+			}
+
+			got, err := s.Read(ctx, d.DomainID, tc.readDeleted)
+			if got, want := err != nil, tc.wantReadErr; got != want {
+				t.Errorf("Read(): %v, want err: %v", err, want)
+			}
+			if err != nil {
+				return
+			}
+			d.Deleted = tc.isDeleted
+			if !cmp.Equal(got, d, cmp.Comparer(proto.Equal)) {
+				t.Errorf("Read(%v, %v): %#v, want %#v, diff: \n%v", d.DomainID, tc.readDeleted, got, d, cmp.Diff(got, d))
+			}
+		})
+	}
+}
+
+func testDomainNotFound(ctx context.Context, t *testing.T, s domain.Storage) {
+	d := genDomain("deleted")
+	if err := s.Write(ctx, d); err != nil {
+		t.Fatalf("Write(): %v", err)
+	}
+	if err := s.SetDelete(ctx, d.DomainID, true); err != nil {
+		t.Fatalf("SetDelete(): %v", err)
+	}
+	for _, tc := range []struct {
+		desc string
+		f    func() error
+	}{
+		{desc: "Read unknown", f: func() error {
+			_, err := s.Read(ctx, "unknown", true)
+			return err
+		}},
+		{desc: "Read deleted", f: func() error {
+			_, err := s.Read(ctx, d.DomainID, false)
+			return err
+		}},
+		{desc: "SetDelete unknown", f: func() error {
+			return s.SetDelete(ctx, "unknown", true)
+		}},
+	} {
+		if got, want := status.Code(tc.f()), codes.NotFound; got != want {
+			t.Errorf("%v: %v, want %v", tc.desc, got, want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storagetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/keytransparency/core/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	tpb "github.com/google/keytransparency/core/api/type/type_go_proto"
+)
+
+// KeySetsFactory returns an empty storage.KeySets and a function that
+// releases it.
+type KeySetsFactory func(ctx context.Context, t *testing.T) (storage.KeySets, func())
+
+// KeySetsTester verifies implementations of storage.KeySets.
+type KeySetsTester struct {
+	NewKeySets KeySetsFactory
+}
+
+// RunAllTests runs all the storage.KeySets tests.
+func (tester KeySetsTester) RunAllTests(t *testing.T) {
+	ctx := context.Background()
+	for _, test := range []struct {
+		name string
+		fn   func(context.Context, *testing.T, storage.KeySets)
+	}{
+		{"TestWriteRead", testKeySetsWriteRead},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			k, done := tester.NewKeySets(ctx, t)
+			defer done()
+			test.fn(ctx, t, k)
+		})
+	}
+}
+
+func testKeySetsWriteRead(ctx context.Context, t *testing.T, keysets storage.KeySets) {
+	ks := &tpb.KeySet{VerifyingKeys: map[string]*tpb.VerifyingKey{
+		"1": {KeyMaterial: []byte("keydata")},
+	}}
+	for _, tc := range []struct {
+		desc         string
+		instanceID   int64
+		domainID     string
+		appID        string
+		write        bool
+		wantWriteErr bool
+		read         bool
+		wantReadErr  codes.Code
+	}{
+		{desc: "write,read", instanceID: 0, domainID: "domain", appID: "app", write: true, read: true},
+		{desc: "double write", instanceID: 0, domainID: "domain", appID: "app", write: true, wantWriteErr: true},
+		{desc: "other instance", instanceID: 1, domainID: "domain", appID: "app", read: true, wantReadErr: codes.NotFound},
+		{desc: "other domain", instanceID: 0, domainID: "domain2", appID: "app", read: true, wantReadErr: codes.NotFound},
+		{desc: "other app", instanceID: 0, domainID: "domain", appID: "app2", read: true, wantReadErr: codes.NotFound},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if tc.write {
+				err := keysets.Set(ctx, tc.instanceID, tc.domainID, tc.appID, ks)
+				if got, want := err != nil, tc.wantWriteErr; got != want {
+					t.Errorf("Set(%v, %v, %v): %v, want err: %v", tc.instanceID, tc.domainID, tc.appID, err, want)
+				}
+			}
+			if tc.read {
+				got, err := keysets.Get(ctx, tc.instanceID, tc.domainID, tc.appID)
+				if status.Code(err) != tc.wantReadErr {
+					t.Fatalf("Get(%v, %v, %v): %v, want %v", tc.instanceID, tc.domainID, tc.appID, err, tc.wantReadErr)
+				}
+				if err != nil {
+					return
+				}
+				if !proto.Equal(got, ks) {
+					t.Errorf("Get(%v, %v, %v): %v, want %v", tc.instanceID, tc.domainID, tc.appID, got, ks)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,385 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storagetest
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/keytransparency/core/mutator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
+)
+
+// MutationStorageFactory returns an empty mutator.MutationStorage and a
+// function that releases it.
+type MutationStorageFactory func(ctx context.Context, t *testing.T) (mutator.MutationStorage, func())
+
+// MutationStorageTester verifies implementations of mutator.MutationStorage.
+type MutationStorageTester struct {
+	NewStorage MutationStorageFactory
+}
+
+// RunAllTests runs all the mutator.MutationStorage tests.
+func (tester MutationStorageTester) RunAllTests(t *testing.T) {
+	ctx := context.Background()
+	for _, test := range []struct {
+		name string
+		fn   func(context.Context, *testing.T, mutator.MutationStorage)
+	}{
+		{"TestReadPage", testReadPage},
+		{"TestWriteBatchReplaces", testWriteBatchReplaces},
+		{"TestSequenceBatch", testSequenceBatch},
+		{"TestListIndexChanges", testListIndexChanges},
+		{"TestMutationStatus", testMutationStatus},
+		{"TestReadRejectedPage", testReadRejectedPage},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			m, done := tester.NewStorage(ctx, t)
+			defer done()
+			test.fn(ctx, t, m)
+		})
+	}
+}
+
+func fillBatches(ctx context.Context, m mutator.MutationStorage) error {
+	for _, tc := range []struct {
+		revision  int64
+		mutations []*pb.Entry
+	}{
+		{revision: 0, mutations: []*pb.Entry{genMutation(1), genMutation(2)}},
+		{revision: 1, mutations: []*pb.Entry{genMutation(3), genMutation(4), genMutation(5)}},
+	} {
+		if err := m.WriteBatch(ctx, domainID, tc.revision, tc.mutations); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func testReadPage(ctx context.Context, t *testing.T, m mutator.MutationStorage) {
+	if err := fillBatches(ctx, m); err != nil {
+		t.Fatalf("Failed to write mutations: %v", err)
+	}
+
+	for _, tc := range []struct {
+		description string
+		domainID    string
+		revision    int64
+		start       int64
+		count       int32
+		wantMax     int64
+		mutations   []*pb.Entry
+	}{
+		{description: "read a single mutation", start: 0, count: 1, wantMax: 0, mutations: []*pb.Entry{genMutation(1)}},
+		{description: "empty mutations list", revision: 100, start: 0, count: 10},
+		{description: "unknown domain", domainID: "unknown", revision: 0, start: 0, count: 10},
+		{description: "start past the end", revision: 0, start: 2, count: 10},
+		{
+			description: "full mutations range size",
+			revision:    0,
+			start:       0,
+			count:       5,
+			wantMax:     1,
+			mutations:   []*pb.Entry{genMutation(1), genMutation(2)},
+		},
+		{
+			description: "non-zero start",
+			revision:    1,
+			start:       1,
+			count:       2,
+			wantMax:     2,
+			mutations:   []*pb.Entry{genMutation(4), genMutation(5)},
+		},
+		{
+			description: "limit by count",
+			revision:    1,
+			start:       0,
+			count:       2,
+			wantMax:     1,
+			mutations:   []*pb.Entry{genMutation(3), genMutation(4)},
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			d := domainID
+			if tc.domainID != "" {
+				d = tc.domainID
+			}
+			max, results, err := m.ReadPage(ctx, d, tc.revision, tc.start, tc.count)
+			if err != nil {
+				t.Fatalf("failed to read mutations: %v", err)
+			}
+			if got, want := max, tc.wantMax; got != want {
+				t.Errorf("ReadPage(%v,%v,%v).max:%v, want %v", tc.revision, tc.start, tc.count, got, want)
+			}
+			if got, want := len(results), len(tc.mutations); got != want {
+				t.Fatalf("len(results)=%v, want %v", got, want)
+			}
+			for i := range results {
+				if got, want := results[i], tc.mutations[i]; !proto.Equal(got, want) {
+					t.Errorf("results[%v] data=%v, want %v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func testWriteBatchReplaces(ctx context.Context, t *testing.T, m mutator.MutationStorage) {
+	if err := fillBatches(ctx, m); err != nil {
+		t.Fatalf("Failed to write mutations: %v", err)
+	}
+	// Rewrite revision 1 with a shorter batch.
+	want := []*pb.Entry{genMutation(6)}
+	if err := m.WriteBatch(ctx, domainID, 1, want); err != nil {
+		t.Fatalf("WriteBatch(): %v", err)
+	}
+	_, got, err := m.ReadPage(ctx, domainID, 1, 0, 10)
+	if err != nil {
+		t.Fatalf("ReadPage(): %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ReadPage(): %v, want %v", got, want)
+	}
+	for i := range got {
+		if !proto.Equal(got[i], want[i]) {
+			t.Errorf("ReadPage()[%v]: %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+// testSequenceBatch verifies that SequenceBatch records the highest sequenced
+// revision, that WriteBatch does not, and that only the revision after the
+// highest sequenced revision can be sequenced. Sequencing queued messages is
+// verified by MutationQueueTester.
+func testSequenceBatch(ctx context.Context, t *testing.T, m mutator.MutationStorage) {
+	for _, tc := range []struct {
+		desc     string
+		write    func() error
+		wantErr  bool
+		revision int64
+		want     []*mutator.QueueMessage
+		wantRev  int64
+	}{
+		{desc: "empty", revision: 1, want: []*mutator.QueueMessage{}, wantRev: 0},
+		{
+			desc:     "sequenced",
+			write:    func() error { return m.SequenceBatch(ctx, domainID, 1, nil) },
+			revision: 1,
+			want:     []*mutator.QueueMessage{},
+			wantRev:  1,
+		},
+		{
+			desc:     "written",
+			write:    func() error { return m.WriteBatch(ctx, domainID, 3, []*pb.Entry{genMutation(3)}) },
+			revision: 3,
+			want:     []*mutator.QueueMessage{{Mutation: genMutation(3)}},
+			wantRev:  1,
+		},
+		{
+			desc:     "next revision",
+			write:    func() error { return m.SequenceBatch(ctx, domainID, 2, nil) },
+			revision: 2,
+			want:     []*mutator.QueueMessage{},
+			wantRev:  2,
+		},
+		{
+			desc:     "rewritten",
+			write:    func() error { return m.WriteBatch(ctx, domainID, 1, []*pb.Entry{genMutation(1)}) },
+			revision: 1,
+			want:     []*mutator.QueueMessage{{Mutation: genMutation(1)}},
+			wantRev:  2,
+		},
+		{
+			desc:     "sequenced again",
+			write:    func() error { return m.SequenceBatch(ctx, domainID, 1, nil) },
+			wantErr:  true,
+			revision: 1,
+			want:     []*mutator.QueueMessage{{Mutation: genMutation(1)}},
+			wantRev:  2,
+		},
+		{
+			desc:     "revision skipped",
+			write:    func() error { return m.SequenceBatch(ctx, domainID, 4, nil) },
+			wantErr:  true,
+			revision: 4,
+			want:     []*mutator.QueueMessage{},
+			wantRev:  2,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if tc.write != nil {
+				err := tc.write()
+				if got, want := err != nil, tc.wantErr; got != want {
+					t.Fatalf("write: %v, want err %v", err, want)
+				}
+				if err != nil && err != mutator.ErrStaleBatch {
+					t.Errorf("write: %v, want %v", err, mutator.ErrStaleBatch)
+				}
+			}
+			got, err := m.ReadBatch(ctx, domainID, tc.revision)
+			if err != nil {
+				t.Fatalf("ReadBatch(): %v", err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("ReadBatch(): %v messages, want %v", len(got), len(tc.want))
+			}
+			for i := range got {
+				if !proto.Equal(got[i].Mutation, tc.want[i].Mutation) {
+					t.Errorf("ReadBatch()[%v].Mutation: %v, want %v", i, got[i].Mutation, tc.want[i].Mutation)
+				}
+				if !proto.Equal(got[i].ExtraData, tc.want[i].ExtraData) {
+					t.Errorf("ReadBatch()[%v].ExtraData: %v, want %v", i, got[i].ExtraData, tc.want[i].ExtraData)
+				}
+			}
+			rev, err := m.HighestSequencedRevision(ctx, domainID)
+			if err != nil {
+				t.Fatalf("HighestSequencedRevision(): %v", err)
+			}
+			if rev != tc.wantRev {
+				t.Errorf("HighestSequencedRevision(): %v, want %v", rev, tc.wantRev)
+			}
+		})
+	}
+}
+
+func testListIndexChanges(ctx context.Context, t *testing.T, m mutator.MutationStorage) {
+	for _, rev := range []struct {
+		revision int64
+		indexes  [][]byte
+	}{
+		{revision: 1, indexes: [][]byte{[]byte("a"), []byte("b")}},
+		{revision: 3, indexes: [][]byte{[]byte("a")}},
+		{revision: 4, indexes: [][]byte{[]byte("b")}},
+		{revision: 7, indexes: [][]byte{[]byte("a")}},
+		{revision: 3, indexes: [][]byte{[]byte("a")}}, // Rewrites are ignored.
+	} {
+		if err := m.WriteIndexChanges(ctx, domainID, rev.revision, rev.indexes); err != nil {
+			t.Fatalf("WriteIndexChanges(%v): %v", rev.revision, err)
+		}
+	}
+
+	for _, tc := range []struct {
+		description string
+		domainID    string
+		index       string
+		start, end  int64
+		limit       int32
+		want        []int64
+	}{
+		{description: "all", index: "a", start: 0, end: 10, limit: 10, want: []int64{1, 3, 7}},
+		{description: "inclusive range", index: "a", start: 3, end: 7, limit: 10, want: []int64{3, 7}},
+		{description: "limit", index: "a", start: 0, end: 10, limit: 2, want: []int64{1, 3}},
+		{description: "limit after start", index: "a", start: 2, end: 10, limit: 1, want: []int64{3}},
+		{description: "other index", index: "b", start: 2, end: 10, limit: 10, want: []int64{4}},
+		{description: "no changes", index: "c", start: 0, end: 10, limit: 10, want: []int64{}},
+		{description: "other domain", domainID: "other", index: "a", start: 0, end: 10, limit: 10, want: []int64{}},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			d := domainID
+			if tc.domainID != "" {
+				d = tc.domainID
+			}
+			got, err := m.ListIndexChanges(ctx, d, []byte(tc.index), tc.start, tc.end, tc.limit)
+			if err != nil {
+				t.Fatalf("ListIndexChanges(): %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ListIndexChanges(): %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func testMutationStatus(ctx context.Context, t *testing.T, m mutator.MutationStorage) {
+	hash := []byte("hash")
+	if _, err := m.ReadStatus(ctx, domainID, hash); status.Code(err) != codes.NotFound {
+		t.Errorf("ReadStatus(unknown): %v, want %v", err, codes.NotFound)
+	}
+
+	for _, tc := range []struct {
+		description string
+		status      *pb.MutationStatus
+	}{
+		{description: "queued", status: &pb.MutationStatus{State: pb.MutationStatus_QUEUED}},
+		{description: "rejected", status: &pb.MutationStatus{State: pb.MutationStatus_REJECTED, Epoch: 3, Error: "mutation: unauthorized"}},
+		{description: "applied", status: &pb.MutationStatus{State: pb.MutationStatus_APPLIED, Epoch: 4}},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			if err := m.WriteStatus(ctx, domainID, hash, tc.status); err != nil {
+				t.Fatalf("WriteStatus(): %v", err)
+			}
+			got, err := m.ReadStatus(ctx, domainID, hash)
+			if err != nil {
+				t.Fatalf("ReadStatus(): %v", err)
+			}
+			if !proto.Equal(got, tc.status) {
+				t.Errorf("ReadStatus(): %v, want %v", got, tc.status)
+			}
+		})
+	}
+
+	if _, err := m.ReadStatus(ctx, "other", hash); status.Code(err) != codes.NotFound {
+		t.Errorf("ReadStatus(other domain): %v, want %v", err, codes.NotFound)
+	}
+}
+
+func testReadRejectedPage(ctx context.Context, t *testing.T, m mutator.MutationStorage) {
+	rejected := []*pb.RejectedMutation{
+		{Index: []byte("a"), MutationHash: []byte("hash1"), Epoch: 2, Sequence: 1, Reason: "mutation replay"},
+		{Index: []byte("b"), MutationHash: []byte("hash3"), Epoch: 2, Sequence: 3, Reason: "mutation: unauthorized"},
+		{Index: []byte("c"), MutationHash: []byte("hash4"), Epoch: 2, Sequence: 4, Reason: "mutation: invalid signature"},
+	}
+	// Write out of order, and replace an earlier record.
+	for _, rs := range [][]*pb.RejectedMutation{
+		{rejected[2], {Index: []byte("a"), MutationHash: []byte("hash1"), Epoch: 2, Sequence: 1, Reason: "replaced"}},
+		{rejected[0], rejected[1]},
+	} {
+		if err := m.WriteRejected(ctx, domainID, 2, rs); err != nil {
+			t.Fatalf("WriteRejected(): %v", err)
+		}
+	}
+
+	for _, tc := range []struct {
+		description string
+		revision    int64
+		start       int64
+		pageSize    int32
+		want        []*pb.RejectedMutation
+	}{
+		{description: "all", revision: 2, start: 0, pageSize: 10, want: rejected},
+		{description: "start", revision: 2, start: 2, pageSize: 10, want: rejected[1:]},
+		{description: "page size", revision: 2, start: 0, pageSize: 2, want: rejected[:2]},
+		{description: "other revision", revision: 3, start: 0, pageSize: 10, want: []*pb.RejectedMutation{}},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			got, err := m.ReadRejectedPage(ctx, domainID, tc.revision, tc.start, tc.pageSize)
+			if err != nil {
+				t.Fatalf("ReadRejectedPage(): %v", err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("ReadRejectedPage(): %d mutations, want %d", len(got), len(tc.want))
+			}
+			for i := range got {
+				if !proto.Equal(got[i], tc.want[i]) {
+					t.Errorf("ReadRejectedPage()[%d]: %v, want %v", i, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+}
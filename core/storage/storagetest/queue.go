@@ -0,0 +1,376 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storagetest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/keytransparency/core/mutator"
+	"github.com/google/keytransparency/core/mutator/entry"
+
+	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
+)
+
+// MutationQueueFactory returns an empty mutator.MutationQueue, the
+// mutator.MutationStorage that SequenceBatch removes its messages with, and a
+// function that releases them.
+type MutationQueueFactory func(ctx context.Context, t *testing.T) (mutator.MutationQueue, mutator.MutationStorage, func())
+
+// MutationQueueTester verifies implementations of mutator.MutationQueue.
+// Receivers are only flushed explicitly, so implementations must support
+// FlushN.
+type MutationQueueTester struct {
+	NewQueue MutationQueueFactory
+}
+
+// RunAllTests runs all the mutator.MutationQueue tests.
+func (tester MutationQueueTester) RunAllTests(t *testing.T) {
+	ctx := context.Background()
+	for _, test := range []struct {
+		name string
+		fn   func(context.Context, *testing.T, mutator.MutationQueue, mutator.MutationStorage)
+	}{
+		{"TestReceive", testQueueReceive},
+		{"TestRedeliver", testQueueRedeliver},
+		{"TestIDs", testQueueIDs},
+		{"TestStats", testQueueStats},
+		{"TestQueuedStatus", testQueuedStatus},
+		{"TestSequenceBatchDequeues", testSequenceBatchDequeues},
+		{"TestSequenceBatchStale", testSequenceBatchStale},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			q, m, done := tester.NewQueue(ctx, t)
+			defer done()
+			test.fn(ctx, t, q, m)
+		})
+	}
+}
+
+// receiverOptions returns options under which batches are only sent by FlushN.
+func receiverOptions(maxBatchSize int32) mutator.ReceiverOptions {
+	return mutator.ReceiverOptions{
+		MaxBatchSize: maxBatchSize,
+		Period:       time.Hour,
+		MaxPeriod:    2 * time.Hour,
+	}
+}
+
+func fillQueue(ctx context.Context, q mutator.MutationQueue, domainID string, first, last int) error {
+	for i := first; i <= last; i++ {
+		if err := q.Send(ctx, domainID, genUpdate(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// batchRecorder records the batches delivered to a receiver.
+type batchRecorder struct {
+	batches [][]*mutator.QueueMessage
+	err     error
+}
+
+func (b *batchRecorder) receive(msgs []*mutator.QueueMessage) error {
+	b.batches = append(b.batches, msgs)
+	return b.err
+}
+
+func (b *batchRecorder) ids() [][]int64 {
+	ret := make([][]int64, 0, len(b.batches))
+	for _, msgs := range b.batches {
+		ids := make([]int64, 0, len(msgs))
+		for _, msg := range msgs {
+			ids = append(ids, msg.ID)
+		}
+		ret = append(ret, ids)
+	}
+	return ret
+}
+
+// testQueueReceive verifies that messages are delivered in the order they were
+// sent, in batches of at most MaxBatchSize.
+func testQueueReceive(ctx context.Context, t *testing.T, q mutator.MutationQueue, _ mutator.MutationStorage) {
+	if err := fillQueue(ctx, q, domainID, 1, 5); err != nil {
+		t.Fatalf("Failed to fill queue: %v", err)
+	}
+	if err := fillQueue(ctx, q, "other", 6, 6); err != nil {
+		t.Fatalf("Failed to fill queue: %v", err)
+	}
+	var b batchRecorder
+	r := q.NewReceiver(ctx, time.Now(), domainID, b.receive, receiverOptions(3))
+	defer r.Close()
+
+	for _, tc := range []struct {
+		description string
+		n           int
+		want        []*pb.EntryUpdate
+		wantErr     bool
+	}{
+		{description: "read half", n: 1, want: []*pb.EntryUpdate{genUpdate(1), genUpdate(2), genUpdate(3)}},
+		{description: "too few", n: 3, wantErr: true},
+		{description: "read rest", n: 2, want: []*pb.EntryUpdate{genUpdate(4), genUpdate(5)}},
+		{description: "empty queue", n: 1, wantErr: true},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			b.batches = nil
+			err := r.FlushN(ctx, tc.n)
+			if got, want := err != nil, tc.wantErr; got != want {
+				t.Fatalf("FlushN(%v): %v, want err: %v", tc.n, err, want)
+			}
+			if err != nil {
+				if len(b.batches) != 0 {
+					t.Errorf("FlushN(%v) failed but delivered %v", tc.n, b.ids())
+				}
+				return
+			}
+			if len(b.batches) != 1 {
+				t.Fatalf("FlushN(%v) delivered %v batches, want 1", tc.n, len(b.batches))
+			}
+			msgs := b.batches[0]
+			if got, want := len(msgs), len(tc.want); got != want {
+				t.Fatalf("len(msgs): %v, want %v", got, want)
+			}
+			for i, msg := range msgs {
+				if got, want := msg.Mutation, tc.want[i].Mutation; !proto.Equal(got, want) {
+					t.Errorf("msg[%v].Mutation: %v, want %v", i, got, want)
+				}
+				if got, want := msg.ExtraData, tc.want[i].Committed; !proto.Equal(got, want) {
+					t.Errorf("msg[%v].ExtraData: %v, want %v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+// testQueueRedeliver verifies that a batch is delivered again if receiveFunc
+// fails, and removed from the queue once it succeeds.
+func testQueueRedeliver(ctx context.Context, t *testing.T, q mutator.MutationQueue, _ mutator.MutationStorage) {
+	if err := fillQueue(ctx, q, domainID, 1, 5); err != nil {
+		t.Fatalf("Failed to fill queue: %v", err)
+	}
+	b := batchRecorder{err: fmt.Errorf("receiveFunc failed")}
+	r := q.NewReceiver(ctx, time.Now(), domainID, b.receive, receiverOptions(2))
+	defer r.Close()
+
+	if err := r.FlushN(ctx, 1); err == nil {
+		t.Errorf("FlushN(): nil, want err after receiveFunc failed")
+	}
+	b.err = nil
+	for i := 0; i < 2; i++ {
+		if err := r.FlushN(ctx, 1); err != nil {
+			t.Errorf("FlushN(): %v", err)
+		}
+	}
+	ids := b.ids()
+	if len(ids) != 3 {
+		t.Fatalf("delivered batches: %v, want 3 batches", ids)
+	}
+	if !reflect.DeepEqual(ids[0], ids[1]) {
+		t.Errorf("delivered batches: %v, want the failed batch delivered again", ids)
+	}
+	if got, want := len(ids[2]), 2; got != want || ids[2][0] <= ids[1][1] {
+		t.Errorf("delivered batches: %v, want the next 2 messages last", ids)
+	}
+}
+
+// testQueueIDs verifies that message IDs increase in the order messages are
+// sent to a domain's queue, and are not reused once messages are removed.
+func testQueueIDs(ctx context.Context, t *testing.T, q mutator.MutationQueue, _ mutator.MutationStorage) {
+	var b batchRecorder
+	r := q.NewReceiver(ctx, time.Now(), domainID, b.receive, receiverOptions(10))
+	defer r.Close()
+
+	for i := 0; i < 2; i++ {
+		if err := fillQueue(ctx, q, domainID, 1, 3); err != nil {
+			t.Fatalf("Failed to fill queue: %v", err)
+		}
+		if err := fillQueue(ctx, q, "other", 1, 2); err != nil {
+			t.Fatalf("Failed to fill queue: %v", err)
+		}
+		if err := r.FlushN(ctx, 3); err != nil {
+			t.Fatalf("FlushN(): %v", err)
+		}
+	}
+	var last int64
+	for _, ids := range b.ids() {
+		if len(ids) != 3 {
+			t.Errorf("delivered batch %v, want 3 messages", ids)
+		}
+		for _, id := range ids {
+			if id <= last {
+				t.Errorf("delivered IDs %v, want increasing IDs", b.ids())
+			}
+			last = id
+		}
+	}
+}
+
+func testQueueStats(ctx context.Context, t *testing.T, q mutator.MutationQueue, _ mutator.MutationStorage) {
+	stats, err := q.Stats(ctx, domainID)
+	if err != nil {
+		t.Fatalf("Stats(): %v", err)
+	}
+	if got, want := stats, (&mutator.QueueStats{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Stats() of empty queue: %+v, want %+v", got, want)
+	}
+
+	before := time.Now()
+	if err := fillQueue(ctx, q, domainID, 1, 5); err != nil {
+		t.Fatalf("Failed to fill queue: %v", err)
+	}
+	after := time.Now()
+	if err := fillQueue(ctx, q, "other", 6, 6); err != nil {
+		t.Fatalf("Failed to fill queue: %v", err)
+	}
+	var b batchRecorder
+	r := q.NewReceiver(ctx, time.Now(), domainID, b.receive, receiverOptions(3))
+	defer r.Close()
+
+	for _, tc := range []struct {
+		desc      string
+		flush     bool
+		wantDepth int64
+	}{
+		{desc: "full", wantDepth: 5},
+		{desc: "received", flush: true, wantDepth: 2},
+	} {
+		if tc.flush {
+			if err := r.FlushN(ctx, 1); err != nil {
+				t.Fatalf("FlushN(): %v", err)
+			}
+		}
+		stats, err := q.Stats(ctx, domainID)
+		if err != nil {
+			t.Fatalf("Stats(): %v", err)
+		}
+		if got, want := stats.Depth, tc.wantDepth; got != want {
+			t.Errorf("%v: Stats().Depth: %v, want %v", tc.desc, got, want)
+		}
+		if stats.Oldest.Before(before) || stats.Oldest.After(after) {
+			t.Errorf("%v: Stats().Oldest: %v, want between %v and %v", tc.desc, stats.Oldest, before, after)
+		}
+	}
+}
+
+// testQueuedStatus verifies that Send records the status of the mutation as
+// QUEUED.
+func testQueuedStatus(ctx context.Context, t *testing.T, q mutator.MutationQueue, m mutator.MutationStorage) {
+	update := genUpdate(1)
+	hash, err := entry.Hash(update.GetMutation())
+	if err != nil {
+		t.Fatalf("entry.Hash(): %v", err)
+	}
+	if err := q.Send(ctx, domainID, update); err != nil {
+		t.Fatalf("Send(): %v", err)
+	}
+	got, err := m.ReadStatus(ctx, domainID, hash)
+	if err != nil {
+		t.Fatalf("ReadStatus(): %v", err)
+	}
+	if want := (&pb.MutationStatus{State: pb.MutationStatus_QUEUED}); !proto.Equal(got, want) {
+		t.Errorf("ReadStatus(): %v, want %v", got, want)
+	}
+}
+
+// testSequenceBatchDequeues verifies that SequenceBatch removes a delivered
+// batch from the queue, even if receiveFunc then fails.
+func testSequenceBatchDequeues(ctx context.Context, t *testing.T, q mutator.MutationQueue, m mutator.MutationStorage) {
+	if err := fillQueue(ctx, q, domainID, 1, 5); err != nil {
+		t.Fatalf("Failed to fill queue: %v", err)
+	}
+	var sequenced []*mutator.QueueMessage
+	r := q.NewReceiver(ctx, time.Now(), domainID, func(msgs []*mutator.QueueMessage) error {
+		if sequenced != nil {
+			return nil
+		}
+		if err := m.SequenceBatch(ctx, domainID, 1, msgs); err != nil {
+			t.Errorf("SequenceBatch(): %v", err)
+		}
+		sequenced = msgs
+		return fmt.Errorf("receiveFunc failed after SequenceBatch")
+	}, receiverOptions(3))
+	defer r.Close()
+
+	if err := r.FlushN(ctx, 3); err == nil {
+		t.Errorf("FlushN(): nil, want err after receiveFunc failed")
+	}
+	stats, err := q.Stats(ctx, domainID)
+	if err != nil {
+		t.Fatalf("Stats(): %v", err)
+	}
+	if got, want := stats.Depth, int64(2); got != want {
+		t.Errorf("Stats().Depth: %v, want %v", got, want)
+	}
+	// Only the messages that were not sequenced are delivered again.
+	if err := r.FlushN(ctx, 3); err == nil {
+		t.Errorf("FlushN(3): nil, want err with 2 messages left")
+	}
+	if err := r.FlushN(ctx, 2); err != nil {
+		t.Errorf("FlushN(2): %v", err)
+	}
+
+	saved, err := m.ReadBatch(ctx, domainID, 1)
+	if err != nil {
+		t.Fatalf("ReadBatch(): %v", err)
+	}
+	if got, want := len(saved), len(sequenced); got != want {
+		t.Fatalf("ReadBatch(): %v messages, want %v", got, want)
+	}
+	for i, msg := range saved {
+		if !proto.Equal(msg.Mutation, sequenced[i].Mutation) || !proto.Equal(msg.ExtraData, sequenced[i].ExtraData) {
+			t.Errorf("ReadBatch()[%v]: %v, want %v", i, msg, sequenced[i])
+		}
+	}
+	rev, err := m.HighestSequencedRevision(ctx, domainID)
+	if err != nil {
+		t.Fatalf("HighestSequencedRevision(): %v", err)
+	}
+	if rev != 1 {
+		t.Errorf("HighestSequencedRevision(): %v, want 1", rev)
+	}
+}
+
+// testSequenceBatchStale verifies that messages can't be sequenced again once
+// they have been removed from the queue.
+func testSequenceBatchStale(ctx context.Context, t *testing.T, q mutator.MutationQueue, m mutator.MutationStorage) {
+	if err := fillQueue(ctx, q, domainID, 1, 3); err != nil {
+		t.Fatalf("Failed to fill queue: %v", err)
+	}
+	var sequenced []*mutator.QueueMessage
+	r := q.NewReceiver(ctx, time.Now(), domainID, func(msgs []*mutator.QueueMessage) error {
+		sequenced = msgs
+		return m.SequenceBatch(ctx, domainID, 1, msgs)
+	}, receiverOptions(3))
+	defer r.Close()
+	if err := r.FlushN(ctx, 3); err != nil {
+		t.Fatalf("FlushN(): %v", err)
+	}
+
+	if err := m.SequenceBatch(ctx, domainID, 2, sequenced); err != mutator.ErrStaleBatch {
+		t.Errorf("SequenceBatch() of dequeued messages: %v, want %v", err, mutator.ErrStaleBatch)
+	}
+	saved, err := m.ReadBatch(ctx, domainID, 2)
+	if err != nil {
+		t.Fatalf("ReadBatch(): %v", err)
+	}
+	if len(saved) != 0 {
+		t.Errorf("ReadBatch(2): %v messages, want 0", len(saved))
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package storagetest exports conformance tests for the storage interfaces of
+// Key Transparency. Every implementation, including fakes, should pass them.
+//
+// Each tester is given a factory that returns new, empty storage. Every test
+// runs against its own storage and calls the returned function when it is
+// done with it.
+package storagetest
+
+import (
+	"fmt"
+
+	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
+)
+
+const domainID = "default"
+
+func genMutation(i int) *pb.Entry {
+	return &pb.Entry{
+		Index:      []byte(fmt.Sprintf("index%d", i)),
+		Commitment: []byte(fmt.Sprintf("mutation%d", i)),
+	}
+}
+
+func genUpdate(i int) *pb.EntryUpdate {
+	return &pb.EntryUpdate{
+		Mutation: genMutation(i),
+		Committed: &pb.Committed{
+			Key:  []byte(fmt.Sprintf("nonce%d", i)),
+			Data: []byte(fmt.Sprintf("data%d", i)),
+		},
+	}
+}
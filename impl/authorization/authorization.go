@@ -57,6 +57,12 @@ func (a *AuthzPolicy) Authorize(ctx context.Context, m interface{}) error {
 
 }
 
+// AuthorizeUpdate verifies that the identity issuing the call may make update.
+// It is used by the key server to authorize each update of a batch.
+func (a *AuthzPolicy) AuthorizeUpdate(ctx context.Context, update *pb.UpdateEntryRequest) error {
+	return a.Authorize(ctx, update)
+}
+
 func (a *AuthzPolicy) checkPermission(sctx *authentication.SecurityContext, domainID, appID, userID string) error {
 	// Case 1.
 	if sctx.Email == userID {
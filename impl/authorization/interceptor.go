@@ -27,6 +27,8 @@ import (
 // AuthPair defines an authentication and authorization pair.
 type AuthPair struct {
 	AuthnFunc grpc_auth.AuthFunc
+	// AuthzFunc is nil for methods that authorize requests themselves,
+	// such as BatchUpdateEntries, which authorizes each update.
 	AuthzFunc AuthzFunc
 }
 
@@ -44,8 +46,10 @@ func UnaryServerInterceptor(authFuncs map[string]AuthPair) grpc.UnaryServerInter
 		if err != nil {
 			return nil, err
 		}
-		if err := policy.AuthzFunc(newCtx, req); err != nil {
-			return nil, err
+		if policy.AuthzFunc != nil {
+			if err := policy.AuthzFunc(newCtx, req); err != nil {
+				return nil, err
+			}
 		}
 		return handler(newCtx, req)
 	}
@@ -65,8 +69,10 @@ func StreamServerInterceptor(authFuncs map[string]AuthPair) grpc.StreamServerInt
 		if err != nil {
 			return err
 		}
-		if err := policy.AuthzFunc(newCtx, stream); err != nil {
-			return err
+		if policy.AuthzFunc != nil {
+			if err := policy.AuthzFunc(newCtx, stream); err != nil {
+				return err
+			}
 		}
 		wrapped := grpc_middleware.WrapServerStream(stream)
 		wrapped.WrappedContext = newCtx
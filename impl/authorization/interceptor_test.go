@@ -0,0 +1,119 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authorization
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/keytransparency/impl/authentication"
+	"github.com/grpc-ecosystem/go-grpc-middleware/util/metautils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
+)
+
+func TestUnaryServerInterceptorBatch(t *testing.T) {
+	ctx := context.Background()
+	const method = "/google.keytransparency.v1.KeyTransparency/BatchUpdateEntries"
+	interceptor := UnaryServerInterceptor(map[string]AuthPair{
+		method: {
+			// Each update is authorized by the handler.
+			AuthnFunc: authentication.FakeAuthFunc,
+		},
+	})
+	for _, tc := range []struct {
+		desc       string
+		identity   string
+		appIDs     []string
+		userIDs    []string
+		wantDenied []int
+	}{
+		{
+			desc:     "self updating own profiles",
+			identity: testUser,
+			appIDs:   []string{"1", "10"},
+			userIDs:  []string{testUser, testUser},
+		},
+		{
+			desc:     "admin authorized for every item",
+			identity: admin3,
+			appIDs:   []string{"2", "2"},
+			userIDs:  []string{"a", "b"},
+		},
+		{
+			desc:       "admin not authorized for one item",
+			identity:   admin1,
+			appIDs:     []string{"1", "2", "1"},
+			userIDs:    []string{"a", "b", "c"},
+			wantDenied: []int{1},
+		},
+		{
+			desc:       "self not authorized for other user",
+			identity:   testUser,
+			appIDs:     []string{"1", "1"},
+			userIDs:    []string{testUser, "other"},
+			wantDenied: []int{1},
+		},
+		{
+			desc:       "no item authorized",
+			identity:   testUser,
+			appIDs:     []string{"1", "2"},
+			userIDs:    []string{"a", "b"},
+			wantDenied: []int{0, 1},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			inCtx := metautils.ExtractOutgoing(authentication.WithOutgoingFakeAuth(ctx, tc.identity)).ToIncoming(ctx)
+			req := &pb.BatchUpdateEntriesRequest{DomainId: "1"}
+			for i := range tc.appIDs {
+				req.Updates = append(req.Updates, &pb.UpdateEntryRequest{
+					AppId:  tc.appIDs[i],
+					UserId: tc.userIDs[i],
+				})
+			}
+			// The batch reaches the handler, which authorizes each
+			// update as the key server does.
+			var denied map[int]*status.Status
+			handler := func(ctx context.Context, in interface{}) (interface{}, error) {
+				denied = make(map[int]*status.Status)
+				batch := in.(*pb.BatchUpdateEntriesRequest)
+				for i, u := range batch.Updates {
+					update := &pb.UpdateEntryRequest{DomainId: batch.DomainId, AppId: u.AppId, UserId: u.UserId}
+					if err := authz.AuthorizeUpdate(ctx, update); err != nil {
+						denied[i] = status.Convert(err)
+					}
+				}
+				return nil, nil
+			}
+			if _, err := interceptor(inCtx, req, &grpc.UnaryServerInfo{FullMethod: method}, handler); err != nil {
+				t.Fatalf("interceptor(): %v", err)
+			}
+			if denied == nil {
+				t.Fatalf("handler not called")
+			}
+			if got, want := len(denied), len(tc.wantDenied); got != want {
+				t.Errorf("%v items denied, want %v", got, want)
+			}
+			for _, i := range tc.wantDenied {
+				if got, want := denied[i].Code(), codes.PermissionDenied; got != want {
+					t.Errorf("item %v: %v, want %v", i, denied[i].Err(), want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filestore
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/google/keytransparency/core/domain"
+	"github.com/google/trillian/crypto/keyspb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
+)
+
+type domainStorage struct {
+	s *Store
+}
+
+// NewDomainStorage returns a domain.Storage backed by s.
+func NewDomainStorage(s *Store) domain.Storage {
+	return &domainStorage{s: s}
+}
+
+// List returns the domains sorted by domain ID.
+func (ds *domainStorage) List(ctx context.Context, showDeleted bool) ([]*domain.Domain, error) {
+	ret := []*domain.Domain{}
+	err := ds.s.read(func(st *state) error {
+		for _, row := range st.Domains {
+			if row.Deleted && !showDeleted {
+				continue
+			}
+			d, err := row.domain()
+			if err != nil {
+				return err
+			}
+			ret = append(ret, d)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].DomainID < ret[j].DomainID })
+	return ret, nil
+}
+
+func (ds *domainStorage) Write(ctx context.Context, d *domain.Domain) error {
+	row, err := newDomainRow(d)
+	if err != nil {
+		return err
+	}
+	return ds.s.update(func(st *state) ([]change, error) {
+		if _, ok := st.Domains[d.DomainID]; ok {
+			return nil, status.Errorf(codes.AlreadyExists, "domain %v already exists", d.DomainID)
+		}
+		return []change{&writeDomain{Row: row}}, nil
+	})
+}
+
+func (ds *domainStorage) Read(ctx context.Context, domainID string, showDeleted bool) (*domain.Domain, error) {
+	var d *domain.Domain
+	err := ds.s.read(func(st *state) error {
+		row, ok := st.Domains[domainID]
+		if !ok || (row.Deleted && !showDeleted) {
+			return status.Errorf(codes.NotFound, "domain %v not found", domainID)
+		}
+		var err error
+		d, err = row.domain()
+		return err
+	})
+	return d, err
+}
+
+func (ds *domainStorage) SetDelete(ctx context.Context, domainID string, isDeleted bool) error {
+	return ds.s.update(func(st *state) ([]change, error) {
+		if _, ok := st.Domains[domainID]; !ok {
+			return nil, status.Errorf(codes.NotFound, "domain %v not found", domainID)
+		}
+		return []change{&setDeleted{DomainID: domainID, Deleted: isDeleted}}, nil
+	})
+}
+
+// newDomainRow marshals the protos of d.
+func newDomainRow(d *domain.Domain) (*domainRow, error) {
+	vrfPriv, err := wrapAnyProto(d.VRFPriv)
+	if err != nil {
+		return nil, err
+	}
+	row := &domainRow{
+		DomainID:      d.DomainID,
+		MapID:         d.MapID,
+		LogID:         d.LogID,
+		VRFPublicKey:  d.VRF.GetDer(),
+		VRFPrivateKey: vrfPriv,
+		MinInterval:   d.MinInterval.Nanoseconds(),
+		MaxInterval:   d.MaxInterval.Nanoseconds(),
+	}
+	if d.ReceiptPriv != nil {
+		row.ReceiptPublicKey = d.ReceiptKey.GetDer()
+		if row.ReceiptPrivateKey, err = wrapAnyProto(d.ReceiptPriv); err != nil {
+			return nil, err
+		}
+	}
+	if d.Sequencing != nil {
+		if row.SequencingConfig, err = proto.Marshal(d.Sequencing); err != nil {
+			return nil, err
+		}
+	}
+	return row, nil
+}
+
+// domain unmarshals the protos of r.
+func (r *domainRow) domain() (*domain.Domain, error) {
+	d := &domain.Domain{
+		DomainID:    r.DomainID,
+		MapID:       r.MapID,
+		LogID:       r.LogID,
+		VRF:         &keyspb.PublicKey{Der: r.VRFPublicKey},
+		MinInterval: time.Duration(r.MinInterval),
+		MaxInterval: time.Duration(r.MaxInterval),
+		Deleted:     r.Deleted,
+	}
+	var err error
+	if d.VRFPriv, err = unwrapAnyProto(r.VRFPrivateKey); err != nil {
+		return nil, err
+	}
+	if len(r.ReceiptPrivateKey) > 0 {
+		d.ReceiptKey = &keyspb.PublicKey{Der: r.ReceiptPublicKey}
+		if d.ReceiptPriv, err = unwrapAnyProto(r.ReceiptPrivateKey); err != nil {
+			return nil, err
+		}
+	}
+	if len(r.SequencingConfig) > 0 {
+		d.Sequencing = &pb.SequencingConfig{}
+		if err := proto.Unmarshal(r.SequencingConfig, d.Sequencing); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// wrapAnyProto returns a serialized any.Any containing msg.
+func wrapAnyProto(msg proto.Message) ([]byte, error) {
+	anyPB, err := ptypes.MarshalAny(msg)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(anyPB)
+}
+
+// unwrapAnyProto returns the proto object serialized inside a serialized any.Any.
+func unwrapAnyProto(anyData []byte) (proto.Message, error) {
+	var anyPB any.Any
+	if err := proto.Unmarshal(anyData, &anyPB); err != nil {
+		return nil, err
+	}
+	var privKey ptypes.DynamicAny
+	if err := ptypes.UnmarshalAny(&anyPB, &privKey); err != nil {
+		return nil, err
+	}
+	return privKey.Message, nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filestore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/keytransparency/core/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	tpb "github.com/google/keytransparency/core/api/type/type_go_proto"
+)
+
+type keySets struct {
+	s *Store
+}
+
+// NewKeySets returns a storage.KeySets backed by s.
+func NewKeySets(s *Store) storage.KeySets {
+	return &keySets{s: s}
+}
+
+// keySetKey returns the key of a keyset in state.KeySets.
+func keySetKey(instance int64, domainID, appID string) string {
+	return fmt.Sprintf("%d/%q/%q", instance, domainID, appID)
+}
+
+// Get returns a stored keyset.
+func (k *keySets) Get(ctx context.Context, instance int64, domainID, appID string) (*tpb.KeySet, error) {
+	var data []byte
+	if err := k.s.read(func(st *state) error {
+		var ok bool
+		data, ok = st.KeySets[keySetKey(instance, domainID, appID)]
+		if !ok {
+			return status.Errorf(codes.NotFound, "keyset %v/%v/%v not found", instance, domainID, appID)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	ks := &tpb.KeySet{}
+	if err := proto.Unmarshal(data, ks); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// Set saves a keyset. Keysets cannot be replaced.
+func (k *keySets) Set(ctx context.Context, instance int64, domainID, appID string, ks *tpb.KeySet) error {
+	data, err := proto.Marshal(ks)
+	if err != nil {
+		return err
+	}
+	key := keySetKey(instance, domainID, appID)
+	return k.s.update(func(st *state) ([]change, error) {
+		if _, ok := st.KeySets[key]; ok {
+			return nil, status.Errorf(codes.AlreadyExists, "keyset %v/%v/%v already exists", instance, domainID, appID)
+		}
+		return []change{&setKeySet{Key: key, KeySet: data}}, nil
+	})
+}
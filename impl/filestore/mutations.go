@@ -0,0 +1,268 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filestore
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/keytransparency/core/mutator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
+)
+
+// Mutations implements mutator.MutationStorage and mutator.MutationQueue.
+type Mutations struct {
+	s *Store
+}
+
+// NewMutations returns mutation storage and a mutation queue backed by s.
+func NewMutations(s *Store) *Mutations {
+	return &Mutations{s: s}
+}
+
+// ReadPage returns up to pageSize mutations of domainID/revision with a
+// sequence number of at least start, and the highest sequence number read.
+func (m *Mutations) ReadPage(ctx context.Context, domainID string, revision, start int64, pageSize int32) (int64, []*pb.Entry, error) {
+	if start < 0 {
+		start = 0
+	}
+	entries := make([]*pb.Entry, 0)
+	if err := m.s.read(func(st *state) error {
+		batch := st.readLog(domainID).Batches[revision]
+		for i := start; i < int64(len(batch)) && len(entries) < int(pageSize); i++ {
+			u, err := unmarshalUpdate(batch[i])
+			if err != nil {
+				return err
+			}
+			entries = append(entries, u.GetMutation())
+		}
+		return nil
+	}); err != nil {
+		return 0, nil, err
+	}
+	if len(entries) == 0 {
+		return 0, entries, nil
+	}
+	return start + int64(len(entries)) - 1, entries, nil
+}
+
+// WriteBatch saves mutations under domainID/revision, replacing any mutations
+// previously saved there.
+func (m *Mutations) WriteBatch(ctx context.Context, domainID string, revision int64, mutations []*pb.Entry) error {
+	msgs := make([]*mutator.QueueMessage, 0, len(mutations))
+	for _, e := range mutations {
+		msgs = append(msgs, &mutator.QueueMessage{Mutation: e})
+	}
+	batch, err := marshalBatch(msgs)
+	if err != nil {
+		return err
+	}
+	return m.s.update(func(*state) ([]change, error) {
+		return []change{&writeBatch{DomainID: domainID, Revision: revision, Mutations: batch}}, nil
+	})
+}
+
+// SequenceBatch saves msgs under domainID/revision, removes msgs from the
+// queue, and records revision as the highest sequenced revision of domainID,
+// in a single record. Nothing is saved unless revision follows the highest
+// sequenced revision and msgs are all still queued.
+func (m *Mutations) SequenceBatch(ctx context.Context, domainID string, revision int64, msgs []*mutator.QueueMessage) error {
+	batch, err := marshalBatch(msgs)
+	if err != nil {
+		return err
+	}
+	return m.s.update(func(st *state) ([]change, error) {
+		if last := st.readLog(domainID).Sequenced; last != 0 && last != revision-1 {
+			return nil, mutator.ErrStaleBatch
+		}
+		changes := []change{&writeBatch{DomainID: domainID, Revision: revision, Mutations: batch}}
+		if len(msgs) > 0 {
+			// msgs is a batch returned by claimQueue.
+			first, last := msgs[0].ID, msgs[len(msgs)-1].ID
+			queued := 0
+			for _, q := range st.readLog(domainID).Queue {
+				if q.ID >= first && q.ID <= last {
+					queued++
+				}
+			}
+			if queued != len(msgs) {
+				// Some messages were sequenced by another receiver.
+				return nil, mutator.ErrStaleBatch
+			}
+			changes = append(changes, &dequeue{DomainID: domainID, First: first, Last: last})
+		}
+		return append(changes, &setSequenced{DomainID: domainID, Revision: revision}), nil
+	})
+}
+
+// ReadBatch returns the messages saved for domainID/revision.
+func (m *Mutations) ReadBatch(ctx context.Context, domainID string, revision int64) ([]*mutator.QueueMessage, error) {
+	msgs := make([]*mutator.QueueMessage, 0)
+	if err := m.s.read(func(st *state) error {
+		for _, data := range st.readLog(domainID).Batches[revision] {
+			u, err := unmarshalUpdate(data)
+			if err != nil {
+				return err
+			}
+			msgs = append(msgs, &mutator.QueueMessage{Mutation: u.GetMutation(), ExtraData: u.GetCommitted()})
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+// HighestSequencedRevision returns the highest revision recorded by
+// SequenceBatch for domainID, or 0 if there is none.
+func (m *Mutations) HighestSequencedRevision(ctx context.Context, domainID string) (int64, error) {
+	var rev int64
+	err := m.s.read(func(st *state) error {
+		rev = st.readLog(domainID).Sequenced
+		return nil
+	})
+	return rev, err
+}
+
+// WriteIndexChanges records the map indexes that changed in revision.
+func (m *Mutations) WriteIndexChanges(ctx context.Context, domainID string, revision int64, indexes [][]byte) error {
+	return m.s.update(func(*state) ([]change, error) {
+		return []change{&writeIndexChanges{DomainID: domainID, Revision: revision, Indexes: indexes}}, nil
+	})
+}
+
+// ListIndexChanges returns the revisions in [start, end] in which the map leaf
+// at index changed, in ascending order. At most limit revisions are returned.
+func (m *Mutations) ListIndexChanges(ctx context.Context, domainID string, index []byte, start, end int64, limit int32) ([]int64, error) {
+	revisions := make([]int64, 0)
+	err := m.s.read(func(st *state) error {
+		for _, rev := range st.readLog(domainID).Changes[string(index)] {
+			if len(revisions) >= int(limit) || rev > end {
+				break
+			}
+			if rev >= start {
+				revisions = append(revisions, rev)
+			}
+		}
+		return nil
+	})
+	return revisions, err
+}
+
+// WriteStatus records the processing state of the mutation identified by hash.
+func (m *Mutations) WriteStatus(ctx context.Context, domainID string, hash []byte, s *pb.MutationStatus) error {
+	return m.s.update(func(*state) ([]change, error) {
+		return []change{newWriteStatus(domainID, hash, s)}, nil
+	})
+}
+
+func newWriteStatus(domainID string, hash []byte, s *pb.MutationStatus) *writeStatus {
+	return &writeStatus{
+		DomainID: domainID,
+		Hash:     hash,
+		Status: storedStatus{
+			State:    int32(s.GetState()),
+			Revision: s.GetEpoch(),
+			Error:    s.GetError(),
+		},
+	}
+}
+
+// ReadStatus returns the processing state of the mutation identified by hash.
+func (m *Mutations) ReadStatus(ctx context.Context, domainID string, hash []byte) (*pb.MutationStatus, error) {
+	var s *pb.MutationStatus
+	err := m.s.read(func(st *state) error {
+		stored, ok := st.readLog(domainID).Statuses[string(hash)]
+		if !ok {
+			return status.Errorf(codes.NotFound, "mutation %x not found", hash)
+		}
+		s = &pb.MutationStatus{
+			State: pb.MutationStatus_State(stored.State),
+			Epoch: stored.Revision,
+			Error: stored.Error,
+		}
+		return nil
+	})
+	return s, err
+}
+
+// WriteRejected records the mutations that were rejected in revision.
+func (m *Mutations) WriteRejected(ctx context.Context, domainID string, revision int64, rejected []*pb.RejectedMutation) error {
+	rs := make([]storedRejected, 0, len(rejected))
+	for _, r := range rejected {
+		rs = append(rs, storedRejected{
+			Sequence:     r.GetSequence(),
+			Index:        r.GetIndex(),
+			MutationHash: r.GetMutationHash(),
+			Reason:       r.GetReason(),
+		})
+	}
+	return m.s.update(func(*state) ([]change, error) {
+		return []change{&writeRejected{DomainID: domainID, Revision: revision, Rejected: rs}}, nil
+	})
+}
+
+// ReadRejectedPage reads the mutations rejected in revision, starting at
+// sequence number start. At most pageSize mutations are returned.
+func (m *Mutations) ReadRejectedPage(ctx context.Context, domainID string, revision, start int64, pageSize int32) ([]*pb.RejectedMutation, error) {
+	results := make([]*pb.RejectedMutation, 0)
+	err := m.s.read(func(st *state) error {
+		for _, r := range st.readLog(domainID).Rejected[revision] {
+			if len(results) >= int(pageSize) {
+				break
+			}
+			if r.Sequence < start {
+				continue
+			}
+			results = append(results, &pb.RejectedMutation{
+				Epoch:        revision,
+				Sequence:     r.Sequence,
+				Index:        r.Index,
+				MutationHash: r.MutationHash,
+				Reason:       r.Reason,
+			})
+		}
+		return nil
+	})
+	return results, err
+}
+
+// marshalBatch marshals msgs as EntryUpdates.
+func marshalBatch(msgs []*mutator.QueueMessage) ([][]byte, error) {
+	batch := make([][]byte, 0, len(msgs))
+	for _, msg := range msgs {
+		data, err := proto.Marshal(&pb.EntryUpdate{Mutation: msg.Mutation, Committed: msg.ExtraData})
+		if err != nil {
+			return nil, err
+		}
+		batch = append(batch, data)
+	}
+	return batch, nil
+}
+
+// unmarshalUpdate unmarshals an EntryUpdate. Its mutation is never nil.
+func unmarshalUpdate(data []byte) (*pb.EntryUpdate, error) {
+	u := &pb.EntryUpdate{}
+	if err := proto.Unmarshal(data, u); err != nil {
+		return nil, err
+	}
+	if u.Mutation == nil {
+		u.Mutation = &pb.Entry{}
+	}
+	return u, nil
+}
@@ -0,0 +1,316 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filestore
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/keytransparency/core/mutator"
+	"github.com/google/keytransparency/core/mutator/entry"
+
+	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
+)
+
+// defaultClaimTimeout is used when ReceiverOptions.ClaimTimeout is not set.
+const defaultClaimTimeout = 5 * time.Minute
+
+// Send adds update to the end of the queue of domainID, with the next ID of
+// the queue, and records the mutation's status as QUEUED.
+func (m *Mutations) Send(ctx context.Context, domainID string, update *pb.EntryUpdate) error {
+	glog.Infof("queue.Send(%v, <mutation>)", domainID)
+	mData, err := proto.Marshal(update)
+	if err != nil {
+		return err
+	}
+	hash, err := entry.Hash(update.GetMutation())
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	return m.s.update(func(*state) ([]change, error) {
+		return []change{
+			&enqueue{DomainID: domainID, Time: now.UnixNano(), Update: mData},
+			newWriteStatus(domainID, hash, &pb.MutationStatus{State: pb.MutationStatus_QUEUED}),
+		}, nil
+	})
+}
+
+// Stats returns the number of mutations in the queue of domainID and the time
+// the oldest of them was sent.
+func (m *Mutations) Stats(ctx context.Context, domainID string) (*mutator.QueueStats, error) {
+	stats := &mutator.QueueStats{}
+	err := m.s.read(func(st *state) error {
+		queue := st.readLog(domainID).Queue
+		stats.Depth = int64(len(queue))
+		if len(queue) > 0 {
+			// Mutations are queued in the order they are sent.
+			stats.Oldest = time.Unix(0, queue[0].Time)
+		}
+		return nil
+	})
+	return stats, err
+}
+
+// claimQueue claims up to batchSize messages from the head of the queue until
+// now+timeout. Messages are claimed in ID order, so if the head of the queue is
+// claimed by another receiver, no messages are returned.
+func (m *Mutations) claimQueue(ctx context.Context, domainID string, batchSize int32, now time.Time, timeout time.Duration) ([]*mutator.QueueMessage, error) {
+	ms := make([]*mutator.QueueMessage, 0)
+	err := m.s.update(func(st *state) ([]change, error) {
+		var claimed []*queuedMutation
+		for _, q := range st.readLog(domainID).Queue {
+			if int32(len(claimed)) >= batchSize || q.claimExpiry > now.UnixNano() {
+				break
+			}
+			u, err := unmarshalUpdate(q.Update)
+			if err != nil {
+				return nil, err
+			}
+			ms = append(ms, &mutator.QueueMessage{
+				ID:        q.ID,
+				Mutation:  u.GetMutation(),
+				ExtraData: u.GetCommitted(),
+			})
+			claimed = append(claimed, q)
+		}
+		// Claims are not persisted.
+		for _, q := range claimed {
+			q.claimExpiry = now.Add(timeout).UnixNano()
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ms, nil
+}
+
+// releaseMessages removes the claim on a batch returned by claimQueue.
+// Messages are only released while they are still claimed until expiry, so
+// that a receiver whose claim expired can't release the claim of another.
+func (m *Mutations) releaseMessages(ctx context.Context, domainID string, mutations []*mutator.QueueMessage, expiry time.Time) error {
+	if len(mutations) == 0 {
+		return nil
+	}
+	first, last := mutations[0].ID, mutations[len(mutations)-1].ID
+	return m.s.update(func(st *state) ([]change, error) {
+		for _, q := range st.readLog(domainID).Queue {
+			if q.ID >= first && q.ID <= last && q.claimExpiry == expiry.UnixNano() {
+				q.claimExpiry = 0
+			}
+		}
+		return nil, nil
+	})
+}
+
+// releaseClaims removes every claim on the queue of domainID.
+func (m *Mutations) releaseClaims(ctx context.Context, domainID string) error {
+	return m.s.update(func(st *state) ([]change, error) {
+		for _, q := range st.readLog(domainID).Queue {
+			q.claimExpiry = 0
+		}
+		return nil, nil
+	})
+}
+
+// deleteMessages deletes a batch returned by claimQueue. The batch holds every
+// message between its first and last ID, so it is deleted as a range.
+func (m *Mutations) deleteMessages(ctx context.Context, domainID string, mutations []*mutator.QueueMessage) error {
+	glog.V(4).Infof("queue.Delete(%v, <%v mutations>)", domainID, len(mutations))
+	if len(mutations) == 0 {
+		return nil
+	}
+	first, last := mutations[0].ID, mutations[len(mutations)-1].ID
+	return m.s.update(func(st *state) ([]change, error) {
+		// SequenceBatch may have removed the batch already.
+		for _, q := range st.readLog(domainID).Queue {
+			if q.ID >= first && q.ID <= last {
+				return []change{&dequeue{DomainID: domainID, First: first, Last: last}}, nil
+			}
+		}
+		return nil, nil
+	})
+}
+
+// NewReceiver starts receiving messages sent to the queue. As batches become
+// ready, receiveFunc will be called. The receiver takes over the queue of
+// domainID: batches claimed by earlier receivers are released, so that they
+// are delivered again right away.
+func (m *Mutations) NewReceiver(ctx context.Context, last time.Time, domainID string, receiveFunc mutator.ReceiveFunc, rOpts mutator.ReceiverOptions) mutator.Receiver {
+	if err := m.releaseClaims(ctx, domainID); err != nil {
+		// The claims will expire.
+		glog.Errorf("releaseClaims(%v): %v", domainID, err)
+	}
+	r := &Receiver{
+		store:       m,
+		domainID:    domainID,
+		opts:        rOpts,
+		last:        last.UnixNano(),
+		more:        make(chan time.Time, 1),
+		ticker:      time.NewTicker(rOpts.Period),
+		maxTicker:   time.NewTicker(rOpts.MaxPeriod),
+		done:        make(chan interface{}),
+		receiveFunc: receiveFunc,
+	}
+
+	r.running.Add(1)
+	go r.run(ctx, last)
+	return r
+}
+
+// Receiver receives messages from a queue.
+type Receiver struct {
+	store       *Mutations
+	domainID    string
+	receiveFunc mutator.ReceiveFunc
+	opts        mutator.ReceiverOptions
+	more        chan time.Time
+	ticker      *time.Ticker
+	maxTicker   *time.Ticker
+	done        chan interface{}
+	running     sync.WaitGroup
+	// last is the time, in UnixNano, of the latest batch received by
+	// receiveFunc. It is accessed atomically.
+	last int64
+	// blown is set once a blown MMD has been reported, and blownAt holds
+	// the value of last at that time. They are only used by run.
+	blown   bool
+	blownAt int64
+}
+
+// Close stops the receiver and returns only when all callbacks are complete.
+func (r *Receiver) Close() {
+	close(r.done)
+	r.running.Wait()
+	r.ticker.Stop()
+	r.maxTicker.Stop()
+}
+
+// FlushN verifies that a minimum of n items are available to send, and sends them.
+func (r *Receiver) FlushN(ctx context.Context, n int) error {
+	sent := r.sendBatch(ctx, int32(n), r.opts.MaxBatchSize)
+	if sent < int32(n) {
+		// The queue is deterministic, so waiting won't help.
+		return fmt.Errorf("sendBatch(): %v, want >= %v", sent, n)
+	}
+	return nil
+}
+
+func (r *Receiver) run(ctx context.Context, last time.Time) {
+	defer r.running.Done()
+
+	r.checkMMD(time.Now())
+
+	if time.Since(last) > (r.opts.MaxPeriod - r.opts.Period) {
+		r.sendBatch(ctx, 0, r.opts.MaxBatchSize) // We will be overdue for an epoch soon.
+	}
+
+	for {
+		var count int32
+		select {
+		case <-r.more:
+			count = r.sendBatch(ctx, 1, r.opts.MaxBatchSize)
+		case <-r.ticker.C:
+			count = r.sendBatch(ctx, 1, r.opts.MaxBatchSize)
+		case <-r.maxTicker.C:
+			count = r.sendBatch(ctx, 0, r.opts.MaxBatchSize)
+		case <-ctx.Done():
+			return
+		case <-r.done:
+			return
+		}
+		r.checkMMD(time.Now())
+		if count >= r.opts.MaxBatchSize {
+			// Continue sending until we drop below batch size.
+			r.more <- time.Now()
+		}
+	}
+}
+
+// sendBatch sends up to maxBatch items to the receiver. Returns the number of
+// sent items. If the number of available items is < minBatch, 0 items are
+// sent.
+//
+// The items are claimed while receiveFunc runs. They are removed from the
+// queue if it succeeds and released for redelivery otherwise.
+func (r *Receiver) sendBatch(ctx context.Context, minBatch, maxBatch int32) int32 {
+	now, timeout := time.Now(), r.claimTimeout()
+	ms, err := r.store.claimQueue(ctx, r.domainID, maxBatch, now, timeout)
+	if err != nil {
+		glog.Errorf("claimQueue(): %v", err)
+		return 0
+	}
+	expiry := now.Add(timeout)
+	if int32(len(ms)) < minBatch {
+		r.release(ctx, ms, expiry)
+		return 0
+	}
+
+	if err := r.receiveFunc(ms); err != nil {
+		glog.Infof("queue.SendBatch failed: %v", err)
+		r.release(ctx, ms, expiry)
+		return 0
+	}
+
+	atomic.StoreInt64(&r.last, time.Now().UnixNano())
+
+	// Acknowledge the batch by deleting it.
+	if err := r.store.deleteMessages(ctx, r.domainID, ms); err != nil {
+		glog.Errorf("deleteMessages(%v, len(ms): %v): %v", r.domainID, len(ms), err)
+	}
+
+	return int32(len(ms))
+}
+
+// checkMMD reports a violation of the maximum merge delay to MMDBlown if no
+// batch has been received for longer than MaxPeriod at now. Each late batch is
+// reported once. Returns true if a violation was reported.
+func (r *Receiver) checkMMD(now time.Time) bool {
+	last := atomic.LoadInt64(&r.last)
+	if r.blown && r.blownAt == last {
+		return false
+	}
+	got, want := now.Sub(time.Unix(0, last)), r.opts.MaxPeriod
+	if got <= want {
+		return false
+	}
+	glog.Warningf("MMD Blown: Time since last revision of domain %v: %v, want < %v", r.domainID, got, want)
+	r.blown, r.blownAt = true, last
+	if r.opts.MMDBlown != nil {
+		r.opts.MMDBlown(time.Unix(0, last))
+	}
+	return true
+}
+
+func (r *Receiver) claimTimeout() time.Duration {
+	if r.opts.ClaimTimeout > 0 {
+		return r.opts.ClaimTimeout
+	}
+	return defaultClaimTimeout
+}
+
+// release makes ms, claimed until expiry, available for delivery again.
+func (r *Receiver) release(ctx context.Context, ms []*mutator.QueueMessage, expiry time.Time) {
+	if err := r.store.releaseMessages(ctx, r.domainID, ms, expiry); err != nil {
+		// The claim will expire.
+		glog.Errorf("releaseMessages(%v, len(ms): %v): %v", r.domainID, len(ms), err)
+	}
+}
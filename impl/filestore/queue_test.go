@@ -0,0 +1,264 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filestore
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/keytransparency/core/mutator"
+	"github.com/google/keytransparency/core/storage/storagetest"
+)
+
+func TestMutationQueue(t *testing.T) {
+	storagetest.MutationQueueTester{NewQueue: func(ctx context.Context, t *testing.T) (mutator.MutationQueue, mutator.MutationStorage, func()) {
+		s, done := newStore(t)
+		m := NewMutations(s)
+		return m, m, done
+	}}.RunAllTests(t)
+}
+
+// newQueue returns mutations backed by a new store, with five queued
+// mutations.
+func newQueue(ctx context.Context, t *testing.T) (*Mutations, func()) {
+	t.Helper()
+	s, cleanup := newStore(t)
+	m := NewMutations(s)
+	for i := 1; i <= 5; i++ {
+		if err := m.Send(ctx, domainID, genUpdate(i)); err != nil {
+			t.Fatalf("Send(): %v", err)
+		}
+	}
+	return m, cleanup
+}
+
+func TestClaimQueue(t *testing.T) {
+	ctx := context.Background()
+	m, cleanup := newQueue(ctx, t)
+	defer cleanup()
+	now := time.Now()
+	timeout := time.Minute
+
+	for _, tc := range []struct {
+		desc    string
+		before  func() error
+		at      time.Time
+		wantIDs []int64
+	}{
+		{desc: "claim head", at: now, wantIDs: []int64{1, 2, 3}},
+		{desc: "head claimed", at: now, wantIDs: []int64{}},
+		{desc: "claim expired", at: now.Add(2 * timeout), wantIDs: []int64{1, 2, 3}},
+		{desc: "released by expired claim", at: now.Add(2 * timeout), wantIDs: []int64{}, before: func() error {
+			return m.releaseMessages(ctx, domainID, []*mutator.QueueMessage{{ID: 1}, {ID: 3}}, now.Add(timeout))
+		}},
+		{desc: "released", at: now.Add(2 * timeout), wantIDs: []int64{1, 2, 3}, before: func() error {
+			return m.releaseMessages(ctx, domainID, []*mutator.QueueMessage{{ID: 1}, {ID: 3}}, now.Add(3*timeout))
+		}},
+		{desc: "taken over", at: now.Add(2 * timeout), wantIDs: []int64{1, 2, 3}, before: func() error {
+			return m.releaseClaims(ctx, domainID)
+		}},
+		{desc: "acknowledged", at: now.Add(2 * timeout), wantIDs: []int64{4, 5}, before: func() error {
+			return m.deleteMessages(ctx, domainID, []*mutator.QueueMessage{{ID: 1}, {ID: 3}})
+		}},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if tc.before != nil {
+				if err := tc.before(); err != nil {
+					t.Fatalf("before(): %v", err)
+				}
+			}
+			ms, err := m.claimQueue(ctx, domainID, 3, tc.at, timeout)
+			if err != nil {
+				t.Fatalf("claimQueue(): %v", err)
+			}
+			ids := make([]int64, 0, len(ms))
+			for _, msg := range ms {
+				ids = append(ids, msg.ID)
+			}
+			if !reflect.DeepEqual(ids, tc.wantIDs) {
+				t.Errorf("claimQueue(): IDs %v, want %v", ids, tc.wantIDs)
+			}
+		})
+	}
+}
+
+func TestReceiverRedelivers(t *testing.T) {
+	ctx := context.Background()
+	m, cleanup := newQueue(ctx, t)
+	defer cleanup()
+
+	var got [][]int64
+	fail := true
+	r := &Receiver{
+		store:    m,
+		domainID: domainID,
+		opts:     mutator.ReceiverOptions{MaxBatchSize: 2},
+		receiveFunc: func(ms []*mutator.QueueMessage) error {
+			ids := make([]int64, 0, len(ms))
+			for _, msg := range ms {
+				ids = append(ids, msg.ID)
+			}
+			got = append(got, ids)
+			if fail {
+				return fmt.Errorf("receiveFunc failed")
+			}
+			return nil
+		},
+	}
+	if n := r.sendBatch(ctx, 1, 2); n != 0 {
+		t.Errorf("sendBatch(): %v, want 0 after failure", n)
+	}
+	fail = false
+	if n := r.sendBatch(ctx, 1, 2); n != 2 {
+		t.Errorf("sendBatch(): %v, want 2", n)
+	}
+	if n := r.sendBatch(ctx, 1, 2); n != 2 {
+		t.Errorf("sendBatch(): %v, want 2", n)
+	}
+	if want := [][]int64{{1, 2}, {1, 2}, {3, 4}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("delivered batches: %v, want %v", got, want)
+	}
+}
+
+func TestNewReceiverReleasesClaims(t *testing.T) {
+	ctx := context.Background()
+	m, cleanup := newQueue(ctx, t)
+	defer cleanup()
+	// A previous receiver stopped while it held a claim on the head of the
+	// queue.
+	if _, err := m.claimQueue(ctx, domainID, 3, time.Now(), time.Hour); err != nil {
+		t.Fatalf("claimQueue(): %v", err)
+	}
+	r := m.NewReceiver(ctx, time.Now(), domainID, func([]*mutator.QueueMessage) error { return nil },
+		mutator.ReceiverOptions{MaxBatchSize: 10, Period: time.Hour, MaxPeriod: 2 * time.Hour})
+	defer r.Close()
+	if err := r.FlushN(ctx, 5); err != nil {
+		t.Errorf("FlushN(5): %v", err)
+	}
+}
+
+func TestSequenceBatchDequeues(t *testing.T) {
+	ctx := context.Background()
+	m, cleanup := newQueue(ctx, t)
+	defer cleanup()
+
+	ms, err := m.claimQueue(ctx, domainID, 3, time.Now(), time.Minute)
+	if err != nil {
+		t.Fatalf("claimQueue(): %v", err)
+	}
+	if err := m.SequenceBatch(ctx, domainID, 1, ms); err != nil {
+		t.Fatalf("SequenceBatch(): %v", err)
+	}
+	// Acknowledging the batch afterwards has no effect.
+	if err := m.deleteMessages(ctx, domainID, ms); err != nil {
+		t.Fatalf("deleteMessages(): %v", err)
+	}
+	stats, err := m.Stats(ctx, domainID)
+	if err != nil {
+		t.Fatalf("Stats(): %v", err)
+	}
+	if got, want := stats.Depth, int64(2); got != want {
+		t.Errorf("Stats().Depth: %v, want %v", got, want)
+	}
+	batch, err := m.ReadBatch(ctx, domainID, 1)
+	if err != nil {
+		t.Fatalf("ReadBatch(): %v", err)
+	}
+	if got, want := len(batch), len(ms); got != want {
+		t.Fatalf("ReadBatch(): %v messages, want %v", got, want)
+	}
+	for i, msg := range batch {
+		if got, want := string(msg.ExtraData.GetData()), fmt.Sprintf("data%d", i+1); got != want {
+			t.Errorf("ReadBatch()[%v].ExtraData.Data: %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestSequenceBatchClaimExpired(t *testing.T) {
+	ctx := context.Background()
+	m, cleanup := newQueue(ctx, t)
+	defer cleanup()
+	now := time.Now()
+	timeout := time.Minute
+
+	expired, err := m.claimQueue(ctx, domainID, 5, now, timeout)
+	if err != nil {
+		t.Fatalf("claimQueue(): %v", err)
+	}
+	// Another receiver claims part of the batch once the claim expires,
+	// and sequences it first.
+	batch, err := m.claimQueue(ctx, domainID, 2, now.Add(2*timeout), timeout)
+	if err != nil {
+		t.Fatalf("claimQueue(): %v", err)
+	}
+	if err := m.SequenceBatch(ctx, domainID, 1, batch); err != nil {
+		t.Fatalf("SequenceBatch(1): %v", err)
+	}
+	if err := m.SequenceBatch(ctx, domainID, 2, expired); err != mutator.ErrStaleBatch {
+		t.Errorf("SequenceBatch(2) of the expired batch: %v, want %v", err, mutator.ErrStaleBatch)
+	}
+	saved, err := m.ReadBatch(ctx, domainID, 2)
+	if err != nil {
+		t.Fatalf("ReadBatch(): %v", err)
+	}
+	if len(saved) != 0 {
+		t.Errorf("ReadBatch(2): %v messages, want 0", len(saved))
+	}
+	stats, err := m.Stats(ctx, domainID)
+	if err != nil {
+		t.Fatalf("Stats(): %v", err)
+	}
+	if got, want := stats.Depth, int64(3); got != want {
+		t.Errorf("Stats().Depth: %v, want %v", got, want)
+	}
+}
+
+func TestCheckMMD(t *testing.T) {
+	last := time.Unix(1000, 0)
+	var blown []time.Time
+	r := &Receiver{
+		domainID: domainID,
+		opts: mutator.ReceiverOptions{
+			MaxPeriod: time.Minute,
+			MMDBlown:  func(last time.Time) { blown = append(blown, last) },
+		},
+		last: last.UnixNano(),
+	}
+	for _, tc := range []struct {
+		desc     string
+		received time.Time // Time of a new batch, if not zero.
+		now      time.Time
+		want     bool
+	}{
+		{desc: "on time", now: last.Add(time.Minute)},
+		{desc: "late", now: last.Add(time.Minute + time.Second), want: true},
+		{desc: "still late", now: last.Add(time.Hour)},
+		{desc: "caught up", received: last.Add(2 * time.Hour), now: last.Add(2 * time.Hour)},
+		{desc: "late again", now: last.Add(3 * time.Hour), want: true},
+	} {
+		if !tc.received.IsZero() {
+			r.last = tc.received.UnixNano()
+		}
+		if got := r.checkMMD(tc.now); got != tc.want {
+			t.Errorf("%v: checkMMD(): %v, want %v", tc.desc, got, tc.want)
+		}
+	}
+	if got, want := blown, []time.Time{last, last.Add(2 * time.Hour)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MMDBlown calls: %v, want %v", got, want)
+	}
+}
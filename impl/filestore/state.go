@@ -0,0 +1,304 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filestore
+
+import (
+	"encoding/gob"
+	"sort"
+)
+
+func init() {
+	gob.Register(&writeDomain{})
+	gob.Register(&setDeleted{})
+	gob.Register(&setKeySet{})
+	gob.Register(&writeBatch{})
+	gob.Register(&dequeue{})
+	gob.Register(&setSequenced{})
+	gob.Register(&writeIndexChanges{})
+	gob.Register(&writeStatus{})
+	gob.Register(&writeRejected{})
+	gob.Register(&enqueue{})
+}
+
+// state is everything held in a store. Exported fields are persisted, the
+// others are lost when the store is closed. Protos are held in their wire
+// format.
+type state struct {
+	Domains map[string]*domainRow
+	// KeySets holds keysets by keySetKey.
+	KeySets map[string][]byte
+	// Logs holds the mutations of each domain by domain ID.
+	Logs map[string]*mutationLog
+}
+
+func newState() *state {
+	st := &state{}
+	st.init()
+	return st
+}
+
+// init creates the maps that gob leaves nil when they are empty.
+func (st *state) init() {
+	if st.Domains == nil {
+		st.Domains = make(map[string]*domainRow)
+	}
+	if st.KeySets == nil {
+		st.KeySets = make(map[string][]byte)
+	}
+	if st.Logs == nil {
+		st.Logs = make(map[string]*mutationLog)
+	}
+	for _, l := range st.Logs {
+		l.init()
+	}
+}
+
+// readLog returns the mutations of domainID, which are empty if none were
+// written. It must not be modified.
+func (st *state) readLog(domainID string) *mutationLog {
+	if l, ok := st.Logs[domainID]; ok {
+		return l
+	}
+	return &mutationLog{}
+}
+
+// log returns the mutations of domainID for modification, adding them to the
+// state if needed.
+func (st *state) log(domainID string) *mutationLog {
+	l, ok := st.Logs[domainID]
+	if !ok {
+		l = &mutationLog{}
+		l.init()
+		st.Logs[domainID] = l
+	}
+	return l
+}
+
+// domainRow is a domain.Domain with its protos marshaled.
+type domainRow struct {
+	DomainID                 string
+	MapID, LogID             int64
+	VRFPublicKey             []byte
+	VRFPrivateKey            []byte
+	ReceiptPublicKey         []byte
+	ReceiptPrivateKey        []byte
+	MinInterval, MaxInterval int64
+	SequencingConfig         []byte
+	Deleted                  bool
+}
+
+// mutationLog holds the mutations, statuses and queue of a domain.
+type mutationLog struct {
+	// Batches holds the mutations of each revision in sequence order, as
+	// marshaled EntryUpdates. Committed is unset for mutations saved
+	// without committed data.
+	Batches map[int64][][]byte
+	// Sequenced is the highest revision saved by SequenceBatch.
+	Sequenced int64
+	// Changes holds the revisions in which each map index changed, in
+	// ascending order.
+	Changes map[string][]int64
+	// Statuses holds the status of each mutation by mutation hash.
+	Statuses map[string]storedStatus
+	// Rejected holds the mutations rejected in each revision, in sequence
+	// order.
+	Rejected map[int64][]storedRejected
+	// Queue holds the queued mutations in ID order.
+	Queue []*queuedMutation
+	// LastID is the ID of the last mutation sent to the queue.
+	LastID int64
+}
+
+func (l *mutationLog) init() {
+	if l.Batches == nil {
+		l.Batches = make(map[int64][][]byte)
+	}
+	if l.Changes == nil {
+		l.Changes = make(map[string][]int64)
+	}
+	if l.Statuses == nil {
+		l.Statuses = make(map[string]storedStatus)
+	}
+	if l.Rejected == nil {
+		l.Rejected = make(map[int64][]storedRejected)
+	}
+}
+
+type storedStatus struct {
+	State    int32
+	Revision int64
+	Error    string
+}
+
+type storedRejected struct {
+	Sequence     int64
+	Index        []byte
+	MutationHash []byte
+	Reason       string
+}
+
+type queuedMutation struct {
+	ID int64
+	// Time is when the mutation was sent, in UnixNano.
+	Time int64
+	// Update is a marshaled EntryUpdate.
+	Update []byte
+	// claimExpiry is the time, in UnixNano, until which the mutation is
+	// claimed by a receiver. Claims do not survive a restart.
+	claimExpiry int64
+}
+
+// change is a modification of the state that is written to the journal.
+// Applying a change must not fail, so changes are validated before they are
+// committed.
+type change interface {
+	apply(st *state)
+}
+
+type writeDomain struct {
+	Row *domainRow
+}
+
+func (c *writeDomain) apply(st *state) {
+	st.Domains[c.Row.DomainID] = c.Row
+}
+
+type setDeleted struct {
+	DomainID string
+	Deleted  bool
+}
+
+func (c *setDeleted) apply(st *state) {
+	if d, ok := st.Domains[c.DomainID]; ok {
+		d.Deleted = c.Deleted
+	}
+}
+
+type setKeySet struct {
+	Key    string
+	KeySet []byte
+}
+
+func (c *setKeySet) apply(st *state) {
+	st.KeySets[c.Key] = c.KeySet
+}
+
+// writeBatch replaces the mutations of a revision.
+type writeBatch struct {
+	DomainID  string
+	Revision  int64
+	Mutations [][]byte
+}
+
+func (c *writeBatch) apply(st *state) {
+	st.log(c.DomainID).Batches[c.Revision] = c.Mutations
+}
+
+// dequeue removes the queued mutations with IDs in [First, Last].
+type dequeue struct {
+	DomainID    string
+	First, Last int64
+}
+
+func (c *dequeue) apply(st *state) {
+	l := st.log(c.DomainID)
+	kept := l.Queue[:0]
+	for _, q := range l.Queue {
+		if q.ID < c.First || q.ID > c.Last {
+			kept = append(kept, q)
+		}
+	}
+	for i := len(kept); i < len(l.Queue); i++ {
+		l.Queue[i] = nil
+	}
+	l.Queue = kept
+}
+
+type setSequenced struct {
+	DomainID string
+	Revision int64
+}
+
+func (c *setSequenced) apply(st *state) {
+	st.log(c.DomainID).Sequenced = c.Revision
+}
+
+type writeIndexChanges struct {
+	DomainID string
+	Revision int64
+	Indexes  [][]byte
+}
+
+func (c *writeIndexChanges) apply(st *state) {
+	l := st.log(c.DomainID)
+	for _, index := range c.Indexes {
+		revs := l.Changes[string(index)]
+		i := sort.Search(len(revs), func(i int) bool { return revs[i] >= c.Revision })
+		if i < len(revs) && revs[i] == c.Revision {
+			continue
+		}
+		revs = append(revs, 0)
+		copy(revs[i+1:], revs[i:])
+		revs[i] = c.Revision
+		l.Changes[string(index)] = revs
+	}
+}
+
+type writeStatus struct {
+	DomainID string
+	Hash     []byte
+	Status   storedStatus
+}
+
+func (c *writeStatus) apply(st *state) {
+	st.log(c.DomainID).Statuses[string(c.Hash)] = c.Status
+}
+
+// writeRejected records rejected mutations, replacing those with the same
+// sequence number.
+type writeRejected struct {
+	DomainID string
+	Revision int64
+	Rejected []storedRejected
+}
+
+func (c *writeRejected) apply(st *state) {
+	l := st.log(c.DomainID)
+	rs := l.Rejected[c.Revision]
+	for _, r := range c.Rejected {
+		i := sort.Search(len(rs), func(i int) bool { return rs[i].Sequence >= r.Sequence })
+		if i < len(rs) && rs[i].Sequence == r.Sequence {
+			rs[i] = r
+			continue
+		}
+		rs = append(rs, storedRejected{})
+		copy(rs[i+1:], rs[i:])
+		rs[i] = r
+	}
+	l.Rejected[c.Revision] = rs
+}
+
+// enqueue adds a mutation to the end of the queue with the next ID.
+type enqueue struct {
+	DomainID string
+	Time     int64
+	Update   []byte
+}
+
+func (c *enqueue) apply(st *state) {
+	l := st.log(c.DomainID)
+	l.LastID++
+	l.Queue = append(l.Queue, &queuedMutation{ID: l.LastID, Time: c.Time, Update: c.Update})
+}
@@ -0,0 +1,304 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package filestore implements the domain, keyset, mutation and queue storage
+// interfaces on a single local file, for single node deployments and tests
+// that do not need a database server.
+//
+// The file is a journal of records. Each record holds a set of changes that
+// are applied together, and is synced to disk before its changes become
+// visible, so a crash loses at most the record being written. A record torn
+// by a crash is discarded when the file is next opened. Once the journal has
+// grown enough, it is compacted into a single record holding a snapshot of
+// the whole state.
+//
+// The whole state is kept in memory. Only one process may open a file at a
+// time.
+package filestore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/golang/glog"
+)
+
+const (
+	// headerSize is the size of the length and checksum that precede the
+	// payload of each record.
+	headerSize = 8
+	// maxRecordSize bounds the payload of a record, to detect corrupt
+	// lengths before allocating memory for them.
+	maxRecordSize = 1 << 30
+)
+
+var (
+	// minCompactSize is the journal size under which the journal is never
+	// compacted.
+	minCompactSize int64 = 1 << 20
+
+	crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+	// errTorn is returned by decodeRecord when the data ends before the
+	// end of the record.
+	errTorn = errors.New("filestore: torn record")
+)
+
+// record is the unit of the journal. A record holds either a snapshot of the
+// whole state, or changes that are applied together.
+type record struct {
+	Snapshot *state
+	Changes  []change
+}
+
+// Store holds the state of all the storage interfaces in a single file.
+type Store struct {
+	path string
+	// mu guards all the fields below, and the state of the file.
+	mu sync.RWMutex
+	f  *os.File
+	// size is the length of the journal. compactAt is the length at which
+	// it is next compacted.
+	size, compactAt int64
+	st              *state
+	// err is set once writing to the file fails. The content of the file
+	// is unknown after that, so no more records are written.
+	err error
+}
+
+// Open opens the store in the file at path, creating it if needed.
+func Open(path string) (*Store, error) {
+	// A snapshot left over by a compaction that crashed before it
+	// replaced the journal is incomplete.
+	if err := os.Remove(tmpPath(path)); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{path: path, f: f, st: newState()}
+	if err := s.load(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close closes the file. The store must not be used afterwards.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.f.Close()
+}
+
+// load applies all the records in the file, and truncates a torn record at
+// the end of the file.
+func (s *Store) load() error {
+	data, err := ioutil.ReadAll(s.f)
+	if err != nil {
+		return err
+	}
+	var off int64
+	for off < int64(len(data)) {
+		r, n, err := decodeRecord(data[off:])
+		if err == errTorn {
+			glog.Warningf("filestore: %v: discarding %v bytes of a torn record at offset %v", s.path, int64(len(data))-off, off)
+			if err := s.f.Truncate(off); err != nil {
+				return err
+			}
+			if err := s.f.Sync(); err != nil {
+				return err
+			}
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("filestore: %v: record at offset %v: %v", s.path, off, err)
+		}
+		if r.Snapshot != nil {
+			s.st = r.Snapshot
+			s.st.init()
+		}
+		for _, c := range r.Changes {
+			c.apply(s.st)
+		}
+		off += n
+	}
+	s.size = off
+	s.compactAt = compactAt(off)
+	return nil
+}
+
+// read runs f with the state locked for reading.
+func (s *Store) read(f func(st *state) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return f(s.st)
+}
+
+// update runs f with the state locked for writing, then writes the changes f
+// returns to the journal and applies them. Nothing is changed if f returns an
+// error. f may modify fields of the state that are not persisted.
+func (s *Store) update(f func(st *state) ([]change, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changes, err := f(s.st)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return s.commit(changes)
+}
+
+// commit appends changes to the journal and applies them once they are on
+// disk. s.mu must be held.
+func (s *Store) commit(changes []change) error {
+	if s.err != nil {
+		return s.err
+	}
+	buf, err := encodeRecord(&record{Changes: changes})
+	if err != nil {
+		return err
+	}
+	if _, err := s.f.WriteAt(buf, s.size); err != nil {
+		s.err = fmt.Errorf("filestore: %v: write failed: %v", s.path, err)
+		return s.err
+	}
+	if err := s.f.Sync(); err != nil {
+		s.err = fmt.Errorf("filestore: %v: sync failed: %v", s.path, err)
+		return s.err
+	}
+	s.size += int64(len(buf))
+	for _, c := range changes {
+		c.apply(s.st)
+	}
+	if s.size >= s.compactAt {
+		if err := s.compact(); err != nil {
+			// The journal is intact. Try again after the next write.
+			glog.Errorf("filestore: %v: compaction failed: %v", s.path, err)
+		}
+	}
+	return nil
+}
+
+// compact replaces the journal with a snapshot of the state. s.mu must be held.
+func (s *Store) compact() error {
+	buf, err := encodeRecord(&record{Snapshot: s.st})
+	if err != nil {
+		return err
+	}
+	tmp := tmpPath(s.path)
+	f, err := os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		f.Close()
+		return err
+	}
+	// f is the journal now, even if the rename is not durable yet.
+	s.f.Close()
+	s.f = f
+	s.size = int64(len(buf))
+	s.compactAt = compactAt(s.size)
+	return syncDir(filepath.Dir(s.path))
+}
+
+// compactAt returns the journal size at which a journal that starts with a
+// snapshot of size bytes is compacted.
+func compactAt(size int64) int64 {
+	if 2*size < minCompactSize {
+		return minCompactSize
+	}
+	return 2 * size
+}
+
+func tmpPath(path string) string {
+	return path + ".tmp"
+}
+
+// syncDir makes renames in dir durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// encodeRecord returns r, preceded by the length and CRC-32C of its encoding.
+func encodeRecord(r *record) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Write(make([]byte, headerSize))
+	if err := gob.NewEncoder(&buf).Encode(r); err != nil {
+		return nil, err
+	}
+	b := buf.Bytes()
+	payload := b[headerSize:]
+	if len(payload) > maxRecordSize {
+		return nil, fmt.Errorf("filestore: record of %v bytes is too large", len(payload))
+	}
+	binary.BigEndian.PutUint32(b[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(b[4:8], crc32.Checksum(payload, crcTable))
+	return b, nil
+}
+
+// decodeRecord decodes the record at the start of data and returns it with
+// its length. It returns errTorn if the record was only partially written,
+// which can only be the case for the last record of a file.
+func decodeRecord(data []byte) (*record, int64, error) {
+	if len(data) < headerSize {
+		return nil, 0, errTorn
+	}
+	size := binary.BigEndian.Uint32(data[0:4])
+	sum := binary.BigEndian.Uint32(data[4:8])
+	end := headerSize + int64(size)
+	if size == 0 || size > maxRecordSize || end > int64(len(data)) {
+		// Either the record is torn, or its header was. Records are
+		// never empty, so a zero length is unwritten space.
+		return nil, 0, errTorn
+	}
+	payload := data[headerSize:end]
+	if crc32.Checksum(payload, crcTable) != sum {
+		if end == int64(len(data)) {
+			// A partial write of the last record.
+			return nil, 0, errTorn
+		}
+		return nil, 0, errors.New("checksum mismatch")
+	}
+	r := &record{}
+	if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(r); err != nil {
+		return nil, 0, err
+	}
+	return r, end, nil
+}
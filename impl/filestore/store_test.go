@@ -0,0 +1,338 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filestore
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/keytransparency/core/domain"
+	"github.com/google/trillian/crypto/keyspb"
+
+	tpb "github.com/google/keytransparency/core/api/type/type_go_proto"
+	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
+)
+
+const domainID = "domain"
+
+// tempPath returns the path of a store file in a new directory, and a function
+// that removes the directory.
+func tempPath(t *testing.T) (string, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "filestore")
+	if err != nil {
+		t.Fatalf("TempDir(): %v", err)
+	}
+	return filepath.Join(dir, "kt.db"), func() { os.RemoveAll(dir) }
+}
+
+func mustOpen(t *testing.T, path string) *Store {
+	t.Helper()
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open(): %v", err)
+	}
+	return s
+}
+
+// newStore returns a new, empty store and a function that closes and removes
+// it.
+func newStore(t *testing.T) (*Store, func()) {
+	t.Helper()
+	path, cleanup := tempPath(t)
+	s := mustOpen(t, path)
+	return s, func() {
+		s.Close()
+		cleanup()
+	}
+}
+
+func genUpdate(i int) *pb.EntryUpdate {
+	return &pb.EntryUpdate{
+		Mutation: &pb.Entry{
+			Index:      []byte(fmt.Sprintf("index%d", i)),
+			Commitment: []byte(fmt.Sprintf("mutation%d", i)),
+		},
+		Committed: &pb.Committed{
+			Key:  []byte(fmt.Sprintf("nonce%d", i)),
+			Data: []byte(fmt.Sprintf("data%d", i)),
+		},
+	}
+}
+
+// fill writes to every interface backed by s.
+func fill(ctx context.Context, t *testing.T, s *Store) {
+	t.Helper()
+	d := &domain.Domain{
+		DomainID:    domainID,
+		MapID:       1,
+		LogID:       2,
+		VRF:         &keyspb.PublicKey{Der: []byte("pubkeybytes")},
+		VRFPriv:     &keyspb.PrivateKey{Der: []byte("privkeybytes")},
+		MinInterval: time.Second,
+		MaxInterval: time.Minute,
+		Sequencing:  &pb.SequencingConfig{MaxBatchSize: 10},
+	}
+	if err := NewDomainStorage(s).Write(ctx, d); err != nil {
+		t.Fatalf("Write(): %v", err)
+	}
+	ks := &tpb.KeySet{VerifyingKeys: map[string]*tpb.VerifyingKey{"1": {KeyMaterial: []byte("keydata")}}}
+	if err := NewKeySets(s).Set(ctx, 0, domainID, "app", ks); err != nil {
+		t.Fatalf("Set(): %v", err)
+	}
+	m := NewMutations(s)
+	for i := 0; i < 3; i++ {
+		if err := m.Send(ctx, domainID, genUpdate(i)); err != nil {
+			t.Fatalf("Send(): %v", err)
+		}
+	}
+	msgs, err := m.claimQueue(ctx, domainID, 2, time.Now(), time.Minute)
+	if err != nil {
+		t.Fatalf("claimQueue(): %v", err)
+	}
+	if err := m.SequenceBatch(ctx, domainID, 1, msgs); err != nil {
+		t.Fatalf("SequenceBatch(): %v", err)
+	}
+	if err := m.WriteIndexChanges(ctx, domainID, 1, [][]byte{[]byte("index0")}); err != nil {
+		t.Fatalf("WriteIndexChanges(): %v", err)
+	}
+	if err := m.WriteRejected(ctx, domainID, 1, []*pb.RejectedMutation{{Sequence: 1, Reason: "bad"}}); err != nil {
+		t.Fatalf("WriteRejected(): %v", err)
+	}
+}
+
+// dump reads everything written by fill.
+func dump(ctx context.Context, t *testing.T, s *Store) []interface{} {
+	t.Helper()
+	domains, err := NewDomainStorage(s).List(ctx, true)
+	if err != nil {
+		t.Fatalf("List(): %v", err)
+	}
+	ks, err := NewKeySets(s).Get(ctx, 0, domainID, "app")
+	if err != nil {
+		t.Fatalf("Get(): %v", err)
+	}
+	m := NewMutations(s)
+	batch, err := m.ReadBatch(ctx, domainID, 1)
+	if err != nil {
+		t.Fatalf("ReadBatch(): %v", err)
+	}
+	rev, err := m.HighestSequencedRevision(ctx, domainID)
+	if err != nil {
+		t.Fatalf("HighestSequencedRevision(): %v", err)
+	}
+	changes, err := m.ListIndexChanges(ctx, domainID, []byte("index0"), 0, 10, 10)
+	if err != nil {
+		t.Fatalf("ListIndexChanges(): %v", err)
+	}
+	rejected, err := m.ReadRejectedPage(ctx, domainID, 1, 0, 10)
+	if err != nil {
+		t.Fatalf("ReadRejectedPage(): %v", err)
+	}
+	stats, err := m.Stats(ctx, domainID)
+	if err != nil {
+		t.Fatalf("Stats(): %v", err)
+	}
+	return []interface{}{domains, ks, batch, rev, changes, rejected, stats}
+}
+
+func TestReopen(t *testing.T) {
+	ctx := context.Background()
+	path, cleanup := tempPath(t)
+	defer cleanup()
+
+	s := mustOpen(t, path)
+	fill(ctx, t, s)
+	want := dump(ctx, t, s)
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close(): %v", err)
+	}
+
+	s = mustOpen(t, path)
+	defer s.Close()
+	if got := dump(ctx, t, s); !cmp.Equal(got, want, cmp.Comparer(proto.Equal)) {
+		t.Errorf("after reopening: %v, want %v, diff:\n%v", got, want, cmp.Diff(got, want, cmp.Comparer(proto.Equal)))
+	}
+	// The queue keeps assigning IDs after the last one.
+	m := NewMutations(s)
+	if err := m.Send(ctx, domainID, genUpdate(3)); err != nil {
+		t.Fatalf("Send(): %v", err)
+	}
+	msgs, err := m.claimQueue(ctx, domainID, 10, time.Now(), time.Minute)
+	if err != nil {
+		t.Fatalf("claimQueue(): %v", err)
+	}
+	if got, want := len(msgs), 2; got != want {
+		t.Fatalf("claimQueue(): %v messages, want %v", got, want)
+	}
+	if got, want := msgs[1].ID, int64(4); got != want {
+		t.Errorf("ID of the message sent after reopening: %v, want %v", got, want)
+	}
+}
+
+func TestTornRecord(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		desc string
+		tail func(record []byte) []byte
+	}{
+		{desc: "partial header", tail: func(r []byte) []byte { return r[:headerSize-2] }},
+		{desc: "partial payload", tail: func(r []byte) []byte { return r[:len(r)-5] }},
+		{desc: "corrupt payload", tail: func(r []byte) []byte { r[len(r)-1] ^= 0xff; return r }},
+		{desc: "unwritten space", tail: func(r []byte) []byte { return make([]byte, len(r)) }},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			path, cleanup := tempPath(t)
+			defer cleanup()
+			s := mustOpen(t, path)
+			fill(ctx, t, s)
+			want := dump(ctx, t, s)
+			s.Close()
+
+			// Append a record as if the process died while writing it.
+			r, err := encodeRecord(&record{Changes: []change{&setSequenced{DomainID: domainID, Revision: 5}}})
+			if err != nil {
+				t.Fatalf("encodeRecord(): %v", err)
+			}
+			f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+			if err != nil {
+				t.Fatalf("OpenFile(): %v", err)
+			}
+			if _, err := f.Write(tc.tail(r)); err != nil {
+				t.Fatalf("Write(): %v", err)
+			}
+			f.Close()
+
+			s = mustOpen(t, path)
+			if got := dump(ctx, t, s); !cmp.Equal(got, want, cmp.Comparer(proto.Equal)) {
+				t.Errorf("after a torn write: %v, want %v", got, want)
+			}
+			// The torn record is gone, so later records can be read.
+			if err := NewMutations(s).WriteBatch(ctx, domainID, 2, []*pb.Entry{}); err != nil {
+				t.Fatalf("WriteBatch(): %v", err)
+			}
+			s.Close()
+			s = mustOpen(t, path)
+			defer s.Close()
+			if got := dump(ctx, t, s); !cmp.Equal(got, want, cmp.Comparer(proto.Equal)) {
+				t.Errorf("after writing over a torn record: %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestCorruptRecord(t *testing.T) {
+	ctx := context.Background()
+	path, cleanup := tempPath(t)
+	defer cleanup()
+	s := mustOpen(t, path)
+	fill(ctx, t, s)
+	s.Close()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile(): %v", err)
+	}
+	// Corrupt the first record, which is followed by others.
+	data[headerSize] ^= 0xff
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("WriteFile(): %v", err)
+	}
+	if _, err := Open(path); err == nil {
+		t.Errorf("Open() with a corrupt record: nil, want error")
+	}
+}
+
+func TestCompact(t *testing.T) {
+	defer func(size int64) { minCompactSize = size }(minCompactSize)
+	minCompactSize = 4096
+	ctx := context.Background()
+	path, cleanup := tempPath(t)
+	defer cleanup()
+	// A snapshot left over by a failed compaction is ignored.
+	if err := ioutil.WriteFile(tmpPath(path), []byte("partial snapshot"), 0600); err != nil {
+		t.Fatalf("WriteFile(): %v", err)
+	}
+
+	s := mustOpen(t, path)
+	fill(ctx, t, s)
+	m := NewMutations(s)
+	for i := 0; i < 200; i++ {
+		if err := m.WriteStatus(ctx, domainID, []byte("hash"), &pb.MutationStatus{Epoch: int64(i)}); err != nil {
+			t.Fatalf("WriteStatus(): %v", err)
+		}
+	}
+	want := dump(ctx, t, s)
+	s.Close()
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat(): %v", err)
+	}
+	if got, max := fi.Size(), 2*minCompactSize; got > max {
+		t.Errorf("journal size: %v, want <= %v", got, max)
+	}
+	if _, err := os.Stat(tmpPath(path)); !os.IsNotExist(err) {
+		t.Errorf("Stat(%v): %v, want not found", tmpPath(path), err)
+	}
+
+	s = mustOpen(t, path)
+	defer s.Close()
+	if got := dump(ctx, t, s); !cmp.Equal(got, want, cmp.Comparer(proto.Equal)) {
+		t.Errorf("after compaction: %v, want %v", got, want)
+	}
+	status, err := NewMutations(s).ReadStatus(ctx, domainID, []byte("hash"))
+	if err != nil {
+		t.Fatalf("ReadStatus(): %v", err)
+	}
+	if got, want := status.GetEpoch(), int64(199); got != want {
+		t.Errorf("ReadStatus().Epoch: %v, want %v", got, want)
+	}
+}
+
+func TestClaimsAreNotPersisted(t *testing.T) {
+	ctx := context.Background()
+	path, cleanup := tempPath(t)
+	defer cleanup()
+	s := mustOpen(t, path)
+	m := NewMutations(s)
+	for i := 0; i < 3; i++ {
+		if err := m.Send(ctx, domainID, genUpdate(i)); err != nil {
+			t.Fatalf("Send(): %v", err)
+		}
+	}
+	if _, err := m.claimQueue(ctx, domainID, 3, time.Now(), time.Hour); err != nil {
+		t.Fatalf("claimQueue(): %v", err)
+	}
+	s.Close()
+
+	// A receiver that died with a claim does not block a restarted one.
+	s = mustOpen(t, path)
+	defer s.Close()
+	msgs, err := NewMutations(s).claimQueue(ctx, domainID, 3, time.Now(), time.Hour)
+	if err != nil {
+		t.Fatalf("claimQueue(): %v", err)
+	}
+	if got, want := len(msgs), 3; got != want {
+		t.Errorf("claimQueue() after reopening: %v messages, want %v", got, want)
+	}
+}
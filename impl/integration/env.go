@@ -16,11 +16,13 @@ package integration
 
 import (
 	"context"
-	"database/sql"
 	"encoding/pem"
 	"fmt"
+	"io/ioutil"
 	"math/rand"
 	"net"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/golang/glog"
@@ -37,8 +39,10 @@ import (
 	"github.com/google/keytransparency/core/mutator"
 	"github.com/google/keytransparency/core/mutator/entry"
 	"github.com/google/keytransparency/core/sequencer"
+	"github.com/google/keytransparency/core/sequencer/election"
 	"github.com/google/keytransparency/impl/authentication"
 	"github.com/google/keytransparency/impl/authorization"
+	"github.com/google/keytransparency/impl/filestore"
 	"github.com/google/keytransparency/impl/sql/domain"
 	"github.com/google/keytransparency/impl/sql/mutationstorage"
 	"github.com/google/trillian/crypto/keys/der"
@@ -86,14 +90,80 @@ func Listen() (string, net.Listener, error) {
 	return addr, lis, nil
 }
 
+// Backend selects where an Env stores domains and mutations.
+type Backend int
+
+const (
+	// SQLBackend stores data in an SQL database.
+	SQLBackend Backend = iota
+	// FileBackend stores data in a filestore in a temporary directory.
+	FileBackend
+)
+
+func (b Backend) String() string {
+	switch b {
+	case SQLBackend:
+		return "sql"
+	case FileBackend:
+		return "file"
+	default:
+		return fmt.Sprintf("Backend(%d)", int(b))
+	}
+}
+
+// mutationStore stores and queues mutations.
+type mutationStore interface {
+	mutator.MutationStorage
+	mutator.MutationQueue
+}
+
 // Env holds a complete testing environment for end-to-end tests.
 type Env struct {
 	*integration.Env
-	mapEnv     *ttest.MapEnv
-	logEnv     *ttest.LogEnv
-	grpcServer *grpc.Server
-	grpcCC     *grpc.ClientConn
-	db         *sql.DB
+	mapEnv       *ttest.MapEnv
+	logEnv       *ttest.LogEnv
+	grpcServer   *grpc.Server
+	grpcCC       *grpc.ClientConn
+	closeStorage func()
+}
+
+// openStorage returns domain and mutation storage of the selected backend, and
+// a function that releases them.
+func openStorage(ctx context.Context, backend Backend) (domaindef.Storage, mutationStore, func(), error) {
+	switch backend {
+	case SQLBackend:
+		db, err := testdb.NewTrillianDB(ctx)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("env: failed to open database: %v", err)
+		}
+		domainStorage, err := domain.NewStorage(db)
+		if err != nil {
+			db.Close()
+			return nil, nil, nil, fmt.Errorf("env: failed to create domain storage: %v", err)
+		}
+		mutations, err := mutationstorage.New(db)
+		if err != nil {
+			db.Close()
+			return nil, nil, nil, fmt.Errorf("env: Failed to create mutations object: %v", err)
+		}
+		return domainStorage, mutations, func() { db.Close() }, nil
+	case FileBackend:
+		dir, err := ioutil.TempDir("", "kt-integration")
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("env: TempDir(): %v", err)
+		}
+		store, err := filestore.Open(filepath.Join(dir, "kt.db"))
+		if err != nil {
+			os.RemoveAll(dir)
+			return nil, nil, nil, fmt.Errorf("env: failed to open filestore: %v", err)
+		}
+		return filestore.NewDomainStorage(store), filestore.NewMutations(store), func() {
+			store.Close()
+			os.RemoveAll(dir)
+		}, nil
+	default:
+		return nil, nil, nil, fmt.Errorf("env: unknown backend %v", backend)
+	}
 }
 
 func vrfKeyGen(ctx context.Context, spec *keyspb.Specification) (proto.Message, error) {
@@ -110,16 +180,12 @@ func keyFromPEM(p string) *any.Any {
 	return a
 }
 
-// NewEnv sets up common resources for tests.
-func NewEnv() (*Env, error) {
+// NewEnv sets up common resources for tests, storing domains and mutations in
+// backend.
+func NewEnv(backend Backend) (*Env, error) {
 	ctx := context.Background()
 	domainID := fmt.Sprintf("domain_%d", rand.Int()) // nolint: gas
 
-	db, err := testdb.NewTrillianDB(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("env: failed to open database: %v", err)
-	}
-
 	// Map server
 	mapEnv, err := ttest.NewMapEnv(ctx)
 	if err != nil {
@@ -135,11 +201,11 @@ func NewEnv() (*Env, error) {
 	}
 
 	// Configure domain, which creates new map and log trees.
-	domainStorage, err := domain.NewStorage(db)
+	domainStorage, mutations, closeStorage, err := openStorage(ctx, backend)
 	if err != nil {
-		return nil, fmt.Errorf("env: failed to create domain storage: %v", err)
+		return nil, err
 	}
-	adminSvr := adminserver.New(logEnv.Log, mapEnv.Map, logEnv.Admin, mapEnv.Admin, domainStorage, vrfKeyGen)
+	adminSvr := adminserver.New(logEnv.Log, mapEnv.Map, logEnv.Admin, mapEnv.Admin, domainStorage, mutations, vrfKeyGen)
 	domainPB, err := adminSvr.CreateDomain(ctx, &pb.CreateDomainRequest{
 		DomainId: domainID,
 		// Only sequence when explicitly asked with receiver.Flush()
@@ -155,16 +221,14 @@ func NewEnv() (*Env, error) {
 	glog.V(5).Infof("Domain: %# v", pretty.Formatter(domainPB))
 
 	// Common data structures.
-	mutations, err := mutationstorage.New(db)
-	if err != nil {
-		return nil, fmt.Errorf("env: Failed to create mutations object: %v", err)
-	}
 	authFunc := authentication.FakeAuthFunc
 	authz := &authorization.AuthzPolicy{}
 
 	queue := mutator.MutationQueue(mutations)
+	mutators := mutator.Registry{pb.SequencingConfig_ENTRY: entry.New()}
 	server := keyserver.New(logEnv.Log, mapEnv.Map, logEnv.Admin, mapEnv.Admin,
-		entry.New(), domainStorage, queue, mutations)
+		mutators, domainStorage, queue, mutations)
+	server.SetAuthorizer(authz.AuthorizeUpdate)
 	gsvr := grpc.NewServer(
 		grpc.UnaryInterceptor(
 			authorization.UnaryServerInterceptor(map[string]authorization.AuthPair{
@@ -172,13 +236,17 @@ func NewEnv() (*Env, error) {
 					AuthnFunc: authFunc,
 					AuthzFunc: authz.Authorize,
 				},
+				"/google.keytransparency.v1.KeyTransparency/BatchUpdateEntries": {
+					// The key server authorizes each update.
+					AuthnFunc: authFunc,
+				},
 			}),
 		),
 	)
 	pb.RegisterKeyTransparencyServer(gsvr, server)
 
 	// Sequencer
-	seq := sequencer.New(logEnv.Log, logEnv.Admin, mapEnv.Map, mapEnv.Admin, entry.New(), domainStorage, mutations, queue)
+	seq := sequencer.New(logEnv.Log, logEnv.Admin, mapEnv.Map, mapEnv.Admin, mutators, domainStorage, mutations, queue, election.NoopFactory{}, nil)
 	d := &domaindef.Domain{
 		DomainID:    domainPB.DomainId,
 		LogID:       domainPB.Log.TreeId,
@@ -225,11 +293,11 @@ func NewEnv() (*Env, error) {
 				return []grpc.CallOption{grpc.PerRPCCredentials(authentication.GetFakeCredential(userID))}
 			},
 		},
-		mapEnv:     mapEnv,
-		logEnv:     logEnv,
-		grpcServer: gsvr,
-		grpcCC:     cc,
-		db:         db,
+		mapEnv:       mapEnv,
+		logEnv:       logEnv,
+		grpcServer:   gsvr,
+		grpcCC:       cc,
+		closeStorage: closeStorage,
 	}, nil
 }
 
@@ -239,5 +307,5 @@ func (env *Env) Close() {
 	env.grpcServer.Stop()
 	env.mapEnv.Close()
 	env.logEnv.Close()
-	env.db.Close()
+	env.closeStorage()
 }
@@ -16,6 +16,7 @@ package integration
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/google/keytransparency/core/integration"
@@ -28,14 +29,16 @@ func TestIntegration(t *testing.T) {
 	testdb.SkipIfNoMySQL(t)
 	ctx := context.Background()
 
-	for _, test := range integration.AllTests {
-		t.Run(test.Name, func(t *testing.T) {
-			env, err := NewEnv()
-			if err != nil {
-				t.Fatalf("Could not create Env: %v", err)
-			}
-			defer env.Close()
-			test.Fn(ctx, env.Env, t)
-		})
+	for _, backend := range []Backend{SQLBackend, FileBackend} {
+		for _, test := range integration.AllTests {
+			t.Run(fmt.Sprintf("%v/%v", backend, test.Name), func(t *testing.T) {
+				env, err := NewEnv(backend)
+				if err != nil {
+					t.Fatalf("Could not create Env: %v", err)
+				}
+				defer env.Close()
+				test.Fn(ctx, env.Env, t)
+			})
+		}
 	}
 }
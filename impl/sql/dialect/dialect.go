@@ -0,0 +1,135 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dialect adapts the SQL statements of the storage packages, which are
+// written for MySQL, to the database engine they run against.
+package dialect
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Dialect identifies an SQL database engine.
+type Dialect int
+
+const (
+	// MySQL is the dialect the statements are written in.
+	MySQL Dialect = iota
+	// SQLite accepts the MySQL statements unchanged.
+	SQLite
+	// Postgres uses $n placeholders, BYTEA columns and ON CONFLICT upserts.
+	Postgres
+)
+
+// Of returns the dialect of the driver db was opened with. Drivers that are
+// not recognized are assumed to speak MySQL.
+func Of(db *sql.DB) Dialect {
+	t := reflect.TypeOf(db.Driver())
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	pkg := t.PkgPath()
+	switch {
+	case strings.HasSuffix(pkg, "github.com/lib/pq"),
+		strings.Contains(pkg, "github.com/jackc/pgx"):
+		return Postgres
+	case strings.HasSuffix(pkg, "github.com/mattn/go-sqlite3"):
+		return SQLite
+	default:
+		return MySQL
+	}
+}
+
+func (d Dialect) String() string {
+	switch d {
+	case MySQL:
+		return "mysql"
+	case SQLite:
+		return "sqlite"
+	case Postgres:
+		return "postgres"
+	default:
+		return fmt.Sprintf("Dialect(%d)", int(d))
+	}
+}
+
+// Query rewrites the ? placeholders of query into the placeholders of d.
+func (d Dialect) Query(query string) string {
+	if d != Postgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	quoted := false
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case r == '?' && !quoted:
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+var blobType = regexp.MustCompile(`\b(MEDIUMBLOB|BLOB|VARBINARY\(\d+\))`)
+
+// Schema rewrites the column types of a CREATE TABLE statement into the types
+// of d.
+func (d Dialect) Schema(stmt string) string {
+	if d != Postgres {
+		return stmt
+	}
+	return blobType.ReplaceAllString(stmt, "BYTEA")
+}
+
+var replaceInto = regexp.MustCompile(`^\s*REPLACE\s+INTO\s+(\w+)\s*\(([^)]*)\)`)
+
+// Upsert rewrites a REPLACE INTO statement into an insert that overwrites the
+// row with the same primary key, which consists of the columns keys. The
+// placeholders are rewritten as by Query.
+func (d Dialect) Upsert(query string, keys ...string) string {
+	if d != Postgres {
+		return query
+	}
+	m := replaceInto.FindStringSubmatchIndex(query)
+	if m == nil {
+		panic(fmt.Sprintf("dialect: not a REPLACE INTO statement: %q", query))
+	}
+	isKey := make(map[string]bool)
+	for _, k := range keys {
+		isKey[k] = true
+	}
+	var set []string
+	for _, c := range strings.Split(query[m[4]:m[5]], ",") {
+		c = strings.TrimSpace(c)
+		if !isKey[c] {
+			set = append(set, fmt.Sprintf("%s = EXCLUDED.%s", c, c))
+		}
+	}
+	action := "DO NOTHING"
+	if len(set) > 0 {
+		action = "DO UPDATE SET " + strings.Join(set, ", ")
+	}
+	stmt := strings.TrimRight(query[m[1]:], "; \t\n")
+	return d.Query(fmt.Sprintf("INSERT INTO %s (%s)%s\nON CONFLICT (%s) %s;", // nolint: gas
+		query[m[2]:m[3]], query[m[4]:m[5]], stmt, strings.Join(keys, ", "), action))
+}
@@ -0,0 +1,118 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dialect
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type otherDriver struct{}
+
+func (otherDriver) Open(string) (driver.Conn, error) { return nil, errors.New("unimplemented") }
+
+func init() {
+	sql.Register("dialect-test", otherDriver{})
+}
+
+func TestOf(t *testing.T) {
+	for _, tc := range []struct {
+		driver string
+		want   Dialect
+	}{
+		{driver: "sqlite3", want: SQLite},
+		{driver: "postgres", want: Postgres},
+		{driver: "dialect-test", want: MySQL},
+	} {
+		db, err := sql.Open(tc.driver, "")
+		if err != nil {
+			t.Fatalf("sql.Open(%v): %v", tc.driver, err)
+		}
+		if got := Of(db); got != tc.want {
+			t.Errorf("Of(%v): %v, want %v", tc.driver, got, tc.want)
+		}
+		db.Close()
+	}
+}
+
+func TestQuery(t *testing.T) {
+	for _, tc := range []struct {
+		d     Dialect
+		query string
+		want  string
+	}{
+		{d: MySQL, query: `SELECT a FROM T WHERE b = ? AND c = ?;`, want: `SELECT a FROM T WHERE b = ? AND c = ?;`},
+		{d: SQLite, query: `SELECT a FROM T WHERE b = ? AND c = ?;`, want: `SELECT a FROM T WHERE b = ? AND c = ?;`},
+		{d: Postgres, query: `SELECT a FROM T WHERE b = ? AND c = ?;`, want: `SELECT a FROM T WHERE b = $1 AND c = $2;`},
+		{d: Postgres, query: `SELECT a FROM T WHERE b = '?' AND c = ?;`, want: `SELECT a FROM T WHERE b = '?' AND c = $1;`},
+	} {
+		if got := tc.d.Query(tc.query); got != tc.want {
+			t.Errorf("%v.Query(%q): %q, want %q", tc.d, tc.query, got, tc.want)
+		}
+	}
+}
+
+func TestSchema(t *testing.T) {
+	stmt := `CREATE TABLE T (A MEDIUMBLOB NOT NULL, B BLOB, C VARBINARY(32), D BIGINT);`
+	for _, tc := range []struct {
+		d    Dialect
+		want string
+	}{
+		{d: MySQL, want: stmt},
+		{d: SQLite, want: stmt},
+		{d: Postgres, want: `CREATE TABLE T (A BYTEA NOT NULL, B BYTEA, C BYTEA, D BIGINT);`},
+	} {
+		if got := tc.d.Schema(stmt); got != tc.want {
+			t.Errorf("%v.Schema(): %q, want %q", tc.d, got, tc.want)
+		}
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	for _, tc := range []struct {
+		d     Dialect
+		query string
+		keys  []string
+		want  string
+	}{
+		{
+			d:     MySQL,
+			query: "REPLACE INTO T (A, B, C)\n\tVALUES (?, ?, ?);",
+			keys:  []string{"A"},
+			want:  "REPLACE INTO T (A, B, C)\n\tVALUES (?, ?, ?);",
+		},
+		{
+			d:     Postgres,
+			query: "\n\tREPLACE INTO T (A, B, C)\n\tVALUES (?, ?, ?);",
+			keys:  []string{"A"},
+			want:  "INSERT INTO T (A, B, C)\n\tVALUES ($1, $2, $3)\nON CONFLICT (A) DO UPDATE SET B = EXCLUDED.B, C = EXCLUDED.C;",
+		},
+		{
+			d:     Postgres,
+			query: "REPLACE INTO T (A, B) VALUES (?, ?);",
+			keys:  []string{"A", "B"},
+			want:  "INSERT INTO T (A, B) VALUES ($1, $2)\nON CONFLICT (A, B) DO NOTHING;",
+		},
+	} {
+		if got := tc.d.Upsert(tc.query, tc.keys...); got != tc.want {
+			t.Errorf("%v.Upsert(%q, %v): %q, want %q", tc.d, tc.query, tc.keys, got, tc.want)
+		}
+	}
+}
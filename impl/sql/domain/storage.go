@@ -25,13 +25,30 @@ import (
 	"github.com/golang/protobuf/ptypes"
 	"github.com/golang/protobuf/ptypes/any"
 	"github.com/google/keytransparency/core/domain"
+	"github.com/google/keytransparency/impl/sql/dialect"
+	"github.com/google/keytransparency/impl/sql/migrate"
 	"github.com/google/trillian/crypto/keyspb"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+
+	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
 )
 
 const (
-	createSQL = `
+	createV1SQL = `
+CREATE TABLE IF NOT EXISTS Domains(
+  DomainId              VARCHAR(40) NOT NULL,
+  MapId                 BIGINT NOT NULL,
+  LogId                 BIGINT NOT NULL,
+  VRFPublicKey          MEDIUMBLOB NOT NULL,
+  VRFPrivateKey         MEDIUMBLOB NOT NULL,
+  MinInterval           BIGINT NOT NULL,
+  MaxInterval           BIGINT NOT NULL,
+  Deleted               INTEGER,
+  DeleteTimeMillis      BIGINT,
+  PRIMARY KEY(DomainId)
+);`
+	createV2SQL = `
 CREATE TABLE IF NOT EXISTS Domains(
   DomainId              VARCHAR(40) NOT NULL,
   MapId                 BIGINT NOT NULL,
@@ -42,50 +59,82 @@ CREATE TABLE IF NOT EXISTS Domains(
   MaxInterval           BIGINT NOT NULL,
   Deleted               INTEGER,
   DeleteTimeMillis      BIGINT,
+  ReceiptPublicKey      MEDIUMBLOB,
+  ReceiptPrivateKey     MEDIUMBLOB,
   PRIMARY KEY(DomainId)
 );`
 	writeSQL = `INSERT INTO Domains 
-(DomainId, MapId, LogId, VRFPublicKey, VRFPrivateKey, MinInterval, MaxInterval, Deleted) 
-VALUES (?, ?, ?, ?, ?, ?, ?, ?);`
+(DomainId, MapId, LogId, VRFPublicKey, VRFPrivateKey, ReceiptPublicKey, ReceiptPrivateKey, MinInterval, MaxInterval, SequencingConfig, Deleted) 
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
 	readSQL = `
-SELECT DomainId, MapId, LogId, VRFPublicKey, VRFPrivateKey, MinInterval, MaxInterval, Deleted
+SELECT DomainId, MapId, LogId, VRFPublicKey, VRFPrivateKey, ReceiptPublicKey, ReceiptPrivateKey, MinInterval, MaxInterval, SequencingConfig, Deleted
 FROM Domains WHERE DomainId = ? AND Deleted = 0;`
 	readDeletedSQL = `
-SELECT DomainId, MapId, LogId, VRFPublicKey, VRFPrivateKey, MinInterval, MaxInterval, Deleted
+SELECT DomainId, MapId, LogId, VRFPublicKey, VRFPrivateKey, ReceiptPublicKey, ReceiptPrivateKey, MinInterval, MaxInterval, SequencingConfig, Deleted
 FROM Domains WHERE DomainId = ?;`
 	listSQL = `
-SELECT DomainId, MapId, LogId, VRFPublicKey, VRFPrivateKey, MinInterval, MaxInterval, Deleted
-FROM Domains WHERE Deleted = 0;`
+SELECT DomainId, MapId, LogId, VRFPublicKey, VRFPrivateKey, ReceiptPublicKey, ReceiptPrivateKey, MinInterval, MaxInterval, SequencingConfig, Deleted
+FROM Domains WHERE Deleted = 0 ORDER BY DomainId;`
 	listDeletedSQL = `
-SELECT DomainId, MapId, LogId, VRFPublicKey, VRFPrivateKey, MinInterval, MaxInterval, Deleted
-FROM Domains;`
+SELECT DomainId, MapId, LogId, VRFPublicKey, VRFPrivateKey, ReceiptPublicKey, ReceiptPrivateKey, MinInterval, MaxInterval, SequencingConfig, Deleted
+FROM Domains ORDER BY DomainId;`
 	setDeletedSQL = `UPDATE Domains SET Deleted = ?, DeleteTimeMillis = ? WHERE DomainId = ?`
 )
 
+var v1Columns = []string{"DomainId", "MapId", "LogId", "VRFPublicKey", "VRFPrivateKey",
+	"MinInterval", "MaxInterval", "Deleted", "DeleteTimeMillis"}
+
+// Schema is the versioned schema of the Domains table.
+var Schema = migrate.Schema{
+	Component: "domains",
+	Migrations: []migrate.Migration{
+		{
+			Version: 1,
+			Desc:    "create Domains",
+			Up:      []string{createV1SQL},
+			Down:    []string{`DROP TABLE Domains;`},
+			Applied: `SELECT DomainId FROM Domains WHERE 1 = 0;`,
+		},
+		{
+			Version: 2,
+			Desc:    "add receipt keys",
+			Up: []string{
+				`ALTER TABLE Domains ADD COLUMN ReceiptPublicKey MEDIUMBLOB;`,
+				`ALTER TABLE Domains ADD COLUMN ReceiptPrivateKey MEDIUMBLOB;`,
+			},
+			Down:    migrate.Rebuild("Domains", createV1SQL, v1Columns...),
+			Applied: `SELECT ReceiptPublicKey, ReceiptPrivateKey FROM Domains WHERE 1 = 0;`,
+		},
+		{
+			Version: 3,
+			Desc:    "add sequencing config",
+			Up:      []string{`ALTER TABLE Domains ADD COLUMN SequencingConfig MEDIUMBLOB;`},
+			Down: migrate.Rebuild("Domains", createV2SQL,
+				append(v1Columns, "ReceiptPublicKey", "ReceiptPrivateKey")...),
+			Applied: `SELECT SequencingConfig FROM Domains WHERE 1 = 0;`,
+		},
+	},
+}
+
 type storage struct {
-	db *sql.DB
+	db      *sql.DB
+	dialect dialect.Dialect
 }
 
 // NewStorage returns a domain.Storage client backed by an SQL table.
 func NewStorage(db *sql.DB) (domain.Storage, error) {
 	s := &storage{
-		db: db,
+		db:      db,
+		dialect: dialect.Of(db),
 	}
-	// Create tables.
-	if err := s.create(); err != nil {
-		return nil, err
+	// Create or upgrade tables.
+	if err := Schema.Up(context.Background(), db); err != nil {
+		return nil, fmt.Errorf("Failed to migrate domain tables: %v", err)
 	}
 	return s, nil
 }
 
-func (s *storage) create() error {
-	_, err := s.db.Exec(createSQL)
-	if err != nil {
-		return fmt.Errorf("Failed to create commitments tables: %v", err)
-	}
-	return nil
-}
-
+// List returns the domains sorted by domain ID.
 func (s *storage) List(ctx context.Context, showDeleted bool) ([]*domain.Domain, error) {
 	var query string
 	if showDeleted {
@@ -93,7 +142,7 @@ func (s *storage) List(ctx context.Context, showDeleted bool) ([]*domain.Domain,
 	} else {
 		query = listSQL
 	}
-	readStmt, err := s.db.PrepareContext(ctx, query)
+	readStmt, err := s.db.PrepareContext(ctx, s.dialect.Query(query))
 	if err != nil {
 		return nil, err
 	}
@@ -107,13 +156,15 @@ func (s *storage) List(ctx context.Context, showDeleted bool) ([]*domain.Domain,
 
 	ret := []*domain.Domain{}
 	for rows.Next() {
-		var pubkey, anyData []byte
+		var pubkey, anyData, receiptPub, receiptData, sequencing []byte
 		d := &domain.Domain{}
 		if err := rows.Scan(
 			&d.DomainID,
 			&d.MapID, &d.LogID,
 			&pubkey, &anyData,
+			&receiptPub, &receiptData,
 			&d.MinInterval, &d.MaxInterval,
+			&sequencing,
 			&d.Deleted); err != nil {
 			return nil, err
 		}
@@ -123,6 +174,12 @@ func (s *storage) List(ctx context.Context, showDeleted bool) ([]*domain.Domain,
 		if err != nil {
 			return nil, err
 		}
+		if err := setReceiptKey(d, receiptPub, receiptData); err != nil {
+			return nil, err
+		}
+		if err := setSequencing(d, sequencing); err != nil {
+			return nil, err
+		}
 		ret = append(ret, d)
 	}
 	return ret, nil
@@ -138,8 +195,23 @@ func (s *storage) Write(ctx context.Context, d *domain.Domain) error {
 	if err != nil {
 		return err
 	}
+	var receiptPub, receiptData []byte
+	if d.ReceiptPriv != nil {
+		receiptPub = d.ReceiptKey.GetDer()
+		receiptData, err = wrapAnyProto(d.ReceiptPriv)
+		if err != nil {
+			return err
+		}
+	}
+	var sequencing []byte
+	if d.Sequencing != nil {
+		sequencing, err = proto.Marshal(d.Sequencing)
+		if err != nil {
+			return err
+		}
+	}
 	// Prepare SQL.
-	writeStmt, err := s.db.PrepareContext(ctx, writeSQL)
+	writeStmt, err := s.db.PrepareContext(ctx, s.dialect.Query(writeSQL))
 	if err != nil {
 		return err
 	}
@@ -148,8 +220,10 @@ func (s *storage) Write(ctx context.Context, d *domain.Domain) error {
 		d.DomainID,
 		d.MapID, d.LogID,
 		d.VRF.Der, anyData,
+		receiptPub, receiptData,
 		d.MinInterval.Nanoseconds(), d.MaxInterval.Nanoseconds(),
-		false)
+		sequencing,
+		deletedValue(false))
 	return err
 }
 
@@ -160,18 +234,20 @@ func (s *storage) Read(ctx context.Context, domainID string, showDeleted bool) (
 	} else {
 		SQL = readSQL
 	}
-	readStmt, err := s.db.PrepareContext(ctx, SQL)
+	readStmt, err := s.db.PrepareContext(ctx, s.dialect.Query(SQL))
 	if err != nil {
 		return nil, err
 	}
 	defer readStmt.Close()
 	d := &domain.Domain{}
-	var pubkey, anyData []byte
+	var pubkey, anyData, receiptPub, receiptData, sequencing []byte
 	if err := readStmt.QueryRowContext(ctx, domainID).Scan(
 		&d.DomainID,
 		&d.MapID, &d.LogID,
 		&pubkey, &anyData,
+		&receiptPub, &receiptData,
 		&d.MinInterval, &d.MaxInterval,
+		&sequencing,
 		&d.Deleted); err == sql.ErrNoRows {
 		return nil, status.Errorf(codes.NotFound, "%v", err)
 	} else if err != nil {
@@ -184,9 +260,56 @@ func (s *storage) Read(ctx context.Context, domainID string, showDeleted bool) (
 	if err != nil {
 		return nil, err
 	}
+	if err := setReceiptKey(d, receiptPub, receiptData); err != nil {
+		return nil, err
+	}
+	if err := setSequencing(d, sequencing); err != nil {
+		return nil, err
+	}
 	return d, nil
 }
 
+// deletedValue returns the value of the Deleted column for isDeleted. The
+// column is an integer, which not every driver converts booleans to.
+func deletedValue(isDeleted bool) int {
+	if isDeleted {
+		return 1
+	}
+	return 0
+}
+
+// setReceiptKey sets the receipt keys of d if a receipt key was stored.
+func setReceiptKey(d *domain.Domain, pubkey, anyData []byte) error {
+	if len(anyData) == 0 {
+		return nil
+	}
+	priv, err := unwrapAnyProto(anyData)
+	if err != nil {
+		return err
+	}
+	d.ReceiptKey = &keyspb.PublicKey{Der: pubkey}
+	d.ReceiptPriv = priv
+	return nil
+}
+
+// setSequencing sets the sequencing config of d if one was stored.
+func setSequencing(d *domain.Domain, data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	d.Sequencing = &pb.SequencingConfig{}
+	return proto.Unmarshal(data, d.Sequencing)
+}
+
+// wrapAnyProto returns a serialized any.Any containing msg.
+func wrapAnyProto(msg proto.Message) ([]byte, error) {
+	anyPB, err := ptypes.MarshalAny(msg)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(anyPB)
+}
+
 // unwrapAnyProto returns the proto object seralized inside a serialized any.Any
 func unwrapAnyProto(anyData []byte) (proto.Message, error) {
 	var anyPB any.Any
@@ -200,7 +323,21 @@ func unwrapAnyProto(anyData []byte) (proto.Message, error) {
 	return privKey.Message, nil
 }
 
+// SetDelete returns a NotFound error if the domain does not exist.
 func (s *storage) SetDelete(ctx context.Context, domainID string, isDeleted bool) error {
-	_, err := s.db.ExecContext(ctx, setDeletedSQL, isDeleted, time.Now().Unix(), domainID)
-	return err
+	result, err := s.db.ExecContext(ctx, s.dialect.Query(setDeletedSQL),
+		deletedValue(isDeleted), time.Now().Unix(), domainID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		// MySQL does not count rows that were left unchanged.
+		_, err := s.Read(ctx, domainID, true)
+		return err
+	}
+	return nil
 }
@@ -23,194 +23,116 @@ import (
 	"github.com/golang/protobuf/proto"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/keytransparency/core/domain"
-
+	"github.com/google/keytransparency/core/storage/storagetest"
+	"github.com/google/keytransparency/impl/sql/dialect"
+	"github.com/google/keytransparency/impl/sql/migrate"
+	"github.com/google/keytransparency/impl/sql/pgtest"
 	"github.com/google/trillian/crypto/keyspb"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
-func TestList(t *testing.T) {
-	ctx := context.Background()
+func newStorage(ctx context.Context, t *testing.T) (domain.Storage, func()) {
 	db, err := sql.Open("sqlite3", ":memory:")
 	if err != nil {
 		t.Fatalf("sql.Open(): %v", err)
 	}
-	defer db.Close()
+	// Each connection to :memory: opens a different database.
+	db.SetMaxOpenConns(1)
 	admin, err := NewStorage(db)
 	if err != nil {
 		t.Fatalf("Failed to create adminstorage: %v", err)
 	}
-	for _, tc := range []struct {
-		domains     []*domain.Domain
-		readDeleted bool
-	}{
-		{
-			domains: []*domain.Domain{
-				{
-					DomainID:    "domain1",
-					MapID:       1,
-					LogID:       2,
-					VRF:         &keyspb.PublicKey{Der: []byte("pubkeybytes")},
-					VRFPriv:     &keyspb.PrivateKey{Der: []byte("privkeybytes")},
-					MinInterval: 1 * time.Second,
-					MaxInterval: 5 * time.Second,
-				},
-				{
-					DomainID:    "domain2",
-					MapID:       1,
-					LogID:       2,
-					VRF:         &keyspb.PublicKey{Der: []byte("pubkeybytes")},
-					VRFPriv:     &keyspb.PrivateKey{Der: []byte("privkeybytes")},
-					MinInterval: 5 * time.Hour,
-					MaxInterval: 500 * time.Hour,
-				},
-			},
-		},
-	} {
-		for _, d := range tc.domains {
-			if err := admin.Write(ctx, d); err != nil {
-				t.Errorf("Write(): %v", err)
-				continue
-			}
-		}
+	return admin, func() { db.Close() }
+}
+
+func TestStorage(t *testing.T) {
+	storagetest.DomainStorageTester{NewStorage: newStorage}.RunAllTests(t)
+}
 
-		domains, err := admin.List(ctx, tc.readDeleted)
+func TestStoragePostgres(t *testing.T) {
+	pgtest.SkipIfNoPostgres(t)
+	storagetest.DomainStorageTester{NewStorage: func(ctx context.Context, t *testing.T) (domain.Storage, func()) {
+		db, done := pgtest.NewDB(t)
+		admin, err := NewStorage(db)
 		if err != nil {
-			t.Errorf("List(): %v", err)
-			continue
-		}
-		if got, want := domains, tc.domains; !cmp.Equal(got, want, cmp.Comparer(proto.Equal)) {
-			t.Errorf("List(): %#v, want %#v, diff: \n%v", got, want, cmp.Diff(got, want))
+			t.Fatalf("Failed to create adminstorage: %v", err)
 		}
-	}
+		return admin, done
+	}}.RunAllTests(t)
 }
 
-func TestWriteReadDelete(t *testing.T) {
-	ctx := context.Background()
+// TestUpgrade opens a Domains table created and written by the first version
+// of this package, and downgrades and upgrades it again.
+func TestUpgrade(t *testing.T) {
 	db, err := sql.Open("sqlite3", ":memory:")
 	if err != nil {
 		t.Fatalf("sql.Open(): %v", err)
 	}
 	defer db.Close()
-	admin, err := NewStorage(db)
+	// Each connection to :memory: opens a different database.
+	db.SetMaxOpenConns(1)
+	testUpgrade(t, db)
+}
+
+func TestUpgradePostgres(t *testing.T) {
+	pgtest.SkipIfNoPostgres(t)
+	db, done := pgtest.NewDB(t)
+	defer done()
+	testUpgrade(t, db)
+}
+
+func testUpgrade(t *testing.T, db *sql.DB) {
+	ctx := context.Background()
+	dia := dialect.Of(db)
+
+	d := &domain.Domain{
+		DomainID:    "olddomain",
+		MapID:       1,
+		LogID:       2,
+		VRF:         &keyspb.PublicKey{Der: []byte("pubkeybytes")},
+		VRFPriv:     &keyspb.PrivateKey{Der: []byte("privkeybytes")},
+		MinInterval: 1 * time.Second,
+		MaxInterval: 5 * time.Second,
+	}
+	anyData, err := wrapAnyProto(d.VRFPriv)
 	if err != nil {
-		t.Fatalf("Failed to create adminstorage: %v", err)
+		t.Fatalf("wrapAnyProto(): %v", err)
+	}
+	if _, err := db.Exec(dia.Schema(createV1SQL)); err != nil {
+		t.Fatalf("Exec(createV1SQL): %v", err)
+	}
+	if _, err := db.Exec(dia.Query(`INSERT INTO Domains
+(DomainId, MapId, LogId, VRFPublicKey, VRFPrivateKey, MinInterval, MaxInterval, Deleted)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?);`),
+		d.DomainID, d.MapID, d.LogID, d.VRF.Der, anyData,
+		d.MinInterval.Nanoseconds(), d.MaxInterval.Nanoseconds(), 0); err != nil {
+		t.Fatalf("INSERT: %v", err)
 	}
 
-	for _, tc := range []struct {
-		desc                 string
-		d                    domain.Domain
-		write                bool
-		wantWriteErr         bool
-		setDelete, isDeleted bool
-		readDeleted          bool
-		wantReadErr          bool
-	}{
-		{
-			desc:  "Success",
-			write: true,
-			d: domain.Domain{
-				DomainID:    "testdomain",
-				MapID:       1,
-				LogID:       2,
-				VRF:         &keyspb.PublicKey{Der: []byte("pubkeybytes")},
-				VRFPriv:     &keyspb.PrivateKey{Der: []byte("privkeybytes")},
-				MinInterval: 1 * time.Second,
-				MaxInterval: 5 * time.Second,
-			},
-		},
-		{
-			desc:  "Duplicate DomainID",
-			write: true,
-			d: domain.Domain{
-				DomainID:    "testdomain",
-				MapID:       1,
-				LogID:       2,
-				VRF:         &keyspb.PublicKey{Der: []byte("pubkeybytes")},
-				VRFPriv:     &keyspb.PrivateKey{Der: []byte("privkeybytes")},
-				MinInterval: 1 * time.Second,
-				MaxInterval: 5 * time.Second,
-			},
-			wantWriteErr: true,
-		},
-		{
-			desc: "Delete",
-			d: domain.Domain{
-				DomainID:    "testdomain",
-				MapID:       1,
-				LogID:       2,
-				VRF:         &keyspb.PublicKey{Der: []byte("pubkeybytes")},
-				VRFPriv:     &keyspb.PrivateKey{Der: []byte("privkeybytes")},
-				MinInterval: 1 * time.Second,
-				MaxInterval: 5 * time.Second,
-			},
-			setDelete:   true,
-			isDeleted:   true,
-			readDeleted: false,
-			wantReadErr: true,
-		},
-		{
-			desc: "Read deleted",
-			d: domain.Domain{
-				DomainID:    "testdomain",
-				MapID:       1,
-				LogID:       2,
-				VRF:         &keyspb.PublicKey{Der: []byte("pubkeybytes")},
-				VRFPriv:     &keyspb.PrivateKey{Der: []byte("privkeybytes")},
-				MinInterval: 1 * time.Second,
-				MaxInterval: 5 * time.Second,
-			},
-			setDelete:   true,
-			isDeleted:   true,
-			readDeleted: true,
-			wantReadErr: false,
-		},
-		{
-			desc: "Undelete",
-			d: domain.Domain{
-				DomainID:    "testdomain",
-				MapID:       1,
-				LogID:       2,
-				VRF:         &keyspb.PublicKey{Der: []byte("pubkeybytes")},
-				VRFPriv:     &keyspb.PrivateKey{Der: []byte("privkeybytes")},
-				MinInterval: 1 * time.Second,
-				MaxInterval: 5 * time.Second,
-			},
-			setDelete:   true,
-			isDeleted:   false,
-			readDeleted: false,
-			wantReadErr: false,
-		},
-	} {
-		t.Run(tc.desc, func(t *testing.T) {
-			if tc.write {
-				err := admin.Write(ctx, &tc.d)
-				if got, want := err != nil, tc.wantWriteErr; got != want {
-					t.Errorf("Write(): %v, want err: %v", err, want)
-					return
-				}
-				if err != nil {
-					return
-				}
-			}
-			if tc.setDelete {
-				if err := admin.SetDelete(ctx, tc.d.DomainID, tc.isDeleted); err != nil {
-					t.Errorf("SetDelete(%v, %v): %v", tc.d.DomainID, tc.isDeleted, err)
-					return
-				}
-			}
+	s, err := NewStorage(db)
+	if err != nil {
+		t.Fatalf("NewStorage(): %v", err)
+	}
+	if got, err := migrate.Version(ctx, db, Schema.Component); err != nil || got != Schema.Latest() {
+		t.Errorf("Version(): %v, %v, want %v", got, err, Schema.Latest())
+	}
+	got, err := s.Read(ctx, d.DomainID, false)
+	if err != nil {
+		t.Fatalf("Read(): %v", err)
+	}
+	if !cmp.Equal(got, d, cmp.Comparer(proto.Equal)) {
+		t.Errorf("Read(): %v, want %v", got, d)
+	}
 
-			domain, err := admin.Read(ctx, tc.d.DomainID, tc.readDeleted)
-			if got, want := err != nil, tc.wantReadErr; got != want {
-				t.Errorf("Read(): %v, want err: %v", err, want)
-			}
-			if err != nil {
-				return
-			}
-			tc.d.Deleted = tc.isDeleted
-			if got, want := *domain, tc.d; !cmp.Equal(got, want, cmp.Comparer(proto.Equal)) {
-				t.Errorf("Read(%v, %v): %#v, want %#v, diff: \n%v", tc.d.DomainID, tc.readDeleted, got, want, cmp.Diff(got, want))
-			}
-		})
+	// A downgrade to the first version keeps the domain.
+	if err := Schema.MigrateTo(ctx, db, 1); err != nil {
+		t.Fatalf("MigrateTo(1): %v", err)
+	}
+	if err := Schema.Up(ctx, db); err != nil {
+		t.Fatalf("Up(): %v", err)
+	}
+	if got, err := s.Read(ctx, d.DomainID, false); err != nil || !cmp.Equal(got, d, cmp.Comparer(proto.Equal)) {
+		t.Errorf("Read() after downgrade: %v, %v, want %v", got, err, d)
 	}
 }
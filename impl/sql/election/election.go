@@ -0,0 +1,164 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package election implements election.Factory with leases stored in an SQL
+// table.
+//
+// Each resource has at most one lease. The holder of an unexpired lease is the
+// master. Replicas compare lease expiry times against their own clocks, so the
+// clocks of all replicas must be roughly synchronized.
+package election
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/keytransparency/core/sequencer/election"
+	"github.com/google/keytransparency/impl/sql/dialect"
+	"github.com/google/keytransparency/impl/sql/migrate"
+)
+
+const (
+	createSQL = `
+CREATE TABLE IF NOT EXISTS Leases(
+  ResourceID VARCHAR(60) NOT NULL,
+  Holder     VARCHAR(60) NOT NULL,
+  Expiry     BIGINT NOT NULL,
+  PRIMARY KEY(ResourceID)
+);`
+	renewSQL = `UPDATE Leases SET Holder = ?, Expiry = ?
+WHERE ResourceID = ? AND (Holder = ? OR Expiry < ?);`
+	insertSQL = `INSERT INTO Leases (ResourceID, Holder, Expiry) VALUES (?, ?, ?);`
+	readSQL   = `SELECT Holder, Expiry FROM Leases WHERE ResourceID = ?;`
+	resignSQL = `UPDATE Leases SET Expiry = 0 WHERE ResourceID = ? AND Holder = ?;`
+)
+
+// Schema is the versioned schema of the Leases table.
+var Schema = migrate.Schema{
+	Component: "leases",
+	Migrations: []migrate.Migration{
+		{
+			Version: 1,
+			Desc:    "create Leases",
+			Up:      []string{createSQL},
+			Down:    []string{`DROP TABLE Leases;`},
+			Applied: `SELECT Expiry FROM Leases WHERE 1 = 0;`,
+		},
+	},
+}
+
+// Factory creates elections backed by SQL leases.
+type Factory struct {
+	db      *sql.DB
+	dialect dialect.Dialect
+	holder  string
+	lease   time.Duration
+	clock   func() time.Time
+}
+
+// NewFactory returns a Factory whose elections are held by holder, which
+// must uniquely identify this replica. Mastership lasts for lease after each
+// successful Campaign.
+func NewFactory(db *sql.DB, holder string, lease time.Duration) (*Factory, error) {
+	if err := Schema.Up(context.Background(), db); err != nil {
+		return nil, fmt.Errorf("failed to migrate lease table: %v", err)
+	}
+	return &Factory{
+		db:      db,
+		dialect: dialect.Of(db),
+		holder:  holder,
+		lease:   lease,
+		clock:   time.Now,
+	}, nil
+}
+
+// NewElection returns an election for resourceID.
+func (f *Factory) NewElection(ctx context.Context, resourceID string) (election.Election, error) {
+	return &Election{
+		db:         f.db,
+		dialect:    f.dialect,
+		resourceID: resourceID,
+		holder:     f.holder,
+		lease:      f.lease,
+		clock:      f.clock,
+	}, nil
+}
+
+// Election implements election.Election with an SQL lease.
+type Election struct {
+	db         *sql.DB
+	dialect    dialect.Dialect
+	resourceID string
+	holder     string
+	lease      time.Duration
+	clock      func() time.Time
+
+	mu     sync.Mutex
+	expiry time.Time // The time at which our mastership ends.
+}
+
+// Campaign acquires the lease if it is free or expired, or extends it if it
+// is held by this instance.
+func (e *Election) Campaign(ctx context.Context) (bool, error) {
+	now := e.clock()
+	expiry := now.Add(e.lease)
+	res, err := e.db.ExecContext(ctx, e.dialect.Query(renewSQL),
+		e.holder, expiry.UnixNano(), e.resourceID, e.holder, now.UnixNano())
+	if err != nil {
+		return false, err
+	}
+	renewed, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if renewed == 0 {
+		// Either nobody has held the lease yet, or somebody else holds it.
+		if _, err := e.db.ExecContext(ctx, e.dialect.Query(insertSQL), e.resourceID, e.holder, expiry.UnixNano()); err != nil {
+			var holder string
+			var leaseExpiry int64
+			if readErr := e.db.QueryRowContext(ctx, e.dialect.Query(readSQL), e.resourceID).Scan(&holder, &leaseExpiry); readErr != nil {
+				return false, err
+			}
+			// The lease exists, and belongs to another instance.
+			e.setExpiry(time.Time{})
+			return false, nil
+		}
+	}
+	e.setExpiry(expiry)
+	return true, nil
+}
+
+// IsMaster returns whether the lease acquired by the last Campaign is still
+// valid.
+func (e *Election) IsMaster(ctx context.Context) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.clock().Before(e.expiry), nil
+}
+
+// Resign releases the lease if this instance holds it.
+func (e *Election) Resign(ctx context.Context) error {
+	e.setExpiry(time.Time{})
+	_, err := e.db.ExecContext(ctx, e.dialect.Query(resignSQL), e.resourceID, e.holder)
+	return err
+}
+
+func (e *Election) setExpiry(expiry time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.expiry = expiry
+}
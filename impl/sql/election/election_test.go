@@ -0,0 +1,129 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package election
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/keytransparency/impl/sql/pgtest"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestCampaign(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("sql.Open(): %v", err)
+	}
+	defer db.Close()
+	testCampaign(t, db)
+}
+
+func TestCampaignPostgres(t *testing.T) {
+	pgtest.SkipIfNoPostgres(t)
+	db, done := pgtest.NewDB(t)
+	defer done()
+	testCampaign(t, db)
+}
+
+func testCampaign(t *testing.T, db *sql.DB) {
+	ctx := context.Background()
+	now := time.Unix(1000, 0)
+	clock := func() time.Time { return now }
+	lease := 10 * time.Second
+	replicas := make(map[string]*Election)
+	for _, holder := range []string{"a", "b"} {
+		f, err := NewFactory(db, holder, lease)
+		if err != nil {
+			t.Fatalf("NewFactory(): %v", err)
+		}
+		f.clock = clock
+		e, err := f.NewElection(ctx, "domain")
+		if err != nil {
+			t.Fatalf("NewElection(): %v", err)
+		}
+		replicas[holder] = e.(*Election)
+	}
+
+	for _, step := range []struct {
+		desc       string
+		advance    time.Duration
+		holder     string
+		resign     bool
+		wantMaster bool
+	}{
+		{desc: "a acquires free lease", holder: "a", wantMaster: true},
+		{desc: "b is standby", holder: "b", wantMaster: false},
+		{desc: "a renews", advance: 5 * time.Second, holder: "a", wantMaster: true},
+		{desc: "b is still standby", advance: 9 * time.Second, holder: "b", wantMaster: false},
+		{desc: "b takes over expired lease", advance: 2 * time.Second, holder: "b", wantMaster: true},
+		{desc: "a lost mastership", holder: "a", wantMaster: false},
+		{desc: "b resigns", holder: "b", resign: true},
+		{desc: "a takes over after resignation", holder: "a", wantMaster: true},
+	} {
+		now = now.Add(step.advance)
+		e := replicas[step.holder]
+		if step.resign {
+			if err := e.Resign(ctx); err != nil {
+				t.Fatalf("%v: Resign(): %v", step.desc, err)
+			}
+			if master, _ := e.IsMaster(ctx); master {
+				t.Errorf("%v: IsMaster(): true after Resign()", step.desc)
+			}
+			continue
+		}
+		master, err := e.Campaign(ctx)
+		if err != nil {
+			t.Fatalf("%v: Campaign(): %v", step.desc, err)
+		}
+		if got, want := master, step.wantMaster; got != want {
+			t.Errorf("%v: Campaign(): %v, want %v", step.desc, got, want)
+		}
+		if got, _ := e.IsMaster(ctx); got != master {
+			t.Errorf("%v: IsMaster(): %v, want %v", step.desc, got, master)
+		}
+	}
+}
+
+func TestIsMasterExpires(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("sql.Open(): %v", err)
+	}
+	defer db.Close()
+
+	now := time.Unix(1000, 0)
+	f, err := NewFactory(db, "a", 10*time.Second)
+	if err != nil {
+		t.Fatalf("NewFactory(): %v", err)
+	}
+	f.clock = func() time.Time { return now }
+	e, err := f.NewElection(ctx, "domain")
+	if err != nil {
+		t.Fatalf("NewElection(): %v", err)
+	}
+	if master, err := e.Campaign(ctx); err != nil || !master {
+		t.Fatalf("Campaign(): %v, %v, want true", master, err)
+	}
+	// Without a renewal, mastership ends when the lease expires.
+	now = now.Add(10 * time.Second)
+	if master, _ := e.IsMaster(ctx); master {
+		t.Errorf("IsMaster(): true after lease expired")
+	}
+}
@@ -12,7 +12,7 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// +build !mysql
+// +build !mysql,!postgres
 
 package engine
 
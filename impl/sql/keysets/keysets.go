@@ -21,14 +21,18 @@ import (
 	"fmt"
 
 	"github.com/google/keytransparency/core/storage"
+	"github.com/google/keytransparency/impl/sql/dialect"
+	"github.com/google/keytransparency/impl/sql/migrate"
 
 	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	tpb "github.com/google/keytransparency/core/api/type/type_go_proto"
 )
 
 const (
-	schema = `
+	createSQL = `
 CREATE TABLE IF NOT EXISTS KeySets(
 InstanceID            BIGINT NOT NULL,
 DomainID              VARCHAR(40) NOT NULL,
@@ -41,9 +45,24 @@ PRIMARY KEY(InstanceID,DomainID,AppID)
 	setSQL = `INSERT INTO KeySets (InstanceID, DomainID, AppID, KeySet) VALUES (?, ?, ?, ?);`
 )
 
+// Schema is the versioned schema of the KeySets table.
+var Schema = migrate.Schema{
+	Component: "keysets",
+	Migrations: []migrate.Migration{
+		{
+			Version: 1,
+			Desc:    "create KeySets",
+			Up:      []string{createSQL},
+			Down:    []string{`DROP TABLE KeySets;`},
+			Applied: `SELECT KeySet FROM KeySets WHERE 1 = 0;`,
+		},
+	},
+}
+
 // Storage stores keysets, backed by an SQL database.
 type Storage struct {
-	db *sql.DB
+	db      *sql.DB
+	dialect dialect.Dialect
 }
 
 type keyset struct {
@@ -78,17 +97,17 @@ func (k *keyset) Proto() (*tpb.KeySet, error) {
 
 // New returns a storage.KeySets client backed by an SQL table.
 func New(db *sql.DB) (storage.KeySets, error) {
-	s := &Storage{db: db}
-	// Create schema.
-	if _, err := s.db.Exec(schema); err != nil {
-		return nil, fmt.Errorf("failed to create keyset table: %v", err)
+	s := &Storage{db: db, dialect: dialect.Of(db)}
+	// Create or upgrade schema.
+	if err := Schema.Up(context.Background(), db); err != nil {
+		return nil, fmt.Errorf("failed to migrate keyset table: %v", err)
 	}
 	return s, db.Ping()
 }
 
 // Get returns a stored keyset.
 func (s *Storage) Get(ctx context.Context, instance int64, domainID, appID string) (*tpb.KeySet, error) {
-	readStmt, err := s.db.PrepareContext(ctx, getSQL)
+	readStmt, err := s.db.PrepareContext(ctx, s.dialect.Query(getSQL))
 	if err != nil {
 		return nil, err
 	}
@@ -98,7 +117,9 @@ func (s *Storage) Get(ctx context.Context, instance int64, domainID, appID strin
 		&r.InstanceID,
 		&r.DomainID,
 		&r.AppID,
-		&r.KeySet); err != nil {
+		&r.KeySet); err == sql.ErrNoRows {
+		return nil, status.Errorf(codes.NotFound, "keyset %v/%v/%v not found", instance, domainID, appID)
+	} else if err != nil {
 		return nil, err
 	}
 	return r.Proto()
@@ -112,7 +133,7 @@ func (s *Storage) Set(ctx context.Context, instance int64, domainID, appID strin
 	}
 
 	// Prepare SQL.
-	writeStmt, err := s.db.PrepareContext(ctx, setSQL)
+	writeStmt, err := s.db.PrepareContext(ctx, s.dialect.Query(setSQL))
 	if err != nil {
 		return err
 	}
@@ -19,77 +19,39 @@ import (
 	"database/sql"
 	"testing"
 
-	"github.com/golang/protobuf/proto"
-	"github.com/google/go-cmp/cmp"
+	"github.com/google/keytransparency/core/storage"
+	"github.com/google/keytransparency/core/storage/storagetest"
+	"github.com/google/keytransparency/impl/sql/pgtest"
 
-	tpb "github.com/google/keytransparency/core/api/type/type_go_proto"
 	_ "github.com/mattn/go-sqlite3"
 )
 
-func TestWriteRead(t *testing.T) {
-	ctx := context.Background()
+func newKeySets(ctx context.Context, t *testing.T) (storage.KeySets, func()) {
 	db, err := sql.Open("sqlite3", ":memory:")
 	if err != nil {
 		t.Fatalf("sql.Open(): %v", err)
 	}
-	defer db.Close()
+	// Each connection to :memory: opens a different database.
+	db.SetMaxOpenConns(1)
 	keysets, err := New(db)
 	if err != nil {
-		t.Fatalf("Failed to create keysets.Storage")
+		t.Fatalf("Failed to create keysets.Storage: %v", err)
 	}
+	return keysets, func() { db.Close() }
+}
 
-	ks := &tpb.KeySet{VerifyingKeys: map[string]*tpb.VerifyingKey{
-		"1": {KeyMaterial: []byte("keydata")},
-	}}
-	for _, tc := range []struct {
-		desc         string
-		instanceID   int64
-		domainID     string
-		appID        string
-		write        bool
-		wantWriteErr bool
-		read         bool
-		wantReadErr  bool
-	}{
-		{
-			desc:       "write,read",
-			instanceID: 0,
-			domainID:   "domain",
-			appID:      "app",
-			write:      true,
-			read:       true,
-		},
-		{
-			desc:         "double write",
-			instanceID:   0,
-			domainID:     "domain",
-			appID:        "app",
-			write:        true,
-			wantWriteErr: true,
-		},
-		{
-			desc:        "notfound",
-			instanceID:  1,
-			domainID:    "domain",
-			appID:       "app",
-			read:        true,
-			wantReadErr: true,
-		},
-	} {
-		if tc.write {
-			err := keysets.Set(ctx, tc.instanceID, tc.domainID, tc.appID, ks)
-			if got, want := err != nil, tc.wantWriteErr; got != want {
-				t.Errorf("Set(%v): %v, wantErr %v", tc.instanceID, err, want)
-			}
-		}
-		if tc.read {
-			ks, err := keysets.Get(ctx, tc.instanceID, tc.domainID, tc.appID)
-			if got, want := err != nil, tc.wantReadErr; got != want {
-				t.Errorf("Read(%v): %v, wantErr %v", tc.instanceID, err, want)
-			}
-			if got, want := ks, ks; !cmp.Equal(got, want, cmp.Comparer(proto.Equal)) {
-				t.Errorf("Read(%v): %v, want %v", tc.instanceID, got, want)
-			}
+func TestKeySets(t *testing.T) {
+	storagetest.KeySetsTester{NewKeySets: newKeySets}.RunAllTests(t)
+}
+
+func TestKeySetsPostgres(t *testing.T) {
+	pgtest.SkipIfNoPostgres(t)
+	storagetest.KeySetsTester{NewKeySets: func(ctx context.Context, t *testing.T) (storage.KeySets, func()) {
+		db, done := pgtest.NewDB(t)
+		keysets, err := New(db)
+		if err != nil {
+			t.Fatalf("Failed to create keysets.Storage: %v", err)
 		}
-	}
+		return keysets, done
+	}}.RunAllTests(t)
 }
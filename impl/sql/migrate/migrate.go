@@ -0,0 +1,212 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package migrate applies versioned schema migrations to SQL databases.
+//
+// The tables of each component, such as the mutation storage, evolve through
+// an ordered list of migrations. The version of each component's tables is
+// recorded in the SchemaVersions table, and each migration is applied in a
+// transaction together with the new version. MySQL commits DDL statements
+// implicitly, so on MySQL a failed migration may be partially applied.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/google/keytransparency/impl/sql/dialect"
+
+	"github.com/golang/glog"
+)
+
+const (
+	createVersionsSQL = `
+CREATE TABLE IF NOT EXISTS SchemaVersions(
+  Component VARCHAR(40) NOT NULL,
+  Version   INTEGER NOT NULL,
+  PRIMARY KEY(Component)
+);`
+	readVersionSQL  = `SELECT Version FROM SchemaVersions WHERE Component = ?;`
+	writeVersionSQL = `REPLACE INTO SchemaVersions (Component, Version) VALUES (?, ?);`
+)
+
+// Migration is one step in the evolution of the tables of a component.
+// Statements are written for MySQL and adapted to other engines by the
+// dialect package.
+type Migration struct {
+	// Version is the schema version once Up has been applied. The first
+	// migration has version 1.
+	Version int
+	// Desc describes the migration.
+	Desc string
+	// Up upgrades the tables from Version-1 to Version.
+	Up []string
+	// Down reverts Up.
+	Down []string
+	// Applied, if set, is a query that succeeds only if the tables already
+	// include this migration. It is used to adopt tables that were created
+	// before their version was recorded.
+	Applied string
+}
+
+// Schema is the ordered list of migrations of a component's tables.
+type Schema struct {
+	// Component identifies the tables in SchemaVersions.
+	Component  string
+	Migrations []Migration
+}
+
+// Latest returns the version of the schema once every migration is applied.
+func (s Schema) Latest() int {
+	return len(s.Migrations)
+}
+
+// Up applies the migrations that have not been applied yet.
+func (s Schema) Up(ctx context.Context, db *sql.DB) error {
+	return s.MigrateTo(ctx, db, s.Latest())
+}
+
+// MigrateTo upgrades or downgrades the tables to version. Version 0 drops the
+// tables.
+func (s Schema) MigrateTo(ctx context.Context, db *sql.DB, version int) error {
+	if err := s.validate(); err != nil {
+		return err
+	}
+	if version < 0 || version > s.Latest() {
+		return fmt.Errorf("%v schema: version %d, want between 0 and %d", s.Component, version, s.Latest())
+	}
+	d := dialect.Of(db)
+	cur, err := s.current(ctx, db, d)
+	if err != nil {
+		return err
+	}
+	if cur > s.Latest() {
+		return fmt.Errorf("%v schema: database is at version %d, newer than the latest known version %d", s.Component, cur, s.Latest())
+	}
+	for ; cur < version; cur++ {
+		m := s.Migrations[cur]
+		if err := s.apply(ctx, db, d, m.Up, m.Version); err != nil {
+			return fmt.Errorf("%v schema: upgrade to version %d (%v): %v", s.Component, m.Version, m.Desc, err)
+		}
+		glog.Infof("Upgraded %v schema to version %d: %v", s.Component, m.Version, m.Desc)
+	}
+	for ; cur > version; cur-- {
+		m := s.Migrations[cur-1]
+		if err := s.apply(ctx, db, d, m.Down, m.Version-1); err != nil {
+			return fmt.Errorf("%v schema: downgrade to version %d (reverting %v): %v", s.Component, m.Version-1, m.Desc, err)
+		}
+		glog.Infof("Downgraded %v schema to version %d", s.Component, m.Version-1)
+	}
+	return nil
+}
+
+// Version returns the recorded version of component's tables, or 0 if none
+// is recorded.
+func Version(ctx context.Context, db *sql.DB, component string) (int, error) {
+	d := dialect.Of(db)
+	if _, err := db.ExecContext(ctx, d.Schema(createVersionsSQL)); err != nil {
+		return 0, fmt.Errorf("failed to create schema version table: %v", err)
+	}
+	var version int
+	err := db.QueryRowContext(ctx, d.Query(readVersionSQL), component).Scan(&version)
+	switch {
+	case err == sql.ErrNoRows:
+		return 0, nil
+	case err != nil:
+		return 0, err
+	}
+	return version, nil
+}
+
+func (s Schema) validate() error {
+	for i, m := range s.Migrations {
+		if m.Version != i+1 {
+			return fmt.Errorf("%v schema: migration %d has version %d, want %d", s.Component, i, m.Version, i+1)
+		}
+	}
+	return nil
+}
+
+// current returns the version of the tables. If no version is recorded, the
+// tables are adopted at the last of the leading migrations whose Applied
+// query succeeds.
+func (s Schema) current(ctx context.Context, db *sql.DB, d dialect.Dialect) (int, error) {
+	cur, err := Version(ctx, db, s.Component)
+	if err != nil || cur > 0 {
+		return cur, err
+	}
+	for _, m := range s.Migrations {
+		if m.Applied == "" || !succeeds(ctx, db, d.Query(m.Applied)) {
+			break
+		}
+		cur = m.Version
+	}
+	if cur == 0 {
+		return 0, nil
+	}
+	if _, err := db.ExecContext(ctx, d.Upsert(writeVersionSQL, "Component"), s.Component, cur); err != nil {
+		return 0, err
+	}
+	glog.Infof("Adopted existing %v tables at schema version %d", s.Component, cur)
+	return cur, nil
+}
+
+// apply runs stmts and records version in a single transaction.
+func (s Schema) apply(ctx context.Context, db *sql.DB, d dialect.Dialect, stmts []string, version int) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, d.Schema(stmt)); err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				glog.Errorf("Rollback(): %v", rbErr)
+			}
+			return fmt.Errorf("%q: %v", strings.TrimSpace(stmt), err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, d.Upsert(writeVersionSQL, "Component"), s.Component, version); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			glog.Errorf("Rollback(): %v", rbErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// succeeds returns whether query runs without error.
+func succeeds(ctx context.Context, db *sql.DB, query string) bool {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return false
+	}
+	rows.Close()
+	return true
+}
+
+// Rebuild returns the statements that recreate table with the definition
+// create, keeping the values of columns. It is used to drop or change
+// columns, which not every engine can do with ALTER TABLE.
+func Rebuild(table, create string, columns ...string) []string {
+	old := table + "Old"
+	cols := strings.Join(columns, ", ")
+	return []string{
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", table, old),
+		create,
+		fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s;", table, cols, cols, old), // nolint: gas
+		fmt.Sprintf("DROP TABLE %s;", old),
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/google/keytransparency/impl/sql/pgtest"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const createV1 = `CREATE TABLE IF NOT EXISTS Items (
+	ID   BIGINT NOT NULL,
+	Name VARCHAR(30) NOT NULL,
+	PRIMARY KEY(ID)
+);`
+
+var testSchema = Schema{
+	Component: "items",
+	Migrations: []Migration{
+		{
+			Version: 1,
+			Desc:    "create Items",
+			Up:      []string{createV1},
+			Down:    []string{`DROP TABLE Items;`},
+			Applied: `SELECT Name FROM Items WHERE 1 = 0;`,
+		},
+		{
+			Version: 2,
+			Desc:    "add Data",
+			Up:      []string{`ALTER TABLE Items ADD COLUMN Data BLOB;`},
+			Down:    Rebuild("Items", createV1, "ID", "Name"),
+			Applied: `SELECT Data FROM Items WHERE 1 = 0;`,
+		},
+	},
+}
+
+func newDB(t *testing.T) (*sql.DB, func()) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("sql.Open(): %v", err)
+	}
+	// Each connection to :memory: opens a different database.
+	db.SetMaxOpenConns(1)
+	return db, func() { db.Close() }
+}
+
+func checkVersion(ctx context.Context, t *testing.T, db *sql.DB, want int) {
+	t.Helper()
+	got, err := Version(ctx, db, testSchema.Component)
+	if err != nil {
+		t.Fatalf("Version(): %v", err)
+	}
+	if got != want {
+		t.Errorf("Version(): %v, want %v", got, want)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	db, done := newDB(t)
+	defer done()
+	testMigrate(t, db)
+}
+
+func TestMigratePostgres(t *testing.T) {
+	pgtest.SkipIfNoPostgres(t)
+	db, done := pgtest.NewDB(t)
+	defer done()
+	testMigrate(t, db)
+}
+
+func testMigrate(t *testing.T, db *sql.DB) {
+	ctx := context.Background()
+	checkVersion(ctx, t, db, 0)
+	if err := testSchema.Up(ctx, db); err != nil {
+		t.Fatalf("Up(): %v", err)
+	}
+	checkVersion(ctx, t, db, 2)
+	if _, err := db.Exec(`INSERT INTO Items (ID, Name, Data) VALUES (1, 'one', NULL);`); err != nil {
+		t.Fatalf("INSERT: %v", err)
+	}
+
+	for _, step := range []struct {
+		version  int
+		wantData bool
+		wantName bool
+	}{
+		{version: 1, wantName: true},
+		{version: 2, wantName: true, wantData: true},
+		{version: 2, wantName: true, wantData: true},
+		{version: 0},
+		{version: 2, wantData: true},
+	} {
+		if err := testSchema.MigrateTo(ctx, db, step.version); err != nil {
+			t.Fatalf("MigrateTo(%v): %v", step.version, err)
+		}
+		checkVersion(ctx, t, db, step.version)
+		if got := succeeds(ctx, db, `SELECT Data FROM Items;`); got != step.wantData {
+			t.Errorf("MigrateTo(%v): Data column exists: %v, want %v", step.version, got, step.wantData)
+		}
+		var name string
+		err := db.QueryRow(`SELECT Name FROM Items WHERE ID = 1;`).Scan(&name)
+		if got := err == nil; got != step.wantName {
+			t.Errorf("MigrateTo(%v): row kept: %v (%v), want %v", step.version, got, err, step.wantName)
+		}
+	}
+}
+
+func TestMigrateErrors(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		desc   string
+		schema Schema
+		// version is recorded before migrating.
+		version     int
+		target      int
+		wantVersion int
+	}{
+		{desc: "target too high", schema: testSchema, target: 3},
+		{desc: "target negative", schema: testSchema, target: -1},
+		{desc: "newer database", schema: testSchema, version: 3, target: 2, wantVersion: 3},
+		{desc: "bad versions", schema: Schema{
+			Component:  testSchema.Component,
+			Migrations: []Migration{testSchema.Migrations[1]},
+		}, target: 1},
+		{desc: "failing statement", schema: Schema{
+			Component: testSchema.Component,
+			Migrations: []Migration{
+				testSchema.Migrations[0],
+				{Version: 2, Up: []string{`CREATE TABLE Other (ID BIGINT);`, `NOT SQL;`}},
+			},
+		}, target: 2, wantVersion: 1},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			db, done := newDB(t)
+			defer done()
+			if tc.version > 0 {
+				if _, err := Version(ctx, db, tc.schema.Component); err != nil {
+					t.Fatalf("Version(): %v", err)
+				}
+				if _, err := db.Exec(`INSERT INTO SchemaVersions (Component, Version) VALUES (?, ?);`,
+					tc.schema.Component, tc.version); err != nil {
+					t.Fatalf("INSERT: %v", err)
+				}
+			}
+			if err := tc.schema.MigrateTo(ctx, db, tc.target); err == nil {
+				t.Errorf("MigrateTo(%v): nil, want error", tc.target)
+			}
+			checkVersion(ctx, t, db, tc.wantVersion)
+			if succeeds(ctx, db, `SELECT ID FROM Other;`) {
+				t.Errorf("Statements of the failed migration were applied")
+			}
+		})
+	}
+}
+
+func TestAdopt(t *testing.T) {
+	ctx := context.Background()
+	// Up fails if tables are not adopted at their actual version, because
+	// the Data column cannot be added twice.
+	for _, tc := range []struct {
+		desc   string
+		create []string
+	}{
+		{desc: "empty database"},
+		{desc: "version 1 tables", create: []string{createV1}},
+		{desc: "version 2 tables", create: []string{createV1, `ALTER TABLE Items ADD COLUMN Data BLOB;`}},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			db, done := newDB(t)
+			defer done()
+			for _, stmt := range tc.create {
+				if _, err := db.Exec(stmt); err != nil {
+					t.Fatalf("Exec(%v): %v", stmt, err)
+				}
+			}
+			if err := testSchema.Up(ctx, db); err != nil {
+				t.Fatalf("Up(): %v", err)
+			}
+			checkVersion(ctx, t, db, testSchema.Latest())
+		})
+	}
+}
@@ -21,85 +21,244 @@ import (
 	"database/sql"
 	"fmt"
 
+	"github.com/google/keytransparency/core/mutator"
+	"github.com/google/keytransparency/impl/sql/dialect"
+	"github.com/google/keytransparency/impl/sql/migrate"
+
+	"github.com/golang/glog"
 	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
 )
 
 const (
+	deleteMutationsExpr = `
+	DELETE FROM Mutations
+	WHERE DomainID = ? AND Revision = ?;`
+	deleteRevisionsExpr = `
+	DELETE FROM Mutations
+	WHERE DomainID = ? AND Revision >= ? AND Revision <= ?;`
 	insertMutationsExpr = `
-	INSERT INTO Mutations (DomainID, Revision, Sequence, Mutation)
-	VALUES (?, ?, ?, ?);`
+	INSERT INTO Mutations (DomainID, Revision, Sequence, Mutation, Committed)
+	VALUES (?, ?, ?, ?, ?);`
+	readBatchExpr = `
+	SELECT Mutation, Committed FROM Mutations
+	WHERE DomainID = ? AND Revision = ?
+	ORDER BY Sequence ASC;`
+	advanceSequencedExpr = `
+	UPDATE SequencedRevisions SET Revision = ?
+	WHERE DomainID = ? AND (Revision = ? OR Revision = 0);`
+	insertSequencedExpr = `
+	INSERT INTO SequencedRevisions (DomainID, Revision)
+	VALUES (?, ?);`
+	readSequencedExpr = `
+	SELECT Revision FROM SequencedRevisions
+	WHERE DomainID = ?;`
 	readMutationsExpr = `
   	SELECT Sequence, Mutation FROM Mutations
   	WHERE DomainID = ? AND Revision = ? AND Sequence >= ?
   	ORDER BY Sequence ASC LIMIT ?;`
-	insertQueueExpr = `
-	INSERT INTO Queue (DomainID, Time, Mutation)
+	insertIndexChangeExpr = `
+	REPLACE INTO IndexChanges (DomainID, MapIndex, Revision)
 	VALUES (?, ?, ?);`
+	readIndexChangesExpr = `
+	SELECT Revision FROM IndexChanges
+	WHERE DomainID = ? AND MapIndex = ? AND Revision >= ? AND Revision <= ?
+	ORDER BY Revision ASC LIMIT ?;`
+	writeStatusExpr = `
+	REPLACE INTO MutationStatus (DomainID, MutationHash, State, Revision, Reason)
+	VALUES (?, ?, ?, ?, ?);`
+	readStatusExpr = `
+	SELECT State, Revision, Reason FROM MutationStatus
+	WHERE DomainID = ? AND MutationHash = ?;`
+	insertRejectedExpr = `
+	REPLACE INTO RejectedMutations (DomainID, Revision, Sequence, MapIndex, MutationHash, Reason)
+	VALUES (?, ?, ?, ?, ?, ?);`
+	readRejectedExpr = `
+	SELECT Sequence, MapIndex, MutationHash, Reason FROM RejectedMutations
+	WHERE DomainID = ? AND Revision = ? AND Sequence >= ?
+	ORDER BY Sequence ASC LIMIT ?;`
+	incQueueIDExpr = `
+	UPDATE QueueIDs SET NextID = NextID + 1
+	WHERE DomainID = ?;`
+	insertQueueIDExpr = `
+	INSERT INTO QueueIDs (DomainID, NextID)
+	VALUES (?, 1);`
+	readQueueIDExpr = `
+	SELECT NextID FROM QueueIDs
+	WHERE DomainID = ?;`
+	insertQueueExpr = `
+	INSERT INTO Queue (DomainID, ID, Time, Mutation, ClaimExpiry)
+	VALUES (?, ?, ?, ?, 0);`
 	readQueueExpr = `
- 	SELECT Time, Mutation FROM Queue
- 	WHERE DomainID = ?
-	ORDER BY Time ASC LIMIT ?;`
+	SELECT ID, Mutation, ClaimExpiry FROM Queue
+	WHERE DomainID = ?
+	ORDER BY ID ASC LIMIT ?;`
+	claimQueueExpr = `
+	UPDATE Queue SET ClaimExpiry = ?
+	WHERE DomainID = ? AND ID >= ? AND ID <= ? AND ClaimExpiry <= ?;`
+	releaseQueueExpr = `
+	UPDATE Queue SET ClaimExpiry = 0
+	WHERE DomainID = ? AND ID >= ? AND ID <= ? AND ClaimExpiry = ?;`
+	releaseClaimsExpr = `
+	UPDATE Queue SET ClaimExpiry = 0
+	WHERE DomainID = ? AND ClaimExpiry <> 0;`
 	deleteQueueExpr = `
 	DELETE FROM Queue
-	WHERE DomainID = ? AND Time = ?;`
+	WHERE DomainID = ? AND ID >= ? AND ID <= ?;`
+	queueStatsExpr = `
+	SELECT COUNT(*), MIN(Time) FROM Queue
+	WHERE DomainID = ?;`
 )
 
-var (
-	createStmt = []string{
-		`CREATE TABLE IF NOT EXISTS Mutations (
+const (
+	createMutationsV1 = `CREATE TABLE IF NOT EXISTS Mutations (
 		DomainID VARCHAR(30)   NOT NULL,
 		Revision BIGINT        NOT NULL,
 		Sequence INTEGER       NOT NULL,
 		Mutation BLOB          NOT NULL,
 		PRIMARY KEY(DomainID, Revision, Sequence)
-	);`,
-		`CREATE TABLE IF NOT EXISTS Queue (
+	);`
+	createQueueV1 = `CREATE TABLE IF NOT EXISTS Queue (
 		DomainID VARCHAR(30)   NOT NULL,
 		Time     BIGINT        NOT NULL,
 		Mutation BLOB          NOT NULL,
 		PRIMARY KEY(DomainID, Time)
-	);`,
-	}
+	);`
 )
 
+// Schema is the versioned schema of the mutation and queue tables.
+var Schema = migrate.Schema{
+	Component: "mutations",
+	Migrations: []migrate.Migration{
+		{
+			Version: 1,
+			Desc:    "create Mutations and Queue",
+			Up:      []string{createMutationsV1, createQueueV1},
+			Down:    []string{`DROP TABLE Queue;`, `DROP TABLE Mutations;`},
+			Applied: `SELECT 1 FROM Mutations, Queue WHERE 1 = 0;`,
+		},
+		{
+			Version: 2,
+			Desc:    "create IndexChanges",
+			Up: []string{`CREATE TABLE IF NOT EXISTS IndexChanges (
+		DomainID VARCHAR(30)   NOT NULL,
+		MapIndex VARBINARY(32) NOT NULL,
+		Revision BIGINT        NOT NULL,
+		PRIMARY KEY(DomainID, MapIndex, Revision)
+	);`},
+			Down:    []string{`DROP TABLE IndexChanges;`},
+			Applied: `SELECT 1 FROM IndexChanges WHERE 1 = 0;`,
+		},
+		{
+			Version: 3,
+			Desc:    "create MutationStatus",
+			Up: []string{`CREATE TABLE IF NOT EXISTS MutationStatus (
+		DomainID     VARCHAR(30)   NOT NULL,
+		MutationHash VARBINARY(32) NOT NULL,
+		State        INTEGER       NOT NULL,
+		Revision     BIGINT        NOT NULL,
+		Reason       TEXT          NOT NULL,
+		PRIMARY KEY(DomainID, MutationHash)
+	);`},
+			Down:    []string{`DROP TABLE MutationStatus;`},
+			Applied: `SELECT 1 FROM MutationStatus WHERE 1 = 0;`,
+		},
+		{
+			Version: 4,
+			Desc:    "create RejectedMutations",
+			Up: []string{`CREATE TABLE IF NOT EXISTS RejectedMutations (
+		DomainID     VARCHAR(30)   NOT NULL,
+		Revision     BIGINT        NOT NULL,
+		Sequence     INTEGER       NOT NULL,
+		MapIndex     VARBINARY(32) NOT NULL,
+		MutationHash VARBINARY(32) NOT NULL,
+		Reason       TEXT          NOT NULL,
+		PRIMARY KEY(DomainID, Revision, Sequence)
+	);`},
+			Down:    []string{`DROP TABLE RejectedMutations;`},
+			Applied: `SELECT 1 FROM RejectedMutations WHERE 1 = 0;`,
+		},
+		{
+			Version: 5,
+			Desc:    "identify queued mutations by ID and add claims",
+			// Queued mutations keep their order: the send time of each
+			// mutation, which was unique within its domain, becomes its ID.
+			Up: []string{
+				`CREATE TABLE IF NOT EXISTS QueueIDs (
+		DomainID VARCHAR(30)   NOT NULL,
+		NextID   BIGINT        NOT NULL,
+		PRIMARY KEY(DomainID)
+	);`,
+				`ALTER TABLE Queue RENAME TO QueueOld;`,
+				`CREATE TABLE Queue (
+		DomainID    VARCHAR(30)   NOT NULL,
+		ID          BIGINT        NOT NULL,
+		Time        BIGINT        NOT NULL,
+		Mutation    BLOB          NOT NULL,
+		ClaimExpiry BIGINT        NOT NULL,
+		PRIMARY KEY(DomainID, ID)
+	);`,
+				`INSERT INTO Queue (DomainID, ID, Time, Mutation, ClaimExpiry)
+		SELECT DomainID, Time, Time, Mutation, 0 FROM QueueOld;`,
+				`INSERT INTO QueueIDs (DomainID, NextID)
+		SELECT DomainID, MAX(ID) FROM Queue GROUP BY DomainID;`,
+				`DROP TABLE QueueOld;`,
+			},
+			Down: append(migrate.Rebuild("Queue", createQueueV1, "DomainID", "Time", "Mutation"),
+				`DROP TABLE QueueIDs;`),
+			Applied: `SELECT ID, ClaimExpiry FROM Queue, QueueIDs WHERE 1 = 0;`,
+		},
+		{
+			Version: 6,
+			Desc:    "add committed data and sequenced revisions",
+			Up: []string{
+				`ALTER TABLE Mutations ADD COLUMN Committed BLOB;`,
+				`CREATE TABLE IF NOT EXISTS SequencedRevisions (
+		DomainID VARCHAR(30)   NOT NULL,
+		Revision BIGINT        NOT NULL,
+		PRIMARY KEY(DomainID)
+	);`,
+			},
+			Down: append([]string{`DROP TABLE SequencedRevisions;`},
+				migrate.Rebuild("Mutations", createMutationsV1, "DomainID", "Revision", "Sequence", "Mutation")...),
+			Applied: `SELECT Committed FROM Mutations, SequencedRevisions WHERE 1 = 0;`,
+		},
+	},
+}
+
 // Mutations implements mutator.MutationStorage and mutator.MutationQueue.
 type Mutations struct {
-	db *sql.DB
+	db      *sql.DB
+	dialect dialect.Dialect
+	// afterStep, if set, is called after each step of SequenceBatch.
+	// Returning an error aborts the transaction. It is used by tests.
+	afterStep func(step string) error
 }
 
 // New creates a new Mutations instance.
 func New(db *sql.DB) (*Mutations, error) {
 	m := &Mutations{
-		db: db,
+		db:      db,
+		dialect: dialect.Of(db),
 	}
 
-	// Create tables.
-	if err := m.createTables(); err != nil {
-		return nil, err
+	// Create or upgrade tables.
+	if err := Schema.Up(context.Background(), db); err != nil {
+		return nil, fmt.Errorf("Failed to migrate mutation tables: %v", err)
 	}
 	return m, nil
 }
 
-// createTables creates new database tables.
-func (m *Mutations) createTables() error {
-	for _, stmt := range createStmt {
-		_, err := m.db.Exec(stmt)
-		if err != nil {
-			return fmt.Errorf("Failed to create mutation tables: %v", err)
-		}
-	}
-	return nil
-}
-
 // ReadPage reads all mutations for a specific given domainID and sequence range.
 // The range is identified by a starting sequence number and a count. Note that
 // startSequence is not included in the result. ReadRange stops when endSequence
 // or count is reached, whichever comes first. ReadRange also returns the maximum
 // sequence number read.
 func (m *Mutations) ReadPage(ctx context.Context, domainID string, revision, start int64, pageSize int32) (int64, []*pb.Entry, error) {
-	readStmt, err := m.db.Prepare(readMutationsExpr)
+	readStmt, err := m.db.Prepare(m.dialect.Query(readMutationsExpr))
 	if err != nil {
 		return 0, nil, err
 	}
@@ -112,25 +271,305 @@ func (m *Mutations) ReadPage(ctx context.Context, domainID string, revision, sta
 	return readMutations(rows)
 }
 
-// WriteBatch saves the mutations in the database.
+// WriteBatch saves the mutations in the database, replacing any mutations
+// previously saved for revision.
 func (m *Mutations) WriteBatch(ctx context.Context, domainID string, revision int64, mutations []*pb.Entry) error {
-	writeStmt, err := m.db.Prepare(insertMutationsExpr)
+	msgs := make([]*mutator.QueueMessage, 0, len(mutations))
+	for _, e := range mutations {
+		msgs = append(msgs, &mutator.QueueMessage{Mutation: e})
+	}
+	return m.inTx(ctx, func(tx *sql.Tx) error {
+		return m.writeBatch(ctx, tx, domainID, revision, msgs)
+	})
+}
+
+// SequenceBatch saves msgs under domainID/revision, deletes msgs from the
+// queue, and records revision as the highest sequenced revision of domainID,
+// in a single transaction. The transaction fails with mutator.ErrStaleBatch
+// unless revision follows the highest sequenced revision and msgs are all
+// still queued, so that a receiver whose claim expired or that lost mastership
+// can't sequence mutations again or replace the batches of its successor.
+func (m *Mutations) SequenceBatch(ctx context.Context, domainID string, revision int64, msgs []*mutator.QueueMessage) error {
+	return m.inTx(ctx, func(tx *sql.Tx) error {
+		if err := m.writeBatch(ctx, tx, domainID, revision, msgs); err != nil {
+			return err
+		}
+		if err := m.step("mutations"); err != nil {
+			return err
+		}
+		if len(msgs) > 0 {
+			// msgs is a batch returned by claimQueue.
+			result, err := tx.ExecContext(ctx, m.dialect.Query(deleteQueueExpr),
+				domainID, msgs[0].ID, msgs[len(msgs)-1].ID)
+			if err != nil {
+				return err
+			}
+			deleted, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if deleted != int64(len(msgs)) {
+				// Some messages were sequenced by another receiver.
+				return mutator.ErrStaleBatch
+			}
+		}
+		if err := m.step("queue"); err != nil {
+			return err
+		}
+		if err := m.advanceSequenced(ctx, tx, domainID, revision); err != nil {
+			return err
+		}
+		return m.step("marker")
+	})
+}
+
+// advanceSequenced records revision as the highest sequenced revision of
+// domainID, if it follows the one recorded. Updating the marker first locks it
+// until the transaction ends.
+func (m *Mutations) advanceSequenced(ctx context.Context, tx *sql.Tx, domainID string, revision int64) error {
+	result, err := tx.ExecContext(ctx, m.dialect.Query(advanceSequencedExpr), revision, domainID, revision-1)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows > 0 {
+		return nil
+	}
+	// No revision is recorded, or the recorded one does not precede
+	// revision.
+	var last int64
+	err = tx.QueryRowContext(ctx, m.dialect.Query(readSequencedExpr), domainID).Scan(&last)
+	switch {
+	case err == sql.ErrNoRows:
+		_, err := tx.ExecContext(ctx, m.dialect.Query(insertSequencedExpr), domainID, revision)
+		return err
+	case err != nil:
+		return err
+	case last == 0 && revision == 0:
+		// Some engines do not count rows the update left unchanged.
+		return nil
+	}
+	return mutator.ErrStaleBatch
+}
+
+// ReadBatch returns the messages saved for domainID/revision.
+func (m *Mutations) ReadBatch(ctx context.Context, domainID string, revision int64) ([]*mutator.QueueMessage, error) {
+	rows, err := m.db.QueryContext(ctx, m.dialect.Query(readBatchExpr), domainID, revision)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	msgs := make([]*mutator.QueueMessage, 0)
+	for rows.Next() {
+		var mData, cData []byte
+		if err := rows.Scan(&mData, &cData); err != nil {
+			return nil, err
+		}
+		msg := &mutator.QueueMessage{Mutation: new(pb.Entry)}
+		if err := proto.Unmarshal(mData, msg.Mutation); err != nil {
+			return nil, err
+		}
+		if cData != nil {
+			msg.ExtraData = new(pb.Committed)
+			if err := proto.Unmarshal(cData, msg.ExtraData); err != nil {
+				return nil, err
+			}
+		}
+		msgs = append(msgs, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+// HighestSequencedRevision returns the highest revision recorded by
+// SequenceBatch for domainID, or 0 if there is none.
+func (m *Mutations) HighestSequencedRevision(ctx context.Context, domainID string) (int64, error) {
+	var rev int64
+	err := m.db.QueryRowContext(ctx, m.dialect.Query(readSequencedExpr), domainID).Scan(&rev)
+	switch {
+	case err == sql.ErrNoRows:
+		return 0, nil
+	case err != nil:
+		return 0, err
+	}
+	return rev, nil
+}
+
+// DeleteRevisions deletes the mutations of revisions [start, end] of
+// domainID, once they have been archived.
+func (m *Mutations) DeleteRevisions(ctx context.Context, domainID string, start, end int64) error {
+	_, err := m.db.ExecContext(ctx, m.dialect.Query(deleteRevisionsExpr), domainID, start, end)
+	return err
+}
+
+// inTx runs f in a transaction, which is committed if f succeeds.
+func (m *Mutations) inTx(ctx context.Context, f func(tx *sql.Tx) error) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := f(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			glog.Errorf("Rollback(): %v", rbErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *Mutations) step(name string) error {
+	if m.afterStep == nil {
+		return nil
+	}
+	return m.afterStep(name)
+}
+
+// writeBatch replaces the mutations saved under domainID/revision with msgs.
+func (m *Mutations) writeBatch(ctx context.Context, tx *sql.Tx, domainID string, revision int64, msgs []*mutator.QueueMessage) error {
+	if _, err := tx.ExecContext(ctx, m.dialect.Query(deleteMutationsExpr), domainID, revision); err != nil {
+		return err
+	}
+	writeStmt, err := tx.PrepareContext(ctx, m.dialect.Query(insertMutationsExpr))
 	if err != nil {
 		return err
 	}
 	defer writeStmt.Close()
-	for i, m := range mutations {
-		mData, err := proto.Marshal(m)
+	for i, msg := range msgs {
+		mData, err := proto.Marshal(msg.Mutation)
 		if err != nil {
 			return err
 		}
-		if _, err := writeStmt.ExecContext(ctx, domainID, revision, i, mData); err != nil {
+		var cData []byte
+		if msg.ExtraData != nil {
+			if cData, err = proto.Marshal(msg.ExtraData); err != nil {
+				return err
+			}
+		}
+		if _, err := writeStmt.ExecContext(ctx, domainID, revision, i, mData, cData); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// WriteIndexChanges records the map indexes that changed in revision.
+func (m *Mutations) WriteIndexChanges(ctx context.Context, domainID string, revision int64, indexes [][]byte) error {
+	writeStmt, err := m.db.Prepare(m.dialect.Upsert(insertIndexChangeExpr, "DomainID", "MapIndex", "Revision"))
+	if err != nil {
+		return err
+	}
+	defer writeStmt.Close()
+	for _, index := range indexes {
+		if _, err := writeStmt.ExecContext(ctx, domainID, index, revision); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListIndexChanges returns the revisions in [start, end] in which the map leaf
+// at index changed, in ascending order. At most limit revisions are returned.
+func (m *Mutations) ListIndexChanges(ctx context.Context, domainID string, index []byte, start, end int64, limit int32) ([]int64, error) {
+	readStmt, err := m.db.Prepare(m.dialect.Query(readIndexChangesExpr))
+	if err != nil {
+		return nil, err
+	}
+	defer readStmt.Close()
+	rows, err := readStmt.QueryContext(ctx, domainID, index, start, end, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	revisions := make([]int64, 0)
+	for rows.Next() {
+		var revision int64
+		if err := rows.Scan(&revision); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// WriteStatus records the processing state of the mutation identified by hash.
+func (m *Mutations) WriteStatus(ctx context.Context, domainID string, hash []byte, s *pb.MutationStatus) error {
+	writeStmt, err := m.db.Prepare(m.dialect.Upsert(writeStatusExpr, "DomainID", "MutationHash"))
+	if err != nil {
+		return err
+	}
+	defer writeStmt.Close()
+	_, err = writeStmt.ExecContext(ctx, domainID, hash, s.GetState(), s.GetEpoch(), s.GetError())
+	return err
+}
+
+// ReadStatus returns the processing state of the mutation identified by hash.
+func (m *Mutations) ReadStatus(ctx context.Context, domainID string, hash []byte) (*pb.MutationStatus, error) {
+	readStmt, err := m.db.Prepare(m.dialect.Query(readStatusExpr))
+	if err != nil {
+		return nil, err
+	}
+	defer readStmt.Close()
+	s := &pb.MutationStatus{}
+	if err := readStmt.QueryRowContext(ctx, domainID, hash).Scan(
+		&s.State, &s.Epoch, &s.Error); err == sql.ErrNoRows {
+		return nil, status.Errorf(codes.NotFound, "mutation %x not found", hash)
+	} else if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// WriteRejected records the mutations that were rejected in revision.
+func (m *Mutations) WriteRejected(ctx context.Context, domainID string, revision int64, rejected []*pb.RejectedMutation) error {
+	writeStmt, err := m.db.Prepare(m.dialect.Upsert(insertRejectedExpr, "DomainID", "Revision", "Sequence"))
+	if err != nil {
+		return err
+	}
+	defer writeStmt.Close()
+	for _, r := range rejected {
+		if _, err := writeStmt.ExecContext(ctx, domainID, revision, r.GetSequence(),
+			r.GetIndex(), r.GetMutationHash(), r.GetReason()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadRejectedPage reads the mutations rejected in revision, starting at
+// sequence number start. At most pageSize mutations are returned.
+func (m *Mutations) ReadRejectedPage(ctx context.Context, domainID string, revision, start int64, pageSize int32) ([]*pb.RejectedMutation, error) {
+	readStmt, err := m.db.Prepare(m.dialect.Query(readRejectedExpr))
+	if err != nil {
+		return nil, err
+	}
+	defer readStmt.Close()
+	rows, err := readStmt.QueryContext(ctx, domainID, revision, start, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	results := make([]*pb.RejectedMutation, 0)
+	for rows.Next() {
+		r := &pb.RejectedMutation{Epoch: revision}
+		if err := rows.Scan(&r.Sequence, &r.Index, &r.MutationHash, &r.Reason); err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
 func readMutations(rows *sql.Rows) (int64, []*pb.Entry, error) {
 	results := make([]*pb.Entry, 0)
 	maxSequence := int64(0)
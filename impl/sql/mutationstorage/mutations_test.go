@@ -17,11 +17,20 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/google/keytransparency/core/mutator"
+	"github.com/google/keytransparency/core/storage/storagetest"
+	"github.com/google/keytransparency/impl/sql/dialect"
+	"github.com/google/keytransparency/impl/sql/migrate"
+	"github.com/google/keytransparency/impl/sql/pgtest"
 
 	"github.com/golang/protobuf/proto"
+	"github.com/google/go-cmp/cmp"
 
 	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
 	_ "github.com/mattn/go-sqlite3"
@@ -44,116 +53,362 @@ func genMutation(i int) *pb.Entry {
 	}
 }
 
-func fillDB(ctx context.Context, m mutator.MutationStorage) error {
-	for _, tc := range []struct {
-		revision  int64
-		mutations []*pb.Entry
-	}{
-		{
-			revision: 0,
-			mutations: []*pb.Entry{
-				genMutation(1),
-				genMutation(2),
-			},
-		},
-		{
-			revision: 1,
-			mutations: []*pb.Entry{
-				genMutation(3),
-				genMutation(4),
-				genMutation(5),
-			},
-		},
-	} {
-		if err := m.WriteBatch(ctx, domainID, tc.revision, tc.mutations); err != nil {
-			return err
-		}
+func newMutations(ctx context.Context, t *testing.T) (mutator.MutationStorage, func()) {
+	db := newDB(t)
+	// Each connection to :memory: opens a different database.
+	db.SetMaxOpenConns(1)
+	m, err := New(db)
+	if err != nil {
+		t.Fatalf("Failed to create mutations: %v", err)
 	}
-	return nil
+	return m, func() { db.Close() }
 }
 
-func TestReadPage(t *testing.T) {
-	ctx := context.Background()
-	db := newDB(t)
+func newPostgresMutations(ctx context.Context, t *testing.T) (mutator.MutationStorage, func()) {
+	db, done := pgtest.NewDB(t)
 	m, err := New(db)
 	if err != nil {
 		t.Fatalf("Failed to create mutations: %v", err)
 	}
-	if err := fillDB(ctx, m); err != nil {
-		t.Fatalf("Failed to write mutations: %v", err)
+	return m, done
+}
+
+func TestMutationStorage(t *testing.T) {
+	storagetest.MutationStorageTester{NewStorage: newMutations}.RunAllTests(t)
+}
+
+func TestMutationStoragePostgres(t *testing.T) {
+	pgtest.SkipIfNoPostgres(t)
+	storagetest.MutationStorageTester{NewStorage: newPostgresMutations}.RunAllTests(t)
+}
+
+// TestSequenceBatchKill stops SequenceBatch at each step, as if the process
+// were killed, and checks that a restarted process finds either none or all
+// of its effects.
+func TestSequenceBatchKill(t *testing.T) {
+	ctx := context.Background()
+	errKilled := fmt.Errorf("killed")
+	now := time.Now()
+	timeout := time.Minute
+
+	for _, tc := range []struct {
+		killAfter string
+		wantDone  bool
+	}{
+		{killAfter: "mutations"},
+		{killAfter: "queue"},
+		{killAfter: "marker"},
+		{killAfter: "commit", wantDone: true}, // There is no such step.
+	} {
+		t.Run(tc.killAfter, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "mutations")
+			if err != nil {
+				t.Fatalf("TempDir(): %v", err)
+			}
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, "kt.db")
+
+			db, err := sql.Open("sqlite3", path)
+			if err != nil {
+				t.Fatalf("sql.Open(): %v", err)
+			}
+			m, err := New(db)
+			if err != nil {
+				t.Fatalf("Failed to create mutations: %v", err)
+			}
+			if err := fillQueue(ctx, m); err != nil {
+				t.Fatalf("Failed to write updates: %v", err)
+			}
+			first, err := m.claimQueue(ctx, domainID, 2, now, timeout)
+			if err != nil {
+				t.Fatalf("claimQueue(): %v", err)
+			}
+			if err := m.SequenceBatch(ctx, domainID, 1, first); err != nil {
+				t.Fatalf("SequenceBatch(1): %v", err)
+			}
+			batch, err := m.claimQueue(ctx, domainID, 10, now, timeout)
+			if err != nil {
+				t.Fatalf("claimQueue(): %v", err)
+			}
+			m.afterStep = func(step string) error {
+				if step == tc.killAfter {
+					return errKilled
+				}
+				return nil
+			}
+			if err := m.SequenceBatch(ctx, domainID, 2, batch); (err != nil) == tc.wantDone {
+				t.Fatalf("SequenceBatch(2): %v", err)
+			}
+			db.Close() // Kill.
+
+			// Restart.
+			db, err = sql.Open("sqlite3", path)
+			if err != nil {
+				t.Fatalf("sql.Open(): %v", err)
+			}
+			defer db.Close()
+			m, err = New(db)
+			if err != nil {
+				t.Fatalf("Failed to create mutations: %v", err)
+			}
+			wantRev, wantBatch, wantQueue := int64(1), 0, len(batch)
+			if tc.wantDone {
+				wantRev, wantBatch, wantQueue = 2, len(batch), 0
+			}
+			rev, err := m.HighestSequencedRevision(ctx, domainID)
+			if err != nil {
+				t.Fatalf("HighestSequencedRevision(): %v", err)
+			}
+			if rev != wantRev {
+				t.Errorf("HighestSequencedRevision(): %v, want %v", rev, wantRev)
+			}
+			saved, err := m.ReadBatch(ctx, domainID, 2)
+			if err != nil {
+				t.Fatalf("ReadBatch(): %v", err)
+			}
+			if got := len(saved); got != wantBatch {
+				t.Errorf("ReadBatch(): %v messages, want %v", got, wantBatch)
+			}
+			for i, msg := range saved {
+				if !proto.Equal(msg.Mutation, batch[i].Mutation) || !proto.Equal(msg.ExtraData, batch[i].ExtraData) {
+					t.Errorf("ReadBatch()[%v]: %v, want %v", i, msg, batch[i])
+				}
+			}
+			// Unsequenced messages are redelivered once their claim expires.
+			queued, err := m.claimQueue(ctx, domainID, 10, now.Add(2*timeout), timeout)
+			if err != nil {
+				t.Fatalf("claimQueue(): %v", err)
+			}
+			if got := len(queued); got != wantQueue {
+				t.Errorf("claimQueue(): %v messages, want %v", got, wantQueue)
+			}
+		})
 	}
+}
+
+// TestSequenceBatchClaimExpired sequences a batch whose claim expired and
+// whose messages were claimed and sequenced by another receiver.
+func TestSequenceBatchClaimExpired(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	timeout := time.Minute
 
 	for _, tc := range []struct {
-		description string
-		revision    int64
-		start       int64
-		count       int32
-		wantMax     int64
-		mutations   []*pb.Entry
+		desc     string
+		revision int64 // Revision of the expired batch.
 	}{
-		{
-			description: "read a single mutation",
-			start:       0,
-			count:       1,
-			wantMax:     0,
-			mutations:   []*pb.Entry{genMutation(1)},
-		},
-		{
-			description: "empty mutations list",
-			revision:    100,
-			start:       0,
-			count:       10,
-		},
-		{
-			description: "full mutations range size",
-			revision:    0,
-			start:       0,
-			count:       5,
-			wantMax:     1,
-			mutations: []*pb.Entry{
-				genMutation(1),
-				genMutation(2),
-			},
-		},
-		{
-			description: "non-zero start",
-			revision:    1,
-			start:       1,
-			count:       2,
-			wantMax:     2,
-			mutations: []*pb.Entry{
-				genMutation(4),
-				genMutation(5),
-			},
-		},
-		{
-			description: "limit by count",
-			revision:    1,
-			start:       0,
-			count:       2,
-			wantMax:     1,
-			mutations: []*pb.Entry{
-				genMutation(3),
-				genMutation(4),
-			},
-		},
+		{desc: "same revision", revision: 1},
+		{desc: "next revision", revision: 2},
 	} {
-		t.Run(tc.description, func(t *testing.T) {
-			max, results, err := m.ReadPage(ctx, domainID, tc.revision, tc.start, tc.count)
+		t.Run(tc.desc, func(t *testing.T) {
+			s, done := newMutations(ctx, t)
+			defer done()
+			m := s.(*Mutations)
+			if err := fillQueue(ctx, m); err != nil {
+				t.Fatalf("Failed to write updates: %v", err)
+			}
+			expired, err := m.claimQueue(ctx, domainID, 5, now, timeout)
+			if err != nil {
+				t.Fatalf("claimQueue(): %v", err)
+			}
+			// Another receiver claims part of the batch once the claim
+			// expires, and sequences it first.
+			batch, err := m.claimQueue(ctx, domainID, 2, now.Add(2*timeout), timeout)
 			if err != nil {
-				t.Errorf("failed to read mutations: %v", err)
+				t.Fatalf("claimQueue(): %v", err)
+			}
+			if err := m.SequenceBatch(ctx, domainID, 1, batch); err != nil {
+				t.Fatalf("SequenceBatch(1): %v", err)
 			}
-			if got, want := max, tc.wantMax; got != want {
-				t.Errorf("ReadPage(%v,%v,%v).max:%v, want %v", tc.revision, tc.start, tc.count, got, want)
+
+			if err := m.SequenceBatch(ctx, domainID, tc.revision, expired); err != mutator.ErrStaleBatch {
+				t.Errorf("SequenceBatch(%v) of the expired batch: %v, want %v", tc.revision, err, mutator.ErrStaleBatch)
 			}
-			if got, want := len(results), len(tc.mutations); got != want {
-				t.Fatalf("len(results)=%v, want %v", got, want)
+			rev, err := m.HighestSequencedRevision(ctx, domainID)
+			if err != nil {
+				t.Fatalf("HighestSequencedRevision(): %v", err)
 			}
-			for i := range results {
-				if got, want := results[i], tc.mutations[i]; !proto.Equal(got, want) {
-					t.Errorf("results[%v] data=%v, want %v", i, got, want)
+			if rev != 1 {
+				t.Errorf("HighestSequencedRevision(): %v, want 1", rev)
+			}
+			for rev, want := range map[int64]int{1: len(batch), 2: 0} {
+				saved, err := m.ReadBatch(ctx, domainID, rev)
+				if err != nil {
+					t.Fatalf("ReadBatch(%v): %v", rev, err)
+				}
+				if got := len(saved); got != want {
+					t.Errorf("ReadBatch(%v): %v messages, want %v", rev, got, want)
 				}
 			}
+			stats, err := m.Stats(ctx, domainID)
+			if err != nil {
+				t.Fatalf("Stats(): %v", err)
+			}
+			if got, want := stats.Depth, int64(5-len(batch)); got != want {
+				t.Errorf("Stats().Depth: %v, want %v", got, want)
+			}
 		})
 	}
 }
+
+func TestDeleteRevisions(t *testing.T) {
+	ctx := context.Background()
+	s, done := newMutations(ctx, t)
+	defer done()
+	m := s.(*Mutations)
+	for rev := int64(1); rev <= 4; rev++ {
+		if err := m.WriteBatch(ctx, domainID, rev, []*pb.Entry{genMutation(int(rev))}); err != nil {
+			t.Fatalf("WriteBatch(%v): %v", rev, err)
+		}
+	}
+	if err := m.WriteBatch(ctx, "other", 2, []*pb.Entry{genMutation(5)}); err != nil {
+		t.Fatalf("WriteBatch(): %v", err)
+	}
+	if err := m.DeleteRevisions(ctx, domainID, 2, 3); err != nil {
+		t.Fatalf("DeleteRevisions(): %v", err)
+	}
+	for _, tc := range []struct {
+		domainID string
+		revision int64
+		want     int
+	}{
+		{domainID: domainID, revision: 1, want: 1},
+		{domainID: domainID, revision: 2, want: 0},
+		{domainID: domainID, revision: 3, want: 0},
+		{domainID: domainID, revision: 4, want: 1},
+		{domainID: "other", revision: 2, want: 1},
+	} {
+		msgs, err := m.ReadBatch(ctx, tc.domainID, tc.revision)
+		if err != nil {
+			t.Fatalf("ReadBatch(): %v", err)
+		}
+		if got := len(msgs); got != tc.want {
+			t.Errorf("ReadBatch(%v, %v): %v messages, want %v", tc.domainID, tc.revision, got, tc.want)
+		}
+	}
+}
+
+// TestUpgrade opens tables created and written by the first version of this
+// package, and downgrades and upgrades them again.
+func TestUpgrade(t *testing.T) {
+	db := newDB(t)
+	defer db.Close()
+	// Each connection to :memory: opens a different database.
+	db.SetMaxOpenConns(1)
+	testUpgrade(t, db)
+}
+
+func TestUpgradePostgres(t *testing.T) {
+	pgtest.SkipIfNoPostgres(t)
+	db, done := pgtest.NewDB(t)
+	defer done()
+	testUpgrade(t, db)
+}
+
+func testUpgrade(t *testing.T, db *sql.DB) {
+	ctx := context.Background()
+	d := dialect.Of(db)
+	for _, stmt := range []string{createMutationsV1, createQueueV1} {
+		if _, err := db.Exec(d.Schema(stmt)); err != nil {
+			t.Fatalf("Exec(%v): %v", stmt, err)
+		}
+	}
+	entries := []*pb.Entry{genMutation(1), genMutation(2)}
+	for i, e := range entries {
+		mData, err := proto.Marshal(e)
+		if err != nil {
+			t.Fatalf("proto.Marshal(): %v", err)
+		}
+		if _, err := db.Exec(d.Query(`INSERT INTO Mutations (DomainID, Revision, Sequence, Mutation) VALUES (?, ?, ?, ?);`),
+			domainID, 1, i, mData); err != nil {
+			t.Fatalf("INSERT Mutations: %v", err)
+		}
+	}
+	// The old queue was ordered by send time.
+	updates := []*pb.EntryUpdate{genUpdate(3), genUpdate(4)}
+	sendTimes := []int64{2000, 1000}
+	for i, u := range updates {
+		mData, err := proto.Marshal(u)
+		if err != nil {
+			t.Fatalf("proto.Marshal(): %v", err)
+		}
+		if _, err := db.Exec(d.Query(`INSERT INTO Queue (DomainID, Time, Mutation) VALUES (?, ?, ?);`),
+			domainID, sendTimes[i], mData); err != nil {
+			t.Fatalf("INSERT Queue: %v", err)
+		}
+	}
+
+	m, err := New(db)
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	if got, err := migrate.Version(ctx, db, Schema.Component); err != nil || got != Schema.Latest() {
+		t.Errorf("Version(): %v, %v, want %v", got, err, Schema.Latest())
+	}
+	_, got, err := m.ReadPage(ctx, domainID, 1, 0, 10)
+	if err != nil {
+		t.Fatalf("ReadPage(): %v", err)
+	}
+	if !cmp.Equal(got, entries, cmp.Comparer(proto.Equal)) {
+		t.Errorf("ReadPage(): %v, want %v", got, entries)
+	}
+	if err := m.Send(ctx, domainID, genUpdate(5)); err != nil {
+		t.Fatalf("Send(): %v", err)
+	}
+	now := time.Now()
+	msgs, err := m.claimQueue(ctx, domainID, 10, now, time.Minute)
+	if err != nil {
+		t.Fatalf("claimQueue(): %v", err)
+	}
+	want := []*pb.Entry{genMutation(4), genMutation(3), genMutation(5)}
+	var gotMutations []*pb.Entry
+	for _, msg := range msgs {
+		gotMutations = append(gotMutations, msg.Mutation)
+	}
+	if !cmp.Equal(gotMutations, want, cmp.Comparer(proto.Equal)) {
+		t.Errorf("claimQueue(): %v, want %v", gotMutations, want)
+	}
+	if err := m.releaseMessages(ctx, domainID, msgs, now.Add(time.Minute)); err != nil {
+		t.Fatalf("releaseMessages(): %v", err)
+	}
+
+	// A downgrade to the first version keeps the mutations and the queue.
+	if err := Schema.MigrateTo(ctx, db, 1); err != nil {
+		t.Fatalf("MigrateTo(1): %v", err)
+	}
+	if err := Schema.Up(ctx, db); err != nil {
+		t.Fatalf("Up(): %v", err)
+	}
+	if _, got, err := m.ReadPage(ctx, domainID, 1, 0, 10); err != nil || len(got) != len(entries) {
+		t.Errorf("ReadPage() after downgrade: %v, %v, want %v entries", len(got), err, len(entries))
+	}
+	stats, err := m.Stats(ctx, domainID)
+	if err != nil {
+		t.Fatalf("Stats(): %v", err)
+	}
+	if got, want := stats.Depth, int64(3); got != want {
+		t.Errorf("Stats().Depth after downgrade: %v, want %v", got, want)
+	}
+}
+
+// TestAdoptUnversioned opens the tables of a database that was created
+// before schema versions were recorded.
+func TestAdoptUnversioned(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+	defer db.Close()
+	// Each connection to :memory: opens a different database.
+	db.SetMaxOpenConns(1)
+	if _, err := New(db); err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+	if _, err := db.Exec(`DROP TABLE SchemaVersions;`); err != nil {
+		t.Fatalf("DROP TABLE: %v", err)
+	}
+	if _, err := New(db); err != nil {
+		t.Fatalf("New() on unversioned tables: %v", err)
+	}
+	if got, err := migrate.Version(ctx, db, Schema.Component); err != nil || got != Schema.Latest() {
+		t.Errorf("Version(): %v, %v, want %v", got, err, Schema.Latest())
+	}
+}
@@ -19,9 +19,11 @@ import (
 	"database/sql"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/keytransparency/core/mutator"
+	"github.com/google/keytransparency/core/mutator/entry"
 
 	"github.com/golang/glog"
 	"github.com/golang/protobuf/proto"
@@ -29,28 +31,81 @@ import (
 	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
 )
 
-// Send writes mutations to the leading edge (by sequence number) of the mutations table.
+// defaultClaimTimeout is used when ReceiverOptions.ClaimTimeout is not set.
+const defaultClaimTimeout = 5 * time.Minute
+
+// Send writes mutations to the leading edge (by sequence number) of the queue.
+// Each message is assigned the next ID of domainID's queue.
+// The mutation's status is recorded as QUEUED in the same transaction, so that
+// it can't overwrite the status written once the mutation is sequenced.
 func (m *Mutations) Send(ctx context.Context, domainID string, update *pb.EntryUpdate) error {
 	glog.Infof("queue.Send(%v, <mutation>)", domainID)
 	mData, err := proto.Marshal(update)
 	if err != nil {
 		return err
 	}
-	writeStmt, err := m.db.Prepare(insertQueueExpr)
+	hash, err := entry.Hash(update.GetMutation())
+	if err != nil {
+		return err
+	}
+	return m.inTx(ctx, func(tx *sql.Tx) error {
+		return m.sendTx(ctx, tx, domainID, time.Now(), mData, hash)
+	})
+}
+
+// sendTx adds mData, the mutation identified by hash, to the queue of domainID
+// and records its status as QUEUED.
+func (m *Mutations) sendTx(ctx context.Context, tx *sql.Tx, domainID string, now time.Time, mData, hash []byte) error {
+	id, err := m.nextQueueID(ctx, tx, domainID)
 	if err != nil {
 		return err
 	}
-	defer writeStmt.Close()
-	_, err = writeStmt.ExecContext(ctx, domainID, time.Now().UnixNano(), mData)
+	if _, err := tx.ExecContext(ctx, m.dialect.Query(insertQueueExpr), domainID, id, now.UnixNano(), mData); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, m.dialect.Upsert(writeStatusExpr, "DomainID", "MutationHash"),
+		domainID, hash, pb.MutationStatus_QUEUED, 0, "")
 	return err
 }
 
+// nextQueueID reserves the next message ID of domainID's queue. IDs are never
+// reused, even after messages are removed from the queue.
+func (m *Mutations) nextQueueID(ctx context.Context, tx *sql.Tx, domainID string) (int64, error) {
+	// Incrementing first locks the counter until the transaction ends.
+	result, err := tx.ExecContext(ctx, m.dialect.Query(incQueueIDExpr), domainID)
+	if err != nil {
+		return 0, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if rows == 0 {
+		// This is the first message sent to domainID.
+		if _, err := tx.ExecContext(ctx, m.dialect.Query(insertQueueIDExpr), domainID); err != nil {
+			return 0, err
+		}
+	}
+	var id int64
+	if err := tx.QueryRowContext(ctx, m.dialect.Query(readQueueIDExpr), domainID).Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
 // NewReceiver starts receiving messages sent to the queue. As batches become ready, recieveFunc will be called.
+// The receiver takes over the queue of domainID: batches claimed by earlier
+// receivers are released, so that they are delivered again right away.
 func (m *Mutations) NewReceiver(ctx context.Context, last time.Time, domainID string, recieveFunc mutator.ReceiveFunc, rOpts mutator.ReceiverOptions) mutator.Receiver {
+	if err := m.releaseClaims(ctx, domainID); err != nil {
+		// The claims will expire.
+		glog.Errorf("releaseClaims(%v): %v", domainID, err)
+	}
 	r := &Receiver{
 		store:       m,
 		domainID:    domainID,
 		opts:        rOpts,
+		last:        last.UnixNano(),
 		more:        make(chan time.Time, 1),
 		ticker:      time.NewTicker(rOpts.Period),
 		maxTicker:   time.NewTicker(rOpts.MaxPeriod),
@@ -74,6 +129,13 @@ type Receiver struct {
 	maxTicker   *time.Ticker
 	done        chan interface{}
 	running     sync.WaitGroup
+	// last is the time, in UnixNano, of the latest batch received by
+	// recieveFunc. It is accessed atomically.
+	last int64
+	// blown is set once a blown MMD has been reported, and blownAt holds
+	// the value of last at that time. They are only used by run.
+	blown   bool
+	blownAt int64
 }
 
 // Close stops the receiver and returns only when all callbacks are complete.
@@ -96,9 +158,7 @@ func (r *Receiver) FlushN(ctx context.Context, n int) error {
 func (r *Receiver) run(ctx context.Context, last time.Time) {
 	defer r.running.Done()
 
-	if got, want := time.Since(last), r.opts.MaxPeriod; got > want {
-		glog.Warningf("MMD Blown: Time since last revision: %v, want < %v", got, want)
-	}
+	r.checkMMD(time.Now())
 
 	if time.Since(last) > (r.opts.MaxPeriod - r.opts.Period) {
 		r.sendBatch(ctx, 0, r.opts.MaxBatchSize) // We will be overdue for an epoch soon.
@@ -118,6 +178,7 @@ func (r *Receiver) run(ctx context.Context, last time.Time) {
 		case <-r.done:
 			return
 		}
+		r.checkMMD(time.Now())
 		if count >= r.opts.MaxBatchSize {
 			// Continue sending until we drop below batch size.
 			r.more <- time.Now()
@@ -128,25 +189,31 @@ func (r *Receiver) run(ctx context.Context, last time.Time) {
 // sendBatch sends up to batchSize items to the receiver. Returns the number of sent items.
 // If the number of available items is < minBatch, 0 items are sent.
 // If the number of available items is > maxBatch only maxBatch items are sent.
+//
+// The items are claimed while receiveFunc runs. They are removed from the
+// queue if it succeeds and released for redelivery otherwise.
 func (r *Receiver) sendBatch(ctx context.Context, minBatch, maxBatch int32) int32 {
-	ms, err := r.store.readQueue(ctx, r.domainID, maxBatch)
+	now, timeout := time.Now(), r.claimTimeout()
+	ms, err := r.store.claimQueue(ctx, r.domainID, maxBatch, now, timeout)
 	if err != nil {
-		glog.Errorf("readQueue(): %v", err)
+		glog.Errorf("claimQueue(): %v", err)
 		return 0
 	}
+	expiry := now.Add(timeout)
 	if int32(len(ms)) < minBatch {
+		r.release(ctx, ms, expiry)
 		return 0
 	}
 
 	if err := r.recieveFunc(ms); err != nil {
 		glog.Infof("queue.SendBatch failed: %v", err)
+		r.release(ctx, ms, expiry)
 		return 0
 	}
-	// TODO(gbelvin): Do we need finer grained errors?
-	// We could put an ack'ed field in a QueueMessage object.
-	// But I don't think we need that level of granularity -- yet?
 
-	// Delete old messages.
+	atomic.StoreInt64(&r.last, time.Now().UnixNano())
+
+	// Acknowledge the batch by deleting it.
 	if err := r.store.deleteMessages(ctx, r.domainID, ms); err != nil {
 		glog.Errorf("deleteQueueMessages(%v, len(ms): %v): %v", r.domainID, len(ms), err)
 	}
@@ -154,35 +221,116 @@ func (r *Receiver) sendBatch(ctx context.Context, minBatch, maxBatch int32) int3
 	return int32(len(ms))
 }
 
-// readQueue reads all mutations that are still in the queue up to batchSize.
-func (m *Mutations) readQueue(ctx context.Context, domainID string, batchSize int32) ([]*mutator.QueueMessage, error) {
-	readStmt, err := m.db.Prepare(readQueueExpr)
+// checkMMD reports a violation of the maximum merge delay to MMDBlown if no
+// batch has been received for longer than MaxPeriod at now. Each late batch is
+// reported once. Returns true if a violation was reported.
+func (r *Receiver) checkMMD(now time.Time) bool {
+	last := atomic.LoadInt64(&r.last)
+	if r.blown && r.blownAt == last {
+		return false
+	}
+	got, want := now.Sub(time.Unix(0, last)), r.opts.MaxPeriod
+	if got <= want {
+		return false
+	}
+	glog.Warningf("MMD Blown: Time since last revision of domain %v: %v, want < %v", r.domainID, got, want)
+	r.blown, r.blownAt = true, last
+	if r.opts.MMDBlown != nil {
+		r.opts.MMDBlown(time.Unix(0, last))
+	}
+	return true
+}
+
+func (r *Receiver) claimTimeout() time.Duration {
+	if r.opts.ClaimTimeout > 0 {
+		return r.opts.ClaimTimeout
+	}
+	return defaultClaimTimeout
+}
+
+// release makes ms, claimed until expiry, available for delivery again.
+func (r *Receiver) release(ctx context.Context, ms []*mutator.QueueMessage, expiry time.Time) {
+	if err := r.store.releaseMessages(ctx, r.domainID, ms, expiry); err != nil {
+		// The claim will expire.
+		glog.Errorf("releaseMessages(%v, len(ms): %v): %v", r.domainID, len(ms), err)
+	}
+}
+
+// Stats returns the number of mutations in the queue of domainID and the time
+// the oldest of them was sent.
+func (m *Mutations) Stats(ctx context.Context, domainID string) (*mutator.QueueStats, error) {
+	var depth int64
+	var oldest sql.NullInt64
+	if err := m.db.QueryRowContext(ctx, m.dialect.Query(queueStatsExpr), domainID).Scan(&depth, &oldest); err != nil {
+		return nil, err
+	}
+	stats := &mutator.QueueStats{Depth: depth}
+	if oldest.Valid {
+		stats.Oldest = time.Unix(0, oldest.Int64)
+	}
+	return stats, nil
+}
+
+// claimQueue claims up to batchSize messages from the head of the queue until
+// now+timeout. Messages are claimed in ID order, so if the head of the queue is
+// claimed by another receiver, no messages are returned.
+func (m *Mutations) claimQueue(ctx context.Context, domainID string, batchSize int32, now time.Time, timeout time.Duration) ([]*mutator.QueueMessage, error) {
+	var ms []*mutator.QueueMessage
+	err := m.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		ms, err = m.claimQueueTx(ctx, tx, domainID, batchSize, now, timeout)
+		return err
+	})
+	return ms, err
+}
+
+func (m *Mutations) claimQueueTx(ctx context.Context, tx *sql.Tx, domainID string, batchSize int32, now time.Time, timeout time.Duration) ([]*mutator.QueueMessage, error) {
+	rows, err := tx.QueryContext(ctx, m.dialect.Query(readQueueExpr), domainID, batchSize)
+	if err != nil {
+		return nil, err
+	}
+	ms, err := readQueueMessages(rows, now)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+	if len(ms) == 0 {
+		return ms, nil
+	}
+	first, last := ms[0].ID, ms[len(ms)-1].ID
+	result, err := tx.ExecContext(ctx, m.dialect.Query(claimQueueExpr),
+		now.Add(timeout).UnixNano(), domainID, first, last, now.UnixNano())
 	if err != nil {
 		return nil, err
 	}
-	defer readStmt.Close()
-	rows, err := readStmt.QueryContext(ctx, domainID, batchSize)
+	claimed, err := result.RowsAffected()
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-	return readQueueMessages(rows)
+	if claimed != int64(len(ms)) {
+		return nil, fmt.Errorf("claimed %v of %v messages, the queue of %v is in use", claimed, len(ms), domainID)
+	}
+	return ms, nil
 }
 
-func readQueueMessages(rows *sql.Rows) ([]*mutator.QueueMessage, error) {
+// readQueueMessages reads messages up to the first message that is claimed at now.
+func readQueueMessages(rows *sql.Rows, now time.Time) ([]*mutator.QueueMessage, error) {
 	results := make([]*mutator.QueueMessage, 0)
 	for rows.Next() {
-		var timestamp int64
+		var id, claimExpiry int64
 		var mData []byte
-		if err := rows.Scan(&timestamp, &mData); err != nil {
+		if err := rows.Scan(&id, &mData, &claimExpiry); err != nil {
 			return nil, err
 		}
+		if claimExpiry > now.UnixNano() {
+			break
+		}
 		entryUpdate := new(pb.EntryUpdate)
 		if err := proto.Unmarshal(mData, entryUpdate); err != nil {
 			return nil, err
 		}
 		results = append(results, &mutator.QueueMessage{
-			ID:        timestamp,
+			ID:        id,
 			Mutation:  entryUpdate.Mutation,
 			ExtraData: entryUpdate.Committed,
 		})
@@ -193,20 +341,32 @@ func readQueueMessages(rows *sql.Rows) ([]*mutator.QueueMessage, error) {
 	return results, nil
 }
 
-func (m *Mutations) deleteMessages(ctx context.Context, domainID string, mutations []*mutator.QueueMessage) error {
-	glog.V(4).Infof("queue.Delete(%v, <mutation>)", domainID)
-	delStmt, err := m.db.Prepare(deleteQueueExpr)
-	if err != nil {
-		return err
+// releaseMessages removes the claim on a batch returned by claimQueue.
+// Messages are only released while they are still claimed until expiry, so
+// that a receiver whose claim expired can't release the claim of another.
+func (m *Mutations) releaseMessages(ctx context.Context, domainID string, mutations []*mutator.QueueMessage, expiry time.Time) error {
+	if len(mutations) == 0 {
+		return nil
 	}
-	defer delStmt.Close()
-	var retErr error
-	for _, mutation := range mutations {
-		if _, err = delStmt.ExecContext(ctx, domainID, mutation.ID); err != nil {
-			// If an error occurs, take note, but continue deleting
-			// the other referenced mutations.
-			retErr = err
-		}
+	_, err := m.db.ExecContext(ctx, m.dialect.Query(releaseQueueExpr),
+		domainID, mutations[0].ID, mutations[len(mutations)-1].ID, expiry.UnixNano())
+	return err
+}
+
+// releaseClaims removes every claim on the queue of domainID.
+func (m *Mutations) releaseClaims(ctx context.Context, domainID string) error {
+	_, err := m.db.ExecContext(ctx, m.dialect.Query(releaseClaimsExpr), domainID)
+	return err
+}
+
+// deleteMessages deletes a batch returned by claimQueue. The batch holds every
+// message between its first and last ID, so it is deleted as a range.
+func (m *Mutations) deleteMessages(ctx context.Context, domainID string, mutations []*mutator.QueueMessage) error {
+	glog.V(4).Infof("queue.Delete(%v, <%v mutations>)", domainID, len(mutations))
+	if len(mutations) == 0 {
+		return nil
 	}
-	return retErr
+	_, err := m.db.ExecContext(ctx, m.dialect.Query(deleteQueueExpr),
+		domainID, mutations[0].ID, mutations[len(mutations)-1].ID)
+	return err
 }
@@ -17,18 +17,36 @@ package mutationstorage
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/google/keytransparency/core/mutator"
-
-	"github.com/golang/protobuf/proto"
+	"github.com/google/keytransparency/core/storage/storagetest"
+	"github.com/google/keytransparency/impl/sql/pgtest"
 
 	pb "github.com/google/keytransparency/core/api/v1/keytransparency_go_proto"
 	_ "github.com/mattn/go-sqlite3"
 )
 
+func newQueue(ctx context.Context, t *testing.T) (mutator.MutationQueue, mutator.MutationStorage, func()) {
+	m, done := newMutations(ctx, t)
+	return m.(*Mutations), m, done
+}
+
+func TestMutationQueue(t *testing.T) {
+	storagetest.MutationQueueTester{NewQueue: newQueue}.RunAllTests(t)
+}
+
+func TestMutationQueuePostgres(t *testing.T) {
+	pgtest.SkipIfNoPostgres(t)
+	storagetest.MutationQueueTester{NewQueue: func(ctx context.Context, t *testing.T) (mutator.MutationQueue, mutator.MutationStorage, func()) {
+		m, done := newPostgresMutations(ctx, t)
+		return m.(*Mutations), m, done
+	}}.RunAllTests(t)
+}
+
 func TestRecieverChan(t *testing.T) {
 	ctx := context.Background()
 	m, err := New(newDB(t))
@@ -117,74 +135,211 @@ func fillQueue(ctx context.Context, m mutator.MutationQueue) error {
 	return nil
 }
 
-func TestQueueSendBatch(t *testing.T) {
+func TestSendAssignsIDs(t *testing.T) {
 	ctx := context.Background()
 	db := newDB(t)
+	// Each connection to :memory: opens a different database.
+	db.SetMaxOpenConns(1)
 	m, err := New(db)
 	if err != nil {
 		t.Fatalf("Failed to create mutations: %v", err)
 	}
 
+	const sends = 20
+	var wg sync.WaitGroup
+	for i := 0; i < sends; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := m.Send(ctx, domainID, genUpdate(i)); err != nil {
+				t.Errorf("Send(%v): %v", i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if err := m.Send(ctx, "other", genUpdate(0)); err != nil {
+		t.Fatalf("Send(other): %v", err)
+	}
+
+	ms, err := m.claimQueue(ctx, domainID, 2*sends, time.Now(), time.Minute)
+	if err != nil {
+		t.Fatalf("claimQueue(): %v", err)
+	}
+	if got, want := len(ms), sends; got != want {
+		t.Fatalf("claimQueue(): %v messages, want %v", got, want)
+	}
+	for i, msg := range ms {
+		if got, want := msg.ID, int64(i+1); got != want {
+			t.Errorf("msg[%v].ID: %v, want %v", i, got, want)
+		}
+	}
+	// IDs are not reused once messages are deleted.
+	if err := m.deleteMessages(ctx, domainID, ms); err != nil {
+		t.Fatalf("deleteMessages(): %v", err)
+	}
+	if err := m.Send(ctx, domainID, genUpdate(0)); err != nil {
+		t.Fatalf("Send(): %v", err)
+	}
+	ms, err = m.claimQueue(ctx, domainID, 10, time.Now(), time.Minute)
+	if err != nil {
+		t.Fatalf("claimQueue(): %v", err)
+	}
+	if len(ms) != 1 || ms[0].ID != sends+1 {
+		t.Errorf("claimQueue(): %v, want one message with ID %v", ms, sends+1)
+	}
+}
+
+func TestClaimQueue(t *testing.T) {
+	ctx := context.Background()
+	m, err := New(newDB(t))
+	if err != nil {
+		t.Fatalf("Failed to create mutations: %v", err)
+	}
 	if err := fillQueue(ctx, m); err != nil {
 		t.Fatalf("Failed to write updates: %v", err)
 	}
+	now := time.Now()
+	timeout := time.Minute
 
 	for _, tc := range []struct {
-		description string
-		updates     []*pb.EntryUpdate
-		batchSize   int32
+		desc    string
+		before  func() error
+		at      time.Time
+		wantIDs []int64
 	}{
-		{
-			description: "read half",
-			updates: []*pb.EntryUpdate{
-				genUpdate(1),
-				genUpdate(2),
-				genUpdate(3),
-			},
-			batchSize: 3,
-		},
-		{
-			description: "read rest",
-			updates: []*pb.EntryUpdate{
-				genUpdate(4),
-				genUpdate(5),
-			},
-			batchSize: 3,
-		},
-		{
-			description: "empty queue",
-			updates:     nil,
-			batchSize:   10,
-		},
+		{desc: "claim head", at: now, wantIDs: []int64{1, 2, 3}},
+		{desc: "head claimed", at: now, wantIDs: []int64{}},
+		{desc: "claim expired", at: now.Add(2 * timeout), wantIDs: []int64{1, 2, 3}},
+		{desc: "released by expired claim", at: now.Add(2 * timeout), wantIDs: []int64{}, before: func() error {
+			return m.releaseMessages(ctx, domainID, []*mutator.QueueMessage{{ID: 1}, {ID: 3}}, now.Add(timeout))
+		}},
+		{desc: "released", at: now.Add(2 * timeout), wantIDs: []int64{1, 2, 3}, before: func() error {
+			return m.releaseMessages(ctx, domainID, []*mutator.QueueMessage{{ID: 1}, {ID: 3}}, now.Add(3*timeout))
+		}},
+		{desc: "taken over", at: now.Add(2 * timeout), wantIDs: []int64{1, 2, 3}, before: func() error {
+			return m.releaseClaims(ctx, domainID)
+		}},
+		{desc: "acknowledged", at: now.Add(2 * timeout), wantIDs: []int64{4, 5}, before: func() error {
+			return m.deleteMessages(ctx, domainID, []*mutator.QueueMessage{{ID: 1}, {ID: 3}})
+		}},
 	} {
-		wg := new(sync.WaitGroup)
-		wg.Add(1)
-		t.Run(tc.description, func(t *testing.T) {
-			r, ok := m.NewReceiver(ctx, time.Now(), domainID, func(msgs []*mutator.QueueMessage) error {
-				if got, want := len(msgs), len(tc.updates); got != want {
-					t.Errorf("len(msgs): %v, want %v", got, want)
-				}
-				for i, msg := range msgs {
-					if got, want := msg.Mutation, tc.updates[i].Mutation; !proto.Equal(got, want) {
-						t.Errorf("msg[%v].Mutation: %v, want %v", i, got, want)
-					}
-					if got, want := msg.ExtraData, tc.updates[i].Committed; !proto.Equal(got, want) {
-						t.Errorf("msg[%v].ExtraData: %v, want %v", i, got, want)
-					}
+		t.Run(tc.desc, func(t *testing.T) {
+			if tc.before != nil {
+				if err := tc.before(); err != nil {
+					t.Fatalf("before(): %v", err)
 				}
-				wg.Done()
-				return nil
-			}, mutator.ReceiverOptions{
-				MaxBatchSize: tc.batchSize,
-				// With Period=MaxPeriod, one batch will be force-sent.
-				Period:    60 * time.Hour,
-				MaxPeriod: 60 * time.Hour,
-			}).(*Receiver)
-			if !ok {
-				t.Fatalf("Failed type assertion: %T", r)
 			}
-			wg.Wait()
-			r.Close()
+			ms, err := m.claimQueue(ctx, domainID, 3, tc.at, timeout)
+			if err != nil {
+				t.Fatalf("claimQueue(): %v", err)
+			}
+			ids := make([]int64, 0, len(ms))
+			for _, msg := range ms {
+				ids = append(ids, msg.ID)
+			}
+			if !reflect.DeepEqual(ids, tc.wantIDs) {
+				t.Errorf("claimQueue(): IDs %v, want %v", ids, tc.wantIDs)
+			}
 		})
 	}
 }
+
+func TestReceiverRedelivers(t *testing.T) {
+	ctx := context.Background()
+	m, err := New(newDB(t))
+	if err != nil {
+		t.Fatalf("Failed to create mutations: %v", err)
+	}
+	if err := fillQueue(ctx, m); err != nil {
+		t.Fatalf("Failed to write updates: %v", err)
+	}
+
+	var got [][]int64
+	fail := true
+	r := &Receiver{
+		store:    m,
+		domainID: domainID,
+		opts:     mutator.ReceiverOptions{MaxBatchSize: 2},
+		recieveFunc: func(ms []*mutator.QueueMessage) error {
+			ids := make([]int64, 0, len(ms))
+			for _, msg := range ms {
+				ids = append(ids, msg.ID)
+			}
+			got = append(got, ids)
+			if fail {
+				return fmt.Errorf("receiveFunc failed")
+			}
+			return nil
+		},
+	}
+	if n := r.sendBatch(ctx, 1, 2); n != 0 {
+		t.Errorf("sendBatch(): %v, want 0 after failure", n)
+	}
+	fail = false
+	if n := r.sendBatch(ctx, 1, 2); n != 2 {
+		t.Errorf("sendBatch(): %v, want 2", n)
+	}
+	if n := r.sendBatch(ctx, 1, 2); n != 2 {
+		t.Errorf("sendBatch(): %v, want 2", n)
+	}
+	if want := [][]int64{{1, 2}, {1, 2}, {3, 4}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("delivered batches: %v, want %v", got, want)
+	}
+}
+
+func TestNewReceiverReleasesClaims(t *testing.T) {
+	ctx := context.Background()
+	m, err := New(newDB(t))
+	if err != nil {
+		t.Fatalf("Failed to create mutations: %v", err)
+	}
+	if err := fillQueue(ctx, m); err != nil {
+		t.Fatalf("Failed to write updates: %v", err)
+	}
+	// A previous receiver stopped while it held a claim on the head of the
+	// queue.
+	if _, err := m.claimQueue(ctx, domainID, 3, time.Now(), time.Hour); err != nil {
+		t.Fatalf("claimQueue(): %v", err)
+	}
+	r := m.NewReceiver(ctx, time.Now(), domainID, func([]*mutator.QueueMessage) error { return nil },
+		mutator.ReceiverOptions{MaxBatchSize: 10, Period: time.Hour, MaxPeriod: 2 * time.Hour})
+	defer r.Close()
+	if err := r.FlushN(ctx, 5); err != nil {
+		t.Errorf("FlushN(5): %v", err)
+	}
+}
+
+func TestCheckMMD(t *testing.T) {
+	last := time.Unix(1000, 0)
+	var blown []time.Time
+	r := &Receiver{
+		domainID: domainID,
+		opts: mutator.ReceiverOptions{
+			MaxPeriod: time.Minute,
+			MMDBlown:  func(last time.Time) { blown = append(blown, last) },
+		},
+		last: last.UnixNano(),
+	}
+	for _, tc := range []struct {
+		desc     string
+		received time.Time // Time of a new batch, if not zero.
+		now      time.Time
+		want     bool
+	}{
+		{desc: "on time", now: last.Add(time.Minute)},
+		{desc: "late", now: last.Add(time.Minute + time.Second), want: true},
+		{desc: "still late", now: last.Add(time.Hour)},
+		{desc: "caught up", received: last.Add(2 * time.Hour), now: last.Add(2 * time.Hour)},
+		{desc: "late again", now: last.Add(3 * time.Hour), want: true},
+	} {
+		if !tc.received.IsZero() {
+			r.last = tc.received.UnixNano()
+		}
+		if got := r.checkMMD(tc.now); got != tc.want {
+			t.Errorf("%v: checkMMD(): %v, want %v", tc.desc, got, tc.want)
+		}
+	}
+	if got, want := blown, []time.Time{last, last.Add(2 * time.Hour)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MMDBlown calls: %v, want %v", got, want)
+	}
+}
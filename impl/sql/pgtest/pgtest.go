@@ -0,0 +1,79 @@
+// Copyright 2018 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pgtest provides tests with databases on a local PostgreSQL server.
+package pgtest
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq" // Register the postgres driver.
+)
+
+var pgURI = flag.String("pg_uri", "postgres://postgres@localhost/postgres?sslmode=disable",
+	"URI of the PostgreSQL server used by storage tests")
+
+// SkipIfNoPostgres skips t if the server at --pg_uri is not reachable.
+func SkipIfNoPostgres(t *testing.T) {
+	t.Helper()
+	db, err := sql.Open("postgres", *pgURI)
+	if err != nil {
+		t.Skipf("PostgreSQL not available, skipping test: %v", err)
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		t.Skipf("PostgreSQL not available, skipping test: %v", err)
+	}
+}
+
+// NewDB returns a connection to a new, empty schema on the server at --pg_uri,
+// and a function that drops the schema.
+func NewDB(t *testing.T) (*sql.DB, func()) {
+	t.Helper()
+	admin, err := sql.Open("postgres", *pgURI)
+	if err != nil {
+		t.Fatalf("sql.Open(): %v", err)
+	}
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	schema := fmt.Sprintf("kt_test_%d", r.Int63())
+	if _, err := admin.Exec(fmt.Sprintf("CREATE SCHEMA %s;", schema)); err != nil { // nolint: gas
+		admin.Close()
+		t.Fatalf("CREATE SCHEMA: %v", err)
+	}
+
+	u, err := url.Parse(*pgURI)
+	if err != nil {
+		t.Fatalf("url.Parse(%v): %v", *pgURI, err)
+	}
+	q := u.Query()
+	q.Set("search_path", schema)
+	u.RawQuery = q.Encode()
+	db, err := sql.Open("postgres", u.String())
+	if err != nil {
+		t.Fatalf("sql.Open(): %v", err)
+	}
+	return db, func() {
+		db.Close()
+		if _, err := admin.Exec(fmt.Sprintf("DROP SCHEMA %s CASCADE;", schema)); err != nil { // nolint: gas
+			t.Errorf("DROP SCHEMA: %v", err)
+		}
+		admin.Close()
+	}
+}